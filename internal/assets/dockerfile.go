@@ -9,6 +9,7 @@ import (
 	"io/fs"
 	"os"
 	"path/filepath"
+	"regexp"
 )
 
 // We embed a copy of the repository's Dockerfile (kept in this package
@@ -27,6 +28,23 @@ func EmbeddedDockerfileSHA256() string {
 	return hex.EncodeToString(sum[:])
 }
 
+var runitServiceRe = regexp.MustCompile(`/etc/service/([A-Za-z0-9_.-]+)/run\b`)
+
+// RunitServices returns the names of the runit services the embedded
+// Dockerfile installs, in the order they are defined.
+func RunitServices() []string {
+	var names []string
+	seen := map[string]bool{}
+	for _, m := range runitServiceRe.FindAllSubmatch(embeddedDockerfile, -1) {
+		name := string(m[1])
+		if !seen[name] {
+			seen[name] = true
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
 // ResolveDockerfile determines which Dockerfile to use and ensures it exists.
 // Priority:
 // 1) Environment variable DV_DOCKERFILE points to an existing file
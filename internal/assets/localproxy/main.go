@@ -20,21 +20,33 @@ import (
 type route struct {
 	Host   string `json:"host"`
 	Target string `json:"target"`
+	// LastRequest is when the route last proxied a request, so dv can tell
+	// whether anyone is still using an agent.
+	LastRequest *time.Time `json:"lastRequest,omitempty"`
 }
 
 type proxyTable struct {
-	mu      sync.RWMutex
-	routes  map[string]*url.URL
-	proxies map[string]*httputil.ReverseProxy
+	mu       sync.RWMutex
+	routes   map[string]*url.URL
+	proxies  map[string]*httputil.ReverseProxy
+	seenMu   sync.Mutex
+	lastSeen map[string]time.Time
 }
 
 func newProxyTable() *proxyTable {
 	return &proxyTable{
-		routes:  map[string]*url.URL{},
-		proxies: map[string]*httputil.ReverseProxy{},
+		routes:   map[string]*url.URL{},
+		proxies:  map[string]*httputil.ReverseProxy{},
+		lastSeen: map[string]time.Time{},
 	}
 }
 
+func (p *proxyTable) touch(host string) {
+	p.seenMu.Lock()
+	defer p.seenMu.Unlock()
+	p.lastSeen[host] = time.Now().UTC()
+}
+
 func (p *proxyTable) set(host string, target *url.URL) {
 	p.mu.Lock()
 	defer p.mu.Unlock()
@@ -61,12 +73,18 @@ func (p *proxyTable) list() []route {
 		hosts = append(hosts, h)
 	}
 	sort.Strings(hosts)
+	p.seenMu.Lock()
+	defer p.seenMu.Unlock()
 	out := make([]route, 0, len(hosts))
 	for _, h := range hosts {
-		out = append(out, route{
+		r := route{
 			Host:   h,
 			Target: p.routes[h].String(),
-		})
+		}
+		if seen, ok := p.lastSeen[h]; ok {
+			r.LastRequest = &seen
+		}
+		out = append(out, r)
 	}
 	return out
 }
@@ -88,6 +106,7 @@ func (p *proxyTable) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "no route for host", http.StatusBadGateway)
 		return
 	}
+	p.touch(host)
 	proxy.ServeHTTP(w, r)
 }
 
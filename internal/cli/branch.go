@@ -50,7 +50,7 @@ var branchCmd = &cobra.Command{
 		if err != nil {
 			return err
 		}
-		cfg, err := config.LoadOrCreate(configDir)
+		cfg, err := loadConfig(configDir)
 		if err != nil {
 			return err
 		}
@@ -0,0 +1,58 @@
+package cli
+
+import (
+	"strings"
+	"testing"
+
+	"dv/internal/config"
+	"dv/internal/docker/dockertest"
+)
+
+func TestBranchChecksOutAndResets(t *testing.T) {
+	env := newTestEnv(t)
+	env.rt.AddContainer(dockertest.Container{Name: "feature", Image: "ai_agent", Workdir: "/var/www/discourse"})
+
+	out := env.mustRun("branch", "--name", "feature", "my-branch")
+	if !strings.Contains(out, "Starting container 'feature'") || !env.rt.Running("feature") {
+		t.Errorf("stopped container was not started:\n%s", out)
+	}
+
+	var script *dockertest.Exec
+	for _, e := range env.rt.Execs() {
+		if e.Interactive {
+			script = &e
+		}
+	}
+	if script == nil {
+		t.Fatal("no interactive exec recorded")
+	}
+	if script.Workdir != "/var/www/discourse" {
+		t.Errorf("workdir = %q", script.Workdir)
+	}
+	cmdline := script.Command()
+	for _, want := range []string{"git reset --hard", "git checkout my-branch", "bin/rake db:migrate", "trap cleanup EXIT"} {
+		if !strings.Contains(cmdline, want) {
+			t.Errorf("script missing %q", want)
+		}
+	}
+	if strings.Index(cmdline, "git checkout my-branch") > strings.Index(cmdline, "bin/rake db:create") {
+		t.Error("databases are reset before the branch is checked out")
+	}
+}
+
+func TestBranchRejectsNonDiscourseImage(t *testing.T) {
+	env := newTestEnv(t)
+	cfg := env.config()
+	cfg.Images["plain"] = config.ImageConfig{Kind: "custom", Tag: "plain", Workdir: "/app"}
+	cfg.ContainerImages = map[string]string{"feature": "plain"}
+	env.saveConfig(cfg)
+	env.rt.AddContainer(dockertest.Container{Name: "feature", Image: "plain", Workdir: "/app", Running: true})
+
+	_, err := env.run("branch", "--name", "feature", "my-branch")
+	if err == nil || !strings.Contains(err.Error(), "only supported for discourse") {
+		t.Fatalf("err = %v", err)
+	}
+	if n := len(env.rt.Execs()); n != 0 {
+		t.Errorf("ran %d exec(s) in a non-discourse agent", n)
+	}
+}
@@ -35,7 +35,7 @@ var buildCmd = &cobra.Command{
 
 		// Try to load configured images; ignore errors for completion purposes
 		if configDir, err := xdg.ConfigDir(); err == nil {
-			if cfg, err := config.LoadOrCreate(configDir); err == nil {
+			if cfg, err := loadConfig(configDir); err == nil {
 				for name := range cfg.Images {
 					if name != "discourse" {
 						suggestions = append(suggestions, name)
@@ -61,7 +61,7 @@ var buildCmd = &cobra.Command{
 		if err != nil {
 			return err
 		}
-		cfg, err := config.LoadOrCreate(configDir)
+		cfg, err := loadConfig(configDir)
 		if err != nil {
 			return err
 		}
@@ -80,6 +80,8 @@ var buildCmd = &cobra.Command{
 		for _, kv := range buildArgs {
 			pass = append(pass, "--build-arg", kv)
 		}
+		// Label dv builds so dv gc can find the images a rebuild supersedes.
+		pass = append(pass, "--label", "com.dv.owner=dv")
 
 		if removeExisting && docker.Exists(cfg.DefaultContainer) {
 			fmt.Fprintf(cmd.OutOrStdout(), "Removing existing container %s...\n", cfg.DefaultContainer)
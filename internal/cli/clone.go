@@ -0,0 +1,95 @@
+package cli
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"dv/internal/config"
+	"dv/internal/docker"
+	"dv/internal/session"
+	"dv/internal/xdg"
+)
+
+var cloneCmd = &cobra.Command{
+	Use:   "clone SRC DST",
+	Short: "Duplicate an agent, including its files and database, and select the copy",
+	Args:  cobra.ExactArgs(2),
+	ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		if len(args) == 0 {
+			return completeAgentNames(cmd, toComplete)
+		}
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	},
+	RunE: func(cmd *cobra.Command, args []string) (err error) {
+		src := strings.TrimSpace(args[0])
+		dst := strings.TrimSpace(args[1])
+		if src == "" || dst == "" {
+			return fmt.Errorf("invalid names")
+		}
+		configDir, err := xdg.ConfigDir()
+		if err != nil {
+			return err
+		}
+		cfg, err := loadConfig(configDir)
+		if err != nil {
+			return err
+		}
+		if !docker.Exists(src) {
+			return fmt.Errorf("agent '%s' does not exist", src)
+		}
+		if docker.Exists(dst) {
+			return fmt.Errorf("an agent named '%s' already exists", dst)
+		}
+		imgName, imgCfg, err := resolveImage(cfg, cfg.ContainerImages[src])
+		if err != nil {
+			return err
+		}
+
+		// The clone keeps its own reference to the image layers, so the
+		// temporary tag is dropped once the container exists.
+		tempImage := "dv-clone:" + dst
+		pgData, err := commitAgentState(cmd, src, tempImage, map[string]string{})
+		if err != nil {
+			return err
+		}
+		defer func() { _ = docker.RemoveImageQuiet(tempImage) }()
+
+		if limits, ok := cfg.AgentLimits[src]; ok {
+			setAgentLimits(&cfg, dst, limits)
+		}
+		fmt.Fprintf(cmd.OutOrStdout(), "Creating agent '%s' from '%s'...\n", dst, src)
+		workdir := agentWorkdir(cfg, src, imgCfg)
+		proxyLabels, proxyEnvs := clearedProxyMetadata()
+		if err := ensureContainerRunningWithWorkdir(cmd, cfg, dst, workdir, tempImage, imgName, false, "", proxyEnvs, proxyLabels); err != nil {
+			return err
+		}
+		defer func() {
+			if err != nil {
+				fmt.Fprintf(cmd.ErrOrStderr(), "Clone failed, removing '%s'...\n", dst)
+				_ = docker.RemoveForce(dst)
+			}
+		}()
+		if err := restoreAgentState(cmd, dst, pgData); err != nil {
+			return err
+		}
+
+		if cfg.ContainerImages == nil {
+			cfg.ContainerImages = map[string]string{}
+		}
+		cfg.ContainerImages[dst] = imgName
+		if w, ok := cfg.CustomWorkdirs[src]; ok {
+			cfg.CustomWorkdirs[dst] = w
+		}
+		cfg.SelectedAgent = dst
+		if err := session.SetCurrentAgent(dst); err != nil {
+			fmt.Fprintf(cmd.ErrOrStderr(), "Warning: could not save session state: %v\n", err)
+		}
+		if err := config.Save(configDir, cfg); err != nil {
+			return err
+		}
+		fmt.Fprintf(cmd.OutOrStdout(), "Agent '%s' cloned from '%s' and selected.\n", dst, src)
+		return nil
+	},
+}
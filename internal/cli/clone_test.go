@@ -0,0 +1,101 @@
+package cli
+
+import (
+	"strings"
+	"testing"
+
+	"dv/internal/docker/dockertest"
+	"dv/internal/localproxy"
+)
+
+func TestCloneCopiesStateAndConfig(t *testing.T) {
+	env := newTestEnv(t)
+	env.rt.AddImage("ai_agent", nil)
+	env.mustRun("new", "src", "--memory", "2g")
+	env.mustRun("config", "workdir", "/var/www/discourse/plugins/chat", "--container", "src")
+	if err := env.rt.WriteFile("src", "/var/www/discourse/notes.md", []byte("approach A")); err != nil {
+		t.Fatal(err)
+	}
+	env.rt.SetLabels("src", map[string]string{"com.example.team": "search"})
+	env.rt.HandleExec("SHOW data_directory", func(e dockertest.Exec) (string, error) {
+		return "/shared/postgres_data\n", nil
+	})
+
+	out := env.mustRun("clone", "src", "dst")
+	if !strings.Contains(out, "Agent 'dst' cloned from 'src' and selected.") {
+		t.Fatalf("clone output:\n%s", out)
+	}
+	src, _ := env.rt.Container("src")
+	dst, ok := env.rt.Container("dst")
+	if !ok {
+		t.Fatal("clone not created")
+	}
+	if data, _ := env.rt.ReadFile("dst", "/var/www/discourse/notes.md"); string(data) != "approach A" {
+		t.Errorf("cloned file = %q", data)
+	}
+	if dst.RunOptions.HostPort == src.RunOptions.HostPort {
+		t.Errorf("clone reused host port %d", dst.RunOptions.HostPort)
+	}
+	if dst.Labels["com.example.team"] != "search" {
+		t.Errorf("clone labels = %+v", dst.Labels)
+	}
+	if dst.RunOptions.Memory != "2g" || dst.Workdir != "/var/www/discourse/plugins/chat" {
+		t.Errorf("clone run options = %+v", dst.RunOptions)
+	}
+	if env.rt.ImageExists("dv-clone:dst") {
+		t.Error("temporary clone image left behind")
+	}
+
+	cfg := env.config()
+	if cfg.ContainerImages["dst"] != "discourse" || cfg.CustomWorkdirs["dst"] != "/var/www/discourse/plugins/chat" ||
+		cfg.AgentLimits["dst"].Memory != "2g" || cfg.SelectedAgent != "dst" {
+		t.Errorf("config after clone: %+v", cfg)
+	}
+
+	if _, err := env.run("clone", "src", "dst"); err == nil {
+		t.Error("cloning onto an existing agent succeeded")
+	}
+}
+
+func TestCloneDropsSourceProxyRoute(t *testing.T) {
+	env := newTestEnv(t)
+	cfg := env.config()
+	cfg.LocalProxy.Enabled = true
+	env.saveConfig(cfg)
+	env.rt.AddContainer(dockertest.Container{
+		Name:    "src",
+		Image:   "ai_agent",
+		Workdir: "/var/www/discourse",
+		Running: true,
+		Labels: map[string]string{
+			"com.dv.owner":                "dv",
+			localproxy.LabelEnabled:       "true",
+			localproxy.LabelHost:          "src.dv.localhost",
+			localproxy.LabelTargetPort:    "4200",
+			localproxy.LabelContainerPort: "4200",
+			localproxy.LabelHTTPPort:      "80",
+		},
+		Env: map[string]string{
+			"DISCOURSE_HOSTNAME":      "src.dv.localhost",
+			"RAILS_DEVELOPMENT_HOSTS": "src.dv.localhost",
+			"DV_LOCAL_PROXY_HOST":     "src.dv.localhost",
+		},
+	})
+
+	env.mustRun("clone", "src", "dst")
+	dst, ok := env.rt.Container("dst")
+	if !ok {
+		t.Fatal("clone not created")
+	}
+	if host, _, _, _, ok := localproxy.RouteFromLabels(dst.Labels); ok {
+		t.Errorf("clone still routes %s: %+v", host, dst.Labels)
+	}
+	for _, k := range []string{"DISCOURSE_HOSTNAME", "RAILS_DEVELOPMENT_HOSTS", "DV_LOCAL_PROXY_HOST"} {
+		if dst.Env[k] == "src.dv.localhost" {
+			t.Errorf("clone inherited %s=%s", k, dst.Env[k])
+		}
+	}
+	if src, _ := env.rt.Container("src"); src.Labels[localproxy.LabelHost] != "src.dv.localhost" {
+		t.Errorf("source route changed: %+v", src.Labels)
+	}
+}
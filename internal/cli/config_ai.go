@@ -77,7 +77,7 @@ configuring new models, and are passed to the container when testing connections
 		if err != nil {
 			return err
 		}
-		cfg, err := config.LoadOrCreate(configDir)
+		cfg, err := loadConfig(configDir)
 		if err != nil {
 			return err
 		}
@@ -73,7 +73,7 @@ into Discourse once ready. The container workdir is updated so 'dv enter' opens
 		if err != nil {
 			return err
 		}
-		cfg, err := config.LoadOrCreate(configDir)
+		cfg, err := loadConfig(configDir)
 		if err != nil {
 			return err
 		}
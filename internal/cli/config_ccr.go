@@ -169,7 +169,7 @@ var configCcrCmd = &cobra.Command{
 		if err != nil {
 			return err
 		}
-		cfg, err := config.LoadOrCreate(configDir)
+		cfg, err := loadConfig(configDir)
 		if err != nil {
 			return err
 		}
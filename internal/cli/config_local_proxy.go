@@ -22,7 +22,7 @@ var configLocalProxyCmd = &cobra.Command{
 		if err != nil {
 			return err
 		}
-		cfg, err := config.LoadOrCreate(configDir)
+		cfg, err := loadConfig(configDir)
 		if err != nil {
 			return err
 		}
@@ -144,6 +144,15 @@ var configLocalProxyCmd = &cobra.Command{
 		}
 		if err := localproxy.Healthy(lp, 5*time.Second); err != nil {
 			fmt.Fprintf(cmd.ErrOrStderr(), "Warning: %v\n", err)
+		} else {
+			// The proxy keeps routes in memory only; restore them for running agents.
+			res, err := localproxy.Reconcile(lp)
+			if err != nil {
+				fmt.Fprintf(cmd.ErrOrStderr(), "Warning: route sync: %v\n", err)
+			}
+			if n := len(res.Added) + len(res.Updated); n > 0 {
+				fmt.Fprintf(cmd.OutOrStdout(), "Restored %d agent route(s).\n", n)
+			}
 		}
 
 		lp.Enabled = true
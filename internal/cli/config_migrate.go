@@ -0,0 +1,69 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+
+	"github.com/spf13/cobra"
+
+	"dv/internal/config"
+	"dv/internal/xdg"
+)
+
+var configMigrateCmd = &cobra.Command{
+	Use:   "migrate",
+	Short: "Upgrade config.json to the current schema version",
+	Long: `Upgrade config.json to the current schema version.
+
+dv upgrades older config files automatically the first time it loads them,
+keeping a backup next to the original (config.json.vN-TIMESTAMP.bak). Use
+--dry-run to see the migrations and the resulting diff without writing.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		configDir, err := xdg.ConfigDir()
+		if err != nil {
+			return err
+		}
+		dryRun, _ := cmd.Flags().GetBool("dry-run")
+		out := cmd.OutOrStdout()
+
+		plan, _, err := config.PlanMigration(configDir)
+		if errors.Is(err, fs.ErrNotExist) {
+			fmt.Fprintf(out, "No config at %s yet; it will be created at schema version %d.\n", config.Path(configDir), config.CurrentSchemaVersion)
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if !plan.Pending() {
+			fmt.Fprintf(out, "Config is already at schema version %d.\n", plan.To)
+			return nil
+		}
+
+		fmt.Fprintf(out, "Config schema version %d -> %d:\n", plan.From, plan.To)
+		for _, step := range plan.Steps {
+			fmt.Fprintf(out, "  - %s\n", step)
+		}
+		if dryRun {
+			fmt.Fprintln(out)
+			fmt.Fprint(out, unifiedDiff(
+				fmt.Sprintf("config.json (v%d)", plan.From),
+				fmt.Sprintf("config.json (v%d)", plan.To),
+				string(plan.Old), string(plan.New)))
+			fmt.Fprintln(out, "\nDry run: nothing written.")
+			return nil
+		}
+		backup, err := config.ApplyMigration(configDir, plan)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(out, "Backed up the previous config to %s\n", backup)
+		return nil
+	},
+}
+
+func init() {
+	configMigrateCmd.Flags().Bool("dry-run", false, "Show the migrations and diff without writing")
+	configCmd.AddCommand(configMigrateCmd)
+}
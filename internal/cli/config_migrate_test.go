@@ -0,0 +1,96 @@
+package cli
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"dv/internal/config"
+)
+
+func TestConfigMigrateDryRun(t *testing.T) {
+	env := newTestEnv(t)
+	configDir := filepath.Join(env.rootDir, "config", "dv")
+	if err := os.MkdirAll(configDir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	legacy := "{\n  \"imageTag\": \"old-tag\",\n  \"customWorkdir\": \"/w\"\n}\n"
+	if err := os.WriteFile(config.Path(configDir), []byte(legacy), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	out := env.mustRun("config", "migrate", "--dry-run")
+	for _, want := range []string{
+		"Config schema version 0 -> 3:",
+		"  - move customWorkdir into customWorkdirs",
+		"--- config.json (v0)",
+		"+++ config.json (v3)",
+		"-  \"customWorkdir\": \"/w\"",
+		"+  \"schemaVersion\": 3,",
+		"Dry run: nothing written.",
+	} {
+		if !strings.Contains(out, want) {
+			t.Fatalf("missing %q in:\n%s", want, out)
+		}
+	}
+	if data, _ := os.ReadFile(config.Path(configDir)); string(data) != legacy {
+		t.Fatalf("dry run rewrote config:\n%s", data)
+	}
+
+	out = env.mustRun("config", "migrate")
+	if !strings.Contains(out, "Backed up the previous config to ") {
+		t.Fatalf("output:\n%s", out)
+	}
+	if cfg := env.config(); cfg.SchemaVersion != config.CurrentSchemaVersion || cfg.Images["discourse"].Tag != "old-tag" {
+		t.Fatalf("config = %+v", cfg)
+	}
+	if out := env.mustRun("config", "migrate", "--dry-run"); !strings.Contains(out, "already at schema version 3") {
+		t.Fatalf("output:\n%s", out)
+	}
+}
+
+func TestConfigSetCustomWorkdirWritesOverride(t *testing.T) {
+	env := newTestEnv(t)
+	env.mustRun("config", "set", "selectedAgent", "feature")
+	env.mustRun("config", "set", "customWorkdir", "/var/www/discourse/plugins/chat")
+
+	cfg := env.config()
+	if cfg.CustomWorkdir != "" || cfg.CustomWorkdirs["feature"] != "/var/www/discourse/plugins/chat" {
+		t.Fatalf("customWorkdir = %q, customWorkdirs = %v", cfg.CustomWorkdir, cfg.CustomWorkdirs)
+	}
+	if out := env.mustRun("config", "get", "customWorkdir"); strings.TrimSpace(out) != "/var/www/discourse/plugins/chat" {
+		t.Fatalf("get = %q", out)
+	}
+	if out := env.mustRun("config", "migrate", "--dry-run"); !strings.Contains(out, "already at schema version 3") {
+		t.Fatalf("config needs migrating after set:\n%s", out)
+	}
+}
+
+func TestUnifiedDiff(t *testing.T) {
+	t.Parallel()
+
+	a := "a\nb\nc\nd\ne\nf\ng\nh\ni\nj\nk\nl\n"
+	b := "a\nB\nc\nd\ne\nf\ng\nh\ni\nj\nk\nl\nm\n"
+	want := `--- old
++++ new
+@@ -1,5 +1,5 @@
+ a
+-b
++B
+ c
+ d
+ e
+@@ -10,3 +10,4 @@
+ j
+ k
+ l
++m
+`
+	if got := unifiedDiff("old", "new", a, b); got != want {
+		t.Fatalf("got:\n%s\nwant:\n%s", got, want)
+	}
+	if got := unifiedDiff("old", "new", a, a); got != "" {
+		t.Fatalf("equal inputs: %q", got)
+	}
+}
@@ -1,7 +1,6 @@
 package cli
 
 import (
-	"dv/internal/config"
 	"dv/internal/discourse"
 	"dv/internal/xdg"
 	"fmt"
@@ -23,7 +22,7 @@ var setSiteSettingCommand = &cobra.Command{
 		if err != nil {
 			return err
 		}
-		cfg, err := config.LoadOrCreate(configDir)
+		cfg, err := loadConfig(configDir)
 		if err != nil {
 			return err
 		}
@@ -0,0 +1,50 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"dv/internal/config"
+)
+
+func TestConfigShowEffective(t *testing.T) {
+	env := newTestEnv(t)
+	cfg := env.config()
+	cfg.Images["plugin"] = cfg.Images["discourse"]
+	env.saveConfig(cfg)
+
+	project := t.TempDir()
+	projectFile := filepath.Join(project, config.ProjectFile)
+	data := "selectedImage: plugin\nagent: chat\nenvPassthrough: [PROJECT_TOKEN]\nextractDir: src\n"
+	if err := os.WriteFile(projectFile, []byte(data), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	t.Chdir(project)
+
+	configPath := config.Path(filepath.Join(env.rootDir, "config", "dv"))
+	out := env.mustRun("config", "show", "--effective")
+	n := len(cfg.EnvPassthrough)
+	for _, want := range [][]string{
+		{"selectedImage", "plugin", projectFile},
+		{"selectedAgent", "chat", projectFile},
+		{"extractDir", filepath.Join(project, "src"), projectFile},
+		{fmt.Sprintf("envPassthrough[%d]", n), "PROJECT_TOKEN", projectFile},
+		{"envPassthrough[0]", cfg.EnvPassthrough[0], configPath},
+		{"hostStartingPort", "4200", configPath},
+	} {
+		if !hasLineWith(out, want...) {
+			t.Fatalf("missing %v in:\n%s", want, out)
+		}
+	}
+
+	// The plain view is config.json alone.
+	if out := env.mustRun("config", "show"); strings.Contains(out, "PROJECT_TOKEN") {
+		t.Fatalf("config show includes project values:\n%s", out)
+	}
+	if got := env.config(); got.SelectedImage != "discourse" || got.ExtractDir != "" {
+		t.Fatalf("project values saved: %+v", got)
+	}
+}
@@ -74,7 +74,7 @@ func runSiteSettings(cmd *cobra.Command, args []string) error {
 	if err != nil {
 		return err
 	}
-	cfg, err := config.LoadOrCreate(configDir)
+	cfg, err := loadConfig(configDir)
 	if err != nil {
 		return err
 	}
@@ -103,20 +103,32 @@ func runSiteSettings(cmd *cobra.Command, args []string) error {
 }
 
 func ApplySiteSettings(cmd *cobra.Command, cfg config.Config, containerName string, settings map[string]interface{}, envs docker.Envs, dryRun bool, filename string) error {
+	_, err := applySiteSettings(cmd, cfg, containerName, settings, envs, dryRun, filename)
+	return err
+}
+
+// siteSettingCounts tallies what applySiteSettings did.
+type siteSettingCounts struct {
+	changed, unchanged, errored int
+}
+
+// applySiteSettings is ApplySiteSettings, also returning how many settings
+// changed.
+func applySiteSettings(cmd *cobra.Command, cfg config.Config, containerName string, settings map[string]interface{}, envs docker.Envs, dryRun bool, filename string) (siteSettingCounts, error) {
 	// Check container state
 	if !docker.Exists(containerName) {
-		return fmt.Errorf("container '%s' does not exist; run 'dv start' first", containerName)
+		return siteSettingCounts{}, fmt.Errorf("container '%s' does not exist; run 'dv start' first", containerName)
 	}
 	if !docker.Running(containerName) {
 		fmt.Fprintf(cmd.OutOrStdout(), "Starting container '%s'...\n", containerName)
 		if err := docker.Start(containerName); err != nil {
-			return err
+			return siteSettingCounts{}, err
 		}
 	}
 
 	if len(settings) == 0 {
 		fmt.Fprintln(cmd.OutOrStdout(), "No settings found in file.")
-		return nil
+		return siteSettingCounts{}, nil
 	}
 
 	// Track original op:// references for display
@@ -162,16 +174,16 @@ func ApplySiteSettings(cmd *cobra.Command, cfg config.Config, containerName stri
 				fmt.Fprintf(cmd.OutOrStdout(), "  %s: %s\n", key, formatValue(value))
 			}
 		}
-		return nil
+		return siteSettingCounts{}, nil
 	}
 
 	// Create Discourse client
 	client, err := discourse.NewClientWrapper(containerName, cfg, envs, false)
 	if err != nil {
-		return fmt.Errorf("create discourse client: %w", err)
+		return siteSettingCounts{}, fmt.Errorf("create discourse client: %w", err)
 	}
 	if err := client.EnsureAPIKey(); err != nil {
-		return fmt.Errorf("ensure API key: %w", err)
+		return siteSettingCounts{}, fmt.Errorf("ensure API key: %w", err)
 	}
 
 	fmt.Fprintf(cmd.OutOrStdout(), "Applying site settings from %s...\n\n", filename)
@@ -218,19 +230,19 @@ func ApplySiteSettings(cmd *cobra.Command, cfg config.Config, containerName stri
 	}
 
 	// Print results
-	var changed, unchanged, errored int
+	var counts siteSettingCounts
 	for _, r := range results {
 		var statusStr string
 		switch r.status {
 		case "changed":
 			statusStr = "[changed]"
-			changed++
+			counts.changed++
 		case "unchanged":
 			statusStr = "[unchanged]"
-			unchanged++
+			counts.unchanged++
 		case "error":
 			statusStr = fmt.Sprintf("[error: %v]", r.err)
-			errored++
+			counts.errored++
 		}
 
 		if r.fromOP {
@@ -243,16 +255,16 @@ func ApplySiteSettings(cmd *cobra.Command, cfg config.Config, containerName stri
 	}
 
 	// Summary
-	fmt.Fprintf(cmd.OutOrStdout(), "\nApplied %d settings", changed)
-	if unchanged > 0 {
-		fmt.Fprintf(cmd.OutOrStdout(), " (%d unchanged)", unchanged)
+	fmt.Fprintf(cmd.OutOrStdout(), "\nApplied %d settings", counts.changed)
+	if counts.unchanged > 0 {
+		fmt.Fprintf(cmd.OutOrStdout(), " (%d unchanged)", counts.unchanged)
 	}
-	if errored > 0 {
-		fmt.Fprintf(cmd.OutOrStdout(), " (%d errors)", errored)
+	if counts.errored > 0 {
+		fmt.Fprintf(cmd.OutOrStdout(), " (%d errors)", counts.errored)
 	}
 	fmt.Fprintln(cmd.OutOrStdout())
 
-	return nil
+	return counts, nil
 }
 
 // formatValue formats a value for display
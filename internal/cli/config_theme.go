@@ -65,7 +65,7 @@ theme root so AI tooling understands the layout.`,
 		if err != nil {
 			return err
 		}
-		cfg, err := config.LoadOrCreate(configDir)
+		cfg, err := loadConfig(configDir)
 		if err != nil {
 			return err
 		}
@@ -103,7 +103,7 @@ theme root so AI tooling understands the layout.`,
 			}
 		}
 
-		dataDir, err := xdg.DataDir()
+		dataDir, err := extractRoot(cfg)
 		if err != nil {
 			return err
 		}
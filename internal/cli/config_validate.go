@@ -0,0 +1,73 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+
+	"github.com/spf13/cobra"
+
+	"dv/internal/config"
+	"dv/internal/xdg"
+)
+
+var configValidateCmd = &cobra.Command{
+	Use:   "validate",
+	Short: "Check config.json and the active overlays for mistakes",
+	Long: `Check config.json, the active profile and the project .dv.yaml for
+mistakes: relative or empty paths, unknown enum values, ports out of range or
+clashing, copy rules writing the same destination and agents mapped to images
+that don't exist.
+
+Errors stop dv from loading the config; warnings are only reported here.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		configDir, err := xdg.ConfigDir()
+		if err != nil {
+			return err
+		}
+		return reportConfigProblems(cmd, configDir, true)
+	},
+}
+
+// reportConfigProblems prints every problem in the effective config and
+// returns an error when any of them is fatal. With reportClean, a config
+// without problems is reported too.
+func reportConfigProblems(cmd *cobra.Command, configDir string, reportClean bool) error {
+	_, cfg, err := config.PlanMigration(configDir)
+	if errors.Is(err, fs.ErrNotExist) {
+		fmt.Fprintf(cmd.OutOrStdout(), "No config at %s yet; dv will create the defaults.\n", config.Path(configDir))
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if _, err := applyOverlays(configDir, &cfg); err != nil {
+		return err
+	}
+	out := cmd.OutOrStdout()
+	var errs, warnings int
+	for _, p := range config.Check(cfg) {
+		level := "error"
+		if p.Warning {
+			level = "warning"
+			warnings++
+		} else {
+			errs++
+		}
+		fmt.Fprintf(out, "%s: %s\n", level, p)
+	}
+	switch {
+	case errs > 0:
+		return fmt.Errorf("%s: %d error(s), %d warning(s)", config.Path(configDir), errs, warnings)
+	case warnings > 0:
+		fmt.Fprintf(out, "%s: %d warning(s)\n", config.Path(configDir), warnings)
+	case reportClean:
+		fmt.Fprintf(out, "%s is valid.\n", config.Path(configDir))
+	}
+	return nil
+}
+
+func init() {
+	configCmd.AddCommand(configValidateCmd)
+}
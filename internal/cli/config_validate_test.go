@@ -0,0 +1,67 @@
+package cli
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"dv/internal/config"
+)
+
+func TestConfigValidate(t *testing.T) {
+	env := newTestEnv(t)
+	env.config()
+	if out := env.mustRun("config", "validate"); !strings.Contains(out, "is valid.") {
+		t.Fatalf("output:\n%s", out)
+	}
+
+	cfg := env.config()
+	cfg.ContainerImages["old"] = "removed-image"
+	env.saveConfig(cfg)
+	if out := env.mustRun("config", "validate"); !strings.Contains(out, `warning: containerImages.old: unknown image "removed-image"`) {
+		t.Fatalf("output:\n%s", out)
+	}
+
+	// Break the file by hand, as an editor would.
+	configDir := filepath.Join(env.rootDir, "config", "dv")
+	data, err := os.ReadFile(config.Path(configDir))
+	if err != nil {
+		t.Fatal(err)
+	}
+	broken := strings.Replace(string(data), `"source": "stock"`, `"source": "stok"`, 1)
+	if err := os.WriteFile(config.Path(configDir), []byte(broken), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	out, err := env.run("config", "validate")
+	if err == nil || !strings.Contains(out, `error: images.discourse.dockerfile.source: unknown source "stok"`) {
+		t.Fatalf("err = %v, output:\n%s", err, out)
+	}
+	if _, err := env.run("list"); err == nil || !strings.Contains(err.Error(), "images.discourse.dockerfile.source") {
+		t.Fatalf("list err = %v", err)
+	}
+
+	// dv config edit still opens a broken config and reports what's left.
+	t.Setenv("VISUAL", "true")
+	out, err = env.run("config", "edit")
+	if err == nil || !strings.Contains(out, "dockerfile.source") {
+		t.Fatalf("edit err = %v, output:\n%s", err, out)
+	}
+}
+
+func TestConfigValidateProjectOverlay(t *testing.T) {
+	env := newTestEnv(t)
+	env.config()
+	project := t.TempDir()
+	projectFile := filepath.Join(project, config.ProjectFile)
+	if err := os.WriteFile(projectFile, []byte("selectedImage: nope\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	t.Chdir(project)
+
+	out, err := env.run("config", "validate")
+	if err == nil || !strings.Contains(out, `selectedImage: unknown image "nope" (from `+projectFile+`)`) {
+		t.Fatalf("err = %v, output:\n%s", err, out)
+	}
+}
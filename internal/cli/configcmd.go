@@ -1,14 +1,20 @@
 package cli
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
+	"io"
 	"os"
 	"os/exec"
+	"sort"
+	"strings"
+	"text/tabwriter"
 
 	"github.com/spf13/cobra"
 
 	"dv/internal/config"
+	"dv/internal/docker"
 	"dv/internal/xdg"
 )
 
@@ -24,14 +30,14 @@ var configGetCmd = &cobra.Command{
 	ValidArgs: []string{
 		"imageTag", "defaultContainerName", "workdir", "customWorkdir",
 		"hostStartingPort", "containerPort", "selectedAgent", "discourseRepo",
-		"extractBranchPrefix",
+		"extractBranchPrefix", "containerRuntime",
 	},
 	RunE: func(cmd *cobra.Command, args []string) error {
 		configDir, err := xdg.ConfigDir()
 		if err != nil {
 			return err
 		}
-		cfg, err := config.LoadOrCreate(configDir)
+		cfg, err := loadConfig(configDir)
 		if err != nil {
 			return err
 		}
@@ -52,14 +58,14 @@ var configSetCmd = &cobra.Command{
 	ValidArgs: []string{
 		"imageTag", "defaultContainerName", "workdir", "customWorkdir",
 		"hostStartingPort", "containerPort", "selectedAgent", "discourseRepo",
-		"extractBranchPrefix",
+		"extractBranchPrefix", "containerRuntime",
 	},
 	RunE: func(cmd *cobra.Command, args []string) error {
 		configDir, err := xdg.ConfigDir()
 		if err != nil {
 			return err
 		}
-		cfg, err := config.LoadOrCreate(configDir)
+		cfg, err := loadConfig(configDir)
 		if err != nil {
 			return err
 		}
@@ -74,11 +80,22 @@ var configSetCmd = &cobra.Command{
 var configShowCmd = &cobra.Command{
 	Use:   "show",
 	Short: "Show full config JSON",
+	Long: `Show full config JSON.
+
+With --effective, show the config dv actually uses in this directory, with
+the project .dv.yaml merged in, and where each value came from.`,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		configDir, err := xdg.ConfigDir()
 		if err != nil {
 			return err
 		}
+		if effective, _ := cmd.Flags().GetBool("effective"); effective {
+			cfg, err := loadConfig(configDir)
+			if err != nil {
+				return err
+			}
+			return printEffectiveConfig(cmd.OutOrStdout(), cfg, config.Path(configDir))
+		}
 		cfg, err := config.LoadOrCreate(configDir)
 		if err != nil {
 			return err
@@ -97,13 +114,15 @@ var configEditCmd = &cobra.Command{
 		if err != nil {
 			return err
 		}
-		// Ensure config exists
-		_, err = config.LoadOrCreate(configDir)
-		if err != nil {
-			return err
+		// Ensure config exists, without loading it: this is how broken
+		// configs get fixed.
+		configPath := config.Path(configDir)
+		if _, err := os.Stat(configPath); os.IsNotExist(err) {
+			if err := config.Save(configDir, config.Default()); err != nil {
+				return err
+			}
 		}
 
-		configPath := config.Path(configDir)
 		editor := getEditor()
 
 		editorCmd := exec.Command(editor, configPath)
@@ -111,7 +130,10 @@ var configEditCmd = &cobra.Command{
 		editorCmd.Stdout = os.Stdout
 		editorCmd.Stderr = os.Stderr
 
-		return editorCmd.Run()
+		if err := editorCmd.Run(); err != nil {
+			return err
+		}
+		return reportConfigProblems(cmd, configDir, false)
 	},
 }
 
@@ -138,7 +160,7 @@ var configResetCmd = &cobra.Command{
 		}
 
 		key := args[0]
-		cfg, err := config.LoadOrCreate(configDir)
+		cfg, err := loadConfig(configDir)
 		if err != nil {
 			return err
 		}
@@ -157,6 +179,7 @@ var configResetCmd = &cobra.Command{
 }
 
 func init() {
+	configShowCmd.Flags().Bool("effective", false, "Show merged values and where each came from")
 	configCmd.AddCommand(configGetCmd)
 	configCmd.AddCommand(configSetCmd)
 	configCmd.AddCommand(configShowCmd)
@@ -173,7 +196,7 @@ func getConfigField(cfg config.Config, key string) (string, error) {
 	case "workdir":
 		return cfg.Workdir, nil
 	case "customWorkdir":
-		return cfg.CustomWorkdir, nil
+		return cfg.CustomWorkdirs[currentAgentName(cfg)], nil
 	case "hostStartingPort":
 		return fmt.Sprint(cfg.HostStartingPort), nil
 	case "containerPort":
@@ -184,6 +207,11 @@ func getConfigField(cfg config.Config, key string) (string, error) {
 		return cfg.DiscourseRepo, nil
 	case "extractBranchPrefix":
 		return cfg.ExtractBranchPrefix, nil
+	case "containerRuntime":
+		if cfg.ContainerRuntime == "" {
+			return docker.RuntimeDocker, nil
+		}
+		return cfg.ContainerRuntime, nil
 	default:
 		return "", fmt.Errorf("unknown key: %s", key)
 	}
@@ -198,7 +226,17 @@ func setConfigField(cfg *config.Config, key, val string) error {
 	case "workdir":
 		cfg.Workdir = val
 	case "customWorkdir":
-		cfg.CustomWorkdir = val
+		// The single customWorkdir field was replaced by per-agent
+		// overrides; the key now sets the current agent's.
+		name := currentAgentName(*cfg)
+		if strings.TrimSpace(val) == "" {
+			delete(cfg.CustomWorkdirs, name)
+		} else {
+			if cfg.CustomWorkdirs == nil {
+				cfg.CustomWorkdirs = map[string]string{}
+			}
+			cfg.CustomWorkdirs[name] = val
+		}
 	case "hostStartingPort":
 		var v int
 		_, err := fmt.Sscanf(val, "%d", &v)
@@ -219,12 +257,86 @@ func setConfigField(cfg *config.Config, key, val string) error {
 		cfg.DiscourseRepo = val
 	case "extractBranchPrefix":
 		cfg.ExtractBranchPrefix = val
+	case "containerRuntime":
+		if _, err := docker.NewRuntime(val); err != nil {
+			return err
+		}
+		cfg.ContainerRuntime = strings.ToLower(strings.TrimSpace(val))
 	default:
 		return fmt.Errorf("unknown key: %s", key)
 	}
 	return nil
 }
 
+// printEffectiveConfig lists every value in cfg with its origin: an overlay
+// file, or configPath for values from config.json. Lists and maps are broken
+// out so each entry shows its own origin.
+func printEffectiveConfig(w io.Writer, cfg config.Config, configPath string) error {
+	b, err := json.Marshal(cfg)
+	if err != nil {
+		return err
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(b, &fields); err != nil {
+		return err
+	}
+	keys := make([]string, 0, len(fields))
+	for k := range fields {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "KEY\tVALUE\tSOURCE")
+	row := func(key string, raw json.RawMessage) {
+		source := cfg.Origin(key)
+		if source == "" {
+			source = configPath
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\n", key, effectiveValue(raw), source)
+	}
+	for _, k := range keys {
+		raw := fields[k]
+		var list []json.RawMessage
+		var obj map[string]json.RawMessage
+		switch {
+		case json.Unmarshal(raw, &list) == nil && len(list) > 0:
+			for i, item := range list {
+				row(fmt.Sprintf("%s[%d]", k, i), item)
+			}
+		case k != "localProxy" && json.Unmarshal(raw, &obj) == nil && len(obj) > 0:
+			names := make([]string, 0, len(obj))
+			for name := range obj {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+			for _, name := range names {
+				row(k+"."+name, obj[name])
+			}
+		default:
+			row(k, raw)
+		}
+	}
+	return tw.Flush()
+}
+
+// effectiveValue renders a JSON value for the effective config table,
+// dropping the quotes around plain strings.
+func effectiveValue(raw json.RawMessage) string {
+	var s string
+	if json.Unmarshal(raw, &s) == nil {
+		if s == "" {
+			return `""`
+		}
+		return s
+	}
+	var buf bytes.Buffer
+	if json.Compact(&buf, raw) != nil {
+		return string(raw)
+	}
+	return buf.String()
+}
+
 // getEditor returns the user's preferred editor based on environment variables
 // or a sensible default for the platform.
 func getEditor() string {
@@ -27,7 +27,7 @@ func prepareContainerExecContext(cmd *cobra.Command, overrideName ...string) (co
 	if err != nil {
 		return containerExecContext{}, false, err
 	}
-	cfg, err := config.LoadOrCreate(configDir)
+	cfg, err := loadConfig(configDir)
 	if err != nil {
 		return containerExecContext{}, false, err
 	}
@@ -8,7 +8,6 @@ import (
 
 	"github.com/spf13/cobra"
 
-	"dv/internal/config"
 	"dv/internal/docker"
 	"dv/internal/xdg"
 )
@@ -40,7 +39,7 @@ Examples:
 		if err != nil {
 			return err
 		}
-		cfg, err := config.LoadOrCreate(configDir)
+		cfg, err := loadConfig(configDir)
 		if err != nil {
 			return err
 		}
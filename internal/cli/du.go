@@ -0,0 +1,285 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+
+	"dv/internal/config"
+	"dv/internal/docker"
+	"dv/internal/xdg"
+)
+
+// agentDiskUsage is the disk footprint of one agent. Images, volumes and
+// extract dirs used by more than one agent are marked shared and counted in
+// full for each of them.
+type agentDiskUsage struct {
+	Agent         string        `json:"agent"`
+	Running       bool          `json:"running"`
+	Image         string        `json:"image,omitempty"`
+	ImageRef      string        `json:"imageRef"`
+	WritableBytes int64         `json:"writableBytes"`
+	ImageBytes    int64         `json:"imageBytes"`
+	ImageShared   bool          `json:"imageShared,omitempty"`
+	Volumes       []volumeUsage `json:"volumes,omitempty"`
+	VolumeBytes   int64         `json:"volumeBytes"`
+	ExtractDir    string        `json:"extractDir,omitempty"`
+	ExtractBytes  int64         `json:"extractBytes"`
+	ExtractShared bool          `json:"extractShared,omitempty"`
+	TotalBytes    int64         `json:"totalBytes"`
+}
+
+type volumeUsage struct {
+	Name   string `json:"name"`
+	Bytes  int64  `json:"bytes"`
+	Shared bool   `json:"shared,omitempty"`
+}
+
+var duSortKeys = []string{"total", "writable", "image", "volumes", "extract", "name"}
+
+var duCmd = &cobra.Command{
+	Use:   "du",
+	Short: "Show disk usage per agent: container layer, image, volumes and extract dir",
+	Long: `Show how much disk each agent uses:
+
+  WRITABLE  the container's writable layer
+  IMAGE     the image it was created from
+  VOLUMES   named volumes mounted with 'dv volumes'
+  EXTRACT   its local extract directory, if one exists
+
+Items used by several agents are marked with * and counted once in the
+overall total. Volume sizes need a 'system df' scan, which can be slow.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		sortBy, _ := cmd.Flags().GetString("sort")
+		if !containsString(duSortKeys, sortBy) {
+			return fmt.Errorf("invalid --sort %q (expected one of: %s)", sortBy, strings.Join(duSortKeys, ", "))
+		}
+		asJSON, _ := cmd.Flags().GetBool("json")
+
+		configDir, err := xdg.ConfigDir()
+		if err != nil {
+			return err
+		}
+		cfg, err := loadConfig(configDir)
+		if err != nil {
+			return err
+		}
+		extractDir, err := extractRoot(cfg)
+		if err != nil {
+			return err
+		}
+		usage, err := collectDiskUsage(cfg, extractDir)
+		if err != nil {
+			return err
+		}
+		sortDiskUsage(usage, sortBy)
+
+		out := cmd.OutOrStdout()
+		if asJSON {
+			enc := json.NewEncoder(out)
+			enc.SetIndent("", "  ")
+			return enc.Encode(usage)
+		}
+		return printDiskUsage(out, usage)
+	},
+}
+
+// collectDiskUsage measures every dv agent, running or stopped.
+func collectDiskUsage(cfg config.Config, extractDir string) ([]agentDiskUsage, error) {
+	containers, err := docker.ListContainers()
+	if err != nil {
+		return nil, err
+	}
+	images, err := docker.ListImages("")
+	if err != nil {
+		return nil, err
+	}
+	imageSizes := map[string]int64{}
+	for _, img := range images {
+		imageSizes[img.ID] = img.Size
+		for _, tag := range img.Tags {
+			imageSizes[tag] = img.Size
+			imageSizes[strings.TrimSuffix(tag, ":latest")] = img.Size
+		}
+	}
+
+	var usage []agentDiskUsage
+	var volumeSizes map[string]int64
+	for _, ctr := range containers {
+		imgName, known := cfg.ContainerImages[ctr.Name]
+		if !known {
+			imgName = ctr.Labels["com.dv.image-name"]
+		}
+		if ctr.Labels["com.dv.owner"] != "dv" && !known {
+			continue
+		}
+		u := agentDiskUsage{Agent: ctr.Name, Running: ctr.Running, Image: imgName, ImageRef: ctr.Image}
+		u.WritableBytes, _ = docker.ContainerSize(ctr.Name)
+		u.ImageBytes = imageSizes[ctr.Image]
+		if u.ImageBytes == 0 {
+			for id, size := range imageSizes {
+				if imageInUse(id, map[string]bool{ctr.Image: true}) {
+					u.ImageBytes = size
+					break
+				}
+			}
+		}
+		if mounts := volumeMountsFromLabels(ctr.Labels); len(mounts) > 0 {
+			if volumeSizes == nil {
+				if volumeSizes, err = docker.VolumeSizes(); err != nil {
+					return nil, fmt.Errorf("measure volumes: %w", err)
+				}
+			}
+			for _, m := range mounts {
+				u.Volumes = append(u.Volumes, volumeUsage{Name: m.Name, Bytes: volumeSizes[m.Name]})
+				u.VolumeBytes += volumeSizes[m.Name]
+			}
+		}
+		dir := filepath.Join(extractDir, "discourse_src")
+		if w := strings.TrimSpace(cfg.CustomWorkdirs[ctr.Name]); w != "" {
+			dir = workspaceLocalPath(extractDir, w)
+		}
+		if st, err := os.Stat(dir); err == nil && st.IsDir() {
+			u.ExtractDir = dir
+			u.ExtractBytes = dirSize(dir)
+		}
+		u.TotalBytes = u.WritableBytes + u.ImageBytes + u.VolumeBytes + u.ExtractBytes
+		usage = append(usage, u)
+	}
+	markSharedUsage(usage)
+	return usage, nil
+}
+
+// markSharedUsage flags images, volumes and extract dirs that appear on
+// more than one agent.
+func markSharedUsage(usage []agentDiskUsage) {
+	count := map[string]int{}
+	for _, u := range usage {
+		count["image:"+u.ImageRef]++
+		if u.ExtractDir != "" {
+			count["extract:"+u.ExtractDir]++
+		}
+		for _, v := range u.Volumes {
+			count["volume:"+v.Name]++
+		}
+	}
+	for i := range usage {
+		u := &usage[i]
+		u.ImageShared = count["image:"+u.ImageRef] > 1
+		u.ExtractShared = u.ExtractDir != "" && count["extract:"+u.ExtractDir] > 1
+		for j := range u.Volumes {
+			u.Volumes[j].Shared = count["volume:"+u.Volumes[j].Name] > 1
+		}
+	}
+}
+
+// uniqueDiskTotal sums usage counting each shared item once.
+func uniqueDiskTotal(usage []agentDiskUsage) int64 {
+	var total int64
+	seen := map[string]bool{}
+	once := func(key string, n int64) {
+		if !seen[key] {
+			seen[key] = true
+			total += n
+		}
+	}
+	for _, u := range usage {
+		total += u.WritableBytes
+		once("image:"+u.ImageRef, u.ImageBytes)
+		if u.ExtractDir != "" {
+			once("extract:"+u.ExtractDir, u.ExtractBytes)
+		}
+		for _, v := range u.Volumes {
+			once("volume:"+v.Name, v.Bytes)
+		}
+	}
+	return total
+}
+
+// sortDiskUsage orders by the chosen column, largest first; "name" sorts
+// alphabetically. Ties fall back to the agent name.
+func sortDiskUsage(usage []agentDiskUsage, by string) {
+	key := func(u agentDiskUsage) int64 {
+		switch by {
+		case "writable":
+			return u.WritableBytes
+		case "image":
+			return u.ImageBytes
+		case "volumes":
+			return u.VolumeBytes
+		case "extract":
+			return u.ExtractBytes
+		default:
+			return u.TotalBytes
+		}
+	}
+	sort.SliceStable(usage, func(i, j int) bool {
+		if by != "name" {
+			if ki, kj := key(usage[i]), key(usage[j]); ki != kj {
+				return ki > kj
+			}
+		}
+		return usage[i].Agent < usage[j].Agent
+	})
+}
+
+func printDiskUsage(out io.Writer, usage []agentDiskUsage) error {
+	if len(usage) == 0 {
+		fmt.Fprintln(out, "No agents found.")
+		return nil
+	}
+	shared := false
+	sizeCol := func(n int64, isShared bool) string {
+		s := "-"
+		if n > 0 {
+			s = formatBytes(n)
+		}
+		if isShared {
+			shared = true
+			s += "*"
+		}
+		return s
+	}
+	tw := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "AGENT\tIMAGE\tWRITABLE\tIMAGE SIZE\tVOLUMES\tEXTRACT\tTOTAL")
+	for _, u := range usage {
+		volShared := false
+		for _, v := range u.Volumes {
+			volShared = volShared || v.Shared
+		}
+		image := u.Image
+		if image == "" {
+			image = u.ImageRef
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
+			u.Agent,
+			image,
+			sizeCol(u.WritableBytes, false),
+			sizeCol(u.ImageBytes, u.ImageShared),
+			sizeCol(u.VolumeBytes, volShared),
+			sizeCol(u.ExtractBytes, u.ExtractShared),
+			formatBytes(u.TotalBytes),
+		)
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+	if shared {
+		fmt.Fprintln(out, "* shared with other agents")
+	}
+	fmt.Fprintf(out, "Total: %s (shared items counted once)\n", formatBytes(uniqueDiskTotal(usage)))
+	return nil
+}
+
+func init() {
+	duCmd.Flags().String("sort", "total", "Sort by total, writable, image, volumes, extract or name")
+	duCmd.Flags().Bool("json", false, "Print usage as JSON")
+}
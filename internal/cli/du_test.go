@@ -0,0 +1,66 @@
+package cli
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestDiskUsage(t *testing.T) {
+	env := newTestEnv(t)
+	env.rt.AddImage("ai_agent", map[string][]byte{"/bin/app": make([]byte, 2048)})
+	env.mustRun("new", "one")
+	env.mustRun("new", "two")
+	if err := env.rt.WriteFile("two", "/var/www/discourse/tmp/big", make([]byte, 8192)); err != nil {
+		t.Fatal(err)
+	}
+	env.rt.SetVolumeSize("dv-pnpm-store", 1000)
+	env.rt.SetVolumeSize("dv-cache", 500)
+	src := filepath.Join(env.rootDir, "data", "dv", "discourse_src")
+	if err := os.MkdirAll(src, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(src, "README.md"), make([]byte, 300), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	var usage []agentDiskUsage
+	if err := json.Unmarshal([]byte(env.mustRun("du", "--json")), &usage); err != nil {
+		t.Fatal(err)
+	}
+	if len(usage) != 2 || usage[0].Agent != "two" || usage[1].Agent != "one" {
+		t.Fatalf("usage order = %+v", usage)
+	}
+	two := usage[0]
+	if two.WritableBytes < 8192 || two.ImageBytes != 2048 || !two.ImageShared {
+		t.Errorf("two = %+v", two)
+	}
+	if two.VolumeBytes != 1500 || len(two.Volumes) != 3 || !two.Volumes[0].Shared {
+		t.Errorf("two volumes = %+v", two.Volumes)
+	}
+	if two.ExtractDir != src || two.ExtractBytes != 300 || !two.ExtractShared {
+		t.Errorf("two extract = %q %d", two.ExtractDir, two.ExtractBytes)
+	}
+	if two.TotalBytes != two.WritableBytes+2048+1500+300 {
+		t.Errorf("two total = %d", two.TotalBytes)
+	}
+
+	out := env.mustRun("du", "--sort", "name")
+	if !strings.Contains(out, "* shared with other agents") || !hasLineWith(out, "one", "2.0 KiB*", "1.5 KiB*") {
+		t.Errorf("du output:\n%s", out)
+	}
+	// Writable layers count per agent; the image, volumes and extract dir once.
+	want := formatBytes(usage[0].WritableBytes + usage[1].WritableBytes + 2048 + 1500 + 300)
+	if !strings.Contains(out, "Total: "+want) {
+		t.Errorf("du total, want %s:\n%s", want, out)
+	}
+	if lines := strings.Split(out, "\n"); !strings.HasPrefix(lines[1], "one") {
+		t.Errorf("--sort name output:\n%s", out)
+	}
+
+	if _, err := env.run("du", "--sort", "size"); err == nil {
+		t.Error("invalid --sort accepted")
+	}
+}
@@ -0,0 +1,487 @@
+package cli
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"dv/internal/config"
+	"dv/internal/docker"
+	"dv/internal/localproxy"
+	"dv/internal/session"
+	"dv/internal/xdg"
+)
+
+// agentArchiveVersion is bumped when the archive layout changes incompatibly.
+const agentArchiveVersion = 1
+
+// Archive entries, written in this order so import can validate the
+// manifest before streaming the (large) image into the runtime.
+const (
+	archiveManifestEntry = "manifest.json"
+	archiveLabelsEntry   = "labels.json"
+	archiveAgentEntry    = "agent.json"
+	archiveImageEntry    = "image.tar"
+)
+
+// agentArchiveManifest describes an exported agent.
+type agentArchiveManifest struct {
+	Version   int       `json:"version"`
+	Agent     string    `json:"agent"`
+	CreatedAt time.Time `json:"createdAt"`
+	DVVersion string    `json:"dvVersion"`
+	// ImageRef is the tag of the committed container inside image.tar.
+	ImageRef string `json:"imageRef"`
+	Workdir  string `json:"workdir"`
+	// PGData is the Postgres data directory archived into the image, if any.
+	PGData string `json:"pgData,omitempty"`
+}
+
+// agentArchiveConfig is the per-agent config carried by an archive.
+type agentArchiveConfig struct {
+	ImageName     string                 `json:"imageName"`
+	Image         config.ImageConfig     `json:"image"`
+	CustomWorkdir string                 `json:"customWorkdir,omitempty"`
+	Limits        *config.ResourceLimits `json:"limits,omitempty"`
+}
+
+var exportCmd = &cobra.Command{
+	Use:   "export [NAME]",
+	Short: "Export an agent, including its database, to a portable archive",
+	Args:  cobra.RangeArgs(0, 1),
+	ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		if len(args) == 0 {
+			return completeAgentNames(cmd, toComplete)
+		}
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	},
+	RunE: func(cmd *cobra.Command, args []string) (err error) {
+		configDir, err := xdg.ConfigDir()
+		if err != nil {
+			return err
+		}
+		cfg, err := loadConfig(configDir)
+		if err != nil {
+			return err
+		}
+		name := currentAgentName(cfg)
+		if len(args) == 1 && strings.TrimSpace(args[0]) != "" {
+			name = strings.TrimSpace(args[0])
+		}
+		if !docker.Exists(name) {
+			return fmt.Errorf("agent '%s' does not exist", name)
+		}
+		output, _ := cmd.Flags().GetString("output")
+		if output == "" {
+			output = name + ".tar.zst"
+		}
+		// Fail on a bad extension before doing any work.
+		if _, err := archiveCompressor(output, io.Discard); err != nil {
+			return err
+		}
+		imgName, imgCfg, err := resolveImage(cfg, cfg.ContainerImages[name])
+		if err != nil {
+			return err
+		}
+		labels, err := docker.Labels(name)
+		if err != nil {
+			return err
+		}
+
+		imageRef := "dv-export:" + name
+		pgData, err := commitAgentState(cmd, name, imageRef, map[string]string{"com.dv.owner": "dv"})
+		if err != nil {
+			return err
+		}
+		defer func() { _ = docker.RemoveImageQuiet(imageRef) }()
+
+		manifest := agentArchiveManifest{
+			Version:   agentArchiveVersion,
+			Agent:     name,
+			CreatedAt: time.Now().UTC(),
+			DVVersion: version,
+			ImageRef:  imageRef,
+			Workdir:   agentWorkdir(cfg, name, imgCfg),
+			PGData:    pgData,
+		}
+		agentCfg := agentArchiveConfig{
+			ImageName:     imgName,
+			Image:         imgCfg,
+			CustomWorkdir: cfg.CustomWorkdirs[name],
+		}
+		if limits, ok := cfg.AgentLimits[name]; ok {
+			agentCfg.Limits = &limits
+		}
+
+		// `docker save` streams an unknown length, but tar headers need the
+		// size up front, so stage the image next to the output.
+		dir := filepath.Dir(output)
+		staged, err := os.CreateTemp(dir, ".dv-export-image-*")
+		if err != nil {
+			return err
+		}
+		defer os.Remove(staged.Name())
+		defer staged.Close()
+		fmt.Fprintln(cmd.OutOrStdout(), "Saving image...")
+		if err := docker.SaveImage(imageRef, staged); err != nil {
+			return fmt.Errorf("save image: %w", err)
+		}
+
+		partial, err := os.CreateTemp(dir, ".dv-export-*")
+		if err != nil {
+			return err
+		}
+		defer func() {
+			partial.Close()
+			if err != nil {
+				os.Remove(partial.Name())
+			}
+		}()
+		fmt.Fprintf(cmd.OutOrStdout(), "Writing %s...\n", output)
+		if err = writeAgentArchive(partial, output, manifest, labels, agentCfg, staged); err != nil {
+			return err
+		}
+		if err = partial.Close(); err != nil {
+			return err
+		}
+		if err = os.Rename(partial.Name(), output); err != nil {
+			return err
+		}
+		size := int64(0)
+		if st, statErr := os.Stat(output); statErr == nil {
+			size = st.Size()
+		}
+		fmt.Fprintf(cmd.OutOrStdout(), "Exported agent '%s' to %s (%s).\n", name, output, formatBytes(size))
+		return nil
+	},
+}
+
+func writeAgentArchive(f *os.File, output string, manifest agentArchiveManifest, labels map[string]string, agentCfg agentArchiveConfig, image *os.File) error {
+	zw, err := archiveCompressor(output, f)
+	if err != nil {
+		return err
+	}
+	tw := tar.NewWriter(zw)
+	for _, entry := range []struct {
+		name string
+		v    any
+	}{
+		{archiveManifestEntry, manifest},
+		{archiveLabelsEntry, labels},
+		{archiveAgentEntry, agentCfg},
+	} {
+		data, err := json.MarshalIndent(entry.v, "", "  ")
+		if err != nil {
+			return err
+		}
+		if err := writeTarEntry(tw, entry.name, int64(len(data)), bytes.NewReader(data)); err != nil {
+			return err
+		}
+	}
+	st, err := image.Stat()
+	if err != nil {
+		return err
+	}
+	if _, err := image.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	if err := writeTarEntry(tw, archiveImageEntry, st.Size(), image); err != nil {
+		return err
+	}
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	return zw.Close()
+}
+
+func writeTarEntry(tw *tar.Writer, name string, size int64, r io.Reader) error {
+	hdr := &tar.Header{Name: name, Mode: 0o644, Size: size, ModTime: time.Now()}
+	if err := tw.WriteHeader(hdr); err != nil {
+		return err
+	}
+	_, err := io.Copy(tw, r)
+	return err
+}
+
+var importAgentCmd = &cobra.Command{
+	Use:   "import-agent FILE",
+	Short: "Recreate an agent from an archive written by dv export",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) (err error) {
+		configDir, err := xdg.ConfigDir()
+		if err != nil {
+			return err
+		}
+		cfg, err := loadConfig(configDir)
+		if err != nil {
+			return err
+		}
+		f, err := os.Open(args[0])
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		zr, err := archiveDecompressor(bufio.NewReader(f))
+		if err != nil {
+			return err
+		}
+		defer zr.Close()
+
+		var (
+			manifest   *agentArchiveManifest
+			agentCfg   agentArchiveConfig
+			labels     map[string]string
+			name, _    = cmd.Flags().GetString("name")
+			loaded     bool
+			loadedRefs []string
+		)
+		tr := tar.NewReader(zr)
+		for !loaded {
+			hdr, err := tr.Next()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				return fmt.Errorf("read archive: %w", err)
+			}
+			switch hdr.Name {
+			case archiveManifestEntry:
+				manifest = &agentArchiveManifest{}
+				if err := json.NewDecoder(tr).Decode(manifest); err != nil {
+					return fmt.Errorf("read manifest: %w", err)
+				}
+				if manifest.Version != agentArchiveVersion {
+					return fmt.Errorf("unsupported archive version %d (this dv reads version %d)", manifest.Version, agentArchiveVersion)
+				}
+				if name == "" {
+					name = manifest.Agent
+				}
+				if docker.Exists(name) {
+					return fmt.Errorf("an agent named '%s' already exists; use --name", name)
+				}
+			case archiveLabelsEntry:
+				if err := json.NewDecoder(tr).Decode(&labels); err != nil {
+					return fmt.Errorf("read labels: %w", err)
+				}
+			case archiveAgentEntry:
+				if err := json.NewDecoder(tr).Decode(&agentCfg); err != nil {
+					return fmt.Errorf("read agent config: %w", err)
+				}
+			case archiveImageEntry:
+				if manifest == nil {
+					return fmt.Errorf("%s is not a dv agent archive", args[0])
+				}
+				fmt.Fprintln(cmd.OutOrStdout(), "Loading image...")
+				refs, err := docker.LoadImage(tr)
+				if err != nil {
+					return fmt.Errorf("load image: %w", err)
+				}
+				loadedRefs, loaded = refs, true
+			}
+		}
+		if !loaded {
+			return fmt.Errorf("%s is not a dv agent archive", args[0])
+		}
+		loadedRef, err := archiveImageRef(manifest.ImageRef, loadedRefs)
+		if err != nil {
+			return err
+		}
+		// Move the image to a name of our own before using or removing it,
+		// so nothing here touches a tag the archive didn't bring along.
+		imageRef := fmt.Sprintf("dv-import:%s-%d", name, time.Now().UnixNano())
+		if err := docker.TagImage(loadedRef, imageRef); err != nil {
+			return fmt.Errorf("tag archive image: %w", err)
+		}
+		_ = docker.RemoveImageQuiet(loadedRef)
+		defer func() { _ = docker.RemoveImageQuiet(imageRef) }()
+
+		imgName := agentCfg.ImageName
+		if _, ok := cfg.Images[imgName]; !ok && imgName != "" {
+			cfg.Images[imgName] = agentCfg.Image
+			fmt.Fprintf(cmd.OutOrStdout(), "Added image definition '%s' from the archive.\n", imgName)
+		}
+		imgName, imgCfg, err := resolveImage(cfg, imgName)
+		if err != nil {
+			return err
+		}
+		if agentCfg.Limits != nil {
+			setAgentLimits(&cfg, name, *agentCfg.Limits)
+		}
+		workdir := manifest.Workdir
+		if workdir == "" {
+			workdir = imgCfg.Workdir
+		}
+
+		fmt.Fprintf(cmd.OutOrStdout(), "Creating agent '%s'...\n", name)
+		// Labels are passed at creation: engines can't change them on an
+		// existing container.
+		proxyLabels, proxyEnvs := clearedProxyMetadata()
+		maps.Copy(proxyLabels, importableLabels(labels))
+		if err := ensureContainerRunningWithWorkdir(cmd, cfg, name, workdir, imageRef, imgName, false, "", proxyEnvs, proxyLabels); err != nil {
+			return err
+		}
+		defer func() {
+			if err != nil {
+				fmt.Fprintf(cmd.ErrOrStderr(), "Import failed, removing '%s'...\n", name)
+				_ = docker.RemoveForce(name)
+			}
+		}()
+		if err := restoreAgentState(cmd, name, manifest.PGData); err != nil {
+			return err
+		}
+
+		if cfg.ContainerImages == nil {
+			cfg.ContainerImages = map[string]string{}
+		}
+		cfg.ContainerImages[name] = imgName
+		if w := strings.TrimSpace(agentCfg.CustomWorkdir); w != "" {
+			if cfg.CustomWorkdirs == nil {
+				cfg.CustomWorkdirs = map[string]string{}
+			}
+			cfg.CustomWorkdirs[name] = w
+		}
+		cfg.SelectedAgent = name
+		if err := session.SetCurrentAgent(name); err != nil {
+			fmt.Fprintf(cmd.ErrOrStderr(), "Warning: could not save session state: %v\n", err)
+		}
+		if err := config.Save(configDir, cfg); err != nil {
+			return err
+		}
+		fmt.Fprintf(cmd.OutOrStdout(), "Agent '%s' imported and selected.\n", name)
+		return nil
+	},
+}
+
+// archiveImageRef returns the tag image.tar restored for ref. Export always
+// writes a dv-export: tag, and the tag must be one the load reported, so an
+// archive can't point import at an image that was already on this machine.
+func archiveImageRef(ref string, loaded []string) (string, error) {
+	if !strings.HasPrefix(ref, "dv-export:") {
+		return "", fmt.Errorf("archive names image %q, which dv export doesn't write", ref)
+	}
+	for _, l := range loaded {
+		// podman qualifies short names with localhost/.
+		if l == ref || strings.TrimPrefix(l, "localhost/") == ref {
+			return l, nil
+		}
+	}
+	return "", fmt.Errorf("image.tar does not contain %s (it held %s)", ref, strings.Join(loaded, ", "))
+}
+
+// importableLabels drops the labels describing the exported container's
+// ports, proxy route and image; import assigns fresh ones.
+func importableLabels(labels map[string]string) map[string]string {
+	out := map[string]string{}
+	for k, v := range labels {
+		switch {
+		case k == "com.dv.owner",
+			strings.HasPrefix(k, "com.dv.image-"),
+			strings.HasPrefix(k, portLabelPrefix),
+			strings.HasPrefix(k, localproxy.LabelEnabled):
+			continue
+		}
+		out[k] = v
+	}
+	return out
+}
+
+// archiveCompressor wraps w with the compression implied by path's
+// extension. zstd has no standard library implementation, so .zst uses the
+// zstd binary.
+func archiveCompressor(path string, w io.Writer) (io.WriteCloser, error) {
+	switch {
+	case strings.HasSuffix(path, ".zst"), strings.HasSuffix(path, ".zstd"):
+		if _, err := exec.LookPath("zstd"); err != nil {
+			return nil, errors.New("zstd is required for .tar.zst archives; install it or use a .tar.gz output")
+		}
+		cmd := exec.Command("zstd", "-q", "-c", "-T0")
+		cmd.Stdout, cmd.Stderr = w, os.Stderr
+		stdin, err := cmd.StdinPipe()
+		if err != nil {
+			return nil, err
+		}
+		if err := cmd.Start(); err != nil {
+			return nil, err
+		}
+		return &cmdWriteCloser{WriteCloser: stdin, cmd: cmd}, nil
+	case strings.HasSuffix(path, ".gz"), strings.HasSuffix(path, ".tgz"):
+		return gzip.NewWriter(w), nil
+	case strings.HasSuffix(path, ".tar"):
+		return nopWriteCloser{w}, nil
+	default:
+		return nil, fmt.Errorf("unsupported archive name %s: use .tar.zst, .tar.gz or .tar", path)
+	}
+}
+
+// archiveDecompressor detects the compression of r from its magic bytes.
+func archiveDecompressor(r *bufio.Reader) (io.ReadCloser, error) {
+	magic, _ := r.Peek(4)
+	switch {
+	case bytes.Equal(magic, []byte{0x28, 0xb5, 0x2f, 0xfd}):
+		if _, err := exec.LookPath("zstd"); err != nil {
+			return nil, errors.New("zstd is required to read .tar.zst archives")
+		}
+		cmd := exec.Command("zstd", "-d", "-q", "-c")
+		cmd.Stdin, cmd.Stderr = r, os.Stderr
+		stdout, err := cmd.StdoutPipe()
+		if err != nil {
+			return nil, err
+		}
+		if err := cmd.Start(); err != nil {
+			return nil, err
+		}
+		return &cmdReadCloser{ReadCloser: stdout, cmd: cmd}, nil
+	case len(magic) >= 2 && magic[0] == 0x1f && magic[1] == 0x8b:
+		return gzip.NewReader(r)
+	default:
+		return io.NopCloser(r), nil
+	}
+}
+
+type nopWriteCloser struct{ io.Writer }
+
+func (nopWriteCloser) Close() error { return nil }
+
+// cmdWriteCloser feeds a filter process; Close flushes it and waits.
+type cmdWriteCloser struct {
+	io.WriteCloser
+	cmd *exec.Cmd
+}
+
+func (c *cmdWriteCloser) Close() error {
+	if err := c.WriteCloser.Close(); err != nil {
+		return err
+	}
+	return c.cmd.Wait()
+}
+
+// cmdReadCloser reads a filter process's output. Close stops the process,
+// which may not have been read to the end.
+type cmdReadCloser struct {
+	io.ReadCloser
+	cmd *exec.Cmd
+}
+
+func (c *cmdReadCloser) Close() error {
+	_ = c.cmd.Process.Kill()
+	_ = c.cmd.Wait()
+	return nil
+}
+
+func init() {
+	exportCmd.Flags().StringP("output", "o", "", "Archive to write: .tar.zst (default NAME.tar.zst), .tar.gz or .tar")
+	importAgentCmd.Flags().String("name", "", "Name for the imported agent (defaults to the exported name)")
+}
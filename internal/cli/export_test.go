@@ -0,0 +1,207 @@
+package cli
+
+import (
+	"archive/tar"
+	"bytes"
+	"encoding/json"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"dv/internal/docker/dockertest"
+)
+
+func TestExportImportAgentRoundTrip(t *testing.T) {
+	env := newTestEnv(t)
+	env.rt.AddImage("ai_agent", nil)
+	env.mustRun("new", "src", "--memory", "2g")
+	env.mustRun("config", "workdir", "/var/www/discourse/plugins/chat", "--container", "src")
+	if err := env.rt.WriteFile("src", "/var/www/discourse/notes.md", []byte("approach A")); err != nil {
+		t.Fatal(err)
+	}
+	env.rt.SetLabels("src", map[string]string{"com.example.team": "search"})
+	env.rt.HandleExec("SHOW data_directory", func(e dockertest.Exec) (string, error) {
+		return "/shared/postgres_data\n", nil
+	})
+
+	archive := filepath.Join(t.TempDir(), "src.tar.gz")
+	out := env.mustRun("export", "src", "-o", archive)
+	if !strings.Contains(out, "Exported agent 'src' to "+archive) {
+		t.Fatalf("export output:\n%s", out)
+	}
+	if env.rt.ImageExists("dv-export:src") {
+		t.Error("temporary export image left behind")
+	}
+
+	if _, err := env.run("import-agent", archive); err == nil {
+		t.Fatal("importing over an existing agent succeeded")
+	}
+	env.mustRun("remove", "src")
+
+	out = env.mustRun("import-agent", archive, "--name", "copy")
+	if !strings.Contains(out, "Agent 'copy' imported and selected.") {
+		t.Fatalf("import output:\n%s", out)
+	}
+	dst, ok := env.rt.Container("copy")
+	if !ok {
+		t.Fatal("imported agent not created")
+	}
+	if data, _ := env.rt.ReadFile("copy", "/var/www/discourse/notes.md"); string(data) != "approach A" {
+		t.Errorf("imported file = %q", data)
+	}
+	if dst.Labels["com.example.team"] != "search" {
+		t.Errorf("imported labels = %+v", dst.Labels)
+	}
+	if dst.RunOptions.HostPort == 0 {
+		t.Error("imported agent has no host port")
+	}
+	if dst.RunOptions.Memory != "2g" || dst.Workdir != "/var/www/discourse/plugins/chat" {
+		t.Errorf("imported run options = %+v", dst.RunOptions)
+	}
+	if env.rt.ImageExists("dv-export:src") {
+		t.Error("loaded archive image left behind")
+	}
+	cfg := env.config()
+	if cfg.ContainerImages["copy"] != "discourse" || cfg.CustomWorkdirs["copy"] != "/var/www/discourse/plugins/chat" ||
+		cfg.AgentLimits["copy"].Memory != "2g" || cfg.SelectedAgent != "copy" {
+		t.Errorf("config after import: %+v", cfg)
+	}
+}
+
+func TestExportZstd(t *testing.T) {
+	if _, err := exec.LookPath("zstd"); err != nil {
+		t.Skip("zstd not installed")
+	}
+	env := newTestEnv(t)
+	env.rt.AddImage("ai_agent", nil)
+	env.mustRun("new", "src")
+	archive := filepath.Join(t.TempDir(), "src.tar.zst")
+	env.mustRun("export", "-o", archive)
+	env.mustRun("remove", "src")
+	env.mustRun("import-agent", archive)
+	if _, ok := env.rt.Container("src"); !ok {
+		t.Fatal("agent not imported from zstd archive")
+	}
+}
+
+func TestExportRejectsUnknownExtension(t *testing.T) {
+	env := newTestEnv(t)
+	env.rt.AddImage("ai_agent", nil)
+	env.mustRun("new", "src")
+	if _, err := env.run("export", "-o", filepath.Join(t.TempDir(), "src.zip")); err == nil {
+		t.Fatal("export to .zip succeeded")
+	}
+}
+
+// rewriteArchive rewrites the entries of an uncompressed agent archive.
+func rewriteArchive(t *testing.T, archive string, fn func(name string, data []byte) []byte) {
+	t.Helper()
+	data, err := os.ReadFile(archive)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tr := tar.NewReader(bytes.NewReader(data))
+	var out bytes.Buffer
+	tw := tar.NewWriter(&out)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, err := io.ReadAll(tr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		body = fn(hdr.Name, body)
+		if err := writeTarEntry(tw, hdr.Name, int64(len(body)), bytes.NewReader(body)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(archive, out.Bytes(), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestImportRejectsForeignImageRef(t *testing.T) {
+	env := newTestEnv(t)
+	env.rt.AddImage("ai_agent", nil)
+	env.mustRun("new", "src")
+	archive := filepath.Join(t.TempDir(), "src.tar")
+	env.mustRun("export", "src", "-o", archive)
+	env.mustRun("remove", "src")
+
+	for _, ref := range []string{"ai_agent", "dv-export:other"} {
+		forged := filepath.Join(t.TempDir(), "forged.tar")
+		data, _ := os.ReadFile(archive)
+		if err := os.WriteFile(forged, data, 0o644); err != nil {
+			t.Fatal(err)
+		}
+		rewriteArchive(t, forged, func(name string, data []byte) []byte {
+			if name != archiveManifestEntry {
+				return data
+			}
+			var m agentArchiveManifest
+			if err := json.Unmarshal(data, &m); err != nil {
+				t.Fatal(err)
+			}
+			m.ImageRef = ref
+			data, _ = json.Marshal(m)
+			return data
+		})
+		if _, err := env.run("import-agent", forged); err == nil {
+			t.Fatalf("import with image ref %q succeeded", ref)
+		}
+		if _, ok := env.rt.Container("src"); ok {
+			t.Fatalf("agent created from image ref %q", ref)
+		}
+		if !env.rt.ImageExists("ai_agent") {
+			t.Fatalf("import with image ref %q removed the local ai_agent image", ref)
+		}
+	}
+}
+
+func TestImportRestoresLabels(t *testing.T) {
+	env := newTestEnv(t)
+	env.rt.AddImage("ai_agent", nil)
+	env.mustRun("new", "src")
+	archive := filepath.Join(t.TempDir(), "src.tar")
+	env.mustRun("export", "src", "-o", archive)
+	env.mustRun("remove", "src")
+	rewriteArchive(t, archive, func(name string, data []byte) []byte {
+		if name != archiveLabelsEntry {
+			return data
+		}
+		var labels map[string]string
+		if err := json.Unmarshal(data, &labels); err != nil {
+			t.Fatal(err)
+		}
+		labels["com.example.ticket"] = "123"
+		labels[portLabelPrefix+"ember"] = "1"
+		data, _ = json.Marshal(labels)
+		return data
+	})
+
+	env.mustRun("import-agent", archive)
+	dst, ok := env.rt.Container("src")
+	if !ok {
+		t.Fatal("agent not imported")
+	}
+	if dst.Labels["com.example.ticket"] != "123" {
+		t.Errorf("label from labels.json not restored: %+v", dst.Labels)
+	}
+	if dst.Labels[portLabelPrefix+"ember"] == "1" {
+		t.Errorf("stale port label restored: %+v", dst.Labels)
+	}
+	if dst.Labels["com.dv.image-tag"] == "dv-export:src" {
+		t.Errorf("agent still refers to the loaded archive tag: %+v", dst.Labels)
+	}
+}
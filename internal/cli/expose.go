@@ -36,7 +36,7 @@ Press Ctrl+C to stop exposing.`,
 		if err != nil {
 			return err
 		}
-		cfg, err := config.LoadOrCreate(configDir)
+		cfg, err := loadConfig(configDir)
 		if err != nil {
 			return err
 		}
@@ -49,11 +49,11 @@ Use 'dv extract plugin <name>' or 'dv extract theme <name>' for tab completion.`
 		if err != nil {
 			return err
 		}
-		dataDir, err := xdg.DataDir()
+		cfg, err := loadConfig(configDir)
 		if err != nil {
 			return err
 		}
-		cfg, err := config.LoadOrCreate(configDir)
+		dataDir, err := extractRoot(cfg)
 		if err != nil {
 			return err
 		}
@@ -232,7 +232,7 @@ func (s *extractSync) runContainerWatcher() error {
 		"inotifywait", "-m", "-r",
 		"-e", "modify", "-e", "create", "-e", "delete", "-e", "move",
 		"--format", "%w%f|%e", "--exclude", "(^|/)\\.git(/|$)", "."}
-	cmd := exec.CommandContext(s.ctx, "docker", args...)
+	cmd := exec.CommandContext(s.ctx, docker.Binary(), args...)
 	stdout, err := cmd.StdoutPipe()
 	if err != nil {
 		return err
@@ -0,0 +1,113 @@
+package cli
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"dv/internal/docker/dockertest"
+)
+
+func TestExtractCopiesChangedFiles(t *testing.T) {
+	env := newTestEnv(t)
+	root := t.TempDir()
+
+	upstream := filepath.Join(root, "upstream")
+	if err := os.Mkdir(upstream, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	gitInit(t, upstream)
+	runGit(t, upstream, "symbolic-ref", "HEAD", "refs/heads/main")
+	writeFile(t, filepath.Join(upstream, "app", "foo.rb"), "original\n")
+	writeFile(t, filepath.Join(upstream, "old.rb"), "old\n")
+	runGit(t, upstream, "add", ".")
+	runGit(t, upstream, "commit", "-m", "initial")
+	commit := strings.TrimSpace(runGit(t, upstream, "rev-parse", "HEAD"))
+	origin := filepath.Join(root, "origin.git")
+	runGit(t, root, "clone", "--bare", upstream, origin)
+	local := filepath.Join(root, "local")
+	runGit(t, root, "clone", origin, local)
+	writeFile(t, filepath.Join(local, "stray.txt"), "left over\n")
+
+	env.rt.AddContainer(dockertest.Container{
+		Name:    "feature",
+		Image:   "ai_agent",
+		Workdir: "/var/www/discourse",
+		Running: true,
+		Files: map[string][]byte{
+			"/var/www/discourse/app/foo.rb": []byte("changed\n"),
+			"/var/www/discourse/new.rb":     []byte("new\n"),
+		},
+	})
+	env.rt.HandleExec("git status --porcelain", func(dockertest.Exec) (string, error) {
+		return " M app/foo.rb\n?? new.rb\n D old.rb\n", nil
+	})
+	env.rt.HandleExec("git rev-parse HEAD", func(dockertest.Exec) (string, error) {
+		return commit + "\n", nil
+	})
+	env.rt.HandleExec("git rev-parse --abbrev-ref HEAD", func(dockertest.Exec) (string, error) {
+		return "main\n", nil
+	})
+
+	out := env.mustRun("extract", "--name", "feature", "--dir", local)
+	for _, want := range []string{"Using existing repo, resetting...", "Branch: main", "Files changed: 3", "Base commit: " + commit} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output missing %q:\n%s", want, out)
+		}
+	}
+
+	for file, want := range map[string]string{"app/foo.rb": "changed\n", "new.rb": "new\n"} {
+		got, err := os.ReadFile(filepath.Join(local, file))
+		if err != nil || string(got) != want {
+			t.Errorf("%s = %q, %v; want %q", file, got, err, want)
+		}
+	}
+	for _, gone := range []string{"old.rb", "stray.txt"} {
+		if _, err := os.Stat(filepath.Join(local, gone)); !os.IsNotExist(err) {
+			t.Errorf("%s should have been removed: %v", gone, err)
+		}
+	}
+	if branch := strings.TrimSpace(runGit(t, local, "rev-parse", "--abbrev-ref", "HEAD")); branch != "main" {
+		t.Errorf("local branch = %q, want main", branch)
+	}
+	for _, e := range env.rt.Execs() {
+		if strings.Contains(e.Command(), "git status --porcelain") && e.Workdir != "/var/www/discourse" {
+			t.Errorf("status ran in %q", e.Workdir)
+		}
+	}
+}
+
+func TestExtractPathCopiesNonGitDirectory(t *testing.T) {
+	env := newTestEnv(t)
+	env.rt.AddContainer(dockertest.Container{
+		Name:    "feature",
+		Image:   "ai_agent",
+		Workdir: "/var/www/discourse",
+		Running: true,
+		Files: map[string][]byte{
+			"/home/discourse/my-theme/about.json":         []byte(`{"name":"My Theme"}`),
+			"/home/discourse/my-theme/common/common.scss": []byte("body {}\n"),
+			"/home/discourse/other/file.txt":              []byte("not extracted\n"),
+		},
+	})
+	env.rt.HandleExec("[ -d", func(dockertest.Exec) (string, error) { return "OK\n", nil })
+	env.rt.HandleExec("--is-inside-work-tree", func(dockertest.Exec) (string, error) { return "false\n", nil })
+
+	out := env.mustRun("extract", "--name", "feature", "/home/discourse/my-theme")
+	if !strings.Contains(out, "Workspace is not a git repository") {
+		t.Errorf("unexpected output:\n%s", out)
+	}
+
+	local := filepath.Join(env.rootDir, "data", "dv", "my-theme_src")
+	got, err := os.ReadFile(filepath.Join(local, "common", "common.scss"))
+	if err != nil || string(got) != "body {}\n" {
+		t.Errorf("common.scss = %q, %v", got, err)
+	}
+	if _, err := os.Stat(filepath.Join(local, "about.json")); err != nil {
+		t.Errorf("about.json not copied: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(local, "file.txt")); !os.IsNotExist(err) {
+		t.Errorf("files outside the path were copied: %v", err)
+	}
+}
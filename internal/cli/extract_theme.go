@@ -7,7 +7,6 @@ import (
 
 	"github.com/spf13/cobra"
 
-	"dv/internal/config"
 	"dv/internal/docker"
 	"dv/internal/xdg"
 )
@@ -27,7 +26,7 @@ var extractThemeCmd = &cobra.Command{
 		if err != nil {
 			return nil, cobra.ShellCompDirectiveNoFileComp
 		}
-		cfg, err := config.LoadOrCreate(configDir)
+		cfg, err := loadConfig(configDir)
 		if err != nil {
 			return nil, cobra.ShellCompDirectiveNoFileComp
 		}
@@ -92,11 +91,11 @@ done
 		if err != nil {
 			return err
 		}
-		dataDir, err := xdg.DataDir()
+		cfg, err := loadConfig(configDir)
 		if err != nil {
 			return err
 		}
-		cfg, err := config.LoadOrCreate(configDir)
+		dataDir, err := extractRoot(cfg)
 		if err != nil {
 			return err
 		}
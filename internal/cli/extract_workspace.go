@@ -11,7 +11,9 @@ import (
 
 	"github.com/spf13/cobra"
 
+	"dv/internal/config"
 	"dv/internal/docker"
+	"dv/internal/xdg"
 )
 
 type workspaceExtractOptions struct {
@@ -302,6 +304,15 @@ func copyWorkspaceDirectory(opts workspaceExtractOptions, logOut io.Writer, reas
 	return nil
 }
 
+// extractRoot returns the directory extracted repos go in: the extractDir
+// setting when set, otherwise the XDG data dir.
+func extractRoot(cfg config.Config) (string, error) {
+	if dir := strings.TrimSpace(cfg.ExtractDir); dir != "" {
+		return expandHostPath(dir), nil
+	}
+	return xdg.DataDir()
+}
+
 func workspaceLocalPath(dataDir, workdir string) string {
 	base := filepath.Base(workdir)
 	if base == "" || base == "." || base == string(filepath.Separator) {
@@ -0,0 +1,362 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"dv/internal/config"
+	"dv/internal/docker"
+	"dv/internal/localproxy"
+	"dv/internal/xdg"
+)
+
+// gcItem is one thing dv gc can reclaim.
+type gcItem struct {
+	kind   string
+	name   string
+	detail string
+	size   int64
+	remove func() error
+}
+
+var gcCmd = &cobra.Command{
+	Use:   "gc",
+	Short: "Remove stale agents, superseded images, old extract dirs and dangling config",
+	Long: `Remove things dv no longer needs:
+
+  config   image/workdir/limit entries for containers that no longer exist
+  agent    agents stopped for longer than --older-than (never the selected one)
+  image    untagged images left behind by dv build, not used by any container
+  extract  *_src extract directories not touched within --older-than
+
+Extract directories with uncommitted or unpushed git work are kept.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		configDir, err := xdg.ConfigDir()
+		if err != nil {
+			return err
+		}
+		cfg, err := loadConfig(configDir)
+		if err != nil {
+			return err
+		}
+		olderThan, _ := cmd.Flags().GetString("older-than")
+		age, err := parseAge(olderThan)
+		if err != nil {
+			return fmt.Errorf("invalid --older-than: %w", err)
+		}
+		dryRun, _ := cmd.Flags().GetBool("dry-run")
+		cutoff := time.Now().Add(-age)
+
+		containers, err := docker.ListContainers()
+		if err != nil {
+			return err
+		}
+		var items []gcItem
+		var skipped []string
+		configDirty := false
+
+		// Agents first: the images they use become collectable with them.
+		existing := map[string]bool{}
+		keptImages := map[string]bool{}
+		selected := currentAgentName(cfg)
+		for _, ctr := range containers {
+			existing[ctr.Name] = true
+			_, known := cfg.ContainerImages[ctr.Name]
+			isAgent := ctr.Labels["com.dv.owner"] == "dv" || known
+			if !isAgent || ctr.Running || ctr.Name == selected || ctr.Name == cfg.LocalProxy.ContainerName || !ctr.CreatedAt.Before(cutoff) {
+				keptImages[ctr.Image] = true
+				continue
+			}
+			// Idle auto-stop stops agents in daily use, so age counts from
+			// the last stop rather than creation.
+			stopped := docker.LastStopped(ctr.Name, ctr.CreatedAt)
+			if !stopped.Before(cutoff) {
+				keptImages[ctr.Image] = true
+				continue
+			}
+			name, labels := ctr.Name, ctr.Labels
+			size, _ := docker.ContainerSize(name)
+			items = append(items, gcItem{
+				kind:   "agent",
+				name:   name,
+				detail: "stopped since " + stopped.Format("2006-01-02"),
+				size:   size,
+				remove: func() error {
+					if err := docker.Remove(name); err != nil {
+						return err
+					}
+					forgetAgentConfig(&cfg, name)
+					configDirty = true
+					if cfg.LocalProxy.Enabled && localproxy.Running(cfg.LocalProxy) {
+						if host, _, _, _, ok := localproxy.RouteFromLabels(labels); ok {
+							_ = localproxy.RemoveRoute(cfg.LocalProxy, host)
+						}
+					}
+					return nil
+				},
+			})
+		}
+
+		for _, name := range staleConfigAgents(cfg, existing) {
+			var where []string
+			if _, ok := cfg.ContainerImages[name]; ok {
+				where = append(where, "containerImages")
+			}
+			if _, ok := cfg.CustomWorkdirs[name]; ok {
+				where = append(where, "customWorkdirs")
+			}
+			if _, ok := cfg.AgentLimits[name]; ok {
+				where = append(where, "agentLimits")
+			}
+			items = append(items, gcItem{
+				kind:   "config",
+				name:   name,
+				detail: strings.Join(where, ", "),
+				remove: func() error {
+					forgetAgentConfig(&cfg, name)
+					configDirty = true
+					return nil
+				},
+			})
+		}
+
+		images, err := docker.ListImages("com.dv.owner")
+		if err != nil {
+			return err
+		}
+		for _, img := range images {
+			if len(img.Tags) > 0 || imageInUse(img.ID, keptImages) {
+				continue
+			}
+			id := img.ID
+			detail := "superseded, built " + img.CreatedAt.Format("2006-01-02")
+			if n := img.Labels["com.dv.image-name"]; n != "" {
+				detail = "superseded " + n + ", built " + img.CreatedAt.Format("2006-01-02")
+			}
+			items = append(items, gcItem{
+				kind:   "image",
+				name:   shortImageID(id),
+				detail: detail,
+				size:   img.Size,
+				remove: func() error { return docker.RemoveImage(id) },
+			})
+		}
+
+		extractDir, err := extractRoot(cfg)
+		if err != nil {
+			return err
+		}
+		dirs, extractSkips := staleExtractDirs(extractDir, cutoff)
+		skipped = append(skipped, extractSkips...)
+		items = append(items, dirs...)
+
+		out := cmd.OutOrStdout()
+		if len(items) == 0 {
+			fmt.Fprintln(out, "Nothing to clean up.")
+			printGCSkipped(out, skipped)
+			return nil
+		}
+		if dryRun {
+			fmt.Fprintln(out, "Would remove:")
+		} else {
+			fmt.Fprintln(out, "Removing:")
+		}
+		tw := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+		var reclaimed int64
+		var failures int
+		for _, item := range items {
+			size := "-"
+			if item.size > 0 {
+				size = formatBytes(item.size)
+			}
+			status := ""
+			if !dryRun {
+				if err := item.remove(); err != nil {
+					status = "failed: " + err.Error()
+					failures++
+				} else {
+					reclaimed += item.size
+				}
+			} else {
+				reclaimed += item.size
+			}
+			fmt.Fprintf(tw, "  %s\t%s\t%s\t%s\t%s\n", item.kind, item.name, item.detail, size, status)
+		}
+		if err := tw.Flush(); err != nil {
+			return err
+		}
+		printGCSkipped(out, skipped)
+
+		if configDirty {
+			if err := config.Save(configDir, cfg); err != nil {
+				return err
+			}
+		}
+		if dryRun {
+			fmt.Fprintf(out, "Would reclaim %s. Run without --dry-run to remove.\n", formatBytes(reclaimed))
+			return nil
+		}
+		fmt.Fprintf(out, "Reclaimed %s.\n", formatBytes(reclaimed))
+		if failures > 0 {
+			return fmt.Errorf("%d item(s) could not be removed", failures)
+		}
+		return nil
+	},
+}
+
+// forgetAgentConfig drops every per-agent config entry for name.
+func forgetAgentConfig(cfg *config.Config, name string) {
+	delete(cfg.ContainerImages, name)
+	delete(cfg.CustomWorkdirs, name)
+	delete(cfg.AgentLimits, name)
+}
+
+// staleConfigAgents lists agents with per-agent config but no container.
+func staleConfigAgents(cfg config.Config, existing map[string]bool) []string {
+	seen := map[string]bool{}
+	var names []string
+	add := func(name string) {
+		if !existing[name] && !seen[name] {
+			seen[name] = true
+			names = append(names, name)
+		}
+	}
+	for name := range cfg.ContainerImages {
+		add(name)
+	}
+	for name := range cfg.CustomWorkdirs {
+		add(name)
+	}
+	for name := range cfg.AgentLimits {
+		add(name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// imageInUse reports whether any kept container references id. Containers
+// whose image lost its tag report the (possibly short) image ID instead.
+func imageInUse(id string, used map[string]bool) bool {
+	hex := strings.TrimPrefix(id, "sha256:")
+	for ref := range used {
+		ref = strings.TrimPrefix(ref, "sha256:")
+		if len(ref) >= 12 && strings.HasPrefix(hex, ref) {
+			return true
+		}
+	}
+	return used[id]
+}
+
+func shortImageID(id string) string {
+	id = strings.TrimPrefix(id, "sha256:")
+	if len(id) > 12 {
+		return id[:12]
+	}
+	return id
+}
+
+// staleExtractDirs finds *_src directories under extractDir last used before
+// cutoff. Repos with uncommitted or unpushed work are reported as skipped.
+func staleExtractDirs(extractDir string, cutoff time.Time) ([]gcItem, []string) {
+	entries, err := os.ReadDir(extractDir)
+	if err != nil {
+		return nil, nil
+	}
+	var items []gcItem
+	var skipped []string
+	for _, e := range entries {
+		if !e.IsDir() || !strings.HasSuffix(e.Name(), "_src") {
+			continue
+		}
+		dir := filepath.Join(extractDir, e.Name())
+		used := dirLastUsed(dir)
+		if !used.Before(cutoff) {
+			continue
+		}
+		if reason := unsavedGitWork(dir); reason != "" {
+			skipped = append(skipped, fmt.Sprintf("extract %s: %s", e.Name(), reason))
+			continue
+		}
+		items = append(items, gcItem{
+			kind:   "extract",
+			name:   e.Name(),
+			detail: "last used " + used.Format("2006-01-02"),
+			size:   dirSize(dir),
+			remove: func() error { return os.RemoveAll(dir) },
+		})
+	}
+	return items, skipped
+}
+
+// dirLastUsed approximates when an extract dir was last used from the
+// directory itself and the git files every checkout, commit or fetch touches.
+func dirLastUsed(dir string) time.Time {
+	var latest time.Time
+	for _, p := range []string{dir, filepath.Join(dir, ".git", "index"), filepath.Join(dir, ".git", "HEAD"), filepath.Join(dir, ".git", "FETCH_HEAD")} {
+		if st, err := os.Stat(p); err == nil && st.ModTime().After(latest) {
+			latest = st.ModTime()
+		}
+	}
+	return latest
+}
+
+// unsavedGitWork describes work in a git checkout that removing it would
+// lose, or returns "" when there is none (or dir is not a repo).
+func unsavedGitWork(dir string) string {
+	if _, err := os.Stat(filepath.Join(dir, ".git")); err != nil {
+		return ""
+	}
+	out, err := execCombined("git", "-C", dir, "status", "--porcelain")
+	if err != nil {
+		return "git status failed"
+	}
+	if strings.TrimSpace(out) != "" {
+		return "uncommitted changes"
+	}
+	out, err = execCombined("git", "-C", dir, "log", "--branches", "--not", "--remotes", "--oneline", "-1")
+	if err == nil && strings.TrimSpace(out) != "" {
+		return "unpushed commits"
+	}
+	return ""
+}
+
+func dirSize(dir string) int64 {
+	var size int64
+	_ = filepath.WalkDir(dir, func(_ string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		if d.Type().IsRegular() {
+			if info, err := d.Info(); err == nil {
+				size += info.Size()
+			}
+		}
+		return nil
+	})
+	return size
+}
+
+func printGCSkipped(out io.Writer, skipped []string) {
+	if len(skipped) == 0 {
+		return
+	}
+	fmt.Fprintln(out, "Kept:")
+	for _, s := range skipped {
+		fmt.Fprintf(out, "  %s\n", s)
+	}
+}
+
+func init() {
+	gcCmd.Flags().String("older-than", "14d", "Age after which stopped agents and extract dirs are removed (e.g. 14d, 72h)")
+	gcCmd.Flags().Bool("dry-run", false, "Show what would be removed without removing it")
+}
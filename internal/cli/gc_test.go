@@ -0,0 +1,210 @@
+package cli
+
+import (
+	"encoding/json"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"dv/internal/docker/dockertest"
+)
+
+func TestGCRemovesStaleState(t *testing.T) {
+	env := newTestEnv(t)
+	env.rt.AddImage("ai_agent", nil)
+	env.mustRun("new", "current")
+	old := time.Now().Add(-30 * 24 * time.Hour)
+	env.rt.AddContainer(dockertest.Container{
+		Name:      "old",
+		Image:     "ai_agent",
+		Labels:    map[string]string{"com.dv.owner": "dv", "com.dv.image-name": "discourse"},
+		Files:     map[string][]byte{"/var/www/discourse/tmp/big": make([]byte, 4096)},
+		CreatedAt: old,
+	})
+	env.rt.AddContainer(dockertest.Container{
+		Name:      "recent",
+		Image:     "ai_agent",
+		Labels:    map[string]string{"com.dv.owner": "dv"},
+		CreatedAt: time.Now().Add(-time.Hour),
+	})
+	// Created long ago but stopped (say by idle auto-stop) last night.
+	env.rt.AddContainer(dockertest.Container{
+		Name:       "daily",
+		Image:      "ai_agent",
+		Labels:     map[string]string{"com.dv.owner": "dv"},
+		CreatedAt:  old,
+		FinishedAt: time.Now().Add(-12 * time.Hour),
+	})
+	env.rt.AddContainer(dockertest.Container{Name: "unrelated", Image: "postgres", CreatedAt: old})
+
+	cfg := env.config()
+	cfg.ContainerImages["ghost"] = "discourse"
+	cfg.CustomWorkdirs = map[string]string{"ghost": "/var/www/discourse/plugins/x"}
+	cfg.ContainerImages["old"] = "discourse"
+	env.saveConfig(cfg)
+
+	// Rebuilding leaves the first dv build dangling.
+	env.mustRun("build")
+	env.mustRun("build")
+
+	dataDir := filepath.Join(env.rootDir, "data", "dv")
+	staleDir := filepath.Join(dataDir, "chat_src")
+	freshDir := filepath.Join(dataDir, "discourse_src")
+	for _, d := range []string{staleDir, freshDir} {
+		if err := os.MkdirAll(d, 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(staleDir, "plugin.rb"), make([]byte, 2048), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(staleDir, old, old); err != nil {
+		t.Fatal(err)
+	}
+
+	out := env.mustRun("gc", "--dry-run")
+	for _, want := range [][]string{
+		{"agent", "old", "4.0 KiB"},
+		{"config", "ghost", "containerImages, customWorkdirs"},
+		{"image", "superseded"},
+		{"extract", "chat_src", "2.0 KiB"},
+	} {
+		if !hasLineWith(out, want...) {
+			t.Errorf("dry run missing %q:\n%s", want, out)
+		}
+	}
+	if !strings.Contains(out, "Would reclaim 6.0 KiB.") {
+		t.Errorf("dry run total:\n%s", out)
+	}
+	for _, unwanted := range []string{"recent", "daily", "current", "unrelated", "discourse_src"} {
+		if strings.Contains(out, unwanted) {
+			t.Errorf("dry run lists %q:\n%s", unwanted, out)
+		}
+	}
+	if _, ok := env.rt.Container("old"); !ok {
+		t.Fatal("dry run removed an agent")
+	}
+
+	out = env.mustRun("gc")
+	if !strings.Contains(out, "Reclaimed 6.0 KiB.") {
+		t.Errorf("gc output:\n%s", out)
+	}
+	if _, ok := env.rt.Container("old"); ok {
+		t.Error("old agent not removed")
+	}
+	for _, kept := range []string{"recent", "daily"} {
+		if _, ok := env.rt.Container(kept); !ok {
+			t.Errorf("%s agent removed", kept)
+		}
+	}
+	if _, err := os.Stat(staleDir); !os.IsNotExist(err) {
+		t.Error("stale extract dir not removed")
+	}
+	if _, err := os.Stat(freshDir); err != nil {
+		t.Error("fresh extract dir removed")
+	}
+	cfg = env.config()
+	if _, ok := cfg.ContainerImages["ghost"]; ok {
+		t.Errorf("stale config kept: %+v", cfg.ContainerImages)
+	}
+	if _, ok := cfg.ContainerImages["old"]; ok {
+		t.Errorf("removed agent's config kept: %+v", cfg.ContainerImages)
+	}
+	images, _ := env.rt.ListImages("com.dv.owner")
+	for _, img := range images {
+		if len(img.Tags) == 0 {
+			t.Errorf("dangling image kept: %+v", img)
+		}
+	}
+
+	if out := env.mustRun("gc"); !strings.Contains(out, "Nothing to clean up.") {
+		t.Errorf("second gc:\n%s", out)
+	}
+}
+
+func TestGCKeepsExtractDirsWithUnsavedWork(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	env := newTestEnv(t)
+	dir := filepath.Join(env.rootDir, "data", "dv", "wip_src")
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if out, err := exec.Command("git", "init", "-q", dir).CombinedOutput(); err != nil {
+		t.Fatalf("git init: %v\n%s", err, out)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "draft.rb"), []byte("wip"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	old := time.Now().Add(-60 * 24 * time.Hour)
+	for _, p := range []string{dir, filepath.Join(dir, ".git", "HEAD")} {
+		if err := os.Chtimes(p, old, old); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	out := env.mustRun("gc")
+	if !strings.Contains(out, "extract wip_src: uncommitted changes") {
+		t.Errorf("gc output:\n%s", out)
+	}
+	if _, err := os.Stat(dir); err != nil {
+		t.Error("extract dir with uncommitted work removed")
+	}
+}
+
+func TestGCAndDUUseExtractDir(t *testing.T) {
+	env := newTestEnv(t)
+	env.rt.AddImage("ai_agent", nil)
+	env.mustRun("new", "web")
+	extractDir := filepath.Join(env.rootDir, "extracted")
+	cfg := env.config()
+	cfg.ExtractDir = extractDir
+	env.saveConfig(cfg)
+
+	src := filepath.Join(extractDir, "discourse_src")
+	stale := filepath.Join(extractDir, "chat_src")
+	for _, d := range []string{src, stale} {
+		if err := os.MkdirAll(d, 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(src, "README.md"), make([]byte, 300), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	old := time.Now().Add(-30 * 24 * time.Hour)
+	if err := os.Chtimes(stale, old, old); err != nil {
+		t.Fatal(err)
+	}
+
+	if out := env.mustRun("gc", "--dry-run"); !hasLineWith(out, "extract", "chat_src") {
+		t.Errorf("gc missed the relocated extract dir:\n%s", out)
+	}
+	var usage []agentDiskUsage
+	if err := json.Unmarshal([]byte(env.mustRun("du", "--json")), &usage); err != nil {
+		t.Fatal(err)
+	}
+	if len(usage) != 1 || usage[0].ExtractDir != src || usage[0].ExtractBytes != 300 {
+		t.Errorf("du usage = %+v", usage)
+	}
+}
+
+// hasLineWith reports whether a single line of out contains every part.
+func hasLineWith(out string, parts ...string) bool {
+	for _, line := range strings.Split(out, "\n") {
+		ok := true
+		for _, p := range parts {
+			if !strings.Contains(line, p) {
+				ok = false
+				break
+			}
+		}
+		if ok {
+			return true
+		}
+	}
+	return false
+}
@@ -1,7 +1,6 @@
 package cli
 
 import (
-	"dv/internal/config"
 	"dv/internal/discourse"
 	"dv/internal/xdg"
 	"fmt"
@@ -22,7 +21,7 @@ var getSiteSettingCommand = &cobra.Command{
 		if err != nil {
 			return err
 		}
-		cfg, err := config.LoadOrCreate(configDir)
+		cfg, err := loadConfig(configDir)
 		if err != nil {
 			return err
 		}
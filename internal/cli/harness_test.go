@@ -0,0 +1,124 @@
+package cli
+
+import (
+	"bytes"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+
+	"dv/internal/config"
+	"dv/internal/docker"
+	"dv/internal/docker/dockertest"
+	"dv/internal/xdg"
+)
+
+// testEnv runs dv commands against a fake container runtime with XDG
+// directories isolated under a temp dir. Commands share global cobra state,
+// so tests using it must not run in parallel.
+type testEnv struct {
+	t       *testing.T
+	rt      *dockertest.Runtime
+	rootDir string
+}
+
+func newTestEnv(t *testing.T) *testEnv {
+	t.Helper()
+	root := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(root, "config"))
+	t.Setenv("XDG_DATA_HOME", filepath.Join(root, "data"))
+	t.Setenv("XDG_CACHE_HOME", filepath.Join(root, "cache"))
+	t.Setenv("XDG_RUNTIME_DIR", filepath.Join(root, "run"))
+	t.Setenv("DV_CONTAINER_RUNTIME", "")
+	t.Setenv("DV_VERBOSE", "")
+	t.Setenv("DV_AGENT", "")
+	t.Setenv("DV_PROFILE", "")
+	t.Setenv(skipUpdateEnvVar, "1")
+	t.Setenv(skipWatcherEnvVar, "1")
+
+	rt := dockertest.New()
+	prevNew := newRuntime
+	newRuntime = func(string) (docker.Runtime, error) { return rt, nil }
+	t.Cleanup(func() {
+		newRuntime = prevNew
+		docker.SetRuntime(nil)
+		rootCmd.SetArgs(nil)
+		rootCmd.SetOut(nil)
+		rootCmd.SetErr(nil)
+		rootCmd.SetIn(nil)
+	})
+	return &testEnv{t: t, rt: rt, rootDir: root}
+}
+
+// run executes dv with args and returns combined stdout and stderr.
+func (e *testEnv) run(args ...string) (string, error) {
+	e.t.Helper()
+	resetFlags(rootCmd)
+	var out bytes.Buffer
+	rootCmd.SetArgs(args)
+	rootCmd.SetOut(&out)
+	rootCmd.SetErr(&out)
+	rootCmd.SetIn(strings.NewReader(""))
+	err := rootCmd.Execute()
+	return out.String(), err
+}
+
+// mustRun is run that fails the test on error.
+func (e *testEnv) mustRun(args ...string) string {
+	e.t.Helper()
+	out, err := e.run(args...)
+	if err != nil {
+		e.t.Fatalf("dv %s: %v\n%s", strings.Join(args, " "), err, out)
+	}
+	return out
+}
+
+// config loads the config written by previous commands.
+func (e *testEnv) config() config.Config {
+	e.t.Helper()
+	configDir, err := xdg.ConfigDir()
+	if err != nil {
+		e.t.Fatal(err)
+	}
+	cfg, err := config.LoadOrCreate(configDir)
+	if err != nil {
+		e.t.Fatal(err)
+	}
+	return cfg
+}
+
+// saveConfig writes cfg as the current config.
+func (e *testEnv) saveConfig(cfg config.Config) {
+	e.t.Helper()
+	configDir, err := xdg.ConfigDir()
+	if err != nil {
+		e.t.Fatal(err)
+	}
+	if err := config.Save(configDir, cfg); err != nil {
+		e.t.Fatal(err)
+	}
+}
+
+// resetFlags restores every flag in the command tree to its default so
+// values don't leak between runs.
+func resetFlags(cmd *cobra.Command) {
+	reset := func(f *pflag.Flag) {
+		if sv, ok := f.Value.(pflag.SliceValue); ok {
+			var vals []string
+			if def := strings.Trim(f.DefValue, "[]"); def != "" {
+				vals = strings.Split(def, ",")
+			}
+			_ = sv.Replace(vals)
+		} else {
+			_ = f.Value.Set(f.DefValue)
+		}
+		f.Changed = false
+	}
+	cmd.Flags().VisitAll(reset)
+	cmd.PersistentFlags().VisitAll(reset)
+	for _, sub := range cmd.Commands() {
+		resetFlags(sub)
+	}
+}
@@ -0,0 +1,100 @@
+package cli
+
+import (
+	"time"
+
+	"dv/internal/config"
+	"dv/internal/docker"
+	"dv/internal/localproxy"
+)
+
+// idleAgentState tracks one agent for the idle policy.
+type idleAgentState struct {
+	// ActiveAt is the last time the agent was seen in use while running.
+	ActiveAt time.Time `json:"activeAt,omitempty"`
+	// AutoStoppedAt is set when the watcher stopped the agent, and cleared
+	// once it runs again.
+	AutoStoppedAt time.Time `json:"autoStoppedAt,omitempty"`
+}
+
+// checkIdleAgents samples every running agent whose image has an idle policy
+// and stops those idle for longer than the policy allows.
+func checkIdleAgents(cfg config.Config, state *watcherState, now time.Time) {
+	containers, err := docker.ListContainers()
+	if err != nil {
+		// The engine may be restarting; try again next time.
+		return
+	}
+	if state.Agents == nil {
+		state.Agents = map[string]*idleAgentState{}
+	}
+
+	lastRequest := map[string]time.Time{}
+	if cfg.LocalProxy.Enabled && localproxy.Running(cfg.LocalProxy) {
+		if routes, err := localproxy.Routes(cfg.LocalProxy); err == nil {
+			for _, r := range routes {
+				lastRequest[r.Host] = r.LastRequest
+			}
+		}
+	}
+
+	existing := map[string]bool{}
+	for _, ctr := range containers {
+		existing[ctr.Name] = true
+		a := state.Agents[ctr.Name]
+		if !ctr.Running {
+			// Restart the clock the next time it runs.
+			if a != nil {
+				a.ActiveAt = time.Time{}
+			}
+			continue
+		}
+		if a != nil {
+			a.AutoStoppedAt = time.Time{}
+		}
+		imgName, known := cfg.ContainerImages[ctr.Name]
+		if !known {
+			imgName = ctr.Labels["com.dv.image-name"]
+		}
+		if ctr.Labels["com.dv.owner"] != "dv" && !known {
+			continue
+		}
+		policy := cfg.Images[imgName].IdleStop
+		if !policy.Enabled() {
+			continue
+		}
+		if a == nil {
+			a = &idleAgentState{}
+			state.Agents[ctr.Name] = a
+		}
+		if a.ActiveAt.IsZero() || agentInUse(ctr, policy, lastRequest, a.ActiveAt) {
+			a.ActiveAt = now.UTC()
+		}
+		if now.Sub(a.ActiveAt) < policy.After() {
+			continue
+		}
+		if err := docker.Stop(ctr.Name); err != nil {
+			continue
+		}
+		a.ActiveAt = time.Time{}
+		a.AutoStoppedAt = now.UTC()
+	}
+	for name, a := range state.Agents {
+		if !existing[name] || (a.ActiveAt.IsZero() && a.AutoStoppedAt.IsZero()) {
+			delete(state.Agents, name)
+		}
+	}
+}
+
+// agentInUse reports whether the agent has an exec session, served proxy
+// traffic since it was last seen active, or is busy on the CPU.
+func agentInUse(ctr docker.ContainerSummary, policy config.IdlePolicy, lastRequest map[string]time.Time, activeAt time.Time) bool {
+	if n, err := docker.ExecSessions(ctr.Name); err != nil || n > 0 {
+		return true
+	}
+	if host, _, _, _, ok := localproxy.RouteFromLabels(ctr.Labels); ok && lastRequest[host].After(activeAt) {
+		return true
+	}
+	cpu, err := docker.CPUPercent(ctr.Name)
+	return err != nil || cpu >= policy.CPUThreshold()
+}
@@ -0,0 +1,91 @@
+package cli
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestIdleAgentsAreStopped(t *testing.T) {
+	env := newTestEnv(t)
+	env.rt.AddImage("ai_agent", nil)
+	env.mustRun("image", "set", "discourse", "--idle-stop", "30m", "--idle-cpu", "10")
+	if out := env.mustRun("image", "show", "discourse"); !strings.Contains(out, "idleStop: after 30m below 10% cpu") {
+		t.Fatalf("image show:\n%s", out)
+	}
+	for _, name := range []string{"busy", "shell", "quiet"} {
+		env.mustRun("new", name)
+	}
+	env.rt.SetActivity("busy", 0, 55)
+	env.rt.SetActivity("shell", 1, 0)
+	env.rt.SetActivity("quiet", 0, 2)
+
+	cfg := env.config()
+	state := &watcherState{}
+	start := time.Now()
+	checkIdleAgents(cfg, state, start)
+	checkIdleAgents(cfg, state, start.Add(20*time.Minute))
+	for _, name := range []string{"busy", "shell", "quiet"} {
+		if ctr, _ := env.rt.Container(name); !ctr.Running {
+			t.Fatalf("%s stopped before its idle time was up", name)
+		}
+	}
+
+	checkIdleAgents(cfg, state, start.Add(31*time.Minute))
+	for name, running := range map[string]bool{"busy": true, "shell": true, "quiet": false} {
+		if ctr, _ := env.rt.Container(name); ctr.Running != running {
+			t.Errorf("%s running = %v, want %v", name, ctr.Running, running)
+		}
+	}
+	if _, ok := state.autoStopped("quiet"); !ok {
+		t.Errorf("quiet not recorded as auto-stopped: %+v", state.Agents)
+	}
+
+	if err := saveWatcherState(filepath.Join(env.rootDir, "config", "dv"), state); err != nil {
+		t.Fatal(err)
+	}
+	if out := env.mustRun("list"); !hasLineWith(out, "quiet", "auto-stopped") || hasLineWith(out, "busy", "auto-stopped") {
+		t.Errorf("list output:\n%s", out)
+	}
+
+	// Once running again the mark goes and the clock restarts.
+	env.mustRun("start", "quiet")
+	later := start.Add(40 * time.Minute)
+	checkIdleAgents(cfg, state, later)
+	if _, ok := state.autoStopped("quiet"); ok || !state.Agents["quiet"].ActiveAt.Equal(later.UTC()) {
+		t.Errorf("quiet state after restart = %+v", state.Agents["quiet"])
+	}
+}
+
+func TestParseIdleStop(t *testing.T) {
+	t.Parallel()
+
+	for in, want := range map[string]int{"45m": 45, "2h": 120, "1d": 1440, "off": 0, "0": 0} {
+		if got, err := parseIdleStop(in); err != nil || got != want {
+			t.Errorf("parseIdleStop(%q) = %d, %v, want %d", in, got, err, want)
+		}
+	}
+	for _, in := range []string{"30s", "soon", ""} {
+		if _, err := parseIdleStop(in); err == nil {
+			t.Errorf("parseIdleStop(%q) succeeded", in)
+		}
+	}
+}
+
+func TestLockWatcher(t *testing.T) {
+	dir := t.TempDir()
+	first, ok, err := lockWatcher(dir)
+	if err != nil || !ok {
+		t.Fatalf("first lock: ok=%v err=%v", ok, err)
+	}
+	if _, ok, err := lockWatcher(dir); err != nil || ok {
+		t.Fatalf("second lock while held: ok=%v err=%v", ok, err)
+	}
+	first.Close()
+	again, ok, err := lockWatcher(dir)
+	if err != nil || !ok {
+		t.Fatalf("lock after release: ok=%v err=%v", ok, err)
+	}
+	again.Close()
+}
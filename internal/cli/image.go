@@ -6,6 +6,7 @@ import (
 	"path/filepath"
 	"sort"
 	"strings"
+	"time"
 
 	"github.com/spf13/cobra"
 
@@ -26,7 +27,7 @@ var imageListCmd = &cobra.Command{
 		if err != nil {
 			return err
 		}
-		cfg, err := config.LoadOrCreate(configDir)
+		cfg, err := loadConfig(configDir)
 		if err != nil {
 			return err
 		}
@@ -41,7 +42,11 @@ var imageListCmd = &cobra.Command{
 				mark = "*"
 			}
 			img := cfg.Images[n]
-			fmt.Fprintf(cmd.OutOrStdout(), "%s %-12s  tag=%s  kind=%s  workdir=%s  port=%d\n", mark, n, img.Tag, img.Kind, img.Workdir, img.ContainerPort)
+			fmt.Fprintf(cmd.OutOrStdout(), "%s %-12s  tag=%s  kind=%s  workdir=%s  port=%d", mark, n, img.Tag, img.Kind, img.Workdir, img.ContainerPort)
+			if len(img.Ports) > 0 {
+				fmt.Fprintf(cmd.OutOrStdout(), "  ports=%s", formatPortSpecs(img.Ports))
+			}
+			fmt.Fprintln(cmd.OutOrStdout())
 		}
 		return nil
 	},
@@ -56,7 +61,7 @@ var imageSelectCmd = &cobra.Command{
 		if err != nil {
 			return err
 		}
-		cfg, err := config.LoadOrCreate(configDir)
+		cfg, err := loadConfig(configDir)
 		if err != nil {
 			return err
 		}
@@ -82,7 +87,7 @@ var imageShowCmd = &cobra.Command{
 		if err != nil {
 			return err
 		}
-		cfg, err := config.LoadOrCreate(configDir)
+		cfg, err := loadConfig(configDir)
 		if err != nil {
 			return err
 		}
@@ -103,6 +108,21 @@ var imageShowCmd = &cobra.Command{
 		default:
 			fmt.Fprintf(cmd.OutOrStdout(), "dockerfile: (unknown)\n")
 		}
+		fmt.Fprintf(cmd.OutOrStdout(), "ports: %s\n", formatPortSpecs(img.Ports))
+		fmt.Fprintf(cmd.OutOrStdout(), "volumes: %s\n", formatVolumeMounts(img.Volumes))
+		fmt.Fprintf(cmd.OutOrStdout(), "logs: %s\n", formatLogPaths(img.LogPaths()))
+		fmt.Fprintf(cmd.OutOrStdout(), "idleStop: %s\n", img.IdleStop)
+		fmt.Fprintf(cmd.OutOrStdout(), "limits: %s\n", img.Limits)
+		agents := make([]string, 0, len(cfg.AgentLimits))
+		for agent := range cfg.AgentLimits {
+			if cfg.ContainerImages[agent] == name {
+				agents = append(agents, agent)
+			}
+		}
+		sort.Strings(agents)
+		for _, agent := range agents {
+			fmt.Fprintf(cmd.OutOrStdout(), "  %s: %s\n", agent, config.EffectiveLimits(cfg, img, agent))
+		}
 		return nil
 	},
 }
@@ -116,7 +136,7 @@ var imageAddCmd = &cobra.Command{
 		if err != nil {
 			return err
 		}
-		cfg, err := config.LoadOrCreate(configDir)
+		cfg, err := loadConfig(configDir)
 		if err != nil {
 			return err
 		}
@@ -203,7 +223,7 @@ var imageRemoveCmd = &cobra.Command{
 		if err != nil {
 			return err
 		}
-		cfg, err := config.LoadOrCreate(configDir)
+		cfg, err := loadConfig(configDir)
 		if err != nil {
 			return err
 		}
@@ -232,7 +252,7 @@ var imageRenameCmd = &cobra.Command{
 		if err != nil {
 			return err
 		}
-		cfg, err := config.LoadOrCreate(configDir)
+		cfg, err := loadConfig(configDir)
 		if err != nil {
 			return err
 		}
@@ -273,7 +293,7 @@ var imageSetCmd = &cobra.Command{
 		if err != nil {
 			return err
 		}
-		cfg, err := config.LoadOrCreate(configDir)
+		cfg, err := loadConfig(configDir)
 		if err != nil {
 			return err
 		}
@@ -315,6 +335,80 @@ var imageSetCmd = &cobra.Command{
 			}
 		}
 
+		if v, _ := cmd.Flags().GetStringArray("port"); len(v) > 0 {
+			for _, raw := range v {
+				spec, err := config.ParsePortSpec(raw)
+				if err != nil {
+					return err
+				}
+				if err := img.SetPort(spec); err != nil {
+					return err
+				}
+			}
+		}
+		if v, _ := cmd.Flags().GetStringArray("remove-port"); len(v) > 0 {
+			for _, portName := range v {
+				if !img.RemovePort(portName) {
+					return fmt.Errorf("image '%s' has no port named '%s'", name, portName)
+				}
+			}
+		}
+		if v, _ := cmd.Flags().GetStringArray("volume"); len(v) > 0 {
+			for _, raw := range v {
+				vol, err := config.ParseVolumeMount(raw)
+				if err != nil {
+					return err
+				}
+				if err := img.SetVolume(vol); err != nil {
+					return err
+				}
+			}
+		}
+		if v, _ := cmd.Flags().GetStringArray("remove-volume"); len(v) > 0 {
+			for _, volName := range v {
+				if !img.RemoveVolume(volName) {
+					return fmt.Errorf("image '%s' has no volume named '%s'", name, volName)
+				}
+			}
+		}
+		if v, _ := cmd.Flags().GetStringArray("log"); len(v) > 0 {
+			for _, raw := range v {
+				service, logPath, err := config.ParseLogPath(raw)
+				if err != nil {
+					return err
+				}
+				img.SetLog(service, logPath)
+			}
+		}
+		if v, _ := cmd.Flags().GetStringArray("remove-log"); len(v) > 0 {
+			for _, service := range v {
+				if !img.RemoveLog(service) {
+					return fmt.Errorf("image '%s' has no log named '%s'", name, service)
+				}
+			}
+		}
+		if cmd.Flags().Changed("idle-stop") {
+			v, _ := cmd.Flags().GetString("idle-stop")
+			minutes, err := parseIdleStop(v)
+			if err != nil {
+				return err
+			}
+			img.IdleStop.Minutes = minutes
+		}
+		if cmd.Flags().Changed("idle-cpu") {
+			img.IdleStop.CPUPercent, _ = cmd.Flags().GetFloat64("idle-cpu")
+		}
+		if err := img.IdleStop.Validate(); err != nil {
+			return err
+		}
+		limits, limitsSet, err := limitsFromFlags(cmd)
+		if err != nil {
+			return err
+		}
+		if limitsSet {
+			img.Limits = img.Limits.Merge(limits)
+		}
+
 		cfg.Images[name] = img
 		if err := config.Save(configDir, cfg); err != nil {
 			return err
@@ -344,4 +438,27 @@ func init() {
 	imageSetCmd.Flags().Int("container-port", 0, "Container port to expose")
 	imageSetCmd.Flags().String("stock", "", "Switch dockerfile source to the stock Discourse image")
 	imageSetCmd.Flags().String("dockerfile", "", "Switch dockerfile source to a custom Dockerfile path")
+	imageSetCmd.Flags().StringArray("port", nil, "Publish a named port: NAME=CONTAINER_PORT[:HOST_STARTING_PORT] (repeatable)")
+	imageSetCmd.Flags().StringArray("remove-port", nil, "Stop publishing a named port (repeatable)")
+	imageSetCmd.Flags().StringArray("volume", nil, "Mount a shared cache volume: NAME=PATH (repeatable)")
+	imageSetCmd.Flags().StringArray("remove-volume", nil, "Stop mounting a shared cache volume (repeatable)")
+	imageSetCmd.Flags().StringArray("log", nil, "Add or override a service log for dv logs: SERVICE=PATH (repeatable)")
+	imageSetCmd.Flags().StringArray("remove-log", nil, "Drop a service from dv logs (repeatable)")
+	imageSetCmd.Flags().String("idle-stop", "", "Stop agents idle this long, e.g. 45m or 2h ('off' to disable)")
+	imageSetCmd.Flags().Float64("idle-cpu", 0, fmt.Sprintf("CPU percent below which an agent counts as idle (default %d)", config.DefaultIdleCPUPercent))
+	addLimitFlags(imageSetCmd)
+}
+
+// parseIdleStop converts an --idle-stop value to whole minutes; "off" and
+// "0" disable idle stopping.
+func parseIdleStop(v string) (int, error) {
+	v = strings.TrimSpace(v)
+	if v == "off" || v == "0" {
+		return 0, nil
+	}
+	d, err := parseAge(v)
+	if err != nil || d < time.Minute {
+		return 0, fmt.Errorf("invalid --idle-stop %q: use a duration of at least a minute such as 45m or 2h, or 'off'", v)
+	}
+	return int(d / time.Minute), nil
 }
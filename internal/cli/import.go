@@ -14,7 +14,6 @@ import (
 
 	"github.com/spf13/cobra"
 
-	"dv/internal/config"
 	"dv/internal/docker"
 	"dv/internal/xdg"
 )
@@ -32,7 +31,7 @@ var importCmd = &cobra.Command{
 		if err != nil {
 			return err
 		}
-		cfg, err := config.LoadOrCreate(configDir)
+		cfg, err := loadConfig(configDir)
 		if err != nil {
 			return err
 		}
@@ -0,0 +1,74 @@
+package cli
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestNewCreatesAndSelectsAgent(t *testing.T) {
+	env := newTestEnv(t)
+	env.rt.AddImage("ai_agent", nil)
+
+	out := env.mustRun("new", "feature")
+	if !strings.Contains(out, "Agent 'feature' is ready and selected.") {
+		t.Errorf("unexpected output:\n%s", out)
+	}
+
+	ctr, ok := env.rt.Container("feature")
+	if !ok {
+		t.Fatal("container was not created")
+	}
+	if !ctr.Running || ctr.Image != "ai_agent" || ctr.Workdir != "/var/www/discourse" {
+		t.Errorf("unexpected container: %+v", ctr)
+	}
+	if ctr.Labels["com.dv.owner"] != "dv" || ctr.Labels["com.dv.image-name"] != "discourse" {
+		t.Errorf("labels = %v", ctr.Labels)
+	}
+	if ctr.Env["DISCOURSE_PORT"] == "" || len(ctr.Ports) != 1 {
+		t.Errorf("port not published: env=%v ports=%v", ctr.Env, ctr.Ports)
+	}
+
+	cfg := env.config()
+	if cfg.SelectedAgent != "feature" || cfg.ContainerImages["feature"] != "discourse" {
+		t.Errorf("config not updated: selected=%q images=%v", cfg.SelectedAgent, cfg.ContainerImages)
+	}
+
+	if _, err := env.run("new", "feature"); err == nil || !strings.Contains(err.Error(), "already exists") {
+		t.Errorf("duplicate new error = %v", err)
+	}
+}
+
+func TestNewFailsWithoutImage(t *testing.T) {
+	env := newTestEnv(t)
+
+	if _, err := env.run("new", "feature"); err == nil {
+		t.Fatal("expected error when the image has not been built")
+	}
+	if env.rt.Exists("feature") {
+		t.Error("container should not exist")
+	}
+}
+
+func TestStopRenameAndList(t *testing.T) {
+	env := newTestEnv(t)
+	env.rt.AddImage("ai_agent", nil)
+	env.mustRun("new", "first")
+
+	out := env.mustRun("stop", "first")
+	if !strings.Contains(out, "Stopping container 'first'") || env.rt.Running("first") {
+		t.Errorf("stop did not stop the container:\n%s", out)
+	}
+
+	env.mustRun("rename", "first", "second")
+	if env.rt.Exists("first") || !env.rt.Exists("second") {
+		t.Fatal("rename did not move the container")
+	}
+	if cfg := env.config(); cfg.SelectedAgent != "second" || cfg.ContainerImages["second"] != "discourse" {
+		t.Errorf("config not renamed: selected=%q images=%v", cfg.SelectedAgent, cfg.ContainerImages)
+	}
+
+	out = env.mustRun("list")
+	if !strings.Contains(out, "total 1") || !strings.Contains(out, "* second") {
+		t.Errorf("unexpected list output:\n%s", out)
+	}
+}
@@ -0,0 +1,52 @@
+package cli
+
+import (
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"dv/internal/config"
+	"dv/internal/docker"
+)
+
+// addLimitFlags registers the resource limit flags shared by new, start and image set.
+func addLimitFlags(cmd *cobra.Command) {
+	cmd.Flags().String("cpus", "", "CPU limit, e.g. 2 or 1.5")
+	cmd.Flags().String("memory", "", "Memory limit, e.g. 4g")
+	cmd.Flags().Int("pids-limit", 0, "Maximum number of processes (-1 for unlimited)")
+	cmd.Flags().String("shm-size", "", "Size of /dev/shm, e.g. 1g")
+}
+
+// limitsFromFlags reads the limit flags. ok is false when none were given.
+func limitsFromFlags(cmd *cobra.Command) (limits config.ResourceLimits, ok bool, err error) {
+	limits.CPUs, _ = cmd.Flags().GetString("cpus")
+	limits.Memory, _ = cmd.Flags().GetString("memory")
+	limits.PidsLimit, _ = cmd.Flags().GetInt("pids-limit")
+	limits.ShmSize, _ = cmd.Flags().GetString("shm-size")
+	limits.CPUs = strings.TrimSpace(limits.CPUs)
+	limits.Memory = strings.TrimSpace(limits.Memory)
+	limits.ShmSize = strings.TrimSpace(limits.ShmSize)
+	if limits.IsZero() {
+		return limits, false, nil
+	}
+	if err := limits.Validate(); err != nil {
+		return limits, false, err
+	}
+	return limits, true, nil
+}
+
+// setAgentLimits merges limits into the per-agent override for name.
+func setAgentLimits(cfg *config.Config, name string, limits config.ResourceLimits) {
+	if cfg.AgentLimits == nil {
+		cfg.AgentLimits = map[string]config.ResourceLimits{}
+	}
+	cfg.AgentLimits[name] = cfg.AgentLimits[name].Merge(limits)
+}
+
+// applyLimits copies resource limits onto container run options.
+func applyLimits(opts *docker.RunOptions, limits config.ResourceLimits) {
+	opts.CPUs = limits.CPUs
+	opts.Memory = limits.Memory
+	opts.PidsLimit = limits.PidsLimit
+	opts.ShmSize = limits.ShmSize
+}
@@ -0,0 +1,69 @@
+package cli
+
+import (
+	"strings"
+	"testing"
+
+	"dv/internal/config"
+)
+
+func TestNewAppliesImageAndAgentLimits(t *testing.T) {
+	env := newTestEnv(t)
+	env.rt.AddImage("ai_agent", nil)
+	env.mustRun("image", "set", "discourse", "--cpus", "4", "--memory", "8g")
+
+	env.mustRun("new", "limited", "--memory", "2g", "--pids-limit", "512")
+
+	ctr, ok := env.rt.Container("limited")
+	if !ok {
+		t.Fatal("container was not created")
+	}
+	opts := ctr.RunOptions
+	if opts.CPUs != "4" || opts.Memory != "2g" || opts.PidsLimit != 512 || opts.ShmSize != "" {
+		t.Errorf("run limits = cpus %q memory %q pids %d shm %q", opts.CPUs, opts.Memory, opts.PidsLimit, opts.ShmSize)
+	}
+	if got := env.config().AgentLimits["limited"]; got != (config.ResourceLimits{Memory: "2g", PidsLimit: 512}) {
+		t.Errorf("agent override = %+v", got)
+	}
+
+	out := env.mustRun("image", "show")
+	if !strings.Contains(out, "limits: cpus=4 memory=8g\n") || !strings.Contains(out, "  limited: cpus=4 memory=2g pids=512\n") {
+		t.Errorf("unexpected image show output:\n%s", out)
+	}
+
+	env.mustRun("remove", "limited")
+	if _, ok := env.config().AgentLimits["limited"]; ok {
+		t.Error("agent override not removed with the agent")
+	}
+}
+
+func TestStartResetRecreatesWithLimits(t *testing.T) {
+	env := newTestEnv(t)
+	env.rt.AddImage("ai_agent", nil)
+	env.mustRun("new", "agent")
+
+	out := env.mustRun("start", "agent", "--shm-size", "1g")
+	if !strings.Contains(out, "apply when the container is recreated") {
+		t.Errorf("expected recreate hint:\n%s", out)
+	}
+	if ctr, _ := env.rt.Container("agent"); ctr.RunOptions.ShmSize != "" {
+		t.Errorf("running container changed without --reset")
+	}
+
+	env.mustRun("start", "agent", "--reset")
+	if ctr, _ := env.rt.Container("agent"); ctr.RunOptions.ShmSize != "1g" {
+		t.Errorf("shm size after reset = %q", ctr.RunOptions.ShmSize)
+	}
+}
+
+func TestLimitFlagsRejectInvalidValues(t *testing.T) {
+	env := newTestEnv(t)
+	env.rt.AddImage("ai_agent", nil)
+
+	if _, err := env.run("new", "agent", "--memory", "lots"); err == nil || !strings.Contains(err.Error(), "invalid memory") {
+		t.Errorf("error = %v, want invalid memory", err)
+	}
+	if env.rt.Exists("agent") {
+		t.Error("container created despite invalid limits")
+	}
+}
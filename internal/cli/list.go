@@ -8,7 +8,7 @@ import (
 
 	"github.com/spf13/cobra"
 
-	"dv/internal/config"
+	"dv/internal/docker"
 	"dv/internal/localproxy"
 	"dv/internal/xdg"
 )
@@ -22,7 +22,7 @@ var listCmd = &cobra.Command{
 		if err != nil {
 			return err
 		}
-		cfg, err := config.LoadOrCreate(configDir)
+		cfg, err := loadConfig(configDir)
 		if err != nil {
 			return err
 		}
@@ -34,33 +34,19 @@ var listCmd = &cobra.Command{
 
 		proxyActive := cfg.LocalProxy.Enabled && localproxy.Running(cfg.LocalProxy)
 
-		// Include Ports, Labels, and CreatedAt for discovery, clickable URLs, and ordering
-		out, _ := runShell("docker ps -a --format '{{.Names}}\t{{.Image}}\t{{.Status}}\t{{.Ports}}\t{{.Labels}}\t{{.CreatedAt}}'")
+		containers, _ := docker.ListContainers()
+		idle, _ := loadWatcherState(configDir)
+		if idle == nil {
+			idle = &watcherState{}
+		}
 		selected := currentAgentName(cfg)
 		var agents []agentInfo
 
-		for _, line := range strings.Split(strings.TrimSpace(out), "\n") {
-			if strings.TrimSpace(line) == "" {
-				continue
-			}
-			parts := strings.SplitN(line, "\t", 6)
-			if len(parts) < 3 {
-				continue
-			}
-			name, image, status := parts[0], parts[1], parts[2]
-			portsField := ""
-			if len(parts) >= 4 {
-				portsField = parts[3]
-			}
-			labelsField := ""
-			if len(parts) >= 5 {
-				labelsField = parts[4]
-			}
-			labelMap := parseLabels(labelsField)
-			createdAt := time.Time{}
-			if len(parts) >= 6 {
-				createdAt = parseDockerTime(parts[5])
-			}
+		for _, ctr := range containers {
+			name, image, status := ctr.Name, ctr.Image, ctr.Status
+			portsField := ctr.Ports
+			labelMap := ctr.Labels
+			createdAt := ctr.CreatedAt
 			// Determine if this container belongs to the selected image
 			belongs := false
 			if imgNameFromCfg, ok := cfg.ContainerImages[name]; ok && imgNameFromCfg == imgName {
@@ -83,6 +69,9 @@ var listCmd = &cobra.Command{
 
 			// Parse status and time
 			statusText, timeText := parseStatus(status)
+			if stoppedAt, ok := idle.autoStopped(name); ok && !ctr.Running {
+				statusText, timeText = "Stopped", "auto-stopped "+formatUptime(time.Since(stoppedAt))+" ago"
+			}
 			urls := parseHostPortURLs(portsField)
 			if proxyActive {
 				if host, _, _, httpPort, ok := localproxy.RouteFromLabels(labelMap); ok && host != "" {
@@ -107,6 +96,8 @@ var listCmd = &cobra.Command{
 				}
 			}
 
+			urls = withNamedPortURLs(urls, namedPortsFromLabels(labelMap))
+
 			agents = append(agents, agentInfo{
 				name:      name,
 				status:    statusText,
@@ -199,33 +190,6 @@ func parseHostPortURLs(portsField string) []string {
 	return urls
 }
 
-// parseLabels converts a docker --format {{.Labels}} string (comma-separated key=value pairs)
-// into a map for easy lookup. Malformed entries are ignored.
-func parseLabels(labelsField string) map[string]string {
-	labelsField = strings.TrimSpace(labelsField)
-	if labelsField == "" {
-		return map[string]string{}
-	}
-	items := strings.Split(labelsField, ",")
-	out := make(map[string]string, len(items))
-	for _, it := range items {
-		it = strings.TrimSpace(it)
-		if it == "" {
-			continue
-		}
-		kv := strings.SplitN(it, "=", 2)
-		if len(kv) != 2 {
-			continue
-		}
-		key := strings.TrimSpace(kv[0])
-		val := strings.TrimSpace(kv[1])
-		if key != "" {
-			out[key] = val
-		}
-	}
-	return out
-}
-
 // agentInfo holds information about a container for formatted display
 type agentInfo struct {
 	name      string
@@ -255,20 +219,6 @@ func calculateMaxNameWidth(agents []agentInfo) int {
 	return maxWidth
 }
 
-// parseDockerTime parses the CreatedAt field emitted by `docker ps --format {{.CreatedAt}}`.
-// Example: "2024-07-20 15:04:05 -0700 MST"
-func parseDockerTime(createdAt string) time.Time {
-	createdAt = strings.TrimSpace(createdAt)
-	if createdAt == "" {
-		return time.Time{}
-	}
-	t, err := time.Parse("2006-01-02 15:04:05 -0700 MST", createdAt)
-	if err != nil {
-		return time.Time{}
-	}
-	return t
-}
-
 // sortAgents orders agents with non-running (stopped/created) first by oldest creation time,
 // followed by running agents also ordered from oldest to newest. This keeps active containers
 // at the bottom while preserving a predictable age-based ordering.
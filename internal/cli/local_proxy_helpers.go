@@ -53,6 +53,40 @@ func applyLocalProxyMetadata(cfg config.Config, containerName string, hostPort i
 	return host
 }
 
+// clearedProxyMetadata returns labels and env that blank the proxy route an
+// image committed from another agent carries. docker run can't unset an
+// image's labels or env, only override them. When the proxy is running,
+// applyLocalProxyMetadata replaces them with the new agent's own route.
+func clearedProxyMetadata() (labels map[string]string, envs map[string]string) {
+	labels = map[string]string{}
+	for _, k := range []string{
+		localproxy.LabelEnabled,
+		localproxy.LabelHost,
+		localproxy.LabelTargetPort,
+		localproxy.LabelContainerPort,
+		localproxy.LabelHTTPPort,
+		localproxy.LabelHTTPSPort,
+	} {
+		labels[k] = ""
+	}
+	envs = map[string]string{
+		"DISCOURSE_HOSTNAME":      "localhost",
+		"RAILS_DEVELOPMENT_HOSTS": "",
+		"DISCOURSE_FORCE_HTTPS":   "false",
+	}
+	for _, k := range []string{
+		"DV_LOCAL_PROXY_HOST",
+		"DV_LOCAL_PROXY_HTTP_PORT",
+		"DV_LOCAL_PROXY_HTTPS_PORT",
+		"DV_LOCAL_PROXY_SCHEME",
+		"DV_LOCAL_PROXY_PORT",
+		"DISCOURSE_DEV_ALLOW_HTTPS",
+	} {
+		envs[k] = ""
+	}
+	return labels, envs
+}
+
 func registerWithLocalProxy(cmd *cobra.Command, cfg config.Config, containerName string, host string, containerPort int) {
 	if host == "" || containerPort <= 0 || !cfg.LocalProxy.Enabled {
 		return
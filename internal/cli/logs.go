@@ -0,0 +1,332 @@
+package cli
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"os/signal"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/spf13/cobra"
+	"golang.org/x/term"
+
+	"dv/internal/docker"
+	"dv/internal/xdg"
+)
+
+// logColors are ANSI foreground colours cycled across service prefixes.
+var logColors = []string{"36", "33", "35", "32", "34", "31"}
+
+var logsCmd = &cobra.Command{
+	Use:   "logs [SERVICE...]",
+	Short: "Show service logs from an agent",
+	Long: `Show service logs from an agent.
+
+Without arguments every known service is shown. Service log paths come from
+the image kind (unicorn, ember-cli, caddy, postgresql and redis for discourse
+images) and can be changed with 'dv image set --log SERVICE=PATH'.`,
+	ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		configDir, err := xdg.ConfigDir()
+		if err != nil {
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		}
+		cfg, err := loadConfig(configDir)
+		if err != nil {
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		}
+		name, _ := cmd.Flags().GetString("name")
+		if name == "" {
+			name = currentAgentName(cfg)
+		}
+		_, imgCfg, err := resolveImage(cfg, cfg.ContainerImages[name])
+		if err != nil {
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		}
+		var services []string
+		for _, svc := range sortedLogServices(imgCfg.LogPaths()) {
+			if strings.HasPrefix(svc, toComplete) && !containsString(args, svc) {
+				services = append(services, svc)
+			}
+		}
+		return services, cobra.ShellCompDirectiveNoFileComp
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		configDir, err := xdg.ConfigDir()
+		if err != nil {
+			return err
+		}
+		cfg, err := loadConfig(configDir)
+		if err != nil {
+			return err
+		}
+		name, _ := cmd.Flags().GetString("name")
+		if name == "" {
+			name = currentAgentName(cfg)
+		}
+		if !docker.Running(name) {
+			return fmt.Errorf("agent '%s' is not running; start it with 'dv start'", name)
+		}
+		imgName, imgCfg, err := resolveImage(cfg, cfg.ContainerImages[name])
+		if err != nil {
+			return err
+		}
+		paths := imgCfg.LogPaths()
+		if len(paths) == 0 {
+			return fmt.Errorf("image '%s' has no logs configured; add some with 'dv image set %s --log SERVICE=PATH'", imgName, imgName)
+		}
+		services := sortedLogServices(paths)
+		if len(args) > 0 {
+			for _, svc := range args {
+				if _, ok := paths[svc]; !ok {
+					return fmt.Errorf("unknown service '%s' (available: %s)", svc, strings.Join(services, ", "))
+				}
+			}
+			services = args
+		}
+
+		follow, _ := cmd.Flags().GetBool("follow")
+		lines, _ := cmd.Flags().GetInt("lines")
+		sinceFlag, _ := cmd.Flags().GetString("since")
+		grepFlag, _ := cmd.Flags().GetString("grep")
+		var since time.Time
+		if sinceFlag != "" {
+			if since, err = parseSince(sinceFlag, time.Now()); err != nil {
+				return err
+			}
+		}
+		var grep *regexp.Regexp
+		if grepFlag != "" {
+			if grep, err = regexp.Compile(grepFlag); err != nil {
+				return fmt.Errorf("invalid --grep: %w", err)
+			}
+		}
+
+		out := cmd.OutOrStdout()
+		color := false
+		if f, ok := out.(*os.File); ok && os.Getenv("NO_COLOR") == "" {
+			color = term.IsTerminal(int(f.Fd()))
+		}
+		width := 0
+		for _, svc := range services {
+			width = max(width, len(svc))
+		}
+
+		ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt)
+		defer stop()
+		tag := ""
+		if follow {
+			// Cancelling an exec only closes our end of the stream, so the
+			// tails are tagged and killed by name once we stop reading.
+			tag = fmt.Sprintf("dv-logs-%d-%d", os.Getpid(), time.Now().UnixNano())
+			defer func() { _, _ = docker.ExecAsRoot(name, "/", nil, []string{"pkill", "-f", tag}) }()
+		}
+		var mu sync.Mutex
+		var wg sync.WaitGroup
+		errs := make([]error, len(services))
+		for i, svc := range services {
+			w := &logLineWriter{mu: &mu, out: out, filter: newLogFilter(since, grep)}
+			if len(services) > 1 {
+				w.prefix = fmt.Sprintf("%-*s | ", width, svc)
+				if color {
+					w.prefix = "\033[" + logColors[i%len(logColors)] + "m" + w.prefix + "\033[0m"
+				}
+			}
+			argv := tailArgv(paths[svc], lines, follow, !since.IsZero(), tag)
+			run := func() {
+				defer w.Flush()
+				errs[i] = docker.ExecAsRootStream(ctx, name, "/", nil, argv, w)
+			}
+			// Without --follow, services are printed one after another so
+			// their output isn't interleaved.
+			if !follow {
+				run()
+				continue
+			}
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				run()
+			}()
+		}
+		wg.Wait()
+		if ctx.Err() != nil {
+			return nil
+		}
+		var failed []string
+		for i, err := range errs {
+			if err != nil {
+				fmt.Fprintf(cmd.ErrOrStderr(), "%s: %s: %v\n", services[i], paths[services[i]], execErrorDetail(err))
+				failed = append(failed, services[i])
+			}
+		}
+		if len(failed) > 0 {
+			return fmt.Errorf("could not read logs for %s", strings.Join(failed, ", "))
+		}
+		return nil
+	},
+}
+
+// tailArgv builds the tail invocation for one log file. -F follows the file
+// by name, so logs rotated by svlogd or logrotate keep streaming. A followed
+// tail runs with tag as its process name so 'pkill -f tag' can stop it.
+func tailArgv(path string, lines int, follow, fromStart bool, tag string) []string {
+	n := strconv.Itoa(lines)
+	if fromStart {
+		// --since filters on our side, so start from the top of the file.
+		n = "+1"
+	}
+	if !follow {
+		return []string{"tail", "-n", n, path}
+	}
+	return []string{"bash", "-c", `exec -a "$0" tail -n ` + n + ` -F "$1"`, tag, path}
+}
+
+func sortedLogServices(paths map[string]string) []string {
+	services := make([]string, 0, len(paths))
+	for svc := range paths {
+		services = append(services, svc)
+	}
+	sort.Strings(services)
+	return services
+}
+
+// formatLogPaths renders SERVICE=PATH pairs sorted by service.
+func formatLogPaths(paths map[string]string) string {
+	if len(paths) == 0 {
+		return "none"
+	}
+	parts := make([]string, 0, len(paths))
+	for _, svc := range sortedLogServices(paths) {
+		parts = append(parts, svc+"="+paths[svc])
+	}
+	return strings.Join(parts, " ")
+}
+
+func execErrorDetail(err error) string {
+	if ee, ok := err.(*docker.ExitError); ok && strings.TrimSpace(ee.Stderr) != "" {
+		return strings.TrimSpace(ee.Stderr)
+	}
+	return err.Error()
+}
+
+// logLineWriter splits a stream into lines, filters them and writes them
+// with a prefix. The mutex is shared by all services so lines never tear.
+type logLineWriter struct {
+	mu     *sync.Mutex
+	out    io.Writer
+	prefix string
+	filter func(string) bool
+	buf    []byte
+}
+
+func (w *logLineWriter) Write(p []byte) (int, error) {
+	w.buf = append(w.buf, p...)
+	for {
+		i := bytes.IndexByte(w.buf, '\n')
+		if i < 0 {
+			break
+		}
+		w.emit(string(w.buf[:i]))
+		w.buf = w.buf[i+1:]
+	}
+	return len(p), nil
+}
+
+// Flush writes any trailing partial line.
+func (w *logLineWriter) Flush() {
+	if len(w.buf) > 0 {
+		w.emit(string(w.buf))
+		w.buf = nil
+	}
+}
+
+func (w *logLineWriter) emit(line string) {
+	line = strings.TrimSuffix(line, "\r")
+	if w.filter != nil && !w.filter(line) {
+		return
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	fmt.Fprintf(w.out, "%s%s\n", w.prefix, line)
+}
+
+// newLogFilter returns a line filter for --since and --grep. Lines without a
+// recognisable timestamp (stack traces, continuation lines) follow the
+// decision made for the last timestamped line.
+func newLogFilter(since time.Time, grep *regexp.Regexp) func(string) bool {
+	if since.IsZero() && grep == nil {
+		return nil
+	}
+	recent := since.IsZero()
+	return func(line string) bool {
+		if !since.IsZero() {
+			if ts, ok := logLineTime(line); ok {
+				recent = !ts.Before(since)
+			}
+			if !recent {
+				return false
+			}
+		}
+		return grep == nil || grep.MatchString(line)
+	}
+}
+
+var (
+	logTimestampRe = regexp.MustCompile(`\d{4}-\d{2}-\d{2}[T _]\d{2}:\d{2}:\d{2}(?:\.\d+)?(?:Z|[+-]\d{2}:?\d{2})?`)
+	logTAI64NRe    = regexp.MustCompile(`^@([0-9a-f]{16})[0-9a-f]{8}`)
+	logJSONTimeRe  = regexp.MustCompile(`"ts":\s*(\d+(?:\.\d+)?)`)
+)
+
+// logLineTime extracts the timestamp of a log line: ISO-style dates (Rails,
+// Postgres, svlogd -tt), svlogd TAI64N labels and Caddy's JSON "ts" field.
+// Times without a zone are taken as UTC, the container default.
+func logLineTime(line string) (time.Time, bool) {
+	if m := logTAI64NRe.FindStringSubmatch(line); m != nil {
+		secs, err := strconv.ParseUint(m[1], 16, 64)
+		if err == nil {
+			// TAI64 labels are offset by 2^62 and run 10s ahead of UTC.
+			return time.Unix(int64(secs-(1<<62))-10, 0).UTC(), true
+		}
+	}
+	if m := logJSONTimeRe.FindStringSubmatch(line); m != nil {
+		if f, err := strconv.ParseFloat(m[1], 64); err == nil {
+			sec, frac := math.Modf(f)
+			return time.Unix(int64(sec), int64(frac*1e9)).UTC(), true
+		}
+	}
+	if m := logTimestampRe.FindString(line); m != "" {
+		s := m[:10] + "T" + m[11:]
+		for _, layout := range []string{"2006-01-02T15:04:05.999999999Z07:00", "2006-01-02T15:04:05.999999999Z0700", "2006-01-02T15:04:05.999999999"} {
+			if ts, err := time.Parse(layout, s); err == nil {
+				return ts, true
+			}
+		}
+	}
+	return time.Time{}, false
+}
+
+// parseSince accepts a duration ago ("10m", "2h", "3d") or an RFC 3339 time.
+func parseSince(s string, now time.Time) (time.Time, error) {
+	if d, err := parseAge(s); err == nil {
+		return now.Add(-d), nil
+	}
+	if ts, err := time.Parse(time.RFC3339, s); err == nil {
+		return ts, nil
+	}
+	return time.Time{}, fmt.Errorf("invalid --since %q: use a duration like 10m or 2d, or an RFC 3339 time", s)
+}
+
+func init() {
+	logsCmd.Flags().String("name", "", "Container name (defaults to selected or default)")
+	logsCmd.Flags().BoolP("follow", "f", false, "Keep streaming new lines, following rotated files")
+	logsCmd.Flags().IntP("lines", "n", 50, "Lines to show from the end of each log")
+	logsCmd.Flags().String("since", "", "Only show lines newer than a duration (10m, 2h, 3d) or RFC 3339 time")
+	logsCmd.Flags().String("grep", "", "Only show lines matching this regular expression")
+}
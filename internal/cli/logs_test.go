@@ -0,0 +1,131 @@
+package cli
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"dv/internal/docker/dockertest"
+)
+
+func TestLogsMultiplexesServices(t *testing.T) {
+	env := newTestEnv(t)
+	env.rt.AddImage("ai_agent", nil)
+	env.mustRun("new", "web")
+	env.rt.HandleExec("tail", func(e dockertest.Exec) (string, error) {
+		if e.User != "root" {
+			t.Errorf("tail ran as %q", e.User)
+		}
+		switch e.Argv[len(e.Argv)-1] {
+		case "/var/www/discourse/log/unicorn.log":
+			return "Started GET /latest\nCompleted 200 OK\n", nil
+		case "/var/log/caddy.log":
+			return "caddy ready", nil
+		}
+		return "", nil
+	})
+
+	out := env.mustRun("logs", "unicorn", "caddy")
+	for _, want := range []string{"unicorn | Started GET /latest\n", "unicorn | Completed 200 OK\n", "caddy   | caddy ready\n"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("logs output missing %q:\n%s", want, out)
+		}
+	}
+	last := env.rt.Execs()[len(env.rt.Execs())-1]
+	if got := strings.Join(last.Argv, " "); got != "tail -n 50 /var/log/caddy.log" {
+		t.Errorf("tail argv = %q", got)
+	}
+
+	out = env.mustRun("logs", "unicorn", "-f", "--grep", "Completed")
+	if out != "Completed 200 OK\n" {
+		t.Errorf("single service output = %q", out)
+	}
+	// The followed tail runs under a tag that is killed when logs returns.
+	execs := env.rt.Execs()
+	follow, kill := execs[len(execs)-2], execs[len(execs)-1]
+	if !strings.Contains(strings.Join(follow.Argv, " "), "tail -n 50 -F") {
+		t.Errorf("follow argv = %v", follow.Argv)
+	}
+	if got := strings.Join(kill.Argv, " "); got != "pkill -f "+follow.Argv[3] || kill.User != "root" {
+		t.Errorf("follow cleanup = %q as %q, want pkill -f %s", got, kill.User, follow.Argv[3])
+	}
+
+	if _, err := env.run("logs", "sidekiq"); err == nil {
+		t.Error("unknown service accepted")
+	}
+}
+
+func TestLogsSinceAndCustomPaths(t *testing.T) {
+	env := newTestEnv(t)
+	env.rt.AddImage("ai_agent", nil)
+	env.mustRun("new", "web")
+	env.mustRun("image", "set", "discourse", "--log", "sidekiq=/var/www/discourse/log/sidekiq.log", "--remove-log", "redis")
+	now := time.Now().UTC()
+	env.rt.HandleExec("sidekiq.log", func(e dockertest.Exec) (string, error) {
+		return now.Add(-2*time.Hour).Format(time.RFC3339) + " old job\n" +
+			"  backtrace of old job\n" +
+			now.Add(-time.Minute).Format(time.RFC3339) + " new job\n" +
+			"  backtrace of new job\n", nil
+	})
+
+	out := env.mustRun("logs", "sidekiq", "--since", "10m")
+	if strings.Contains(out, "old job") || !strings.Contains(out, "new job\n  backtrace of new job\n") {
+		t.Errorf("since output:\n%s", out)
+	}
+	last := env.rt.Execs()[len(env.rt.Execs())-1]
+	if got := strings.Join(last.Argv, " "); got != "tail -n +1 /var/www/discourse/log/sidekiq.log" {
+		t.Errorf("tail argv = %q", got)
+	}
+	if _, err := env.run("logs", "redis"); err == nil {
+		t.Error("removed service still available")
+	}
+	if out := env.mustRun("image", "show", "discourse"); !strings.Contains(out, "sidekiq=/var/www/discourse/log/sidekiq.log") {
+		t.Errorf("image show:\n%s", out)
+	}
+}
+
+func TestLogLineTime(t *testing.T) {
+	t.Parallel()
+
+	want := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	lines := []string{
+		"I, [2024-05-01T10:00:00.000000 #123]  INFO -- : Started GET",
+		"2024-05-01 10:00:00.000 UTC [42] LOG:  checkpoint starting",
+		"2024-05-01 12:00:00+02:00 redis ready",
+		`{"level":"info","ts":1714557600.0,"msg":"serving"}`,
+		"@40000000663212aa00000000 ready to accept connections",
+		"2024-05-01_10:00:00.00000 svlogd line",
+	}
+	for _, line := range lines {
+		got, ok := logLineTime(line)
+		if !ok || !got.Equal(want) {
+			t.Errorf("logLineTime(%q) = %s, %v; want %s", line, got, ok, want)
+		}
+	}
+	if _, ok := logLineTime("  from app/models/post.rb:12"); ok {
+		t.Error("continuation line parsed as timestamped")
+	}
+}
+
+func TestLogFilterKeepsContinuationLines(t *testing.T) {
+	t.Parallel()
+
+	since := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	filter := newLogFilter(since, regexp.MustCompile("Error|from"))
+	var kept []string
+	for _, line := range []string{
+		"2024-05-01T09:00:00Z Error old",
+		"  from old.rb:1",
+		"2024-05-01T10:30:00Z Error new",
+		"  from new.rb:1",
+		"2024-05-01T10:31:00Z all good",
+	} {
+		if filter(line) {
+			kept = append(kept, line)
+		}
+	}
+	if strings.Join(kept, "|") != "2024-05-01T10:30:00Z Error new|  from new.rb:1" {
+		t.Errorf("kept = %q", kept)
+	}
+}
@@ -9,7 +9,6 @@ import (
 
 	"github.com/spf13/cobra"
 
-	"dv/internal/config"
 	"dv/internal/docker"
 	"dv/internal/xdg"
 )
@@ -33,7 +32,7 @@ Press Ctrl+C to stop both MailHog and the tunnel.`,
 		if err != nil {
 			return err
 		}
-		cfg, err := config.LoadOrCreate(configDir)
+		cfg, err := loadConfig(configDir)
 		if err != nil {
 			return err
 		}
@@ -57,7 +56,7 @@ Press Ctrl+C to stop both MailHog and the tunnel.`,
 
 		// Start MailHog in the container as discourse user
 		log("Starting MailHog process: docker exec -u discourse %s mailhog", name)
-		mailhogProcess := exec.Command("docker", "exec", "-u", "discourse", name, "mailhog")
+		mailhogProcess := exec.Command(docker.Binary(), "exec", "-u", "discourse", name, "mailhog")
 		mailhogProcess.Stdout = nil
 		mailhogProcess.Stderr = os.Stderr
 		if err := mailhogProcess.Start(); err != nil {
@@ -68,7 +67,7 @@ Press Ctrl+C to stop both MailHog and the tunnel.`,
 		// Ensure MailHog is killed on exit (must kill inside container since docker exec doesn't forward signals)
 		defer func() {
 			log("Cleanup: killing mailhog inside container")
-			killCmd := exec.Command("docker", "exec", name, "pkill", "-f", "mailhog")
+			killCmd := exec.Command(docker.Binary(), "exec", name, "pkill", "-f", "mailhog")
 			if err := killCmd.Run(); err != nil {
 				log("pkill mailhog returned: %v", err)
 			} else {
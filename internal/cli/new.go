@@ -2,15 +2,12 @@ package cli
 
 import (
 	"fmt"
-	"io"
-	"net/http"
 	"os"
 	"os/exec"
 	"path"
 	"strings"
 
 	"github.com/spf13/cobra"
-	"gopkg.in/yaml.v3"
 
 	"dv/internal/config"
 	"dv/internal/docker"
@@ -27,37 +24,24 @@ var newCmd = &cobra.Command{
 		if err != nil {
 			return err
 		}
-		cfg, err := config.LoadOrCreate(configDir)
+		cfg, err := loadConfig(configDir)
 		if err != nil {
 			return err
 		}
 
 		templatePath, _ := cmd.Flags().GetString("template")
+		setFlags, _ := cmd.Flags().GetStringArray("set")
+		if len(setFlags) > 0 && templatePath == "" {
+			return fmt.Errorf("--set needs --template")
+		}
+		sets, err := parseTemplateSets(setFlags)
+		if err != nil {
+			return err
+		}
 		var tpl *templateConfig
 		if templatePath != "" {
-			var data []byte
-			if strings.HasPrefix(templatePath, "http://") || strings.HasPrefix(templatePath, "https://") {
-				resp, fetchErr := http.Get(templatePath)
-				if fetchErr != nil {
-					return fmt.Errorf("fetch template URL: %w", fetchErr)
-				}
-				defer resp.Body.Close()
-				if resp.StatusCode != http.StatusOK {
-					return fmt.Errorf("fetch template URL: %s returned status %d", templatePath, resp.StatusCode)
-				}
-				data, err = io.ReadAll(resp.Body)
-				if err != nil {
-					return fmt.Errorf("read template body: %w", err)
-				}
-			} else {
-				data, err = os.ReadFile(templatePath)
-				if err != nil {
-					return fmt.Errorf("read template: %w", err)
-				}
-			}
-			tpl = &templateConfig{}
-			if err = yaml.Unmarshal(data, tpl); err != nil {
-				return fmt.Errorf("parse template YAML: %w", err)
+			if tpl, err = loadTemplate(cfg, templatePath, sets); err != nil {
+				return err
 			}
 		}
 
@@ -90,6 +74,10 @@ var newCmd = &cobra.Command{
 		if docker.Exists(name) {
 			return fmt.Errorf("an agent named '%s' already exists", name)
 		}
+		limits, limitsSet, err := limitsFromFlags(cmd)
+		if err != nil {
+			return err
+		}
 
 		// Cleanup on failure
 		keepOnFailure, _ := cmd.Flags().GetBool("keep-on-failure")
@@ -140,6 +128,10 @@ var newCmd = &cobra.Command{
 			}
 		}
 
+		if limitsSet {
+			setAgentLimits(&cfg, name, limits)
+		}
+
 		// Apply template-specific config changes before saving
 		if tpl != nil {
 			// Add copy rules
@@ -161,7 +153,7 @@ var newCmd = &cobra.Command{
 		if tpl != nil {
 			templateEnvs = tpl.Env
 		}
-		if err = ensureContainerRunningWithWorkdir(cmd, cfg, name, workdir, imageTag, imgName, false, sshAuthSock, templateEnvs); err != nil {
+		if err = ensureContainerRunningWithWorkdir(cmd, cfg, name, workdir, imageTag, imgName, false, sshAuthSock, templateEnvs, nil); err != nil {
 			return err
 		}
 		containerCreated = true
@@ -197,6 +189,100 @@ var newCmd = &cobra.Command{
 	},
 }
 
+// templatePluginPath is where a template plugin is cloned, relative to the
+// Discourse workdir.
+func templatePluginPath(p templatePlugin) string {
+	if p.Path != "" {
+		return p.Path
+	}
+	return path.Join("plugins", path.Base(strings.TrimSuffix(p.Repo, ".git")))
+}
+
+func cloneTemplatePlugin(cmd *cobra.Command, name, workdir string, envList docker.Envs, p templatePlugin) error {
+	pPath := templatePluginPath(p)
+	fmt.Fprintf(cmd.OutOrStdout(), "Installing plugin %s into %s...\n", p.Repo, pPath)
+	cloneCmd := fmt.Sprintf("git clone %s %s", shellQuote(p.Repo), shellQuote(pPath))
+	if p.Branch != "" {
+		cloneCmd = fmt.Sprintf("git clone -b %s %s %s", shellQuote(p.Branch), shellQuote(p.Repo), shellQuote(pPath))
+	}
+	if err := docker.ExecInteractive(name, workdir, envList, []string{"bash", "-lc", cloneCmd}); err != nil {
+		return fmt.Errorf("failed to clone plugin %s: %w", p.Repo, err)
+	}
+	return nil
+}
+
+// startDiscourseAndWait starts unicorn and ember-cli and waits up to 120s for
+// Discourse to answer. A timeout is only a warning.
+func startDiscourseAndWait(cmd *cobra.Command, name, workdir string) error {
+	startScript := "sudo /usr/bin/sv start unicorn ember-cli || true"
+	if _, err := docker.ExecOutput(name, workdir, nil, []string{"bash", "-lc", startScript}); err != nil {
+		return fmt.Errorf("failed to start services: %w", err)
+	}
+
+	// Wait for health check (max 120s)
+	healthCmd := "timeout 120 bash -c 'until curl -s -f http://localhost:4200/srv/status > /dev/null 2>&1; do sleep 2; done' || exit 1"
+	if _, err := docker.ExecOutput(name, workdir, nil, []string{"bash", "-lc", healthCmd}); err != nil {
+		fmt.Fprintf(cmd.ErrOrStderr(), "Warning: Discourse did not become healthy within 120s. Some settings might fail.\n")
+	} else {
+		fmt.Fprintf(cmd.OutOrStdout(), "Discourse is ready.\n")
+	}
+	return nil
+}
+
+func templateThemeContext(cfg *config.Config, name, workdir string, envList docker.Envs, verbose bool) themeCommandContext {
+	dataDir, _ := extractRoot(*cfg)
+	configDir, _ := xdg.ConfigDir()
+	return themeCommandContext{
+		cfg:           cfg,
+		configDir:     configDir,
+		containerName: name,
+		discourseRoot: workdir,
+		dataDir:       dataDir,
+		verbose:       verbose || isTruthyEnv("DV_VERBOSE"),
+		envs:          envList,
+	}
+}
+
+// templateThemePath is where handleThemeClone puts a template theme.
+func templateThemePath(t templateTheme) string {
+	_, defaultName := normalizeThemeRepo(t.Repo)
+	name := t.Name
+	if name == "" {
+		name = defaultName
+	}
+	return path.Join("/home/discourse", themeDirSlug(name))
+}
+
+func configureTemplateMCP(cmd *cobra.Command, name, workdir string, envList docker.Envs, m templateMCP) error {
+	fmt.Fprintf(cmd.OutOrStdout(), "Configuring MCP %s...\n", m.Name)
+	if m.Command != "" {
+		// Custom MCP
+		mcpCfg := mcpConfiguration{
+			name:            m.Name,
+			registrationCmd: fmt.Sprintf("claude mcp add -s user %s -- %s %s", m.Name, m.Command, strings.Join(m.Args, " ")),
+			codexCommand:    m.Command,
+			codexArgs:       m.Args,
+			geminiCommand:   m.Command,
+			geminiArgs:      m.Args,
+		}
+		if err := configureMCP(cmd, name, workdir, envList, mcpCfg); err != nil {
+			return fmt.Errorf("failed to configure custom MCP %s: %w", m.Name, err)
+		}
+		return nil
+	}
+	// Stock MCP (playwright, discourse, chrome-devtools)
+	switch m.Name {
+	case "playwright":
+		return configurePlaywrightMCP(cmd, name, workdir, envList)
+	case "discourse":
+		return configureDiscourseMCP(cmd, name, workdir, envList)
+	case "chrome-devtools":
+		return configureChromeDevToolsMCP(cmd, name, workdir, envList)
+	default:
+		return fmt.Errorf("unknown stock MCP: %s", m.Name)
+	}
+}
+
 func checkoutPR(cmd *cobra.Command, cfg config.Config, name, workdir string, prNumber int, envs docker.Envs) error {
 	owner, repo := prSearchOwnerRepoFromContainer(cfg, name)
 	if owner == "" || repo == "" {
@@ -333,17 +419,8 @@ ssh-keyscan github.com >> ~/.ssh/known_hosts 2>/dev/null
 		_ = docker.ExecInteractive(name, workdir, envList, []string{"bash", "-lc", testCmd})
 	}
 	for _, p := range tpl.Plugins {
-		pPath := p.Path
-		if pPath == "" {
-			pPath = path.Join("plugins", path.Base(strings.TrimSuffix(p.Repo, ".git")))
-		}
-		fmt.Fprintf(cmd.OutOrStdout(), "Installing plugin %s into %s...\n", p.Repo, pPath)
-		cloneCmd := fmt.Sprintf("git clone %s %s", shellQuote(p.Repo), shellQuote(pPath))
-		if p.Branch != "" {
-			cloneCmd = fmt.Sprintf("git clone -b %s %s %s", shellQuote(p.Branch), shellQuote(p.Repo), shellQuote(pPath))
-		}
-		if err := docker.ExecInteractive(name, workdir, envList, []string{"bash", "-lc", cloneCmd}); err != nil {
-			return fmt.Errorf("failed to clone plugin %s: %w", p.Repo, err)
+		if err := cloneTemplatePlugin(cmd, name, workdir, envList, p); err != nil {
+			return err
 		}
 	}
 
@@ -355,17 +432,8 @@ ssh-keyscan github.com >> ~/.ssh/known_hosts 2>/dev/null
 
 	// 6. Start Services and Wait for Health
 	fmt.Fprintf(cmd.OutOrStdout(), "Provisioning complete. Starting Discourse and waiting for it to be ready...\n")
-	startScript := "sudo /usr/bin/sv start unicorn ember-cli || true"
-	if _, err = docker.ExecOutput(name, workdir, nil, []string{"bash", "-lc", startScript}); err != nil {
-		return fmt.Errorf("failed to start services: %w", err)
-	}
-
-	// Wait for health check (max 120s)
-	healthCmd := "timeout 120 bash -c 'until curl -s -f http://localhost:4200/srv/status > /dev/null 2>&1; do sleep 2; done' || exit 1"
-	if _, err = docker.ExecOutput(name, workdir, nil, []string{"bash", "-lc", healthCmd}); err != nil {
-		fmt.Fprintf(cmd.ErrOrStderr(), "Warning: Discourse did not become healthy within 120s. Some settings might fail.\n")
-	} else {
-		fmt.Fprintf(cmd.OutOrStdout(), "Discourse is ready.\n")
+	if err = startDiscourseAndWait(cmd, name, workdir); err != nil {
+		return err
 	}
 
 	// 8. Post-Boot Configuration (Settings, Themes, MCP)
@@ -382,17 +450,7 @@ ssh-keyscan github.com >> ~/.ssh/known_hosts 2>/dev/null
 	// Themes
 	for _, t := range tpl.Themes {
 		fmt.Fprintf(cmd.OutOrStdout(), "Installing theme %s...\n", t.Repo)
-		dataDir, _ := xdg.DataDir()
-		configDir, _ := xdg.ConfigDir()
-		ctx := themeCommandContext{
-			cfg:           &cfg,
-			configDir:     configDir,
-			containerName: name,
-			discourseRoot: workdir,
-			dataDir:       dataDir,
-			verbose:       verbose || isTruthyEnv("DV_VERBOSE"),
-			envs:          envList,
-		}
+		ctx := templateThemeContext(&cfg, name, workdir, envList, verbose)
 		if err = handleThemeClone(cmd, ctx, t.Repo, t.Name); err != nil {
 			return fmt.Errorf("failed to install theme %s: %w", t.Repo, err)
 		}
@@ -429,38 +487,8 @@ ssh-keyscan github.com >> ~/.ssh/known_hosts 2>/dev/null
 
 	// MCP
 	for _, m := range tpl.MCP {
-		fmt.Fprintf(cmd.OutOrStdout(), "Configuring MCP %s...\n", m.Name)
-		mcpCfg := mcpConfiguration{
-			name: m.Name,
-		}
-		if m.Command != "" {
-			// Custom MCP
-			mcpCfg.registrationCmd = fmt.Sprintf("claude mcp add -s user %s -- %s %s", m.Name, m.Command, strings.Join(m.Args, " "))
-			mcpCfg.codexCommand = m.Command
-			mcpCfg.codexArgs = m.Args
-			mcpCfg.geminiCommand = m.Command
-			mcpCfg.geminiArgs = m.Args
-			if err = configureMCP(cmd, name, workdir, envList, mcpCfg); err != nil {
-				return fmt.Errorf("failed to configure custom MCP %s: %w", m.Name, err)
-			}
-		} else {
-			// Stock MCP (playwright, discourse, chrome-devtools)
-			switch m.Name {
-			case "playwright":
-				if err = configurePlaywrightMCP(cmd, name, workdir, envList); err != nil {
-					return err
-				}
-			case "discourse":
-				if err = configureDiscourseMCP(cmd, name, workdir, envList); err != nil {
-					return err
-				}
-			case "chrome-devtools":
-				if err = configureChromeDevToolsMCP(cmd, name, workdir, envList); err != nil {
-					return err
-				}
-			default:
-				return fmt.Errorf("unknown stock MCP: %s", m.Name)
-			}
+		if err = configureTemplateMCP(cmd, name, workdir, envList, m); err != nil {
+			return err
 		}
 	}
 
@@ -469,18 +497,21 @@ ssh-keyscan github.com >> ~/.ssh/known_hosts 2>/dev/null
 
 func init() {
 	newCmd.Flags().String("image", "", "Image to use (defaults to selected image)")
-	newCmd.Flags().String("template", "", "Path to a template YAML file")
+	newCmd.Flags().String("template", "", "Path or URL of a template YAML file, or a template name (see dv template list)")
+	newCmd.Flags().StringArray("set", nil, "Set a template variable (NAME=VALUE)")
 	newCmd.Flags().Bool("keep-on-failure", false, "Keep the container even if provisioning fails")
 	newCmd.Flags().BoolP("verbose", "v", false, "Print verbose debugging output")
 	newCmd.Flags().String("pr", "", "PR number or search query to checkout")
 	newCmd.Flags().String("branch", "", "Branch to checkout")
+	addLimitFlags(newCmd)
 
+	newCmd.RegisterFlagCompletionFunc("template", completeTemplateRefs)
 	newCmd.RegisterFlagCompletionFunc("pr", func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
 		configDir, err := xdg.ConfigDir()
 		if err != nil {
 			return nil, cobra.ShellCompDirectiveNoFileComp
 		}
-		cfg, err := config.LoadOrCreate(configDir)
+		cfg, err := loadConfig(configDir)
 		if err != nil {
 			return nil, cobra.ShellCompDirectiveNoFileComp
 		}
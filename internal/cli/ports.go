@@ -0,0 +1,108 @@
+package cli
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"dv/internal/config"
+	"dv/internal/docker"
+)
+
+// portLabelPrefix namespaces named port labels: com.dv.port.NAME=HOST:CONTAINER.
+const portLabelPrefix = "com.dv.port."
+
+// namedPort is a published image port recorded on a container.
+type namedPort struct {
+	Name          string
+	HostPort      int
+	ContainerPort int
+}
+
+// allocateNamedPorts picks a free host port for each spec, starting at its
+// preferred port and skipping anything already allocated, including ports
+// chosen earlier in the same call. Choices are recorded in labels.
+func allocateNamedPorts(specs []config.PortSpec, allocated map[int]bool, labels map[string]string) []docker.PortMapping {
+	var mappings []docker.PortMapping
+	for _, spec := range specs {
+		hostPort := spec.StartingPort()
+		for isPortInUse(hostPort, allocated) {
+			hostPort++
+		}
+		allocated[hostPort] = true
+		labels[portLabelPrefix+spec.Name] = fmt.Sprintf("%d:%d", hostPort, spec.ContainerPort)
+		mappings = append(mappings, docker.PortMapping{HostPort: hostPort, ContainerPort: spec.ContainerPort})
+	}
+	return mappings
+}
+
+// namedPortsFromLabels reads the named ports recorded on a container, sorted by name.
+func namedPortsFromLabels(labels map[string]string) []namedPort {
+	var ports []namedPort
+	for key, val := range labels {
+		name, ok := strings.CutPrefix(key, portLabelPrefix)
+		if !ok || name == "" {
+			continue
+		}
+		hostStr, containerStr, ok := strings.Cut(val, ":")
+		if !ok {
+			continue
+		}
+		hostPort, err1 := strconv.Atoi(hostStr)
+		containerPort, err2 := strconv.Atoi(containerStr)
+		if err1 != nil || err2 != nil {
+			continue
+		}
+		ports = append(ports, namedPort{Name: name, HostPort: hostPort, ContainerPort: containerPort})
+	}
+	sort.Slice(ports, func(i, j int) bool { return ports[i].Name < ports[j].Name })
+	return ports
+}
+
+// portMappings converts recorded named ports back into run options.
+func portMappings(ports []namedPort) []docker.PortMapping {
+	var mappings []docker.PortMapping
+	for _, p := range ports {
+		mappings = append(mappings, docker.PortMapping{HostPort: p.HostPort, ContainerPort: p.ContainerPort})
+	}
+	return mappings
+}
+
+// formatPortSpecs renders image ports as "name=container" pairs, or "none".
+func formatPortSpecs(specs []config.PortSpec) string {
+	if len(specs) == 0 {
+		return "none"
+	}
+	parts := make([]string, 0, len(specs))
+	for _, p := range specs {
+		part := fmt.Sprintf("%s=%d", p.Name, p.ContainerPort)
+		if p.HostStartingPort > 0 {
+			part += fmt.Sprintf(":%d", p.HostStartingPort)
+		}
+		parts = append(parts, part)
+	}
+	return strings.Join(parts, " ")
+}
+
+// withNamedPortURLs drops named ports from the generic URL list and appends
+// them as name=URL so each role is identifiable.
+func withNamedPortURLs(urls []string, ports []namedPort) []string {
+	if len(ports) == 0 {
+		return urls
+	}
+	named := make(map[string]bool, len(ports))
+	for _, p := range ports {
+		named["http://localhost:"+strconv.Itoa(p.HostPort)] = true
+	}
+	out := make([]string, 0, len(urls)+len(ports))
+	for _, u := range urls {
+		if !named[u] {
+			out = append(out, u)
+		}
+	}
+	for _, p := range ports {
+		out = append(out, fmt.Sprintf("%s=http://localhost:%d", p.Name, p.HostPort))
+	}
+	return out
+}
@@ -0,0 +1,112 @@
+package cli
+
+import (
+	"fmt"
+	"net"
+	"reflect"
+	"strings"
+	"testing"
+
+	"dv/internal/config"
+)
+
+func TestAllocateNamedPortsSkipsAllocated(t *testing.T) {
+	t.Parallel()
+
+	// Hold a real listener so the bind check also fails for this port.
+	l, err := net.Listen("tcp", ":0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	busy := l.Addr().(*net.TCPAddr).Port
+
+	allocated := map[int]bool{busy + 1: true}
+	labels := map[string]string{}
+	specs := []config.PortSpec{
+		{Name: "a", ContainerPort: 9000, HostStartingPort: busy},
+		{Name: "b", ContainerPort: 9001, HostStartingPort: busy},
+	}
+	got := allocateNamedPorts(specs, allocated, labels)
+	if len(got) != 2 || got[0].HostPort <= busy+1 || got[1].HostPort <= got[0].HostPort {
+		t.Fatalf("allocateNamedPorts() = %+v (busy %d)", got, busy)
+	}
+	if got[0].ContainerPort != 9000 || got[1].ContainerPort != 9001 {
+		t.Errorf("container ports = %+v", got)
+	}
+
+	ports := namedPortsFromLabels(labels)
+	want := []namedPort{
+		{Name: "a", HostPort: got[0].HostPort, ContainerPort: 9000},
+		{Name: "b", HostPort: got[1].HostPort, ContainerPort: 9001},
+	}
+	if !reflect.DeepEqual(ports, want) {
+		t.Errorf("namedPortsFromLabels() = %+v, want %+v", ports, want)
+	}
+	if !reflect.DeepEqual(portMappings(ports), got) {
+		t.Errorf("portMappings() = %+v, want %+v", portMappings(ports), got)
+	}
+}
+
+func TestNamedPortsFromLabelsIgnoresMalformed(t *testing.T) {
+	t.Parallel()
+
+	labels := map[string]string{
+		"com.dv.owner":        "dv",
+		"com.dv.port.":        "1:2",
+		"com.dv.port.bad":     "nope",
+		"com.dv.port.alsobad": "1:x",
+		"com.dv.port.ok":      "8026:8025",
+	}
+	got := namedPortsFromLabels(labels)
+	if len(got) != 1 || got[0] != (namedPort{Name: "ok", HostPort: 8026, ContainerPort: 8025}) {
+		t.Errorf("namedPortsFromLabels() = %+v", got)
+	}
+}
+
+func TestWithNamedPortURLs(t *testing.T) {
+	t.Parallel()
+
+	urls := []string{"http://localhost:4201", "http://localhost:8026"}
+	got := withNamedPortURLs(urls, []namedPort{{Name: "mailhog", HostPort: 8026, ContainerPort: 8025}})
+	want := []string{"http://localhost:4201", "mailhog=http://localhost:8026"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("withNamedPortURLs() = %v, want %v", got, want)
+	}
+}
+
+func TestNewPublishesNamedPorts(t *testing.T) {
+	env := newTestEnv(t)
+	env.rt.AddImage("ai_agent", nil)
+	env.mustRun("image", "set", "discourse", "--port", "mailhog=8025:18025", "--port", "rails=3000:13000")
+
+	env.mustRun("new", "ported")
+	ctr, _ := env.rt.Container("ported")
+	extra := ctr.RunOptions.ExtraPorts
+	if len(extra) != 2 || extra[0].ContainerPort != 8025 || extra[1].ContainerPort != 3000 {
+		t.Fatalf("ExtraPorts = %+v", extra)
+	}
+	for _, p := range extra {
+		if p.HostPort == ctr.RunOptions.HostPort {
+			t.Errorf("named port reused the main host port %d", p.HostPort)
+		}
+	}
+	mailhog := extra[0]
+	if got, want := ctr.Labels["com.dv.port.mailhog"], fmt.Sprintf("%d:8025", mailhog.HostPort); got != want {
+		t.Errorf("mailhog label = %q", got)
+	}
+
+	out := env.mustRun("list")
+	if !strings.Contains(out, "mailhog=http://localhost:") || !strings.Contains(out, "rails=http://localhost:") {
+		t.Errorf("list does not show named ports:\n%s", out)
+	}
+
+	out = env.mustRun("image", "show")
+	if !strings.Contains(out, "ports: mailhog=8025:18025 rails=3000:13000\n") {
+		t.Errorf("image show output:\n%s", out)
+	}
+	env.mustRun("image", "set", "discourse", "--remove-port", "rails")
+	if img := env.config().Images["discourse"]; len(img.Ports) != 1 {
+		t.Errorf("ports after removal = %+v", img.Ports)
+	}
+}
@@ -0,0 +1,286 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+
+	"dv/internal/config"
+	"dv/internal/session"
+	"dv/internal/xdg"
+)
+
+var profileCmd = &cobra.Command{
+	Use:   "profile",
+	Short: "Switch between named config overlays",
+	Long: `Profiles are named overlays on config.json, stored as YAML under
+${XDG_CONFIG_HOME}/dv/profiles. A profile can set the selected image and
+agent, add env passthrough variables, copy rules and templates, change the
+extract dir and override local proxy settings.
+
+The active profile is chosen per terminal with 'dv profile use', or with the
+DV_PROFILE environment variable. A project's .dv.yaml is applied on top.`,
+}
+
+var profileCreateCmd = &cobra.Command{
+	Use:   "create NAME",
+	Short: "Create a profile",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		configDir, err := xdg.ConfigDir()
+		if err != nil {
+			return err
+		}
+		name := args[0]
+		if err := config.ValidateProfileName(name); err != nil {
+			return err
+		}
+		path := config.ProfilePath(configDir, name)
+		force, _ := cmd.Flags().GetBool("force")
+		if _, err := os.Stat(path); err == nil && !force {
+			return fmt.Errorf("profile '%s' already exists (use --force to replace it)", name)
+		}
+
+		var o config.Overlay
+		o.SelectedImage, _ = cmd.Flags().GetString("image")
+		o.Agent, _ = cmd.Flags().GetString("agent")
+		o.EnvPassthrough, _ = cmd.Flags().GetStringSlice("env")
+		copies, _ := cmd.Flags().GetStringArray("copy")
+		for _, c := range copies {
+			host, container, ok := strings.Cut(c, ":")
+			if !ok || host == "" || container == "" {
+				return fmt.Errorf("invalid --copy %q: expected HOST:CONTAINER", c)
+			}
+			if !filepath.IsAbs(host) && !strings.HasPrefix(host, "~") && !strings.HasPrefix(host, "$") {
+				if abs, err := filepath.Abs(host); err == nil {
+					host = abs
+				}
+			}
+			o.CopyRules = append(o.CopyRules, config.CopyRule{Host: host, Container: container})
+		}
+		if o.SelectedImage != "" {
+			cfg, err := config.LoadOrCreate(configDir)
+			if err != nil {
+				return err
+			}
+			if _, _, err := resolveImage(cfg, o.SelectedImage); err != nil {
+				return err
+			}
+		}
+		if err := config.SaveOverlay(path, o); err != nil {
+			return err
+		}
+		fmt.Fprintf(cmd.OutOrStdout(), "Created profile '%s' at %s\n", name, path)
+
+		if edit, _ := cmd.Flags().GetBool("edit"); edit {
+			editorCmd := exec.Command(getEditor(), path)
+			editorCmd.Stdin = os.Stdin
+			editorCmd.Stdout = os.Stdout
+			editorCmd.Stderr = os.Stderr
+			if err := editorCmd.Run(); err != nil {
+				return err
+			}
+			if _, err := config.LoadProfile(configDir, name); err != nil {
+				return err
+			}
+		}
+		return nil
+	},
+}
+
+var profileUseCmd = &cobra.Command{
+	Use:   "use [NAME]",
+	Short: "Use a profile in this terminal",
+	Args:  cobra.RangeArgs(0, 1),
+	ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		return completeProfileNames(args)
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if clearProfile, _ := cmd.Flags().GetBool("clear"); clearProfile {
+			if len(args) > 0 {
+				return fmt.Errorf("--clear does not take a profile name")
+			}
+			if err := session.SetCurrentProfile(""); err != nil {
+				return err
+			}
+			fmt.Fprintln(cmd.OutOrStdout(), "No profile in use in this terminal.")
+			return nil
+		}
+		if len(args) == 0 {
+			return fmt.Errorf("profile name required (or --clear)")
+		}
+		configDir, err := xdg.ConfigDir()
+		if err != nil {
+			return err
+		}
+		name := args[0]
+		if _, err := config.LoadProfile(configDir, name); err != nil {
+			return err
+		}
+		if err := session.SetCurrentProfile(name); err != nil {
+			return err
+		}
+		fmt.Fprintf(cmd.OutOrStdout(), "Using profile '%s' in this terminal.\n", name)
+		if env := strings.TrimSpace(os.Getenv("DV_PROFILE")); env != "" && env != name {
+			fmt.Fprintf(cmd.ErrOrStderr(), "Warning: DV_PROFILE=%s takes precedence in this shell.\n", env)
+		}
+		return nil
+	},
+}
+
+var profileListCmd = &cobra.Command{
+	Use:     "list",
+	Aliases: []string{"ls"},
+	Short:   "List profiles",
+	Args:    cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		configDir, err := xdg.ConfigDir()
+		if err != nil {
+			return err
+		}
+		names, err := config.ListProfiles(configDir)
+		if err != nil {
+			return err
+		}
+		if len(names) == 0 {
+			fmt.Fprintln(cmd.OutOrStdout(), "(no profiles)")
+			return nil
+		}
+		active, _ := currentProfile()
+		w := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 4, 2, ' ', 0)
+		fmt.Fprintln(w, "\tNAME\tOVERRIDES")
+		for _, name := range names {
+			marker := ""
+			if name == active {
+				marker = "*"
+			}
+			summary := "(invalid)"
+			if o, err := config.LoadProfile(configDir, name); err == nil {
+				summary = overlaySummary(o)
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\n", marker, name, summary)
+		}
+		return w.Flush()
+	},
+}
+
+var profileDiffCmd = &cobra.Command{
+	Use:   "diff PROFILE [OTHER]",
+	Short: "Show how a profile changes the config, or how two profiles differ",
+	Args:  cobra.RangeArgs(1, 2),
+	ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		return completeProfileNames(args)
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		configDir, err := xdg.ConfigDir()
+		if err != nil {
+			return err
+		}
+		base, err := config.LoadOrCreate(configDir)
+		if err != nil {
+			return err
+		}
+		render := func(name string) (string, string, error) {
+			cfg := base
+			label := "config.json"
+			if name != "" {
+				o, err := config.LoadProfile(configDir, name)
+				if err != nil {
+					return "", "", err
+				}
+				cfg.Apply(o, "profile "+name)
+				label = "profile " + name
+			}
+			b, err := json.MarshalIndent(cfg, "", "  ")
+			if err != nil {
+				return "", "", err
+			}
+			return label, string(b) + "\n", nil
+		}
+		from, to := "", args[0]
+		if len(args) == 2 {
+			from, to = args[0], args[1]
+		}
+		oldName, oldText, err := render(from)
+		if err != nil {
+			return err
+		}
+		newName, newText, err := render(to)
+		if err != nil {
+			return err
+		}
+		diff := unifiedDiff(oldName, newName, oldText, newText)
+		if diff == "" {
+			fmt.Fprintln(cmd.OutOrStdout(), "No differences.")
+			return nil
+		}
+		fmt.Fprint(cmd.OutOrStdout(), diff)
+		return nil
+	},
+}
+
+// overlaySummary lists what an overlay sets, for dv profile list.
+func overlaySummary(o config.Overlay) string {
+	var parts []string
+	if o.SelectedImage != "" {
+		parts = append(parts, "image="+o.SelectedImage)
+	}
+	if o.Agent != "" {
+		parts = append(parts, "agent="+o.Agent)
+	}
+	if n := len(o.EnvPassthrough); n > 0 {
+		parts = append(parts, fmt.Sprintf("env+%d", n))
+	}
+	if n := len(o.CopyRules); n > 0 {
+		parts = append(parts, fmt.Sprintf("copyRules+%d", n))
+	}
+	if n := len(o.Templates); n > 0 {
+		parts = append(parts, fmt.Sprintf("templates+%d", n))
+	}
+	if o.ExtractDir != "" {
+		parts = append(parts, "extractDir")
+	}
+	if o.LocalProxy != nil {
+		parts = append(parts, "localProxy")
+	}
+	if len(parts) == 0 {
+		return "(nothing)"
+	}
+	return strings.Join(parts, ", ")
+}
+
+func completeProfileNames(args []string) ([]string, cobra.ShellCompDirective) {
+	configDir, err := xdg.ConfigDir()
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	names, _ := config.ListProfiles(configDir)
+	var out []string
+	for _, n := range names {
+		if !containsString(args, n) {
+			out = append(out, n)
+		}
+	}
+	return out, cobra.ShellCompDirectiveNoFileComp
+}
+
+func init() {
+	profileCmd.AddCommand(profileCreateCmd)
+	profileCmd.AddCommand(profileUseCmd)
+	profileCmd.AddCommand(profileListCmd)
+	profileCmd.AddCommand(profileDiffCmd)
+
+	profileCreateCmd.Flags().String("image", "", "Image to select while the profile is active")
+	profileCreateCmd.Flags().String("agent", "", "Agent to use when none is selected in the terminal")
+	profileCreateCmd.Flags().StringSlice("env", nil, "Extra environment variables to pass through (repeatable)")
+	profileCreateCmd.Flags().StringArray("copy", nil, "Extra copy rule as HOST:CONTAINER (repeatable)")
+	profileCreateCmd.Flags().Bool("edit", false, "Open the new profile in your editor")
+	profileCreateCmd.Flags().Bool("force", false, "Replace an existing profile")
+	profileUseCmd.Flags().Bool("clear", false, "Stop using a profile in this terminal")
+}
@@ -0,0 +1,74 @@
+package cli
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestProfileLifecycle(t *testing.T) {
+	env := newTestEnv(t)
+	cfg := env.config()
+	cfg.Images["plugin"] = cfg.Images["discourse"]
+	env.saveConfig(cfg)
+
+	env.mustRun("profile", "create", "plugin-work", "--image", "plugin", "--env", "PLUGIN_TOKEN", "--copy", "/host/.env:/home/discourse/.env")
+	env.mustRun("profile", "create", "core")
+	if _, err := env.run("profile", "create", "core"); err == nil {
+		t.Fatal("expected error creating an existing profile")
+	}
+	if _, err := env.run("profile", "create", "plugin/work"); err == nil {
+		t.Fatal("expected error for invalid profile name")
+	}
+	if _, err := env.run("profile", "create", "bad", "--image", "nope"); err == nil {
+		t.Fatal("expected error for unknown image")
+	}
+
+	out := env.mustRun("profile", "list")
+	if !hasLineWith(out, "plugin-work", "image=plugin, env+1, copyRules+1") || !hasLineWith(out, "core", "(nothing)") {
+		t.Fatalf("list output:\n%s", out)
+	}
+
+	out = env.mustRun("profile", "diff", "plugin-work")
+	for _, want := range []string{
+		"--- config.json",
+		"+++ profile plugin-work",
+		`-  "selectedImage": "discourse",`,
+		`+  "selectedImage": "plugin",`,
+		`+    "PLUGIN_TOKEN"`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Fatalf("missing %q in diff:\n%s", want, out)
+		}
+	}
+	if out := env.mustRun("profile", "diff", "core"); !strings.Contains(out, "No differences.") {
+		t.Fatalf("diff output:\n%s", out)
+	}
+
+	if _, err := env.run("profile", "use", "missing"); err == nil {
+		t.Fatal("expected error using a missing profile")
+	}
+	env.mustRun("profile", "use", "plugin-work")
+	if out := env.mustRun("profile", "list"); !hasLineWith(out, "*", "plugin-work") {
+		t.Fatalf("active profile not marked:\n%s", out)
+	}
+	out = env.mustRun("config", "show", "--effective")
+	if !hasLineWith(out, "selectedImage", "plugin", "profile plugin-work") {
+		t.Fatalf("effective config:\n%s", out)
+	}
+	// Commands that save config don't persist the profile.
+	env.mustRun("config", "set", "discourseRepo", "https://example.com/discourse.git")
+	if got := env.config(); got.SelectedImage != "discourse" || containsString(got.EnvPassthrough, "PLUGIN_TOKEN") {
+		t.Fatalf("profile saved into config.json: %+v", got)
+	}
+
+	t.Setenv("DV_PROFILE", "core")
+	if out := env.mustRun("config", "show", "--effective"); !hasLineWith(out, "selectedImage", "discourse") {
+		t.Fatalf("DV_PROFILE ignored:\n%s", out)
+	}
+	t.Setenv("DV_PROFILE", "")
+
+	env.mustRun("profile", "use", "--clear")
+	if out := env.mustRun("profile", "list"); strings.Contains(out, "*") {
+		t.Fatalf("profile still active:\n%s", out)
+	}
+}
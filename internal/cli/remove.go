@@ -30,7 +30,7 @@ var removeCmd = &cobra.Command{
 		if err != nil {
 			return err
 		}
-		cfg, err := config.LoadOrCreate(configDir)
+		cfg, err := loadConfig(configDir)
 		if err != nil {
 			return err
 		}
@@ -86,6 +86,12 @@ var removeCmd = &cobra.Command{
 				dirty = true
 			}
 		}
+		if cfg.AgentLimits != nil {
+			if _, ok := cfg.AgentLimits[name]; ok {
+				delete(cfg.AgentLimits, name)
+				dirty = true
+			}
+		}
 
 		// If we removed the selected agent, choose the first remaining container for the selected image
 		if cfg.SelectedAgent == name {
@@ -97,17 +103,10 @@ var removeCmd = &cobra.Command{
 				_, imgCfg, _ = resolveImage(cfg, "")
 			}
 
-			out, _ := runShell("docker ps -a --format '{{.Names}}\t{{.Image}}'")
+			containers, _ := docker.ListContainers()
 			var first string
-			for _, line := range strings.Split(strings.TrimSpace(out), "\n") {
-				if strings.TrimSpace(line) == "" {
-					continue
-				}
-				parts := strings.SplitN(line, "\t", 2)
-				if len(parts) < 2 {
-					continue
-				}
-				n, image := parts[0], parts[1]
+			for _, ctr := range containers {
+				n, image := ctr.Name, ctr.Image
 				if image != imgCfg.Tag {
 					continue
 				}
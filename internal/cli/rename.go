@@ -34,7 +34,7 @@ var renameCmd = &cobra.Command{
 		if err != nil {
 			return err
 		}
-		cfg, err := config.LoadOrCreate(configDir)
+		cfg, err := loadConfig(configDir)
 		if err != nil {
 			return err
 		}
@@ -76,6 +76,12 @@ var renameCmd = &cobra.Command{
 				cfg.CustomWorkdirs[newName] = w
 			}
 		}
+		if cfg.AgentLimits != nil {
+			if l, ok := cfg.AgentLimits[oldName]; ok {
+				delete(cfg.AgentLimits, oldName)
+				cfg.AgentLimits[newName] = l
+			}
+		}
 		if err := config.Save(configDir, cfg); err != nil {
 			return err
 		}
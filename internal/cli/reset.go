@@ -39,7 +39,7 @@ func resetDbRunE(cmd *cobra.Command, args []string) error {
 	if err != nil {
 		return err
 	}
-	cfg, err := config.LoadOrCreate(configDir)
+	cfg, err := loadConfig(configDir)
 	if err != nil {
 		return err
 	}
@@ -93,7 +93,7 @@ func resetGitRunE(cmd *cobra.Command, args []string) error {
 	if err != nil {
 		return err
 	}
-	cfg, err := config.LoadOrCreate(configDir)
+	cfg, err := loadConfig(configDir)
 	if err != nil {
 		return err
 	}
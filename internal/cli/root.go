@@ -3,8 +3,13 @@ package cli
 import (
 	"fmt"
 	"os"
+	"strings"
 
 	"github.com/spf13/cobra"
+
+	"dv/internal/config"
+	"dv/internal/docker"
+	"dv/internal/xdg"
 )
 
 var rootCmd = &cobra.Command{
@@ -12,16 +17,44 @@ var rootCmd = &cobra.Command{
 	Short:         "Discourse Vibe: manage local Discourse dev containers",
 	SilenceUsage:  true,
 	SilenceErrors: true,
-	PersistentPreRun: func(cmd *cobra.Command, args []string) {
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
 		if verbose, _ := cmd.Flags().GetBool("verbose"); verbose {
 			os.Setenv("DV_VERBOSE", "1")
 		}
+		if err := configureRuntime(); err != nil {
+			return err
+		}
+		maybeStartWatcher()
+		return nil
 	},
 	CompletionOptions: cobra.CompletionOptions{
 		DisableDefaultCmd: true,
 	},
 }
 
+// newRuntime constructs the container runtime for a config value.
+var newRuntime = docker.NewRuntime
+
+// configureRuntime installs the container runtime selected by
+// DV_CONTAINER_RUNTIME or the containerRuntime config key.
+func configureRuntime() error {
+	kind := strings.TrimSpace(os.Getenv("DV_CONTAINER_RUNTIME"))
+	if kind == "" {
+		// Config errors surface from the command itself; fall back to docker here.
+		if configDir, err := xdg.ConfigDir(); err == nil {
+			if cfg, err := config.Read(configDir); err == nil {
+				kind = cfg.ContainerRuntime
+			}
+		}
+	}
+	rt, err := newRuntime(kind)
+	if err != nil {
+		return err
+	}
+	docker.SetRuntime(rt)
+	return nil
+}
+
 func Execute() error {
 	return rootCmd.Execute()
 }
@@ -77,19 +110,31 @@ Use "{{.CommandPath}} [command] --help" for more information about a command.{{e
 	rootCmd.AddCommand(tuiCmd)
 	// Top-level agent management commands
 	rootCmd.AddCommand(listCmd)
+	rootCmd.AddCommand(statusCmd)
+	rootCmd.AddCommand(logsCmd)
 	rootCmd.AddCommand(newCmd)
 	rootCmd.AddCommand(selectCmd)
 	rootCmd.AddCommand(renameCmd)
+	rootCmd.AddCommand(cloneCmd)
 	rootCmd.AddCommand(extractCmd)
 	rootCmd.AddCommand(importCmd)
+	rootCmd.AddCommand(exportCmd)
+	rootCmd.AddCommand(importAgentCmd)
 	rootCmd.AddCommand(configCmd)
+	rootCmd.AddCommand(profileCmd)
+	rootCmd.AddCommand(templateCmd)
 	rootCmd.AddCommand(dataCmd)
 	rootCmd.AddCommand(imageCmd)
+	rootCmd.AddCommand(volumesCmd)
+	rootCmd.AddCommand(gcCmd)
+	rootCmd.AddCommand(duCmd)
+	rootCmd.AddCommand(snapshotCmd)
 	rootCmd.AddCommand(pullCmd)
 	rootCmd.AddCommand(updateCmd)
 	rootCmd.AddCommand(versionCmd)
 
 	setupUpdateChecks()
+	rootCmd.AddCommand(watcherCmd)
 	setupUpgradeCommand()
 }
 
@@ -1,15 +1,26 @@
 package cli
 
 import (
+	"errors"
 	"fmt"
+	"os"
+	"os/exec"
+	"os/signal"
+	"sort"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
 
 	"github.com/spf13/cobra"
+	"golang.org/x/term"
 
 	"dv/internal/docker"
+	"dv/internal/xdg"
 )
 
 var runCmd = &cobra.Command{
-	Use:   "run [--name NAME] [--root] -- CMD [ARGS...]",
+	Use:   "run [--name NAME | --all | --agents A,B | --image NAME] [--root] -- CMD [ARGS...]",
 	Short: "Run a command inside the container as 'discourse' (use --root for root)",
 	Args:  cobra.ArbitraryArgs,
 	RunE: func(cmd *cobra.Command, args []string) error {
@@ -18,6 +29,18 @@ var runCmd = &cobra.Command{
 			return fmt.Errorf("no command specified. Provide a command after '--' or use 'dv enter' for an interactive shell")
 		}
 
+		asRoot, _ := cmd.Flags().GetBool("root")
+		shellCmd := shellJoin(execArgs)
+		finalArgs := []string{"bash", "-lc", shellCmd}
+
+		targets, fanOut, err := runTargets(cmd)
+		if err != nil {
+			return err
+		}
+		if fanOut {
+			return runOnAgents(cmd, targets, finalArgs, asRoot)
+		}
+
 		ctx, ok, err := prepareContainerExecContext(cmd)
 		if err != nil {
 			return err
@@ -26,10 +49,6 @@ var runCmd = &cobra.Command{
 			return nil
 		}
 
-		asRoot, _ := cmd.Flags().GetBool("root")
-		shellCmd := shellJoin(execArgs)
-		finalArgs := []string{"bash", "-lc", shellCmd}
-
 		if asRoot {
 			return docker.ExecInteractiveAsRoot(ctx.name, ctx.workdir, ctx.envs, finalArgs)
 		}
@@ -40,6 +59,11 @@ var runCmd = &cobra.Command{
 func init() {
 	runCmd.Flags().String("name", "", "Container name (defaults to selected or default)")
 	runCmd.Flags().Bool("root", false, "Run as root user")
+	runCmd.Flags().Bool("all", false, "Run in every running agent")
+	runCmd.Flags().StringSlice("agents", nil, "Run in these agents (comma-separated), starting them if needed")
+	runCmd.Flags().String("image", "", "Run in every running agent of this image")
+	runCmd.Flags().Bool("include-stopped", false, "With --all or --image, start stopped agents too")
+	runCmd.Flags().IntP("parallel", "p", 4, "Maximum agents to run in at once")
 }
 
 func extractCommandArgs(args []string) []string {
@@ -50,3 +74,182 @@ func extractCommandArgs(args []string) []string {
 	}
 	return args
 }
+
+// runTargets resolves --all, --agents and --image to agent names. fanOut is
+// false when none is set and the command runs in a single agent as before.
+func runTargets(cmd *cobra.Command) (targets []string, fanOut bool, err error) {
+	all, _ := cmd.Flags().GetBool("all")
+	agents, _ := cmd.Flags().GetStringSlice("agents")
+	image, _ := cmd.Flags().GetString("image")
+	modes := 0
+	for _, set := range []bool{all, len(agents) > 0, image != ""} {
+		if set {
+			modes++
+		}
+	}
+	if modes == 0 {
+		return nil, false, nil
+	}
+	if modes > 1 {
+		return nil, true, fmt.Errorf("use only one of --all, --agents or --image")
+	}
+	if name, _ := cmd.Flags().GetString("name"); name != "" {
+		return nil, true, fmt.Errorf("--name cannot be combined with --all, --agents or --image")
+	}
+
+	if len(agents) > 0 {
+		for _, a := range agents {
+			a = strings.TrimSpace(a)
+			if a == "" || containsString(targets, a) {
+				continue
+			}
+			if !docker.Exists(a) {
+				return nil, true, fmt.Errorf("agent '%s' does not exist", a)
+			}
+			targets = append(targets, a)
+		}
+		return targets, true, nil
+	}
+
+	configDir, err := xdg.ConfigDir()
+	if err != nil {
+		return nil, true, err
+	}
+	cfg, err := loadConfig(configDir)
+	if err != nil {
+		return nil, true, err
+	}
+	if image != "" {
+		if _, ok := cfg.Images[image]; !ok {
+			return nil, true, fmt.Errorf("unknown image '%s'", image)
+		}
+	}
+	includeStopped, _ := cmd.Flags().GetBool("include-stopped")
+	containers, err := docker.ListContainers()
+	if err != nil {
+		return nil, true, err
+	}
+	for _, ctr := range containers {
+		imgName := ctr.Labels["com.dv.image-name"]
+		if n, ok := cfg.ContainerImages[ctr.Name]; ok {
+			imgName = n
+		}
+		if ctr.Labels["com.dv.owner"] != "dv" && imgName == "" {
+			continue
+		}
+		if image != "" && imgName != image {
+			continue
+		}
+		if !ctr.Running && !includeStopped {
+			continue
+		}
+		targets = append(targets, ctr.Name)
+	}
+	sort.Strings(targets)
+	if len(targets) == 0 {
+		return nil, true, fmt.Errorf("no matching agents are running (use --include-stopped to start stopped ones)")
+	}
+	return targets, true, nil
+}
+
+type agentRunResult struct {
+	agent    string
+	exitCode int
+	err      error
+	duration time.Duration
+}
+
+// runOnAgents runs argv in every target with at most --parallel at once,
+// prefixing output with the agent name, then prints an exit code summary.
+func runOnAgents(cmd *cobra.Command, targets []string, argv []string, asRoot bool) error {
+	parallel, _ := cmd.Flags().GetInt("parallel")
+	if parallel < 1 {
+		parallel = 1
+	}
+
+	// Starting containers and copying configured files prints progress, so
+	// prepare every agent up front before output starts interleaving.
+	execCtxs := make([]containerExecContext, 0, len(targets))
+	for _, name := range targets {
+		execCtx, ok, err := prepareContainerExecContext(cmd, name)
+		if err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+		if ok {
+			execCtxs = append(execCtxs, execCtx)
+		}
+	}
+
+	out := cmd.OutOrStdout()
+	color := false
+	if f, ok := out.(*os.File); ok && os.Getenv("NO_COLOR") == "" {
+		color = term.IsTerminal(int(f.Fd()))
+	}
+	width := 0
+	for _, ec := range execCtxs {
+		width = max(width, len(ec.name))
+	}
+
+	ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt)
+	defer stop()
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, parallel)
+	results := make([]agentRunResult, len(execCtxs))
+	for i, ec := range execCtxs {
+		w := &logLineWriter{mu: &mu, out: out, prefix: fmt.Sprintf("%-*s | ", width, ec.name)}
+		if color {
+			w.prefix = "\033[" + logColors[i%len(logColors)] + "m" + w.prefix + "\033[0m"
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			start := time.Now()
+			err := docker.ExecCombinedStream(ctx, ec.name, ec.workdir, ec.envs, argv, asRoot, w)
+			w.Flush()
+			results[i] = agentRunResult{agent: ec.name, exitCode: execExitCode(err), err: err, duration: time.Since(start)}
+		}()
+	}
+	wg.Wait()
+
+	failed := 0
+	fmt.Fprintln(out)
+	tw := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "AGENT\tEXIT\tDURATION")
+	for _, r := range results {
+		exit := fmt.Sprint(r.exitCode)
+		if r.err != nil {
+			failed++
+			if r.exitCode < 0 {
+				exit = "error: " + r.err.Error()
+			}
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\n", r.agent, exit, r.duration.Round(100*time.Millisecond))
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+	if failed > 0 {
+		return fmt.Errorf("command failed in %d of %d agents", failed, len(results))
+	}
+	return nil
+}
+
+// execExitCode returns the exit status carried by err: 0 for nil and -1 when
+// the command did not run to completion.
+func execExitCode(err error) int {
+	if err == nil {
+		return 0
+	}
+	var dockerErr *docker.ExitError
+	if errors.As(err, &dockerErr) {
+		return dockerErr.Code
+	}
+	var cliErr *exec.ExitError
+	if errors.As(err, &cliErr) {
+		return cliErr.ExitCode()
+	}
+	return -1
+}
@@ -0,0 +1,60 @@
+package cli
+
+import (
+	"strings"
+	"testing"
+
+	"dv/internal/docker"
+	"dv/internal/docker/dockertest"
+)
+
+func TestRunFansOutAcrossAgents(t *testing.T) {
+	env := newTestEnv(t)
+	env.rt.AddImage("ai_agent", nil)
+	env.mustRun("new", "alpha")
+	env.mustRun("new", "beta")
+	env.mustRun("new", "gamma")
+	env.mustRun("stop", "gamma")
+	env.rt.HandleExec("pull", func(e dockertest.Exec) (string, error) {
+		if e.Container == "beta" {
+			return "conflict in app.rb\n", &docker.ExitError{Code: 3}
+		}
+		return "Already up to date.\n", nil
+	})
+
+	out, err := env.run("run", "--all", "--", "git", "pull")
+	if err == nil || !strings.Contains(err.Error(), "command failed in 1 of 2 agents") {
+		t.Fatalf("run --all err = %v\n%s", err, out)
+	}
+	for _, want := range []string{"alpha | Already up to date.\n", "beta  | conflict in app.rb\n"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output missing %q:\n%s", want, out)
+		}
+	}
+	summary := out[strings.Index(out, "AGENT"):]
+	if !strings.Contains(summary, "alpha") || !strings.Contains(summary, "beta   3") || strings.Contains(summary, "gamma") {
+		t.Errorf("summary:\n%s", summary)
+	}
+
+	before := len(env.rt.Execs())
+	env.mustRun("run", "--agents", "gamma,alpha", "--root", "--", "git", "pull")
+	var ran []string
+	for _, e := range env.rt.Execs()[before:] {
+		if strings.Contains(e.Command(), "pull") {
+			if e.User != "root" {
+				t.Errorf("exec in %s ran as %q", e.Container, e.User)
+			}
+			ran = append(ran, e.Container)
+		}
+	}
+	if len(ran) != 2 || !containsString(ran, "gamma") || !containsString(ran, "alpha") {
+		t.Errorf("--agents ran in %v", ran)
+	}
+
+	if _, err := env.run("run", "--all", "--image", "discourse", "--", "true"); err == nil {
+		t.Error("--all with --image accepted")
+	}
+	if _, err := env.run("run", "--image", "nope", "--", "true"); err == nil {
+		t.Error("unknown image accepted")
+	}
+}
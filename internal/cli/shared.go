@@ -28,6 +28,64 @@ func isTruthyEnv(key string) bool {
 	}
 }
 
+// loadConfig loads config.json with the active profile and then the
+// project's .dv.yaml, if any, merged over it. config.Save leaves the merged
+// values out of config.json.
+func loadConfig(configDir string) (config.Config, error) {
+	cfg, err := config.LoadOrCreate(configDir)
+	if err != nil {
+		return config.Config{}, err
+	}
+	applied, err := applyOverlays(configDir, &cfg)
+	if err != nil {
+		return config.Config{}, err
+	}
+	if applied {
+		if err := config.Validate(cfg); err != nil {
+			return config.Config{}, fmt.Errorf("invalid config after applying overlays:\n%w", err)
+		}
+	}
+	return cfg, nil
+}
+
+// applyOverlays merges the active profile and then the project's .dv.yaml
+// over cfg, and reports whether there were any.
+func applyOverlays(configDir string, cfg *config.Config) (bool, error) {
+	applied := false
+	if name, selectedBy := currentProfile(); name != "" {
+		overlay, err := config.LoadProfile(configDir, name)
+		if err != nil {
+			return false, fmt.Errorf("%w (selected by %s)", err, selectedBy)
+		}
+		cfg.Apply(overlay, "profile "+name)
+		applied = true
+	}
+	wd, err := os.Getwd()
+	if err != nil {
+		return applied, nil
+	}
+	overlay, path, err := config.LoadProjectOverlay(wd)
+	if err != nil {
+		return false, err
+	}
+	if path != "" {
+		cfg.Apply(overlay, path)
+		applied = true
+	}
+	return applied, nil
+}
+
+// currentProfile returns the active profile name and what selected it.
+func currentProfile() (string, string) {
+	if name := strings.TrimSpace(os.Getenv("DV_PROFILE")); name != "" {
+		return name, "DV_PROFILE"
+	}
+	if name := session.GetCurrentProfile(); name != "" {
+		return name, "'dv profile use'"
+	}
+	return "", ""
+}
+
 func currentAgentName(cfg config.Config) string {
 	// 1. Explicit environment override
 	if envAgent := os.Getenv("DV_AGENT"); envAgent != "" {
@@ -112,7 +170,7 @@ func completeAgentNames(cmd *cobra.Command, toComplete string) ([]string, cobra.
 	if err != nil {
 		return nil, cobra.ShellCompDirectiveNoFileComp
 	}
-	cfg, err := config.LoadOrCreate(configDir)
+	cfg, err := loadConfig(configDir)
 	if err != nil {
 		return nil, cobra.ShellCompDirectiveNoFileComp
 	}
@@ -120,28 +178,17 @@ func completeAgentNames(cmd *cobra.Command, toComplete string) ([]string, cobra.
 	if err != nil {
 		return nil, cobra.ShellCompDirectiveNoFileComp
 	}
-	out, _ := runShell("docker ps -a --format '{{.Names}}\t{{.Image}}\t{{.Labels}}'")
+	containers, _ := docker.ListContainers()
 	var suggestions []string
 	prefix := strings.ToLower(strings.TrimSpace(toComplete))
-	for _, line := range strings.Split(strings.TrimSpace(out), "\n") {
-		if strings.TrimSpace(line) == "" {
-			continue
-		}
-		parts := strings.SplitN(line, "\t", 3)
-		if len(parts) < 2 {
-			continue
-		}
-		name, image := parts[0], parts[1]
-		labelsField := ""
-		if len(parts) >= 3 {
-			labelsField = parts[2]
-		}
+	for _, ctr := range containers {
+		name, image := ctr.Name, ctr.Image
 		belongs := false
 		if imgNameFromCfg, ok := cfg.ContainerImages[name]; ok && imgNameFromCfg == cfg.SelectedImage {
 			belongs = true
 		}
 		if !belongs {
-			if labelMap := parseLabels(labelsField); labelMap["com.dv.owner"] == "dv" && labelMap["com.dv.image-name"] == cfg.SelectedImage {
+			if labelMap := ctr.Labels; labelMap["com.dv.owner"] == "dv" && labelMap["com.dv.image-name"] == cfg.SelectedImage {
 				belongs = true
 			}
 		}
@@ -205,8 +252,12 @@ var execCommand = defaultExec
 func defaultExec(name string, arg ...string) *exec.Cmd { return exec.Command(name, arg...) }
 
 func containerImage(name string) (string, error) {
-	out, err := runShell(fmt.Sprintf("docker inspect -f '{{.Config.Image}}' %s 2>/dev/null || true", name))
-	return strings.TrimSpace(out), err
+	info, err := docker.Current().Inspect(name)
+	if err != nil {
+		// Missing containers report no image, matching `docker inspect ... || true`.
+		return "", nil
+	}
+	return info.Image, nil
 }
 
 // shellQuote returns a single-quoted shell-safe string.
@@ -232,21 +283,29 @@ func ensureContainerRunning(cmd *cobra.Command, cfg config.Config, name string,
 	}
 	workdir := imgCfg.Workdir
 	imageTag := imgCfg.Tag
-	return ensureContainerRunningWithWorkdir(cmd, cfg, name, workdir, imageTag, imgName, reset, sshAuthSock, nil)
+	return ensureContainerRunningWithWorkdir(cmd, cfg, name, workdir, imageTag, imgName, reset, sshAuthSock, nil, nil)
 }
 
-func ensureContainerRunningWithWorkdir(cmd *cobra.Command, cfg config.Config, name string, workdir string, imageTag string, imgName string, reset bool, sshAuthSock string, templateEnvs map[string]string) error {
+// ensureContainerRunningWithWorkdir creates the agent when it doesn't exist,
+// otherwise starts it. templateEnvs and extraLabels are set on a new
+// container; dv's own labels and the local proxy's env take precedence.
+func ensureContainerRunningWithWorkdir(cmd *cobra.Command, cfg config.Config, name string, workdir string, imageTag string, imgName string, reset bool, sshAuthSock string, templateEnvs map[string]string, extraLabels map[string]string) error {
 	if reset && docker.Exists(name) {
 		_ = docker.Stop(name)
 		_ = docker.Remove(name)
 	}
 	if !docker.Exists(name) {
+		_, imgCfg, err := resolveImage(cfg, imgName)
+		if err != nil {
+			return err
+		}
 		// Choose the first available port starting from configured starting port
 		allocated, err := docker.AllocatedPorts()
 		if err != nil {
 			if isTruthyEnv("DV_VERBOSE") {
 				fmt.Fprintf(cmd.ErrOrStderr(), "Warning: failed to detect allocated Docker ports: %v\n", err)
 			}
+			allocated = map[int]bool{}
 		}
 		chosenPort := cfg.HostStartingPort
 		if isTruthyEnv("DV_VERBOSE") {
@@ -258,11 +317,15 @@ func ensureContainerRunningWithWorkdir(cmd *cobra.Command, cfg config.Config, na
 		if isTruthyEnv("DV_VERBOSE") {
 			fmt.Fprintf(cmd.OutOrStdout(), "Selected port %d.\n", chosenPort)
 		}
-		labels := map[string]string{
-			"com.dv.owner":      "dv",
-			"com.dv.image-name": imgName,
-			"com.dv.image-tag":  imageTag,
+		labels := map[string]string{}
+		for k, v := range extraLabels {
+			labels[k] = v
 		}
+		labels["com.dv.owner"] = "dv"
+		labels["com.dv.image-name"] = imgName
+		labels["com.dv.image-tag"] = imageTag
+		allocated[chosenPort] = true
+		extraPorts := allocateNamedPorts(imgCfg.Ports, allocated, labels)
 		envs := map[string]string{
 			"DISCOURSE_PORT": strconv.Itoa(chosenPort),
 		}
@@ -275,9 +338,26 @@ func ensureContainerRunningWithWorkdir(cmd *cobra.Command, cfg config.Config, na
 		if proxyHost != "" {
 			extraHosts = append(extraHosts, fmt.Sprintf("%s:127.0.0.1", proxyHost))
 		}
-		if err := docker.RunDetached(name, workdir, imageTag, chosenPort, cfg.ContainerPort, labels, envs, extraHosts, sshAuthSock); err != nil {
+		opts := docker.RunOptions{
+			Name:          name,
+			Workdir:       workdir,
+			Image:         imageTag,
+			HostPort:      chosenPort,
+			ContainerPort: cfg.ContainerPort,
+			Labels:        labels,
+			Envs:          envs,
+			ExtraHosts:    extraHosts,
+			ExtraPorts:    extraPorts,
+			SSHAuthSock:   sshAuthSock,
+		}
+		applyLimits(&opts, config.EffectiveLimits(cfg, imgCfg, name))
+		if err := attachVolumes(&opts, imgCfg.Volumes); err != nil {
+			return err
+		}
+		if err := docker.RunDetached(opts); err != nil {
 			return err
 		}
+		fixVolumeOwnership(name, opts.Volumes)
 		if proxyHost != "" {
 			registerWithLocalProxy(cmd, cfg, name, proxyHost, cfg.ContainerPort)
 		}
@@ -291,3 +371,49 @@ func ensureContainerRunningWithWorkdir(cmd *cobra.Command, cfg config.Config, na
 	}
 	return nil
 }
+
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// lastLine returns the last non-empty line of out, trimmed.
+func lastLine(out string) string {
+	lines := strings.Split(strings.TrimSpace(out), "\n")
+	return strings.TrimSpace(lines[len(lines)-1])
+}
+
+// formatBytes renders a byte count with a binary unit, e.g. 1.5 GiB.
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
+
+// parseAge parses a Go duration, also accepting whole days such as "14d".
+func parseAge(s string) (time.Duration, error) {
+	s = strings.TrimSpace(s)
+	if days, ok := strings.CutSuffix(s, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil || n < 0 {
+			return 0, fmt.Errorf("invalid duration %q", s)
+		}
+		return time.Duration(n) * 24 * time.Hour, nil
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil || d < 0 {
+		return 0, fmt.Errorf("invalid duration %q", s)
+	}
+	return d, nil
+}
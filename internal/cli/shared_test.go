@@ -3,6 +3,7 @@ package cli
 import (
 	"net"
 	"testing"
+	"time"
 )
 
 func TestShellQuote(t *testing.T) {
@@ -393,3 +394,52 @@ func TestIsPortInUse_AvailablePort(t *testing.T) {
 		// Don't fail - this can happen due to TIME_WAIT or port reuse
 	}
 }
+
+func TestFormatBytes(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		input    int64
+		expected string
+	}{
+		{0, "0 B"},
+		{1023, "1023 B"},
+		{1024, "1.0 KiB"},
+		{1536, "1.5 KiB"},
+		{5 * 1024 * 1024 * 1024, "5.0 GiB"},
+	}
+	for _, tt := range tests {
+		if got := formatBytes(tt.input); got != tt.expected {
+			t.Errorf("formatBytes(%d) = %q, want %q", tt.input, got, tt.expected)
+		}
+	}
+}
+
+func TestParseAge(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		input    string
+		expected time.Duration
+		wantErr  bool
+	}{
+		{input: "90m", expected: 90 * time.Minute},
+		{input: "14d", expected: 14 * 24 * time.Hour},
+		{input: " 2h ", expected: 2 * time.Hour},
+		{input: "d", wantErr: true},
+		{input: "-3d", wantErr: true},
+		{input: "soon", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := parseAge(tt.input)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("parseAge(%q) succeeded, want error", tt.input)
+			}
+			continue
+		}
+		if err != nil || got != tt.expected {
+			t.Errorf("parseAge(%q) = %s, %v; want %s", tt.input, got, err, tt.expected)
+		}
+	}
+}
@@ -0,0 +1,440 @@
+package cli
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"dv/internal/config"
+	"dv/internal/docker"
+	"dv/internal/session"
+	"dv/internal/xdg"
+)
+
+const (
+	// snapshotRepo is the image repository snapshots are tagged into.
+	snapshotRepo = "dv-snapshot"
+
+	snapshotLabel        = "com.dv.snapshot"
+	snapshotAgentLabel   = "com.dv.snapshot.agent"
+	snapshotImageLabel   = "com.dv.snapshot.image-name"
+	snapshotWorkdirLabel = "com.dv.snapshot.workdir"
+	snapshotCreatedLabel = "com.dv.snapshot.created"
+	snapshotMessageLabel = "com.dv.snapshot.message"
+	// snapshotPGDataLabel holds the Postgres data directory when it lives on
+	// a volume and was archived into the image; empty otherwise.
+	snapshotPGDataLabel = "com.dv.snapshot.pgdata"
+
+	// pgArchivePath is where the Postgres data directory is archived inside
+	// the container so `docker commit` captures it.
+	pgArchivePath = "/var/lib/dv/pgdata.tar.gz"
+)
+
+var snapshotNamePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_.-]{0,127}$`)
+
+// snapshotInfo is a snapshot image and its metadata labels.
+type snapshotInfo struct {
+	Name      string
+	Agent     string
+	ImageName string
+	Workdir   string
+	Message   string
+	PGData    string
+	CreatedAt time.Time
+	Size      int64
+}
+
+func snapshotRef(name string) string { return snapshotRepo + ":" + name }
+
+// listSnapshots returns snapshots sorted by creation time, newest first.
+// Images committed from a restored snapshot inherit its labels, so only
+// images tagged under snapshotRepo count.
+func listSnapshots() ([]snapshotInfo, error) {
+	images, err := docker.ListImages(snapshotLabel)
+	if err != nil {
+		return nil, err
+	}
+	var snaps []snapshotInfo
+	for _, img := range images {
+		name := img.Labels[snapshotLabel]
+		if name == "" || !containsString(img.Tags, snapshotRef(name)) {
+			continue
+		}
+		s := snapshotInfo{
+			Name:      name,
+			Agent:     img.Labels[snapshotAgentLabel],
+			ImageName: img.Labels[snapshotImageLabel],
+			Workdir:   img.Labels[snapshotWorkdirLabel],
+			Message:   img.Labels[snapshotMessageLabel],
+			PGData:    img.Labels[snapshotPGDataLabel],
+			CreatedAt: img.CreatedAt,
+			Size:      img.Size,
+		}
+		if t, err := time.Parse(time.RFC3339, img.Labels[snapshotCreatedLabel]); err == nil {
+			s.CreatedAt = t
+		}
+		snaps = append(snaps, s)
+	}
+	sort.Slice(snaps, func(i, j int) bool { return snaps[i].CreatedAt.After(snaps[j].CreatedAt) })
+	return snaps, nil
+}
+
+func findSnapshot(name string) (snapshotInfo, error) {
+	snaps, err := listSnapshots()
+	if err != nil {
+		return snapshotInfo{}, err
+	}
+	for _, s := range snaps {
+		if s.Name == name {
+			return s, nil
+		}
+	}
+	return snapshotInfo{}, fmt.Errorf("no snapshot named '%s' (see 'dv snapshot list')", name)
+}
+
+// agentWorkdir returns the custom workdir for an agent, else its image's.
+func agentWorkdir(cfg config.Config, name string, imgCfg config.ImageConfig) string {
+	if w := strings.TrimSpace(cfg.CustomWorkdirs[name]); w != "" {
+		return w
+	}
+	return imgCfg.Workdir
+}
+
+// commitAgentState commits a container into imageRef with labels. A Postgres
+// data directory on a volume is invisible to `docker commit`, so it is
+// archived into the container filesystem first with Postgres briefly
+// stopped. The archived directory is returned and recorded in labels.
+func commitAgentState(cmd *cobra.Command, name, imageRef string, labels map[string]string) (string, error) {
+	if !docker.Running(name) {
+		if err := docker.Start(name); err != nil {
+			return "", err
+		}
+		defer func() { _ = docker.Stop(name) }()
+	}
+	script := `
+set -e
+data=$(su postgres -c "psql -tAc 'SHOW data_directory'" 2>/dev/null || true)
+data=${data:-/shared/postgres_data}
+[ -d "$data" ] || exit 0
+# Data in the container filesystem is captured by commit as-is.
+[ "$(stat -c %d "$data")" != "$(stat -c %d /)" ] || exit 0
+sv stop postgres >/dev/null
+trap 'sv start postgres >/dev/null' EXIT
+mkdir -p ` + shellQuote(path.Dir(pgArchivePath)) + `
+tar -C "$data" -czf ` + shellQuote(pgArchivePath) + ` .
+echo "$data"
+`
+	fmt.Fprintln(cmd.OutOrStdout(), "Capturing PostgreSQL data...")
+	out, err := docker.ExecAsRootCombined(name, "/", nil, []string{"bash", "-c", script})
+	if err != nil {
+		return "", fmt.Errorf("archive postgres data: %w\n%s", err, strings.TrimSpace(out))
+	}
+	pgData := lastLine(out)
+	labels[snapshotPGDataLabel] = pgData
+
+	fmt.Fprintf(cmd.OutOrStdout(), "Committing '%s' to %s...\n", name, imageRef)
+	err = docker.CommitContainerWithLabels(name, imageRef, labels)
+	if pgData != "" {
+		_, _ = docker.ExecAsRoot(name, "/", nil, []string{"rm", "-f", pgArchivePath})
+	}
+	if err != nil {
+		return "", fmt.Errorf("commit %s: %w", name, err)
+	}
+	return pgData, nil
+}
+
+// restoreAgentState unpacks a Postgres archive left by commitAgentState into
+// dataDir of a container created from the committed image.
+func restoreAgentState(cmd *cobra.Command, name, dataDir string) error {
+	if dataDir == "" {
+		return nil
+	}
+	data := shellQuote(dataDir)
+	archive := shellQuote(pgArchivePath)
+	script := `
+set -e
+[ -f ` + archive + ` ] || exit 0
+for i in $(seq 1 30); do sv status postgres >/dev/null 2>&1 && break; sleep 1; done
+sv stop postgres >/dev/null || true
+mkdir -p ` + data + `
+find ` + data + ` -mindepth 1 -delete
+tar -C ` + data + ` -xzf ` + archive + `
+chown -R postgres:postgres ` + data + `
+rm -f ` + archive + `
+sv start postgres >/dev/null
+`
+	fmt.Fprintln(cmd.OutOrStdout(), "Restoring PostgreSQL data...")
+	if out, err := docker.ExecAsRootCombined(name, "/", nil, []string{"bash", "-c", script}); err != nil {
+		return fmt.Errorf("restore postgres data: %w\n%s", err, strings.TrimSpace(out))
+	}
+	return nil
+}
+
+var snapshotCmd = &cobra.Command{
+	Use:   "snapshot",
+	Short: "Checkpoint agents into images and restore them",
+}
+
+var snapshotCreateCmd = &cobra.Command{
+	Use:   "create [SNAPSHOT]",
+	Short: "Commit an agent, including its database, into a snapshot image",
+	Args:  cobra.RangeArgs(0, 1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		configDir, err := xdg.ConfigDir()
+		if err != nil {
+			return err
+		}
+		cfg, err := loadConfig(configDir)
+		if err != nil {
+			return err
+		}
+		agent, _ := cmd.Flags().GetString("agent")
+		if agent == "" {
+			agent = currentAgentName(cfg)
+		}
+		if !docker.Exists(agent) {
+			return fmt.Errorf("agent '%s' does not exist", agent)
+		}
+		name := fmt.Sprintf("%s-%s", agentNameSlug(agent), time.Now().Format("20060102-150405"))
+		if len(args) == 1 {
+			name = args[0]
+		}
+		if !snapshotNamePattern.MatchString(name) {
+			return fmt.Errorf("invalid snapshot name %q: use lowercase letters, digits, '.', '-' or '_'", name)
+		}
+		if docker.ImageExists(snapshotRef(name)) {
+			return fmt.Errorf("snapshot '%s' already exists", name)
+		}
+
+		imgName, imgCfg, err := resolveImage(cfg, cfg.ContainerImages[agent])
+		if err != nil {
+			return err
+		}
+		message, _ := cmd.Flags().GetString("message")
+		labels := map[string]string{
+			"com.dv.owner":       "dv",
+			snapshotLabel:        name,
+			snapshotAgentLabel:   agent,
+			snapshotImageLabel:   imgName,
+			snapshotWorkdirLabel: agentWorkdir(cfg, agent, imgCfg),
+			snapshotCreatedLabel: time.Now().UTC().Format(time.RFC3339),
+			snapshotMessageLabel: message,
+		}
+		if _, err := commitAgentState(cmd, agent, snapshotRef(name), labels); err != nil {
+			return err
+		}
+		fmt.Fprintf(cmd.OutOrStdout(), "Snapshot '%s' created from agent '%s'.\n", name, agent)
+		return nil
+	},
+}
+
+var snapshotListCmd = &cobra.Command{
+	Use:     "list",
+	Aliases: []string{"ls"},
+	Short:   "List snapshots",
+	Args:    cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		agent, _ := cmd.Flags().GetString("agent")
+		snaps, err := listSnapshots()
+		if err != nil {
+			return err
+		}
+		w := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 4, 2, ' ', 0)
+		fmt.Fprintln(w, "NAME\tAGENT\tIMAGE\tCREATED\tSIZE\tMESSAGE")
+		count := 0
+		for _, s := range snaps {
+			if agent != "" && s.Agent != agent {
+				continue
+			}
+			count++
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n", s.Name, s.Agent, s.ImageName,
+				s.CreatedAt.Local().Format("2006-01-02 15:04"), formatBytes(s.Size), s.Message)
+		}
+		if count == 0 {
+			fmt.Fprintln(cmd.OutOrStdout(), "(no snapshots)")
+			return nil
+		}
+		return w.Flush()
+	},
+}
+
+var snapshotRestoreCmd = &cobra.Command{
+	Use:   "restore SNAPSHOT",
+	Short: "Replace the snapshot's agent with it, or start it as a new agent with --as",
+	Long: `Restore a snapshot as a new agent with --as, or replace the agent it was
+taken from. Replacing asks for confirmation unless --force is given. The
+current agent is stopped and set aside until the snapshot is running, and is
+put back if the restore fails.`,
+	Args: cobra.ExactArgs(1),
+	ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		return completeSnapshotNames(args, toComplete)
+	},
+	RunE: func(cmd *cobra.Command, args []string) (err error) {
+		configDir, err := xdg.ConfigDir()
+		if err != nil {
+			return err
+		}
+		cfg, err := loadConfig(configDir)
+		if err != nil {
+			return err
+		}
+		snap, err := findSnapshot(args[0])
+		if err != nil {
+			return err
+		}
+		imgName, imgCfg, err := resolveImage(cfg, snap.ImageName)
+		if err != nil {
+			return err
+		}
+
+		target, _ := cmd.Flags().GetString("as")
+		replace := target == ""
+		if replace {
+			target = snap.Agent
+		}
+		if target == "" {
+			return fmt.Errorf("snapshot '%s' has no source agent; use --as NAME", snap.Name)
+		}
+		if docker.Exists(target) {
+			if !replace {
+				return fmt.Errorf("an agent named '%s' already exists", target)
+			}
+			if force, _ := cmd.Flags().GetBool("force"); !force {
+				prompt := fmt.Sprintf("Replace agent '%s' with snapshot '%s'? Its current state will be lost. (y/N): ", target, snap.Name)
+				if ok, _ := promptYesNo(cmd.InOrStdin(), cmd.ErrOrStderr(), prompt); !ok {
+					return fmt.Errorf("not replacing agent '%s'; use --force, or --as NAME to restore alongside it", target)
+				}
+			}
+			// Keep the current agent under another name until the snapshot
+			// is up, so a failed restore doesn't lose it.
+			aside := fmt.Sprintf("%s-before-restore-%d", target, time.Now().Unix())
+			wasRunning := docker.Running(target)
+			fmt.Fprintf(cmd.OutOrStdout(), "Setting agent '%s' aside as '%s'...\n", target, aside)
+			if wasRunning {
+				if err := docker.Stop(target); err != nil {
+					return err
+				}
+			}
+			if err := docker.Rename(target, aside); err != nil {
+				return err
+			}
+			defer func() {
+				if err == nil {
+					if rmErr := docker.RemoveForce(aside); rmErr != nil {
+						fmt.Fprintf(cmd.ErrOrStderr(), "Warning: could not remove '%s': %v\n", aside, rmErr)
+					}
+					return
+				}
+				fmt.Fprintf(cmd.ErrOrStderr(), "Restore failed, putting agent '%s' back...\n", target)
+				if docker.Exists(target) {
+					_ = docker.RemoveForce(target)
+				}
+				if renameErr := docker.Rename(aside, target); renameErr != nil {
+					fmt.Fprintf(cmd.ErrOrStderr(), "Warning: could not rename '%s' back to '%s': %v\n", aside, target, renameErr)
+					return
+				}
+				if wasRunning {
+					_ = docker.Start(target)
+				}
+			}()
+		}
+
+		workdir := snap.Workdir
+		if workdir == "" {
+			workdir = imgCfg.Workdir
+		}
+		fmt.Fprintf(cmd.OutOrStdout(), "Restoring snapshot '%s' as agent '%s'...\n", snap.Name, target)
+		proxyLabels, proxyEnvs := clearedProxyMetadata()
+		if err := ensureContainerRunningWithWorkdir(cmd, cfg, target, workdir, snapshotRef(snap.Name), imgName, false, "", proxyEnvs, proxyLabels); err != nil {
+			return err
+		}
+		if err := restoreAgentState(cmd, target, snap.PGData); err != nil {
+			return err
+		}
+
+		if cfg.ContainerImages == nil {
+			cfg.ContainerImages = map[string]string{}
+		}
+		cfg.ContainerImages[target] = imgName
+		if workdir != imgCfg.Workdir {
+			if cfg.CustomWorkdirs == nil {
+				cfg.CustomWorkdirs = map[string]string{}
+			}
+			cfg.CustomWorkdirs[target] = workdir
+		}
+		if !replace {
+			cfg.SelectedAgent = target
+			if err := session.SetCurrentAgent(target); err != nil {
+				fmt.Fprintf(cmd.ErrOrStderr(), "Warning: could not save session state: %v\n", err)
+			}
+		}
+		if err := config.Save(configDir, cfg); err != nil {
+			return err
+		}
+		if replace {
+			fmt.Fprintf(cmd.OutOrStdout(), "Agent '%s' restored from snapshot '%s'.\n", target, snap.Name)
+		} else {
+			fmt.Fprintf(cmd.OutOrStdout(), "Agent '%s' created from snapshot '%s' and selected.\n", target, snap.Name)
+		}
+		return nil
+	},
+}
+
+var snapshotDeleteCmd = &cobra.Command{
+	Use:     "delete SNAPSHOT...",
+	Aliases: []string{"rm"},
+	Short:   "Delete snapshots",
+	Args:    cobra.MinimumNArgs(1),
+	ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		return completeSnapshotNames(args, toComplete)
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		for _, name := range args {
+			if _, err := findSnapshot(name); err != nil {
+				return err
+			}
+			if err := docker.RemoveImage(snapshotRef(name)); err != nil {
+				return fmt.Errorf("delete snapshot '%s': %w", name, err)
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "Deleted snapshot '%s'.\n", name)
+		}
+		return nil
+	},
+}
+
+func completeSnapshotNames(args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	snaps, err := listSnapshots()
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	var names []string
+	for _, s := range snaps {
+		if strings.HasPrefix(s.Name, toComplete) && !containsString(args, s.Name) {
+			names = append(names, s.Name)
+		}
+	}
+	return names, cobra.ShellCompDirectiveNoFileComp
+}
+
+func init() {
+	snapshotCmd.AddCommand(snapshotCreateCmd)
+	snapshotCmd.AddCommand(snapshotListCmd)
+	snapshotCmd.AddCommand(snapshotRestoreCmd)
+	snapshotCmd.AddCommand(snapshotDeleteCmd)
+
+	snapshotCreateCmd.Flags().String("agent", "", "Agent to snapshot (defaults to selected)")
+	snapshotCreateCmd.Flags().StringP("message", "m", "", "Description stored with the snapshot")
+	snapshotListCmd.Flags().String("agent", "", "Only list snapshots of this agent")
+	snapshotRestoreCmd.Flags().String("as", "", "Create a new agent with this name instead of replacing the original")
+	snapshotRestoreCmd.Flags().Bool("force", false, "Replace the original agent without asking")
+
+	snapshotCreateCmd.RegisterFlagCompletionFunc("agent", func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		return completeAgentNames(cmd, toComplete)
+	})
+}
@@ -0,0 +1,127 @@
+package cli
+
+import (
+	"strings"
+	"testing"
+
+	"dv/internal/docker"
+	"dv/internal/docker/dockertest"
+)
+
+func TestSnapshotCreateRestoreDelete(t *testing.T) {
+	env := newTestEnv(t)
+	env.rt.AddImage("ai_agent", nil)
+	env.mustRun("new", "seeded")
+	if err := env.rt.WriteFile("seeded", "/var/www/discourse/seed.txt", []byte("users")); err != nil {
+		t.Fatal(err)
+	}
+	// Pretend the Postgres data dir is on a volume so it gets archived.
+	env.rt.HandleExec("SHOW data_directory", func(e dockertest.Exec) (string, error) {
+		return "/shared/postgres_data\n", nil
+	})
+
+	out := env.mustRun("snapshot", "create", "baseline", "-m", "seeded users")
+	if !strings.Contains(out, "Snapshot 'baseline' created from agent 'seeded'") {
+		t.Fatalf("create output:\n%s", out)
+	}
+	images, _ := env.rt.ListImages("com.dv.snapshot")
+	if len(images) != 1 || images[0].Tags[0] != "dv-snapshot:baseline" {
+		t.Fatalf("snapshot images = %+v", images)
+	}
+	labels := images[0].Labels
+	if labels["com.dv.snapshot.agent"] != "seeded" || labels["com.dv.snapshot.image-name"] != "discourse" ||
+		labels["com.dv.snapshot.pgdata"] != "/shared/postgres_data" || labels["com.dv.snapshot.message"] != "seeded users" {
+		t.Errorf("snapshot labels = %+v", labels)
+	}
+	if _, err := env.run("snapshot", "create", "baseline"); err == nil {
+		t.Error("creating a duplicate snapshot succeeded")
+	}
+
+	out = env.mustRun("snapshot", "list")
+	if !strings.Contains(out, "baseline") || !strings.Contains(out, "seeded users") {
+		t.Errorf("list output:\n%s", out)
+	}
+
+	env.mustRun("snapshot", "restore", "baseline", "--as", "copy")
+	ctr, ok := env.rt.Container("copy")
+	if !ok || ctr.Image != "dv-snapshot:baseline" {
+		t.Fatalf("restored container = %+v", ctr)
+	}
+	if data, _ := env.rt.ReadFile("copy", "/var/www/discourse/seed.txt"); string(data) != "users" {
+		t.Errorf("seed file = %q", data)
+	}
+	var restored bool
+	for _, e := range env.rt.Execs() {
+		if e.Container == "copy" && strings.Contains(e.Command(), "tar -C '/shared/postgres_data' -xzf") {
+			restored = true
+		}
+	}
+	if !restored {
+		t.Error("postgres data was not restored")
+	}
+	cfg := env.config()
+	if cfg.ContainerImages["copy"] != "discourse" || cfg.SelectedAgent != "copy" {
+		t.Errorf("config after restore: images=%v selected=%q", cfg.ContainerImages, cfg.SelectedAgent)
+	}
+
+	// Restoring without --as replaces the source agent.
+	if err := env.rt.WriteFile("seeded", "/var/www/discourse/seed.txt", []byte("broken")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := env.run("snapshot", "restore", "baseline"); err == nil {
+		t.Fatal("replacing an agent without --force or confirmation succeeded")
+	}
+	if data, _ := env.rt.ReadFile("seeded", "/var/www/discourse/seed.txt"); string(data) != "broken" {
+		t.Fatalf("unconfirmed restore touched the agent: seed file = %q", data)
+	}
+	env.mustRun("snapshot", "restore", "baseline", "--force")
+	if data, _ := env.rt.ReadFile("seeded", "/var/www/discourse/seed.txt"); string(data) != "users" {
+		t.Errorf("replaced agent seed file = %q", data)
+	}
+	ctrs, _ := env.rt.List()
+	for _, c := range ctrs {
+		if strings.HasPrefix(c.Name, "seeded-before-restore-") {
+			t.Errorf("set-aside agent %s left behind", c.Name)
+		}
+	}
+
+	if _, err := env.run("snapshot", "delete", "baseline"); err == nil {
+		t.Error("deleting a snapshot in use succeeded")
+	}
+	env.mustRun("remove", "copy")
+	env.mustRun("remove", "seeded")
+	env.mustRun("snapshot", "delete", "baseline")
+	if out := env.mustRun("snapshot", "list"); !strings.Contains(out, "(no snapshots)") {
+		t.Errorf("list after delete:\n%s", out)
+	}
+}
+
+func TestSnapshotRestoreFailureKeepsAgent(t *testing.T) {
+	env := newTestEnv(t)
+	env.rt.AddImage("ai_agent", nil)
+	env.mustRun("new", "seeded")
+	env.rt.HandleExec("SHOW data_directory", func(e dockertest.Exec) (string, error) {
+		return "/shared/postgres_data\n", nil
+	})
+	env.mustRun("snapshot", "create", "baseline")
+	if err := env.rt.WriteFile("seeded", "/var/www/discourse/work.txt", []byte("unsaved")); err != nil {
+		t.Fatal(err)
+	}
+	env.rt.HandleExec("-xzf", func(e dockertest.Exec) (string, error) {
+		return "", &docker.ExitError{Code: 2, Stderr: "tar: corrupt archive"}
+	})
+
+	if _, err := env.run("snapshot", "restore", "baseline", "--force"); err == nil {
+		t.Fatal("restore with a failing database restore succeeded")
+	}
+	ctr, ok := env.rt.Container("seeded")
+	if !ok || !ctr.Running || ctr.Image == "dv-snapshot:baseline" {
+		t.Fatalf("original agent not put back: %+v", ctr)
+	}
+	if data, _ := env.rt.ReadFile("seeded", "/var/www/discourse/work.txt"); string(data) != "unsaved" {
+		t.Errorf("original agent lost its work: %q", data)
+	}
+	if ctrs, _ := env.rt.List(); len(ctrs) != 1 {
+		t.Errorf("containers after a failed restore = %+v, want only the original", ctrs)
+	}
+}
@@ -13,7 +13,7 @@ import (
 )
 
 var startCmd = &cobra.Command{
-	Use:   "start [name] [--reset] [--no-remap] [--image NAME] [--host-starting-port N] [--container-port N]",
+	Use:   "start [name] [--reset] [--no-remap] [--image NAME] [--host-starting-port N] [--container-port N] [--cpus N] [--memory SIZE]",
 	Short: "Create or start a container for the selected image",
 	Args:  cobra.MaximumNArgs(1),
 	ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
@@ -28,7 +28,7 @@ var startCmd = &cobra.Command{
 		if err != nil {
 			return err
 		}
-		cfg, err := config.LoadOrCreate(configDir)
+		cfg, err := loadConfig(configDir)
 		if err != nil {
 			return err
 		}
@@ -61,6 +61,20 @@ var startCmd = &cobra.Command{
 		imageTag := imgCfg.Tag
 		workdir := imgCfg.Workdir
 
+		limits, limitsSet, err := limitsFromFlags(cmd)
+		if err != nil {
+			return err
+		}
+		if limitsSet {
+			setAgentLimits(&cfg, name, limits)
+			if err := config.Save(configDir, cfg); err != nil {
+				return err
+			}
+			if docker.Exists(name) && !reset {
+				fmt.Fprintf(cmd.OutOrStdout(), "Saved resource limits for '%s'; they apply when the container is recreated (use --reset).\n", name)
+			}
+		}
+
 		if reset && docker.Exists(name) {
 			fmt.Fprintf(cmd.OutOrStdout(), "Stopping and removing existing container '%s'...\n", name)
 			_ = docker.Stop(name)
@@ -74,6 +88,7 @@ var startCmd = &cobra.Command{
 				if isTruthyEnv("DV_VERBOSE") {
 					fmt.Fprintf(cmd.ErrOrStderr(), "Warning: failed to detect allocated Docker ports: %v\n", err)
 				}
+				allocated = map[int]bool{}
 			}
 			chosenPort := hostPort
 			if isTruthyEnv("DV_VERBOSE") {
@@ -94,6 +109,8 @@ var startCmd = &cobra.Command{
 				"com.dv.image-name": imgName,
 				"com.dv.image-tag":  imageTag,
 			}
+			allocated[chosenPort] = true
+			extraPorts := allocateNamedPorts(imgCfg.Ports, allocated, labels)
 			envs := map[string]string{
 				"DISCOURSE_PORT": strconv.Itoa(chosenPort),
 			}
@@ -102,9 +119,25 @@ var startCmd = &cobra.Command{
 			if proxyHost != "" {
 				extraHosts = append(extraHosts, fmt.Sprintf("%s:127.0.0.1", proxyHost))
 			}
-			if err := docker.RunDetached(name, workdir, imageTag, chosenPort, containerPort, labels, envs, extraHosts, ""); err != nil {
+			opts := docker.RunOptions{
+				Name:          name,
+				Workdir:       workdir,
+				Image:         imageTag,
+				HostPort:      chosenPort,
+				ContainerPort: containerPort,
+				Labels:        labels,
+				Envs:          envs,
+				ExtraHosts:    extraHosts,
+				ExtraPorts:    extraPorts,
+			}
+			applyLimits(&opts, config.EffectiveLimits(cfg, imgCfg, name))
+			if err := attachVolumes(&opts, imgCfg.Volumes); err != nil {
 				return err
 			}
+			if err := docker.RunDetached(opts); err != nil {
+				return err
+			}
+			fixVolumeOwnership(name, opts.Volumes)
 
 			// give it a moment to boot services
 			time.Sleep(500 * time.Millisecond)
@@ -164,10 +197,23 @@ var startCmd = &cobra.Command{
 
 					// Recreate container with new port from snapshot
 					fmt.Fprintf(cmd.OutOrStdout(), "Recreating container with new port...\n")
-					if err := docker.RunDetached(name, existingWorkdir, tempImage, newPort, containerPort, labels, existingEnvs, nil, ""); err != nil {
+					opts := docker.RunOptions{
+						Name:          name,
+						Workdir:       existingWorkdir,
+						Image:         tempImage,
+						HostPort:      newPort,
+						ContainerPort: containerPort,
+						Labels:        labels,
+						Envs:          existingEnvs,
+						ExtraPorts:    portMappings(namedPortsFromLabels(labels)),
+						Volumes:       volumeMountsFromLabels(labels),
+					}
+					applyLimits(&opts, config.EffectiveLimits(cfg, imgCfg, name))
+					if err := docker.RunDetached(opts); err != nil {
 						// Try to restore from snapshot
 						fmt.Fprintf(cmd.ErrOrStderr(), "Failed to recreate, attempting restore...\n")
-						_ = docker.RunDetached(name, existingWorkdir, tempImage, existingPort, containerPort, labels, existingEnvs, nil, "")
+						opts.HostPort = existingPort
+						_ = docker.RunDetached(opts)
 						_ = docker.RemoveImage(tempImage)
 						return fmt.Errorf("failed to recreate container: %w", err)
 					}
@@ -222,4 +268,5 @@ func init() {
 	startCmd.Flags().Int("host-starting-port", 0, "First host port to try for container port mapping")
 	startCmd.Flags().Int("container-port", 0, "Container port to expose")
 	startCmd.Flags().String("image", "", "Override image to start (defaults to selected image)")
+	addLimitFlags(startCmd)
 }
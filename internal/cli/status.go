@@ -0,0 +1,299 @@
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"regexp"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"dv/internal/assets"
+	"dv/internal/config"
+	"dv/internal/docker"
+	"dv/internal/xdg"
+)
+
+// statusScript reports every runit service plus the database checks as
+// tab-separated lines, so one exec covers the whole agent. Restart counters
+// are kept by the finish scripts in the embedded Dockerfile.
+const statusScript = `
+if cd /etc/service 2>/dev/null; then
+  for s in */; do
+    s=${s%/}
+    printf 'service\t%s\t%s\t%s\n' "$s" "$(sv status "$s" 2>&1 | head -n1)" "$(cat "/var/lib/dv/restarts/$s" 2>/dev/null)"
+  done
+fi
+out=$(pg_isready -h localhost 2>&1); rc=$?
+printf 'check\tpostgres\t%s\t%s\n' "$rc" "$out"
+out=$(redis-cli ping 2>&1); rc=$?
+[ "$out" = PONG ] || rc=1
+printf 'check\tredis\t%s\t%s\n' "$rc" "$out"
+`
+
+// svStatusRe matches the first part of `sv status` output, e.g.
+// "run: unicorn: (pid 123) 45s; run: log: (pid 7) 50s" or "down: caddy: 3s, normally up".
+var svStatusRe = regexp.MustCompile(`^(run|down|finish): [^:]+: (?:\(pid (\d+)\) )?(\d+)s`)
+
+type serviceStatus struct {
+	Name          string `json:"name"`
+	State         string `json:"state"`
+	PID           int    `json:"pid,omitempty"`
+	UptimeSeconds int    `json:"uptimeSeconds"`
+	Restarts      *int   `json:"restarts,omitempty"`
+	Detail        string `json:"detail,omitempty"`
+}
+
+type checkStatus struct {
+	Name   string `json:"name"`
+	OK     bool   `json:"ok"`
+	Detail string `json:"detail,omitempty"`
+}
+
+type agentStatus struct {
+	Agent    string          `json:"agent"`
+	Running  bool            `json:"running"`
+	Services []serviceStatus `json:"services"`
+	Checks   []checkStatus   `json:"checks"`
+}
+
+// healthy reports whether every service is up and every check passes.
+func (s agentStatus) healthy() bool {
+	if !s.Running {
+		return false
+	}
+	for _, svc := range s.Services {
+		if svc.State != "run" {
+			return false
+		}
+	}
+	for _, c := range s.Checks {
+		if !c.OK {
+			return false
+		}
+	}
+	return true
+}
+
+var statusCmd = &cobra.Command{
+	Use:   "status [NAME]",
+	Short: "Show runit services, Postgres and Redis health for an agent",
+	Args:  cobra.RangeArgs(0, 1),
+	ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		if len(args) == 0 {
+			return completeAgentNames(cmd, toComplete)
+		}
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		configDir, err := xdg.ConfigDir()
+		if err != nil {
+			return err
+		}
+		cfg, err := loadConfig(configDir)
+		if err != nil {
+			return err
+		}
+		name := currentAgentName(cfg)
+		if len(args) == 1 && strings.TrimSpace(args[0]) != "" {
+			name = strings.TrimSpace(args[0])
+		}
+		if !docker.Exists(name) {
+			return fmt.Errorf("agent '%s' does not exist", name)
+		}
+		_, imgCfg, err := resolveImage(cfg, cfg.ContainerImages[name])
+		if err != nil {
+			return err
+		}
+		asJSON, _ := cmd.Flags().GetBool("json")
+		watch, _ := cmd.Flags().GetBool("watch")
+		interval, _ := cmd.Flags().GetDuration("interval")
+		if interval <= 0 {
+			interval = 2 * time.Second
+		}
+		out := cmd.OutOrStdout()
+
+		if !watch {
+			status := collectAgentStatus(cmd.Context(), name, imgCfg)
+			if err := printAgentStatus(out, status, asJSON); err != nil {
+				return err
+			}
+			if !status.healthy() {
+				return fmt.Errorf("agent '%s' is not healthy", name)
+			}
+			return nil
+		}
+
+		ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt)
+		defer stop()
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			status := collectAgentStatus(ctx, name, imgCfg)
+			if ctx.Err() != nil {
+				return nil
+			}
+			if !asJSON {
+				// Clear the screen and home the cursor between refreshes.
+				fmt.Fprint(out, "\033[H\033[2J")
+				fmt.Fprintf(out, "%s  (every %s, Ctrl+C to stop)\n\n", time.Now().Format("15:04:05"), interval)
+			}
+			if err := printAgentStatus(out, status, asJSON); err != nil {
+				return err
+			}
+			select {
+			case <-ctx.Done():
+				return nil
+			case <-ticker.C:
+			}
+		}
+	},
+}
+
+// collectAgentStatus queries the services and checks for name. Services the
+// embedded Dockerfile defines but the container lacks are reported as missing
+// for images built from it; other images are judged only by what sv reports.
+// The Postgres and Redis checks only apply to discourse images.
+func collectAgentStatus(ctx context.Context, name string, imgCfg config.ImageConfig) agentStatus {
+	status := agentStatus{Agent: name, Running: docker.Running(name)}
+	if !status.Running {
+		return status
+	}
+	raw, err := docker.ExecAsRootContext(ctx, name, "/", nil, []string{"bash", "-c", statusScript})
+	if err != nil && strings.TrimSpace(raw) == "" {
+		status.Checks = append(status.Checks, checkStatus{Name: "exec", Detail: err.Error()})
+		return status
+	}
+	status.Services, status.Checks = parseAgentStatusOutput(raw)
+	if imgCfg.Kind != "discourse" {
+		status.Checks = nil
+		return status
+	}
+	if imgCfg.Dockerfile.Source != "stock" {
+		return status
+	}
+
+	have := map[string]bool{}
+	for _, svc := range status.Services {
+		have[svc.Name] = true
+	}
+	for _, expected := range assets.RunitServices() {
+		if !have[expected] {
+			status.Services = append(status.Services, serviceStatus{Name: expected, State: "missing"})
+		}
+	}
+	return status
+}
+
+func parseAgentStatusOutput(raw string) ([]serviceStatus, []checkStatus) {
+	var services []serviceStatus
+	var checks []checkStatus
+	for _, line := range strings.Split(raw, "\n") {
+		fields := strings.SplitN(line, "\t", 4)
+		switch {
+		case fields[0] == "service" && len(fields) >= 3:
+			svc := parseSvStatus(fields[1], fields[2])
+			if len(fields) == 4 {
+				if n, err := strconv.Atoi(strings.TrimSpace(fields[3])); err == nil {
+					svc.Restarts = &n
+				}
+			}
+			services = append(services, svc)
+		case fields[0] == "check" && len(fields) == 4:
+			checks = append(checks, checkStatus{
+				Name:   fields[1],
+				OK:     strings.TrimSpace(fields[2]) == "0",
+				Detail: strings.TrimSpace(fields[3]),
+			})
+		}
+	}
+	return services, checks
+}
+
+func parseSvStatus(name, line string) serviceStatus {
+	svc := serviceStatus{Name: name, State: "unknown", Detail: strings.TrimSpace(line)}
+	m := svStatusRe.FindStringSubmatch(line)
+	if m == nil {
+		return svc
+	}
+	svc.State = m[1]
+	svc.PID, _ = strconv.Atoi(m[2])
+	svc.UptimeSeconds, _ = strconv.Atoi(m[3])
+	svc.Detail = ""
+	return svc
+}
+
+func printAgentStatus(w io.Writer, status agentStatus, asJSON bool) error {
+	if asJSON {
+		return json.NewEncoder(w).Encode(status)
+	}
+	if !status.Running {
+		fmt.Fprintf(w, "Agent '%s' is not running.\n", status.Agent)
+		return nil
+	}
+	fmt.Fprintf(w, "Agent: %s\n\n", status.Agent)
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "SERVICE\tSTATE\tPID\tUPTIME\tRESTARTS")
+	for _, svc := range status.Services {
+		pid, uptime, restarts := "-", "-", "-"
+		if svc.PID > 0 {
+			pid = strconv.Itoa(svc.PID)
+		}
+		if svc.State == "run" || svc.State == "down" {
+			uptime = formatUptime(time.Duration(svc.UptimeSeconds) * time.Second)
+		}
+		if svc.Restarts != nil {
+			restarts = strconv.Itoa(*svc.Restarts)
+		}
+		state := svc.State
+		if svc.Detail != "" {
+			state += " (" + svc.Detail + ")"
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", svc.Name, state, pid, uptime, restarts)
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+	fmt.Fprintln(w)
+	tw = tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "CHECK\tSTATUS\tDETAIL")
+	for _, c := range status.Checks {
+		result := "ok"
+		if !c.OK {
+			result = "FAIL"
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\n", c.Name, result, c.Detail)
+	}
+	return tw.Flush()
+}
+
+// formatUptime renders d with at most two units, e.g. "3d4h", "5m20s".
+func formatUptime(d time.Duration) string {
+	secs := int(d / time.Second)
+	days, secs := secs/86400, secs%86400
+	hours, secs := secs/3600, secs%3600
+	mins, secs := secs/60, secs%60
+	switch {
+	case days > 0:
+		return fmt.Sprintf("%dd%dh", days, hours)
+	case hours > 0:
+		return fmt.Sprintf("%dh%dm", hours, mins)
+	case mins > 0:
+		return fmt.Sprintf("%dm%ds", mins, secs)
+	default:
+		return fmt.Sprintf("%ds", secs)
+	}
+}
+
+func init() {
+	statusCmd.Flags().Bool("json", false, "Print status as JSON (one object per refresh with --watch)")
+	statusCmd.Flags().BoolP("watch", "w", false, "Refresh until interrupted")
+	statusCmd.Flags().Duration("interval", 2*time.Second, "Refresh interval for --watch")
+}
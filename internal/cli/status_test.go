@@ -0,0 +1,144 @@
+package cli
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"dv/internal/config"
+	"dv/internal/docker/dockertest"
+)
+
+const fakeStatusOutput = "service\tcaddy\trun: caddy: (pid 41) 3700s; run: log: (pid 12) 3710s\t\n" +
+	"service\tember-cli\tdown: ember-cli: 12s, normally up\t3\n" +
+	"service\tpostgres\trun: postgres: (pid 30) 3705s\t\n" +
+	"service\tunicorn\trun: unicorn: (pid 1234) 95s\t2\n" +
+	"check\tpostgres\t0\tlocalhost:5432 - accepting connections\n" +
+	"check\tredis\t1\tCould not connect to Redis at 127.0.0.1:6379: Connection refused\n"
+
+func TestStatusReportsServicesAndChecks(t *testing.T) {
+	env := newTestEnv(t)
+	env.rt.AddImage("ai_agent", nil)
+	env.mustRun("new", "web")
+	env.rt.HandleExec("sv status", func(e dockertest.Exec) (string, error) {
+		if e.User != "root" {
+			t.Errorf("status exec ran as %q", e.User)
+		}
+		return fakeStatusOutput, nil
+	})
+
+	out, err := env.run("status")
+	if err == nil {
+		t.Fatal("status of an unhealthy agent succeeded")
+	}
+	for _, want := range []string{"unicorn", "1234", "1m35s", "down", "ember-cli", "redis", "FAIL", "accepting connections"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("status output missing %q:\n%s", want, out)
+		}
+	}
+
+	out, _ = env.run("status", "web", "--json")
+	var status agentStatus
+	if err := json.Unmarshal([]byte(out), &status); err != nil {
+		t.Fatalf("status --json: %v\n%s", err, out)
+	}
+	byName := map[string]serviceStatus{}
+	for _, svc := range status.Services {
+		byName[svc.Name] = svc
+	}
+	if u := byName["unicorn"]; u.State != "run" || u.PID != 1234 || u.UptimeSeconds != 95 || u.Restarts == nil || *u.Restarts != 2 {
+		t.Errorf("unicorn = %+v", u)
+	}
+	if c := byName["caddy"]; c.Restarts != nil || c.PID != 41 {
+		t.Errorf("caddy = %+v", c)
+	}
+	if len(status.Checks) != 2 || !status.Checks[0].OK || status.Checks[1].OK {
+		t.Errorf("checks = %+v", status.Checks)
+	}
+}
+
+func TestStatusMarksMissingServices(t *testing.T) {
+	env := newTestEnv(t)
+	env.rt.AddImage("ai_agent", nil)
+	env.mustRun("new", "web")
+	env.rt.HandleExec("sv status", func(e dockertest.Exec) (string, error) {
+		return "check\tpostgres\t0\tok\ncheck\tredis\t0\tPONG\n", nil
+	})
+	out, _ := env.run("status", "--json")
+	var status agentStatus
+	if err := json.Unmarshal([]byte(out), &status); err != nil {
+		t.Fatal(err)
+	}
+	if len(status.Services) == 0 {
+		t.Fatal("no services reported")
+	}
+	for _, svc := range status.Services {
+		if svc.State != "missing" {
+			t.Errorf("%s state = %q, want missing", svc.Name, svc.State)
+		}
+	}
+}
+
+func TestStatusNonStockImages(t *testing.T) {
+	env := newTestEnv(t)
+	env.rt.AddImage("ai_agent", nil)
+	env.mustRun("new", "web")
+	env.rt.HandleExec("sv status", func(e dockertest.Exec) (string, error) {
+		return "service\tapp\trun: app: (pid 7) 60s\t\n" +
+			"check\tpostgres\t1\tpg_isready: command not found\n", nil
+	})
+
+	// A discourse image from its own Dockerfile: no missing services, but
+	// the database checks still count.
+	cfg := env.config()
+	img := cfg.Images["discourse"]
+	img.Dockerfile = config.ImageSource{Source: "path", Path: "/tmp/Dockerfile"}
+	cfg.Images["discourse"] = img
+	env.saveConfig(cfg)
+	out, err := env.run("status", "--json")
+	if err == nil {
+		t.Fatal("failing postgres check reported healthy")
+	}
+	var status agentStatus
+	if err := json.Unmarshal([]byte(out), &status); err != nil {
+		t.Fatal(err)
+	}
+	if len(status.Services) != 1 || len(status.Checks) != 1 {
+		t.Errorf("path Dockerfile status = %+v", status)
+	}
+
+	// A custom image is healthy when every service sv reports is up.
+	img.Kind = "custom"
+	cfg.Images["discourse"] = img
+	env.saveConfig(cfg)
+	if out, err := env.run("status"); err != nil || !strings.Contains(out, "app") || strings.Contains(out, "missing") {
+		t.Fatalf("custom image status: err=%v\n%s", err, out)
+	}
+}
+
+func TestStatusStoppedAgent(t *testing.T) {
+	env := newTestEnv(t)
+	env.rt.AddImage("ai_agent", nil)
+	env.mustRun("new", "web")
+	env.mustRun("stop")
+	out, err := env.run("status")
+	if err == nil || !strings.Contains(out, "Agent 'web' is not running.") {
+		t.Fatalf("status of stopped agent: err=%v\n%s", err, out)
+	}
+}
+
+func TestFormatUptime(t *testing.T) {
+	t.Parallel()
+	cases := map[time.Duration]string{
+		5 * time.Second:               "5s",
+		95 * time.Second:              "1m35s",
+		2*time.Hour + 3*time.Minute:   "2h3m",
+		50*time.Hour + 10*time.Minute: "2d2h",
+	}
+	for d, want := range cases {
+		if got := formatUptime(d); got != want {
+			t.Errorf("formatUptime(%s) = %q, want %q", d, got, want)
+		}
+	}
+}
@@ -1,42 +1,297 @@
 package cli
 
 import (
+	"fmt"
+	"io"
+	"io/fs"
+	"maps"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
 	"dv/internal/config"
+	"dv/templates"
 )
 
 type templateConfig struct {
+	// Extends names a base template (path, URL or template name) that this
+	// one is merged over; see mergeTemplates.
+	Extends string `yaml:"extends,omitempty"`
+	// Vars declares the variables that ${name} and {{ .name }} refer to in
+	// the rest of the template.
+	Vars      map[string]templateVar `yaml:"vars,omitempty"`
 	Discourse struct {
-		Branch string `yaml:"branch"`
-		PR     int    `yaml:"pr"`
-		Repo   string `yaml:"repo"`
-	} `yaml:"discourse"`
+		Branch string `yaml:"branch,omitempty"`
+		PR     int    `yaml:"pr,omitempty"`
+		Repo   string `yaml:"repo,omitempty"`
+	} `yaml:"discourse,omitempty"`
 	Git struct {
-		SSHForward bool `yaml:"ssh_forward"`
-	} `yaml:"git"`
-	Copy     []config.CopyRule `yaml:"copy"`
-	Env      map[string]string `yaml:"env"`
-	OnCreate []string          `yaml:"on_create"`
-	Plugins  []templatePlugin  `yaml:"plugins"`
-	Themes   []templateTheme   `yaml:"themes"`
-	Settings map[string]any    `yaml:"settings"`
-	MCP      []templateMCP     `yaml:"mcp"`
+		SSHForward bool `yaml:"ssh_forward,omitempty"`
+	} `yaml:"git,omitempty"`
+	Copy     []config.CopyRule `yaml:"copy,omitempty"`
+	Env      map[string]string `yaml:"env,omitempty"`
+	OnCreate []string          `yaml:"on_create,omitempty"`
+	Plugins  []templatePlugin  `yaml:"plugins,omitempty"`
+	Themes   []templateTheme   `yaml:"themes,omitempty"`
+	Settings map[string]any    `yaml:"settings,omitempty"`
+	MCP      []templateMCP     `yaml:"mcp,omitempty"`
 }
 
 type templatePlugin struct {
 	Repo   string `yaml:"repo"`
-	Path   string `yaml:"path"`
-	Branch string `yaml:"branch"`
+	Path   string `yaml:"path,omitempty"`
+	Branch string `yaml:"branch,omitempty"`
 }
 
 type templateTheme struct {
 	Repo      string `yaml:"repo"`
-	Name      string `yaml:"name"`
-	Path      string `yaml:"path"`
-	AutoWatch bool   `yaml:"auto_watch"`
+	Name      string `yaml:"name,omitempty"`
+	Path      string `yaml:"path,omitempty"`
+	AutoWatch bool   `yaml:"auto_watch,omitempty"`
 }
 
 type templateMCP struct {
 	Name    string   `yaml:"name"`
-	Command string   `yaml:"command"`
-	Args    []string `yaml:"args"`
+	Command string   `yaml:"command,omitempty"`
+	Args    []string `yaml:"args,omitempty"`
+}
+
+// loadTemplate reads the template ref (a path, URL or template name), merges
+// it over everything it extends and fills in its vars from their defaults
+// and sets. Required vars without a value are an error.
+func loadTemplate(cfg config.Config, ref string, sets map[string]string) (*templateConfig, error) {
+	layers, err := loadTemplateLayers(cfg, resolveTemplateRef(cfg, ref, ""), nil)
+	if err != nil {
+		return nil, err
+	}
+	values, err := templateVarValues(templateLayerVars(layers), sets)
+	if err != nil {
+		return nil, err
+	}
+	return mergeTemplateLayers(layers, values)
+}
+
+// templateLayerVars merges the vars declared along an extends chain.
+func templateLayerVars(layers []*templateLayer) map[string]templateVar {
+	vars := map[string]templateVar{}
+	for _, l := range layers {
+		vars = mergeMaps(vars, l.head.Vars)
+	}
+	return vars
+}
+
+// mergeTemplateLayers interpolates each layer with values and merges them in
+// order.
+func mergeTemplateLayers(layers []*templateLayer, values map[string]string) (*templateConfig, error) {
+	var out *templateConfig
+	for _, l := range layers {
+		interpolateTemplate(l.doc, values)
+		tpl := &templateConfig{}
+		if err := l.doc.Decode(tpl); err != nil {
+			return nil, fmt.Errorf("parse template YAML %s: %w", l.loc, err)
+		}
+		if out == nil {
+			out = tpl
+		} else {
+			out = mergeTemplates(out, tpl)
+		}
+	}
+	out.Extends = ""
+	out.Vars = nil
+	return out, nil
+}
+
+// templateLayer is one file in an extends chain, parsed but not yet
+// interpolated.
+type templateLayer struct {
+	loc  string
+	data []byte
+	doc  *yaml.Node
+	head struct {
+		Extends string                 `yaml:"extends"`
+		Vars    map[string]templateVar `yaml:"vars"`
+	}
+}
+
+// loadTemplateLayers returns the extends chain of the template at loc, base
+// first.
+func loadTemplateLayers(cfg config.Config, loc string, seen []string) ([]*templateLayer, error) {
+	if slices.Contains(seen, loc) {
+		return nil, fmt.Errorf("template extends cycle: %s", strings.Join(append(seen, loc), " -> "))
+	}
+	seen = append(seen, loc)
+
+	data, err := readTemplate(loc)
+	if err != nil {
+		return nil, err
+	}
+	l := &templateLayer{loc: loc, data: data, doc: &yaml.Node{}}
+	if err := yaml.Unmarshal(data, l.doc); err != nil {
+		return nil, fmt.Errorf("parse template YAML %s: %w", loc, err)
+	}
+	if err := l.doc.Decode(&l.head); err != nil {
+		return nil, fmt.Errorf("parse template YAML %s: %w", loc, err)
+	}
+	if l.head.Extends == "" {
+		return []*templateLayer{l}, nil
+	}
+	base, err := loadTemplateLayers(cfg, resolveTemplateRef(cfg, l.head.Extends, loc), seen)
+	if err != nil {
+		return nil, err
+	}
+	return append(base, l), nil
+}
+
+// resolveTemplateRef turns a template reference into a URL, file path or
+// built-in template. References in a template's extends are relative to that
+// template; a template name (see registeredTemplates) is used when no such
+// file exists.
+func resolveTemplateRef(cfg config.Config, ref, from string) string {
+	if isTemplateURL(ref) {
+		return ref
+	}
+	named, isNamed := lookupTemplateName(cfg, ref)
+	if strings.HasPrefix(from, builtinTemplatePrefix) {
+		// Built-ins extend each other by name or by file name.
+		if !isNamed {
+			named, isNamed = lookupTemplateName(cfg, strings.TrimSuffix(path.Base(ref), ".yaml"))
+		}
+		if isNamed {
+			return named
+		}
+		return ref
+	}
+	if isTemplateURL(from) {
+		if isNamed {
+			return named
+		}
+		base, err := url.Parse(from)
+		if err != nil {
+			return ref
+		}
+		rel, err := url.Parse(ref)
+		if err != nil {
+			return ref
+		}
+		return base.ResolveReference(rel).String()
+	}
+	p := ref
+	if from != "" && !filepath.IsAbs(ref) {
+		p = filepath.Join(filepath.Dir(from), ref)
+	}
+	if isNamed {
+		if _, err := os.Stat(p); err != nil {
+			return named
+		}
+	}
+	return p
+}
+
+func isTemplateURL(s string) bool {
+	return strings.HasPrefix(s, "http://") || strings.HasPrefix(s, "https://")
+}
+
+func readTemplate(loc string) ([]byte, error) {
+	if name, ok := strings.CutPrefix(loc, builtinTemplatePrefix); ok {
+		data, err := fs.ReadFile(templates.FS, name+".yaml")
+		if err != nil {
+			return nil, fmt.Errorf("read built-in template: %w", err)
+		}
+		return data, nil
+	}
+	if !isTemplateURL(loc) {
+		data, err := os.ReadFile(loc)
+		if err != nil {
+			return nil, fmt.Errorf("read template: %w", err)
+		}
+		return data, nil
+	}
+	resp, err := http.Get(loc)
+	if err != nil {
+		return nil, fmt.Errorf("fetch template URL: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("fetch template URL: %s returned status %d", loc, resp.StatusCode)
+	}
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("read template body: %w", err)
+	}
+	return data, nil
+}
+
+// mergeTemplates layers child over base. Scalars and map entries set in
+// child win; a child branch or PR replaces both of the base's. Lists are
+// appended and deduplicated, with a child entry replacing the base entry for
+// the same plugin or theme repo, MCP name, copy destination or on_create
+// command. ssh_forward stays on if either template enables it.
+func mergeTemplates(base, child *templateConfig) *templateConfig {
+	out := *base
+	if child.Discourse.Branch != "" || child.Discourse.PR != 0 {
+		out.Discourse.Branch = child.Discourse.Branch
+		out.Discourse.PR = child.Discourse.PR
+	}
+	if child.Discourse.Repo != "" {
+		out.Discourse.Repo = child.Discourse.Repo
+	}
+	out.Git.SSHForward = base.Git.SSHForward || child.Git.SSHForward
+	out.Env = mergeMaps(base.Env, child.Env)
+	out.Settings = mergeMaps(base.Settings, child.Settings)
+	out.Copy = mergeLists(base.Copy, child.Copy, func(r config.CopyRule) string {
+		if strings.TrimSpace(r.Container) == "" {
+			return ""
+		}
+		return path.Clean(strings.TrimSpace(r.Container))
+	})
+	out.OnCreate = mergeLists(base.OnCreate, child.OnCreate, strings.TrimSpace)
+	out.Plugins = mergeLists(base.Plugins, child.Plugins, func(p templatePlugin) string { return repoKey(p.Repo) })
+	out.Themes = mergeLists(base.Themes, child.Themes, func(t templateTheme) string {
+		if t.Repo == "" {
+			return t.Name
+		}
+		return repoKey(t.Repo)
+	})
+	out.MCP = mergeLists(base.MCP, child.MCP, func(m templateMCP) string { return m.Name })
+	return &out
+}
+
+// repoKey lets https://host/org/repo and https://host/org/repo.git match.
+func repoKey(repo string) string {
+	return strings.TrimSuffix(strings.TrimSuffix(strings.TrimSpace(repo), "/"), ".git")
+}
+
+func mergeMaps[V any](base, child map[string]V) map[string]V {
+	if len(base) == 0 && len(child) == 0 {
+		return nil
+	}
+	out := make(map[string]V, len(base)+len(child))
+	maps.Copy(out, base)
+	maps.Copy(out, child)
+	return out
+}
+
+// mergeLists appends child to base, keeping one entry per key: later entries
+// replace earlier ones in place. Entries with an empty key are always kept.
+func mergeLists[T any](base, child []T, key func(T) string) []T {
+	var out []T
+	index := map[string]int{}
+	for _, v := range slices.Concat(base, child) {
+		k := key(v)
+		if i, ok := index[k]; ok && k != "" {
+			out[i] = v
+			continue
+		}
+		if k != "" {
+			index[k] = len(out)
+		}
+		out = append(out, v)
+	}
+	return out
 }
@@ -0,0 +1,325 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"path"
+	"reflect"
+	"slices"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+
+	"dv/internal/config"
+	"dv/internal/docker"
+	"dv/internal/xdg"
+)
+
+var templateApplyCmd = &cobra.Command{
+	Use:   "apply NAME|PATH|URL",
+	Short: "Apply a template's plugins, themes, settings and MCP servers to an existing agent",
+	Long: `Run the parts of a template that are safe to repeat against an existing
+agent: copy rules are added to the config, missing plugins are cloned (then
+bundled and migrated), missing themes are installed, site settings are set and
+missing MCP servers are registered. Plugins, themes and MCP servers that are
+already there are left alone.
+
+The Discourse branch or PR, ssh_forward, env and on_create commands only make
+sense for a new container and are reported as skipped. Use 'dv branch' or
+'dv pr' to switch the agent's checkout.`,
+	Args:              cobra.ExactArgs(1),
+	ValidArgsFunction: completeTemplateRefs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		configDir, err := xdg.ConfigDir()
+		if err != nil {
+			return err
+		}
+		cfg, err := loadConfig(configDir)
+		if err != nil {
+			return err
+		}
+		setFlags, _ := cmd.Flags().GetStringArray("set")
+		sets, err := parseTemplateSets(setFlags)
+		if err != nil {
+			return err
+		}
+		tpl, err := loadTemplate(cfg, args[0], sets)
+		if err != nil {
+			return err
+		}
+
+		name, _ := cmd.Flags().GetString("agent")
+		if name == "" {
+			name = currentAgentName(cfg)
+		}
+		if !docker.Exists(name) {
+			return fmt.Errorf("agent '%s' does not exist; create it with 'dv new %s --template %s'", name, name, args[0])
+		}
+		if !docker.Running(name) {
+			fmt.Fprintf(cmd.OutOrStdout(), "Starting container '%s'...\n", name)
+			if err := docker.Start(name); err != nil {
+				return err
+			}
+		}
+		imgName := cfg.ContainerImages[name]
+		imgCfg, ok := cfg.Images[imgName]
+		if !ok {
+			if _, imgCfg, err = resolveImage(cfg, ""); err != nil {
+				return err
+			}
+		}
+		// Template paths are relative to the Discourse root, not to a custom
+		// workdir such as a theme.
+		workdir := imgCfg.Workdir
+		verbose, _ := cmd.Flags().GetBool("verbose")
+
+		report := applyTemplate(cmd, &cfg, configDir, name, workdir, tpl, verbose)
+		return report.print(cmd, name)
+	},
+}
+
+const (
+	applyChanged   = "changed"
+	applyUnchanged = "unchanged"
+	applySkipped   = "skipped"
+	applyFailed    = "failed"
+)
+
+type applyResult struct {
+	section, item, status, detail string
+}
+
+// applyReport collects what dv template apply did with each template entry.
+type applyReport struct {
+	results []applyResult
+}
+
+func (r *applyReport) add(section, item, status, detail string) {
+	r.results = append(r.results, applyResult{section, item, status, detail})
+}
+
+func (r *applyReport) count(status string) int {
+	n := 0
+	for _, res := range r.results {
+		if res.status == status {
+			n++
+		}
+	}
+	return n
+}
+
+func (r *applyReport) print(cmd *cobra.Command, name string) error {
+	out := cmd.OutOrStdout()
+	fmt.Fprintf(out, "\nTemplate applied to '%s':\n", name)
+	if len(r.results) == 0 {
+		fmt.Fprintln(out, "  (the template has nothing to apply)")
+		return nil
+	}
+	w := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "  SECTION\tITEM\tSTATUS\tDETAIL")
+	for _, res := range r.results {
+		fmt.Fprintf(w, "  %s\t%s\t%s\t%s\n", res.section, res.item, res.status, res.detail)
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	failed := r.count(applyFailed)
+	fmt.Fprintf(out, "%d changed, %d unchanged, %d skipped, %d failed\n",
+		r.count(applyChanged), r.count(applyUnchanged), r.count(applySkipped), failed)
+	if failed > 0 {
+		return fmt.Errorf("%d template step(s) failed", failed)
+	}
+	return nil
+}
+
+// applyTemplate runs the repeatable parts of tpl against a running agent.
+// Failures are recorded in the report rather than stopping the run, so one
+// bad plugin doesn't keep the settings from being applied.
+func applyTemplate(cmd *cobra.Command, cfg *config.Config, configDir, name, workdir string, tpl *templateConfig, verbose bool) *applyReport {
+	report := &applyReport{}
+
+	if tpl.Discourse.PR != 0 {
+		report.add("discourse", fmt.Sprintf("pr %d", tpl.Discourse.PR), applySkipped, "checking out resets the working tree; use 'dv pr'")
+	} else if tpl.Discourse.Branch != "" {
+		report.add("discourse", "branch "+tpl.Discourse.Branch, applySkipped, "checking out resets the working tree; use 'dv branch'")
+	}
+	if tpl.Discourse.Repo != "" {
+		report.add("discourse", "repo "+tpl.Discourse.Repo, applySkipped, "only used when creating an agent")
+	}
+	if tpl.Git.SSHForward {
+		report.add("git", "ssh_forward", applySkipped, "the agent socket is mounted when the container is created")
+	}
+	envList := collectEnvPassthrough(*cfg)
+	for _, k := range slices.Sorted(maps.Keys(tpl.Env)) {
+		envList = append(envList, fmt.Sprintf("%s=%s", k, tpl.Env[k]))
+		report.add("env", k, applySkipped, "container env is fixed at creation; used for this run only")
+	}
+
+	// Copy rules, scoped to this agent as dv new does.
+	added := false
+	for _, rule := range tpl.Copy {
+		rule.Agents = []string{name}
+		item := rule.Host + " -> " + rule.Container
+		if containsCopyRule(cfg.CopyRules, rule) {
+			report.add("copy", item, applyUnchanged, "already in config")
+			continue
+		}
+		cfg.CopyRules = append(cfg.CopyRules, rule)
+		added = true
+		report.add("copy", item, applyChanged, "added to config")
+	}
+	if added {
+		if err := config.Save(configDir, *cfg); err != nil {
+			report.add("copy", "config", applyFailed, err.Error())
+		}
+	}
+
+	// Plugins: clone the missing ones, then bundle and migrate once.
+	cloned := 0
+	for _, p := range tpl.Plugins {
+		pPath := templatePluginPath(p)
+		if _, err := docker.ExecOutput(name, workdir, nil, []string{"test", "-d", pPath}); err == nil {
+			current, _ := docker.ExecOutput(name, workdir, nil, []string{"git", "-C", pPath, "rev-parse", "--abbrev-ref", "HEAD"})
+			current = strings.TrimSpace(current)
+			if p.Branch != "" && current != "" && current != p.Branch {
+				report.add("plugins", p.Repo, applySkipped, fmt.Sprintf("%s is on branch %s, not %s", pPath, current, p.Branch))
+			} else {
+				report.add("plugins", p.Repo, applyUnchanged, "already at "+pPath)
+			}
+			continue
+		}
+		if err := cloneTemplatePlugin(cmd, name, workdir, envList, p); err != nil {
+			report.add("plugins", p.Repo, applyFailed, err.Error())
+			continue
+		}
+		cloned++
+		report.add("plugins", p.Repo, applyChanged, "cloned into "+pPath)
+	}
+	if cloned > 0 {
+		fmt.Fprintf(cmd.OutOrStdout(), "Stopping services for bundle and migrate...\n")
+		stopScript := "sudo /usr/bin/sv force-stop unicorn ember-cli || true"
+		if _, err := docker.ExecOutput(name, workdir, nil, []string{"bash", "-lc", stopScript}); err != nil {
+			fmt.Fprintf(cmd.ErrOrStderr(), "Warning: failed to stop services: %v\n", err)
+		}
+		if err := runMaintenance(cmd, name, workdir, envList); err != nil {
+			report.add("plugins", "bundle and migrate", applyFailed, err.Error())
+		} else {
+			report.add("plugins", "bundle and migrate", applyChanged, fmt.Sprintf("ran for %d new plugin(s)", cloned))
+		}
+	}
+
+	// Settings and themes need a running Discourse.
+	if cloned > 0 || len(tpl.Settings) > 0 || len(tpl.Themes) > 0 {
+		fmt.Fprintf(cmd.OutOrStdout(), "Starting Discourse and waiting for it to be ready...\n")
+		if err := startDiscourseAndWait(cmd, name, workdir); err != nil {
+			report.add("discourse", "services", applyFailed, err.Error())
+		}
+	}
+
+	if len(tpl.Settings) > 0 {
+		// applySiteSettings prints each setting as changed or unchanged.
+		item := fmt.Sprintf("%d setting(s)", len(tpl.Settings))
+		counts, err := applySiteSettings(cmd, *cfg, name, tpl.Settings, envList, false, "template")
+		detail := fmt.Sprintf("%d changed, %d unchanged, %d failed (see above)", counts.changed, counts.unchanged, counts.errored)
+		switch {
+		case err != nil:
+			report.add("settings", item, applyFailed, err.Error())
+		case counts.errored > 0:
+			report.add("settings", item, applyFailed, detail)
+		case counts.changed > 0:
+			report.add("settings", item, applyChanged, detail)
+		default:
+			report.add("settings", item, applyUnchanged, detail)
+		}
+	}
+
+	for _, t := range tpl.Themes {
+		themePath := templateThemePath(t)
+		if _, err := docker.ExecOutput(name, workdir, nil, []string{"test", "-e", themePath}); err == nil {
+			report.add("themes", t.Repo, applyUnchanged, "already at "+themePath)
+			continue
+		}
+		fmt.Fprintf(cmd.OutOrStdout(), "Installing theme %s...\n", t.Repo)
+		if err := handleThemeClone(cmd, templateThemeContext(cfg, name, workdir, envList, verbose), t.Repo, t.Name); err != nil {
+			report.add("themes", t.Repo, applyFailed, err.Error())
+			continue
+		}
+		report.add("themes", t.Repo, applyChanged, "installed at "+themePath)
+	}
+
+	for _, c := range tpl.OnCreate {
+		report.add("on_create", c, applySkipped, "commands may not be safe to repeat")
+	}
+
+	// Registering an MCP server replaces any existing registration, so only
+	// servers that are missing or registered differently are redone.
+	registered, err := registeredMCPServers(name, workdir)
+	if err != nil && len(tpl.MCP) > 0 {
+		fmt.Fprintf(cmd.ErrOrStderr(), "Warning: could not read registered MCP servers: %v\n", err)
+	}
+	for _, m := range tpl.MCP {
+		if r, ok := registered[m.Name]; ok && (m.Command == "" || r.Command == m.Command && slices.Equal(r.Args, m.Args)) {
+			report.add("mcp", m.Name, applyUnchanged, "already registered")
+			continue
+		}
+		if err := configureTemplateMCP(cmd, name, workdir, envList, m); err != nil {
+			report.add("mcp", m.Name, applyFailed, err.Error())
+			continue
+		}
+		report.add("mcp", m.Name, applyChanged, "registered with claude, codex and gemini")
+	}
+	return report
+}
+
+// registeredMCP is an MCP server entry in the Gemini settings.
+type registeredMCP struct {
+	Command string   `json:"command"`
+	Args    []string `json:"args"`
+}
+
+// registeredMCPServers reads the MCP servers registered in an agent from the
+// Gemini settings, which configureMCP keeps as plain JSON alongside the
+// Claude and Codex entries.
+func registeredMCPServers(name, workdir string) (map[string]registeredMCP, error) {
+	homeDir, _ := docker.ExecOutput(name, "/", nil, []string{"bash", "-lc", "echo $HOME"})
+	homeDir = strings.TrimSpace(homeDir)
+	if homeDir == "" {
+		homeDir = "/home/discourse"
+	}
+	settingsPath := path.Join(homeDir, ".gemini/settings.json")
+	if _, err := docker.ExecOutput(name, workdir, nil, []string{"test", "-f", settingsPath}); err != nil {
+		return nil, nil
+	}
+	data, err := docker.ExecOutput(name, workdir, nil, []string{"cat", settingsPath})
+	if err != nil {
+		return nil, err
+	}
+	var settings struct {
+		MCPServers map[string]registeredMCP `json:"mcpServers"`
+	}
+	if err := json.Unmarshal([]byte(data), &settings); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", settingsPath, err)
+	}
+	return settings.MCPServers, nil
+}
+
+func containsCopyRule(rules []config.CopyRule, rule config.CopyRule) bool {
+	for _, r := range rules {
+		if r.Host == rule.Host && path.Clean(r.Container) == path.Clean(rule.Container) && reflect.DeepEqual(r.Agents, rule.Agents) {
+			return true
+		}
+	}
+	return false
+}
+
+func init() {
+	templateApplyCmd.Flags().String("agent", "", "Agent to apply the template to (defaults to the selected agent)")
+	templateApplyCmd.Flags().StringArray("set", nil, "Set a template variable (NAME=VALUE)")
+	templateApplyCmd.Flags().BoolP("verbose", "v", false, "Print verbose debugging output")
+	templateApplyCmd.RegisterFlagCompletionFunc("agent", func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		return completeAgentNames(cmd, toComplete)
+	})
+	templateCmd.AddCommand(templateApplyCmd)
+}
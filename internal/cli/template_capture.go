@@ -0,0 +1,294 @@
+package cli
+
+import (
+	"bytes"
+	"fmt"
+	"maps"
+	"os"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+
+	"dv/internal/config"
+	"dv/internal/discourse"
+	"dv/internal/docker"
+	"dv/internal/xdg"
+)
+
+var templateCaptureCmd = &cobra.Command{
+	Use:   "capture [NAME]",
+	Short: "Write a template that recreates an agent's plugins, themes, settings and MCP servers",
+	Long: `Inspect an existing agent and write a template for it:
+
+  plugins   every git checkout under plugins/, with its origin and branch
+  themes    the theme workspaces under /home/discourse, with their origin
+  settings  site settings that differ from their default (secrets left out)
+  mcp       the MCP servers registered in the agent
+
+The template is printed unless -o is given. Check it before sharing: settings
+may hold values that only make sense on this agent.`,
+	Args: cobra.MaximumNArgs(1),
+	ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		if len(args) > 0 {
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		}
+		return completeAgentNames(cmd, toComplete)
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		configDir, err := xdg.ConfigDir()
+		if err != nil {
+			return err
+		}
+		cfg, err := loadConfig(configDir)
+		if err != nil {
+			return err
+		}
+		name := currentAgentName(cfg)
+		if len(args) > 0 {
+			name = args[0]
+		}
+		if !docker.Exists(name) {
+			return fmt.Errorf("agent '%s' does not exist", name)
+		}
+		if !docker.Running(name) {
+			fmt.Fprintf(cmd.ErrOrStderr(), "Starting container '%s'...\n", name)
+			if err := docker.Start(name); err != nil {
+				return err
+			}
+		}
+		imgName := cfg.ContainerImages[name]
+		imgCfg, ok := cfg.Images[imgName]
+		if !ok {
+			if _, imgCfg, err = resolveImage(cfg, ""); err != nil {
+				return err
+			}
+		}
+		workdir := imgCfg.Workdir
+
+		// A section that can't be read is left out with a warning, so a
+		// stopped Discourse still leaves the plugins and themes captured.
+		warn := func(section string, err error) {
+			fmt.Fprintf(cmd.ErrOrStderr(), "Warning: could not capture %s: %v\n", section, err)
+		}
+		tpl := &templateConfig{}
+		if tpl.Plugins, err = capturePlugins(name, workdir); err != nil {
+			warn("plugins", err)
+		}
+		if tpl.Themes, err = captureThemes(name); err != nil {
+			warn("themes", err)
+		}
+		secrets, err := captureSettings(cfg, name, tpl)
+		if err != nil {
+			warn("site settings", err)
+		}
+		if len(secrets) > 0 {
+			fmt.Fprintf(cmd.ErrOrStderr(), "Left out secret setting(s) %s; add them by hand, ideally as op:// references\n", strings.Join(secrets, ", "))
+		}
+		if tpl.MCP, err = captureMCP(name, workdir); err != nil {
+			warn("MCP servers", err)
+		}
+
+		var buf bytes.Buffer
+		fmt.Fprintf(&buf, "# Captured from agent '%s' with 'dv template capture'.\n", name)
+		enc := yaml.NewEncoder(&buf)
+		enc.SetIndent(2)
+		if err := enc.Encode(tpl); err != nil {
+			return err
+		}
+		if err := enc.Close(); err != nil {
+			return err
+		}
+
+		output, _ := cmd.Flags().GetString("output")
+		summary := fmt.Sprintf("Captured %d plugin(s), %d theme(s), %d setting(s) and %d MCP server(s) from '%s'",
+			len(tpl.Plugins), len(tpl.Themes), len(tpl.Settings), len(tpl.MCP), name)
+		if output == "" {
+			fmt.Fprintln(cmd.ErrOrStderr(), summary)
+			_, err := cmd.OutOrStdout().Write(buf.Bytes())
+			return err
+		}
+		if err := os.WriteFile(output, buf.Bytes(), 0o644); err != nil {
+			return err
+		}
+		fmt.Fprintf(cmd.OutOrStdout(), "%s into %s\n", summary, output)
+		return nil
+	},
+}
+
+// capturePlugins lists the plugins with their own git checkout; plugins that
+// ship with Discourse are part of the core repo and have none.
+func capturePlugins(name, workdir string) ([]templatePlugin, error) {
+	script := `for d in plugins/*/; do
+  d=${d%/}
+  [ -e "$d/.git" ] || continue
+  printf '%s\t%s\t%s\n' "$(basename "$d")" "$(git -C "$d" remote get-url origin 2>/dev/null)" "$(git -C "$d" rev-parse --abbrev-ref HEAD 2>/dev/null)"
+done
+`
+	out, err := docker.ExecOutput(name, workdir, nil, []string{"bash", "-lc", script})
+	if err != nil {
+		return nil, err
+	}
+	var plugins []templatePlugin
+	for _, fields := range captureRows(out) {
+		dir, repo, branch := fields[0], fields[1], fields[2]
+		if repo == "" {
+			continue
+		}
+		p := templatePlugin{Repo: repo}
+		if branch != "HEAD" {
+			p.Branch = branch
+		}
+		if pPath := path.Join("plugins", dir); pPath != templatePluginPath(p) {
+			p.Path = pPath
+		}
+		plugins = append(plugins, p)
+	}
+	return plugins, nil
+}
+
+// captureThemes lists the theme workspaces under /home/discourse, the same
+// directories 'dv extract theme' offers.
+func captureThemes(name string) ([]templateTheme, error) {
+	script := `for d in /home/discourse/*/; do
+  d=${d%/}
+  b=$(basename "$d")
+  case "$b" in
+    .*|ai-tools) continue ;;
+  esac
+  [ -f "$d/about.json" ] || continue
+  git -C "$d" rev-parse --is-inside-work-tree >/dev/null 2>&1 || continue
+  watch=no
+  [ -d "/etc/service/theme-watch-$b" ] && watch=yes
+  printf '%s\t%s\t%s\n' "$b" "$(git -C "$d" remote get-url origin 2>/dev/null)" "$watch"
+done
+`
+	out, err := docker.ExecOutput(name, "/home/discourse", nil, []string{"bash", "-lc", script})
+	if err != nil {
+		return nil, err
+	}
+	var themes []templateTheme
+	for _, fields := range captureRows(out) {
+		dir, repo, watch := fields[0], fields[1], fields[2]
+		if repo == "" {
+			continue
+		}
+		t := templateTheme{Repo: repo, AutoWatch: watch == "yes"}
+		if _, defaultName := normalizeThemeRepo(repo); themeDirSlug(defaultName) != dir {
+			t.Name = dir
+		}
+		themes = append(themes, t)
+	}
+	return themes, nil
+}
+
+// captureRows splits tab-separated script output into rows of three fields.
+func captureRows(out string) [][]string {
+	var rows [][]string
+	for _, line := range strings.Split(out, "\n") {
+		fields := strings.Split(strings.TrimRight(line, "\r"), "\t")
+		if len(fields) != 3 || fields[0] == "" {
+			continue
+		}
+		for i := range fields {
+			fields[i] = strings.TrimSpace(fields[i])
+		}
+		rows = append(rows, fields)
+	}
+	return rows
+}
+
+// captureSettings fills tpl.Settings with the site settings that differ from
+// their default and returns the names of secret ones it left out.
+func captureSettings(cfg config.Config, name string, tpl *templateConfig) ([]string, error) {
+	client, err := discourse.NewClientWrapper(name, cfg, collectEnvPassthrough(cfg), false)
+	if err != nil {
+		return nil, fmt.Errorf("create discourse client: %w", err)
+	}
+	if err := client.EnsureAPIKey(); err != nil {
+		return nil, fmt.Errorf("ensure API key: %w", err)
+	}
+	all, err := client.ListSiteSettings()
+	if err != nil {
+		return nil, err
+	}
+	settings, secrets := nonDefaultSettings(all)
+	if len(settings) > 0 {
+		tpl.Settings = settings
+	}
+	return secrets, nil
+}
+
+// nonDefaultSettings returns the settings whose value differs from their
+// default, typed as a template would write them, and the names of changed
+// secret settings, which are left out.
+func nonDefaultSettings(all []discourse.SiteSetting) (map[string]any, []string) {
+	settings := map[string]any{}
+	var secrets []string
+	for _, s := range all {
+		value, def := siteSettingValue(s.Value, s.Type), siteSettingValue(s.Default, s.Type)
+		if fmt.Sprint(value) == fmt.Sprint(def) {
+			continue
+		}
+		if s.Secret {
+			secrets = append(secrets, s.Setting)
+			continue
+		}
+		settings[s.Setting] = value
+	}
+	slices.Sort(secrets)
+	return settings, secrets
+}
+
+// siteSettingValue converts the strings the admin API returns for booleans
+// and numbers, so `enable_foo: true` isn't captured as "true".
+func siteSettingValue(v any, typ string) any {
+	if v == nil {
+		return ""
+	}
+	s, ok := v.(string)
+	if !ok {
+		return v
+	}
+	switch typ {
+	case "bool":
+		if b, err := strconv.ParseBool(s); err == nil {
+			return b
+		}
+	case "integer":
+		if n, err := strconv.ParseInt(s, 10, 64); err == nil {
+			return n
+		}
+	case "float":
+		if f, err := strconv.ParseFloat(s, 64); err == nil {
+			return f
+		}
+	}
+	return s
+}
+
+// captureMCP lists the registered MCP servers, naming stock ones only.
+func captureMCP(name, workdir string) ([]templateMCP, error) {
+	registered, err := registeredMCPServers(name, workdir)
+	if err != nil {
+		return nil, err
+	}
+	var servers []templateMCP
+	for _, n := range slices.Sorted(maps.Keys(registered)) {
+		if containsString(stockMCPNames, n) {
+			servers = append(servers, templateMCP{Name: n})
+			continue
+		}
+		s := registered[n]
+		servers = append(servers, templateMCP{Name: n, Command: s.Command, Args: s.Args})
+	}
+	return servers, nil
+}
+
+func init() {
+	templateCaptureCmd.Flags().StringP("output", "o", "", "Write the template to this file instead of stdout")
+	templateCmd.AddCommand(templateCaptureCmd)
+}
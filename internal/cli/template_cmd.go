@@ -0,0 +1,81 @@
+package cli
+
+import (
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+
+	"dv/internal/xdg"
+)
+
+var templateCmd = &cobra.Command{
+	Use:   "template",
+	Short: "Work with dv new templates",
+	Long: `Templates describe how 'dv new --template' provisions an agent: the
+Discourse branch or PR, plugins, themes, site settings, copy rules, env,
+on_create commands and MCP servers.
+
+A template can build on another with 'extends:', given as a path (relative to
+the extending template), a URL or a template name (see 'dv template list').
+The extending template is merged over its base:
+
+  discourse   a branch or pr replaces the base's; repo overrides
+  env         keys override
+  settings    keys override
+  copy        appended; a rule for the same container path replaces the base's
+  plugins     appended; the same repo replaces the base's entry
+  themes      appended; the same repo replaces the base's entry
+  mcp         appended; the same name replaces the base's entry
+  on_create   appended; repeated commands run once
+
+A vars block declares variables with their defaults; a var without a default
+is required. Any value in the template can refer to them as ${name} or
+{{ .name }}, and they are set with --set NAME=VALUE. Other ${...} and {{...}}
+text is left as written:
+
+  vars:
+    branch: main
+    plugin_branch:
+      description: branch of discourse-ai to test
+  discourse:
+    branch: ${branch}
+  plugins:
+    - repo: https://github.com/discourse/discourse-ai.git
+      branch: "{{ .plugin_branch }}"`,
+}
+
+var templateRenderCmd = &cobra.Command{
+	Use:               "render NAME|PATH|URL",
+	Short:             "Print a template with everything it extends merged in and its vars filled",
+	Args:              cobra.ExactArgs(1),
+	ValidArgsFunction: completeTemplateRefs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		configDir, err := xdg.ConfigDir()
+		if err != nil {
+			return err
+		}
+		cfg, err := loadConfig(configDir)
+		if err != nil {
+			return err
+		}
+		setFlags, _ := cmd.Flags().GetStringArray("set")
+		sets, err := parseTemplateSets(setFlags)
+		if err != nil {
+			return err
+		}
+		tpl, err := loadTemplate(cfg, args[0], sets)
+		if err != nil {
+			return err
+		}
+		enc := yaml.NewEncoder(cmd.OutOrStdout())
+		enc.SetIndent(2)
+		if err := enc.Encode(tpl); err != nil {
+			return err
+		}
+		return enc.Close()
+	},
+}
+
+func init() {
+	templateRenderCmd.Flags().StringArray("set", nil, "Set a template variable (NAME=VALUE)")
+	templateCmd.AddCommand(templateRenderCmd)
+}
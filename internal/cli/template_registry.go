@@ -0,0 +1,287 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+
+	"dv/internal/config"
+	"dv/internal/xdg"
+	"dv/templates"
+)
+
+// builtinTemplatePrefix marks template locations inside the binary.
+const builtinTemplatePrefix = "builtin:"
+
+var templateNameRe = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]*$`)
+
+// templatesDir holds templates added with dv template add, one NAME.yaml each.
+func templatesDir(configDir string) string { return filepath.Join(configDir, "templates") }
+
+func validateTemplateName(name string) error {
+	if !templateNameRe.MatchString(name) || strings.HasSuffix(name, ".yaml") {
+		return fmt.Errorf("invalid template name %q: use letters, digits, '.', '_' or '-' (without .yaml)", name)
+	}
+	return nil
+}
+
+// templateEntry is a named template and where its name resolves to.
+type templateEntry struct {
+	Name     string
+	Source   string
+	Location string
+}
+
+// registeredTemplates lists every named template once, with the location
+// that wins: the templates config (and overlays) first, then the registry,
+// then the built-ins.
+func registeredTemplates(cfg config.Config) []templateEntry {
+	byName := map[string]templateEntry{}
+	if entries, err := fs.ReadDir(templates.FS, "."); err == nil {
+		for _, e := range entries {
+			if name, ok := strings.CutSuffix(e.Name(), ".yaml"); ok {
+				byName[name] = templateEntry{Name: name, Source: "built-in", Location: builtinTemplatePrefix + name}
+			}
+		}
+	}
+	if configDir, err := xdg.ConfigDir(); err == nil {
+		if entries, err := os.ReadDir(templatesDir(configDir)); err == nil {
+			for _, e := range entries {
+				name, ok := strings.CutSuffix(e.Name(), ".yaml")
+				if e.IsDir() || !ok || validateTemplateName(name) != nil {
+					continue
+				}
+				byName[name] = templateEntry{Name: name, Source: "registry", Location: filepath.Join(templatesDir(configDir), e.Name())}
+			}
+		}
+	}
+	for name, loc := range cfg.Templates {
+		source := cfg.Origin("templates." + name)
+		if source == "" {
+			source = "config.json"
+		}
+		byName[name] = templateEntry{Name: name, Source: source, Location: loc}
+	}
+	out := make([]templateEntry, 0, len(byName))
+	for _, e := range byName {
+		out = append(out, e)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return out
+}
+
+// lookupTemplateName returns the location of a named template.
+func lookupTemplateName(cfg config.Config, name string) (string, bool) {
+	for _, e := range registeredTemplates(cfg) {
+		if e.Name == name {
+			return e.Location, true
+		}
+	}
+	return "", false
+}
+
+func completeTemplateNames(cfg config.Config, args []string) []string {
+	var out []string
+	for _, e := range registeredTemplates(cfg) {
+		if !containsString(args, e.Name) {
+			out = append(out, e.Name)
+		}
+	}
+	return out
+}
+
+// completeTemplateRefs suggests template names and, through the shell, files.
+func completeTemplateRefs(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	configDir, err := xdg.ConfigDir()
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveDefault
+	}
+	cfg, err := loadConfig(configDir)
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveDefault
+	}
+	return completeTemplateNames(cfg, args), cobra.ShellCompDirectiveDefault
+}
+
+var templateListCmd = &cobra.Command{
+	Use:     "list",
+	Aliases: []string{"ls"},
+	Short:   "List named templates",
+	Args:    cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		configDir, err := xdg.ConfigDir()
+		if err != nil {
+			return err
+		}
+		cfg, err := loadConfig(configDir)
+		if err != nil {
+			return err
+		}
+		w := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 4, 2, ' ', 0)
+		fmt.Fprintln(w, "NAME\tSOURCE\tLOCATION")
+		for _, e := range registeredTemplates(cfg) {
+			loc := e.Location
+			if strings.HasPrefix(loc, builtinTemplatePrefix) {
+				loc = "-"
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\n", e.Name, e.Source, loc)
+		}
+		return w.Flush()
+	},
+}
+
+var templateShowCmd = &cobra.Command{
+	Use:               "show NAME|PATH|URL",
+	Short:             "Print a template as written (see 'dv template render' for the merged result)",
+	Args:              cobra.ExactArgs(1),
+	ValidArgsFunction: completeTemplateRefs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		configDir, err := xdg.ConfigDir()
+		if err != nil {
+			return err
+		}
+		cfg, err := loadConfig(configDir)
+		if err != nil {
+			return err
+		}
+		data, err := readTemplate(resolveTemplateRef(cfg, args[0], ""))
+		if err != nil {
+			return err
+		}
+		_, err = cmd.OutOrStdout().Write(data)
+		return err
+	},
+}
+
+var templateAddCmd = &cobra.Command{
+	Use:   "add NAME PATH|URL",
+	Short: "Copy a template into the registry under NAME",
+	Long: `Copy a template file or URL into ${XDG_CONFIG_HOME}/dv/templates/NAME.yaml
+so it can be used as 'dv new --template NAME'. The template is validated first.
+A registry template with the same name as a built-in one replaces it.`,
+	Args: cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		configDir, err := xdg.ConfigDir()
+		if err != nil {
+			return err
+		}
+		cfg, err := loadConfig(configDir)
+		if err != nil {
+			return err
+		}
+		name, src := args[0], args[1]
+		if err := validateTemplateName(name); err != nil {
+			return err
+		}
+		dest := filepath.Join(templatesDir(configDir), name+".yaml")
+		force, _ := cmd.Flags().GetBool("force")
+		if _, err := os.Stat(dest); err == nil && !force {
+			return fmt.Errorf("template '%s' already exists (use --force to replace it)", name)
+		}
+
+		loc := resolveTemplateRef(cfg, src, "")
+		if problems := validateTemplate(cfg, loc, nil); len(problems) > 0 {
+			for _, p := range problems {
+				fmt.Fprintf(cmd.ErrOrStderr(), "%s: %s\n", src, p)
+			}
+			return fmt.Errorf("not adding invalid template %s", src)
+		}
+		data, err := readTemplate(loc)
+		if err != nil {
+			return err
+		}
+		var head struct {
+			Extends string `yaml:"extends"`
+		}
+		_ = yaml.Unmarshal(data, &head)
+		if ext := head.Extends; ext != "" && !isTemplateURL(ext) {
+			if _, named := lookupTemplateName(cfg, ext); !named {
+				fmt.Fprintf(cmd.ErrOrStderr(), "Warning: extends %q is a relative path; from the registry it resolves against %s\n", ext, templatesDir(configDir))
+			}
+		}
+
+		if err := os.MkdirAll(templatesDir(configDir), 0o755); err != nil {
+			return err
+		}
+		if err := os.WriteFile(dest, data, 0o644); err != nil {
+			return err
+		}
+		fmt.Fprintf(cmd.OutOrStdout(), "Added template '%s' (%s)\n", name, dest)
+		return nil
+	},
+}
+
+var templateRemoveCmd = &cobra.Command{
+	Use:     "remove NAME",
+	Aliases: []string{"rm"},
+	Short:   "Remove a template from the registry",
+	Args:    cobra.ExactArgs(1),
+	ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		configDir, err := xdg.ConfigDir()
+		if err != nil {
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		}
+		cfg, err := loadConfig(configDir)
+		if err != nil {
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		}
+		var out []string
+		for _, e := range registeredTemplates(cfg) {
+			if e.Source == "registry" && !containsString(args, e.Name) {
+				out = append(out, e.Name)
+			}
+		}
+		return out, cobra.ShellCompDirectiveNoFileComp
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		configDir, err := xdg.ConfigDir()
+		if err != nil {
+			return err
+		}
+		cfg, err := loadConfig(configDir)
+		if err != nil {
+			return err
+		}
+		name := args[0]
+		if err := validateTemplateName(name); err != nil {
+			return err
+		}
+		dest := filepath.Join(templatesDir(configDir), name+".yaml")
+		err = os.Remove(dest)
+		if errors.Is(err, fs.ErrNotExist) {
+			for _, e := range registeredTemplates(cfg) {
+				if e.Name != name {
+					continue
+				}
+				if e.Source == "built-in" {
+					return fmt.Errorf("'%s' is a built-in template and can't be removed", name)
+				}
+				return fmt.Errorf("'%s' is set in the templates config (%s); remove it there", name, e.Source)
+			}
+			return fmt.Errorf("template '%s' does not exist", name)
+		}
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(cmd.OutOrStdout(), "Removed template '%s'\n", name)
+		return nil
+	},
+}
+
+func init() {
+	templateCmd.AddCommand(templateListCmd)
+	templateCmd.AddCommand(templateShowCmd)
+	templateCmd.AddCommand(templateAddCmd)
+	templateCmd.AddCommand(templateRemoveCmd)
+
+	templateAddCmd.Flags().Bool("force", false, "Replace an existing registry template")
+}
@@ -0,0 +1,508 @@
+package cli
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"dv/internal/config"
+	"dv/internal/discourse"
+	"dv/internal/docker"
+	"dv/internal/docker/dockertest"
+)
+
+func writeTemplateFile(t *testing.T, dir, name, body string) string {
+	t.Helper()
+	p := filepath.Join(dir, name)
+	if err := os.WriteFile(p, []byte(body), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return p
+}
+
+func TestLoadTemplateExtends(t *testing.T) {
+	dir := t.TempDir()
+	writeTemplateFile(t, dir, "base.yaml", `
+discourse:
+  branch: main
+env: {A: "1", B: "2"}
+copy:
+  - host: ~/.gitconfig
+    container: /home/discourse/.gitconfig
+plugins:
+  - repo: https://github.com/discourse/discourse-solved.git
+    branch: main
+themes:
+  - repo: https://github.com/discourse/discourse-canvas-theme.git
+settings: {title: base, max_topic_title_length: 255}
+on_create: ["echo base"]
+mcp:
+  - name: playwright
+`)
+	writeTemplateFile(t, dir, "team.yaml", `
+extends: base.yaml
+discourse:
+  pr: 123
+env: {B: "3"}
+plugins:
+  - repo: https://github.com/discourse/discourse-solved
+    branch: stable
+  - repo: https://github.com/discourse/discourse-ai.git
+`)
+	child := writeTemplateFile(t, dir, "child.yaml", `
+extends: team.yaml
+copy:
+  - host: ~/work/.gitconfig
+    container: /home/discourse/.gitconfig
+settings: {title: child}
+on_create: ["echo base", "echo child"]
+mcp:
+  - name: discourse
+  - name: playwright
+`)
+
+	tpl, err := loadTemplate(config.Default(), child, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tpl.Extends != "" || tpl.Discourse.PR != 123 || tpl.Discourse.Branch != "" {
+		t.Fatalf("discourse = %+v, extends = %q", tpl.Discourse, tpl.Extends)
+	}
+	if want := map[string]string{"A": "1", "B": "3"}; !reflect.DeepEqual(tpl.Env, want) {
+		t.Fatalf("env = %v", tpl.Env)
+	}
+	if len(tpl.Copy) != 1 || tpl.Copy[0].Host != "~/work/.gitconfig" {
+		t.Fatalf("copy = %+v", tpl.Copy)
+	}
+	wantPlugins := []templatePlugin{
+		{Repo: "https://github.com/discourse/discourse-solved", Branch: "stable"},
+		{Repo: "https://github.com/discourse/discourse-ai.git"},
+	}
+	if !reflect.DeepEqual(tpl.Plugins, wantPlugins) {
+		t.Fatalf("plugins = %+v", tpl.Plugins)
+	}
+	if len(tpl.Themes) != 1 {
+		t.Fatalf("themes = %+v", tpl.Themes)
+	}
+	if tpl.Settings["title"] != "child" || tpl.Settings["max_topic_title_length"] != 255 {
+		t.Fatalf("settings = %v", tpl.Settings)
+	}
+	if !reflect.DeepEqual(tpl.OnCreate, []string{"echo base", "echo child"}) {
+		t.Fatalf("on_create = %v", tpl.OnCreate)
+	}
+	if len(tpl.MCP) != 2 || tpl.MCP[0].Name != "playwright" || tpl.MCP[1].Name != "discourse" {
+		t.Fatalf("mcp = %+v", tpl.MCP)
+	}
+}
+
+func TestLoadTemplateExtendsCycle(t *testing.T) {
+	dir := t.TempDir()
+	a := writeTemplateFile(t, dir, "a.yaml", "extends: b.yaml\n")
+	writeTemplateFile(t, dir, "b.yaml", "extends: a.yaml\n")
+
+	_, err := loadTemplate(config.Default(), a, nil)
+	if err == nil || !strings.Contains(err.Error(), "template extends cycle") {
+		t.Fatalf("err = %v", err)
+	}
+}
+
+func TestLoadTemplateExtendsURLAndName(t *testing.T) {
+	dir := t.TempDir()
+	named := writeTemplateFile(t, dir, "shared.yaml", "env: {SHARED: \"1\"}\n")
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/t/child.yaml":
+			_, _ = w.Write([]byte("extends: base.yaml\nenv: {CHILD: \"1\"}\n"))
+		case "/t/base.yaml":
+			_, _ = w.Write([]byte("extends: shared\nenv: {BASE: \"1\"}\n"))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	cfg := config.Default()
+	cfg.Templates = map[string]string{"shared": named}
+	tpl, err := loadTemplate(cfg, srv.URL+"/t/child.yaml", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := map[string]string{"SHARED": "1", "BASE": "1", "CHILD": "1"}; !reflect.DeepEqual(tpl.Env, want) {
+		t.Fatalf("env = %v", tpl.Env)
+	}
+}
+
+func TestTemplateRender(t *testing.T) {
+	env := newTestEnv(t)
+	dir := t.TempDir()
+	base := writeTemplateFile(t, dir, "base.yaml", "discourse:\n  branch: main\nmcp:\n  - name: playwright\n")
+	writeTemplateFile(t, dir, "child.yaml", "extends: base\nmcp:\n  - name: discourse\n")
+	cfg := env.config()
+	cfg.Templates = map[string]string{"base": base}
+	env.saveConfig(cfg)
+
+	out := env.mustRun("template", "render", filepath.Join(dir, "child.yaml"))
+	want := "discourse:\n  branch: main\nmcp:\n  - name: playwright\n  - name: discourse\n"
+	if out != want {
+		t.Fatalf("render output:\n%s\nwant:\n%s", out, want)
+	}
+}
+
+func TestLoadTemplateVars(t *testing.T) {
+	dir := t.TempDir()
+	writeTemplateFile(t, dir, "base.yaml", `
+vars:
+  branch: main
+  plugin_branch:
+    description: discourse-ai branch
+discourse:
+  branch: ${branch}
+plugins:
+  - repo: https://github.com/discourse/discourse-ai.git
+    branch: "{{ .plugin_branch }}"
+`)
+	child := writeTemplateFile(t, dir, "child.yaml", `
+extends: base.yaml
+vars:
+  branch: stable
+  pr: ""
+discourse:
+  pr: ${pr}
+settings:
+  title: "${branch} sandbox"
+on_create: ["echo $HOME ${HOME} ${branch}"]
+`)
+
+	_, err := loadTemplate(config.Default(), child, nil)
+	if err == nil || !strings.Contains(err.Error(), "plugin_branch (discourse-ai branch)") {
+		t.Fatalf("err = %v", err)
+	}
+	if _, err := loadTemplate(config.Default(), child, map[string]string{"plugin_branch": "x", "typo": "y"}); err == nil || !strings.Contains(err.Error(), "typo") {
+		t.Fatalf("err = %v", err)
+	}
+
+	tpl, err := loadTemplate(config.Default(), child, map[string]string{"plugin_branch": "fix", "pr": "42"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tpl.Discourse.Branch != "" || tpl.Discourse.PR != 42 || tpl.Vars != nil {
+		t.Fatalf("tpl = %+v", tpl)
+	}
+	if tpl.Plugins[0].Branch != "fix" || tpl.Settings["title"] != "stable sandbox" {
+		t.Fatalf("plugins = %+v, settings = %v", tpl.Plugins, tpl.Settings)
+	}
+	if want := "echo $HOME ${HOME} stable"; tpl.OnCreate[0] != want {
+		t.Fatalf("on_create = %q, want %q", tpl.OnCreate[0], want)
+	}
+
+	tpl, err = loadTemplate(config.Default(), child, map[string]string{"plugin_branch": "fix", "branch": "feature"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tpl.Discourse.Branch != "feature" || tpl.Discourse.PR != 0 {
+		t.Fatalf("discourse = %+v", tpl.Discourse)
+	}
+}
+
+func TestLoadTemplateKeepsLiteralBraces(t *testing.T) {
+	dir := t.TempDir()
+	plain := writeTemplateFile(t, dir, "plain.yaml", `
+settings:
+  title: "{{ site }}"
+on_create:
+  - docker inspect --format '{{.Id}}' db
+`)
+	tpl, err := loadTemplate(config.Default(), plain, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "docker inspect --format '{{.Id}}' db"; tpl.OnCreate[0] != want {
+		t.Fatalf("on_create = %q, want %q", tpl.OnCreate[0], want)
+	}
+	if tpl.Settings["title"] != "{{ site }}" {
+		t.Fatalf("settings = %v", tpl.Settings)
+	}
+
+	withVars := writeTemplateFile(t, dir, "vars.yaml", `
+extends: plain.yaml
+vars:
+  db: postgres
+on_create:
+  - "echo {{ .db }} {{.Id}} {{ .Missing }}"
+`)
+	tpl, err = loadTemplate(config.Default(), withVars, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "echo postgres {{.Id}} {{ .Missing }}"; tpl.OnCreate[1] != want {
+		t.Fatalf("on_create = %q, want %q", tpl.OnCreate, want)
+	}
+}
+
+func TestNewTemplateMissingVarCreatesNothing(t *testing.T) {
+	env := newTestEnv(t)
+	tpl := writeTemplateFile(t, t.TempDir(), "t.yaml", "vars:\n  branch:\ndiscourse:\n  branch: ${branch}\n")
+
+	_, err := env.run("new", "agent", "--template", tpl)
+	if err == nil || !strings.Contains(err.Error(), "required template variable(s) not set: branch") {
+		t.Fatalf("err = %v", err)
+	}
+	if docker.Exists("agent") {
+		t.Fatal("container created despite missing template var")
+	}
+	if _, err := env.run("new", "agent", "--set", "branch=main"); err == nil {
+		t.Fatal("expected --set without --template to fail")
+	}
+}
+
+func TestTemplateRegistry(t *testing.T) {
+	env := newTestEnv(t)
+	env.config()
+	dir := t.TempDir()
+
+	out := env.mustRun("template", "list")
+	if !hasLineWith(out, "full", "built-in") || !hasLineWith(out, "theme-dev", "built-in") {
+		t.Fatalf("list output:\n%s", out)
+	}
+	if out := env.mustRun("template", "render", "stable"); out != "discourse:\n  branch: stable\n" {
+		t.Fatalf("render stable:\n%s", out)
+	}
+
+	typo := writeTemplateFile(t, dir, "typo.yaml", "themes:\n  - repo: https://github.com/discourse/graceful.git\n    auto_wach: true\n")
+	out, err := env.run("template", "add", "typo", typo)
+	if err == nil || !strings.Contains(out, `line 3: unknown key "auto_wach"`) {
+		t.Fatalf("err = %v, output:\n%s", err, out)
+	}
+
+	team := writeTemplateFile(t, dir, "team.yaml", "extends: stable\nplugins:\n  - repo: https://github.com/discourse/discourse-ai.git\n")
+	env.mustRun("template", "add", "team", team)
+	if _, err := env.run("template", "add", "team", team); err == nil {
+		t.Fatal("expected error adding an existing template")
+	}
+	// A registry template shadows the built-in of the same name.
+	env.mustRun("template", "add", "stable", writeTemplateFile(t, dir, "stable.yaml", "discourse:\n  branch: tests-passed\n"))
+
+	out = env.mustRun("template", "list")
+	if !hasLineWith(out, "team", "registry") || !hasLineWith(out, "stable", "registry") {
+		t.Fatalf("list output:\n%s", out)
+	}
+	out = env.mustRun("template", "render", "team")
+	if !strings.Contains(out, "branch: tests-passed") || !strings.Contains(out, "discourse-ai.git") {
+		t.Fatalf("render team:\n%s", out)
+	}
+	if out := env.mustRun("template", "show", "team"); !strings.HasPrefix(out, "extends: stable\n") {
+		t.Fatalf("show team:\n%s", out)
+	}
+
+	out, err = env.run("template", "validate", "team", typo)
+	if err == nil || !hasLineWith(out, "team: ok") || !strings.Contains(out, typo+`: line 3: unknown key "auto_wach"`) {
+		t.Fatalf("err = %v, output:\n%s", err, out)
+	}
+	if out := env.mustRun("template", "validate"); !hasLineWith(out, "full: ok") {
+		t.Fatalf("validate output:\n%s", out)
+	}
+
+	env.mustRun("template", "remove", "stable")
+	if out := env.mustRun("template", "list"); !hasLineWith(out, "stable", "built-in") {
+		t.Fatalf("built-in not restored:\n%s", out)
+	}
+	if _, err := env.run("template", "remove", "full"); err == nil || !strings.Contains(err.Error(), "built-in") {
+		t.Fatalf("err = %v", err)
+	}
+
+	names, _ := completeTemplateRefs(newCmd, nil, "")
+	if !containsString(names, "team") || !containsString(names, "full") {
+		t.Fatalf("completion = %v", names)
+	}
+}
+
+func TestCheckTemplate(t *testing.T) {
+	tpl := &templateConfig{
+		Plugins: []templatePlugin{{}},
+		MCP:     []templateMCP{{Name: "playwright"}, {Name: "mystery"}},
+		Copy:    []config.CopyRule{{Host: "~/.x", Container: "x"}},
+	}
+	want := []string{
+		`copy[0].container: container path "x" must be absolute`,
+		"plugins[0].repo: must not be empty",
+		`mcp[1]: unknown stock MCP "mystery" (want playwright, discourse, chrome-devtools, or set command)`,
+	}
+	if got := checkTemplate(tpl); !reflect.DeepEqual(got, want) {
+		t.Fatalf("problems = %q", got)
+	}
+}
+
+func TestTemplateApply(t *testing.T) {
+	env := newTestEnv(t)
+	env.rt.AddImage("ai_agent", nil)
+	env.mustRun("new", "agent")
+	if err := env.rt.WriteFile("agent", "/var/www/discourse/plugins/discourse-solved/plugin.rb", nil); err != nil {
+		t.Fatal(err)
+	}
+	env.rt.HandleExec("rev-parse --abbrev-ref HEAD", func(e dockertest.Exec) (string, error) {
+		return "main\n", nil
+	})
+	env.rt.HandleExec("git clone", func(e dockertest.Exec) (string, error) {
+		return "", env.rt.WriteFile("agent", "/var/www/discourse/plugins/discourse-ai/plugin.rb", nil)
+	})
+	tpl := writeTemplateFile(t, t.TempDir(), "t.yaml", `
+discourse:
+  branch: stable
+env: {FOO: "1"}
+copy:
+  - host: ~/.gitconfig
+    container: /home/discourse/.gitconfig
+plugins:
+  - repo: https://github.com/discourse/discourse-solved.git
+    branch: stable
+  - repo: https://github.com/discourse/discourse-ai.git
+on_create: ["echo hi"]
+`)
+
+	out := env.mustRun("template", "apply", tpl, "--agent", "agent")
+	for _, want := range [][]string{
+		{"discourse", "branch stable", "skipped"},
+		{"env", "FOO", "skipped"},
+		{"copy", "~/.gitconfig -> /home/discourse/.gitconfig", "changed"},
+		{"plugins", "discourse-solved.git", "skipped", "on branch main, not stable"},
+		{"plugins", "discourse-ai.git", "changed", "cloned into plugins/discourse-ai"},
+		{"plugins", "bundle and migrate", "changed"},
+		{"on_create", "echo hi", "skipped"},
+		{"3 changed, 0 unchanged, 4 skipped, 0 failed"},
+	} {
+		if !hasLineWith(out, want...) {
+			t.Fatalf("missing %q in output:\n%s", want, out)
+		}
+	}
+	if rules := env.config().CopyRules; rules[len(rules)-1].Agents[0] != "agent" {
+		t.Fatalf("copy rule not scoped to agent: %+v", rules[len(rules)-1])
+	}
+
+	// A second run finds everything in place.
+	out = env.mustRun("template", "apply", tpl, "--agent", "agent")
+	if !hasLineWith(out, "0 changed, 2 unchanged, 4 skipped, 0 failed") {
+		t.Fatalf("second apply:\n%s", out)
+	}
+
+	if _, err := env.run("template", "apply", tpl, "--agent", "missing"); err == nil {
+		t.Fatal("expected error for a missing agent")
+	}
+}
+
+func TestTemplateCapture(t *testing.T) {
+	env := newTestEnv(t)
+	env.rt.AddImage("ai_agent", nil)
+	env.mustRun("new", "agent")
+	env.rt.HandleExec("for d in plugins/*/", func(e dockertest.Exec) (string, error) {
+		return "discourse-ai\thttps://github.com/discourse/discourse-ai.git\tmain\n" +
+			"solved\thttps://github.com/discourse/discourse-solved.git\tHEAD\n" +
+			"scratch\t\tmain\n", nil
+	})
+	env.rt.HandleExec("for d in /home/discourse/*/", func(e dockertest.Exec) (string, error) {
+		return "horizon\thttps://github.com/discourse/horizon.git\tyes\n" +
+			"my-theme\thttps://github.com/discourse/horizon.git\tno\n", nil
+	})
+	err := env.rt.WriteFile("agent", "/home/discourse/.gemini/settings.json", []byte(`{"mcpServers": {
+		"playwright": {"command": "npx", "args": ["-y", "@playwright/mcp@latest"]},
+		"tools": {"command": "/usr/local/bin/tools-mcp", "args": ["--stdio"]}
+	}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	out := filepath.Join(t.TempDir(), "my.yaml")
+	res := env.mustRun("template", "capture", "agent", "-o", out)
+	if !hasLineWith(res, "Captured 2 plugin(s), 2 theme(s), 0 setting(s) and 2 MCP server(s) from 'agent' into "+out) {
+		t.Fatalf("unexpected output:\n%s", res)
+	}
+	// The fake runtime has no Discourse to ask for settings.
+	if !hasLineWith(res, "Warning: could not capture site settings") {
+		t.Fatalf("expected a settings warning:\n%s", res)
+	}
+
+	tpl, err := loadTemplate(env.config(), out, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantPlugins := []templatePlugin{
+		{Repo: "https://github.com/discourse/discourse-ai.git", Branch: "main"},
+		{Repo: "https://github.com/discourse/discourse-solved.git", Path: "plugins/solved"},
+	}
+	if !reflect.DeepEqual(tpl.Plugins, wantPlugins) {
+		t.Fatalf("plugins = %+v", tpl.Plugins)
+	}
+	wantThemes := []templateTheme{
+		{Repo: "https://github.com/discourse/horizon.git", AutoWatch: true},
+		{Repo: "https://github.com/discourse/horizon.git", Name: "my-theme"},
+	}
+	if !reflect.DeepEqual(tpl.Themes, wantThemes) {
+		t.Fatalf("themes = %+v", tpl.Themes)
+	}
+	wantMCP := []templateMCP{
+		{Name: "playwright"},
+		{Name: "tools", Command: "/usr/local/bin/tools-mcp", Args: []string{"--stdio"}},
+	}
+	if !reflect.DeepEqual(tpl.MCP, wantMCP) {
+		t.Fatalf("mcp = %+v", tpl.MCP)
+	}
+	if problems := checkTemplate(tpl); len(problems) > 0 {
+		t.Fatalf("captured template has problems: %v", problems)
+	}
+}
+
+func TestNonDefaultSettings(t *testing.T) {
+	settings, secrets := nonDefaultSettings([]discourse.SiteSetting{
+		{Setting: "title", Value: "Dev", Default: "Discourse", Type: "string"},
+		{Setting: "login_required", Value: "true", Default: "false", Type: "bool"},
+		{Setting: "max_users", Value: "20", Default: "10", Type: "integer"},
+		{Setting: "enable_foo", Value: true, Default: true, Type: "bool"},
+		{Setting: "tags", Value: "", Default: nil, Type: "list"},
+		{Setting: "ai_openai_api_key", Value: "sk-123", Default: "", Type: "secret", Secret: true},
+	})
+	want := map[string]any{"title": "Dev", "login_required": true, "max_users": int64(20)}
+	if !reflect.DeepEqual(settings, want) {
+		t.Fatalf("settings = %#v", settings)
+	}
+	if !reflect.DeepEqual(secrets, []string{"ai_openai_api_key"}) {
+		t.Fatalf("secrets = %v", secrets)
+	}
+}
+
+func TestTemplateApplyMCP(t *testing.T) {
+	env := newTestEnv(t)
+	env.rt.AddImage("ai_agent", nil)
+	env.mustRun("new", "agent")
+	err := env.rt.WriteFile("agent", "/home/discourse/.gemini/settings.json", []byte(`{"mcpServers": {
+		"playwright": {"command": "npx", "args": ["-y", "@playwright/mcp@latest"]},
+		"tools": {"command": "/usr/local/bin/tools-mcp", "args": ["--stdio"]}
+	}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tpl := writeTemplateFile(t, t.TempDir(), "t.yaml", `
+mcp:
+  - name: playwright
+  - name: tools
+    command: /usr/local/bin/tools-mcp
+    args: ["--stdio"]
+  - name: search
+    command: /usr/local/bin/search-mcp
+`)
+
+	out, _ := env.run("template", "apply", tpl, "--agent", "agent")
+	for _, want := range [][]string{
+		{"mcp", "playwright", "unchanged", "already registered"},
+		{"mcp", "tools", "unchanged", "already registered"},
+	} {
+		if !hasLineWith(out, want...) {
+			t.Fatalf("missing %q in output:\n%s", want, out)
+		}
+	}
+	if hasLineWith(out, "mcp", "search", "unchanged") {
+		t.Fatalf("unregistered MCP reported unchanged:\n%s", out)
+	}
+}
@@ -0,0 +1,177 @@
+package cli
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+
+	"dv/internal/config"
+	"dv/internal/xdg"
+)
+
+var templateValidateCmd = &cobra.Command{
+	Use:   "validate [NAME|PATH|URL...]",
+	Short: "Check templates for unknown keys and invalid values",
+	Long: `Check templates, and everything they extend, for misspelled or unknown
+keys, values of the wrong type, and entries dv new can't act on, such as a
+plugin without a repo or an unknown stock MCP server.
+
+Without arguments every named template is checked. Required vars don't need a
+value here; they are checked as if set to an empty string.`,
+	ValidArgsFunction: completeTemplateRefs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		configDir, err := xdg.ConfigDir()
+		if err != nil {
+			return err
+		}
+		cfg, err := loadConfig(configDir)
+		if err != nil {
+			return err
+		}
+		setFlags, _ := cmd.Flags().GetStringArray("set")
+		sets, err := parseTemplateSets(setFlags)
+		if err != nil {
+			return err
+		}
+		refs := args
+		if len(refs) == 0 {
+			for _, e := range registeredTemplates(cfg) {
+				refs = append(refs, e.Name)
+			}
+		}
+		out := cmd.OutOrStdout()
+		invalid := 0
+		for _, ref := range refs {
+			problems := validateTemplate(cfg, resolveTemplateRef(cfg, ref, ""), sets)
+			if len(problems) == 0 {
+				fmt.Fprintf(out, "%s: ok\n", ref)
+				continue
+			}
+			invalid++
+			for _, p := range problems {
+				fmt.Fprintf(out, "%s: %s\n", ref, p)
+			}
+		}
+		if invalid > 0 {
+			return fmt.Errorf("%d of %d template(s) invalid", invalid, len(refs))
+		}
+		return nil
+	},
+}
+
+var unknownFieldRe = regexp.MustCompile(`field (\S+) not found in type \S+`)
+
+// validateTemplate returns every problem in the template at loc and the
+// templates it extends.
+func validateTemplate(cfg config.Config, loc string, sets map[string]string) []string {
+	layers, err := loadTemplateLayers(cfg, loc, nil)
+	if err != nil {
+		return []string{err.Error()}
+	}
+	var problems []string
+	for _, l := range layers {
+		prefix := ""
+		if len(layers) > 1 {
+			prefix = l.loc + ": "
+		}
+		for _, p := range unknownTemplateKeys(l.data) {
+			problems = append(problems, prefix+p)
+		}
+	}
+
+	vars := templateLayerVars(layers)
+	filled := map[string]string{}
+	for name, v := range vars {
+		if v.Default == nil {
+			filled[name] = ""
+		}
+	}
+	for name, value := range sets {
+		filled[name] = value
+	}
+	values, err := templateVarValues(vars, filled)
+	if err != nil {
+		return append(problems, err.Error())
+	}
+	tpl, err := mergeTemplateLayers(layers, values)
+	if err != nil {
+		return append(problems, err.Error())
+	}
+	return append(problems, checkTemplate(tpl)...)
+}
+
+// unknownTemplateKeys decodes a template strictly and reports the keys
+// templateConfig doesn't have. Type errors are left to the interpolated
+// decode, since `pr: ${pr}` is only a number once vars are filled in.
+func unknownTemplateKeys(data []byte) []string {
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	err := dec.Decode(&templateConfig{})
+	var typeErr *yaml.TypeError
+	if err == nil || errors.Is(err, io.EOF) || !errors.As(err, &typeErr) {
+		return nil
+	}
+	var out []string
+	for _, msg := range typeErr.Errors {
+		if m := unknownFieldRe.FindStringSubmatch(msg); m != nil {
+			out = append(out, strings.Replace(msg, m[0], fmt.Sprintf("unknown key %q", m[1]), 1))
+		}
+	}
+	return out
+}
+
+// checkTemplate reports entries that dv new would fail on or ignore.
+func checkTemplate(tpl *templateConfig) []string {
+	var problems []string
+	add := func(format string, args ...any) {
+		problems = append(problems, fmt.Sprintf(format, args...))
+	}
+	if tpl.Discourse.Branch != "" && tpl.Discourse.PR != 0 {
+		add("discourse.branch: ignored because discourse.pr is set")
+	}
+	for i, r := range tpl.Copy {
+		if strings.TrimSpace(r.Host) == "" {
+			add("copy[%d].host: must not be empty", i)
+		}
+		switch c := strings.TrimSpace(r.Container); {
+		case c == "":
+			add("copy[%d].container: must not be empty", i)
+		case !path.IsAbs(c):
+			add("copy[%d].container: container path %q must be absolute", i, r.Container)
+		}
+	}
+	for i, p := range tpl.Plugins {
+		if strings.TrimSpace(p.Repo) == "" {
+			add("plugins[%d].repo: must not be empty", i)
+		}
+	}
+	for i, t := range tpl.Themes {
+		if strings.TrimSpace(t.Repo) == "" {
+			add("themes[%d].repo: must not be empty", i)
+		}
+	}
+	for i, m := range tpl.MCP {
+		switch {
+		case strings.TrimSpace(m.Name) == "":
+			add("mcp[%d].name: must not be empty", i)
+		case m.Command == "" && !containsString(stockMCPNames, m.Name):
+			add("mcp[%d]: unknown stock MCP %q (want %s, or set command)", i, m.Name, strings.Join(stockMCPNames, ", "))
+		}
+	}
+	return problems
+}
+
+// stockMCPNames are the MCP servers a template can add by name alone.
+var stockMCPNames = []string{"playwright", "discourse", "chrome-devtools"}
+
+func init() {
+	templateValidateCmd.Flags().StringArray("set", nil, "Set a template variable (NAME=VALUE)")
+	templateCmd.AddCommand(templateValidateCmd)
+}
@@ -0,0 +1,157 @@
+package cli
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// templateVar is an entry in a template's vars block. It is written either
+// as its default value (`branch: main`) or as a mapping with default and
+// description. A var without a default is required.
+type templateVar struct {
+	Default     *string `yaml:"default"`
+	Description string  `yaml:"description"`
+}
+
+func (v *templateVar) UnmarshalYAML(n *yaml.Node) error {
+	if n.Kind == yaml.ScalarNode {
+		*v = templateVar{}
+		if n.ShortTag() != "!!null" {
+			value := n.Value
+			v.Default = &value
+		}
+		return nil
+	}
+	if n.Kind == yaml.MappingNode {
+		for i := 0; i < len(n.Content); i += 2 {
+			if k := n.Content[i].Value; k != "default" && k != "description" {
+				return fmt.Errorf("line %d: unknown key %q in var (want default or description)", n.Content[i].Line, k)
+			}
+		}
+	}
+	type plain templateVar
+	var p plain
+	if err := n.Decode(&p); err != nil {
+		return err
+	}
+	*v = templateVar(p)
+	return nil
+}
+
+// parseTemplateSets turns --set NAME=VALUE flags into a map.
+func parseTemplateSets(sets []string) (map[string]string, error) {
+	out := map[string]string{}
+	for _, s := range sets {
+		name, value, ok := strings.Cut(s, "=")
+		if !ok || strings.TrimSpace(name) == "" {
+			return nil, fmt.Errorf("invalid --set %q: expected NAME=VALUE", s)
+		}
+		out[strings.TrimSpace(name)] = value
+	}
+	return out, nil
+}
+
+// templateVarValues resolves every declared var from sets or its default.
+// Sets for undeclared vars and required vars without a value are errors.
+func templateVarValues(vars map[string]templateVar, sets map[string]string) (map[string]string, error) {
+	values := map[string]string{}
+	for name, v := range vars {
+		if v.Default != nil {
+			values[name] = *v.Default
+		}
+	}
+	var unknown []string
+	for name, value := range sets {
+		if _, ok := vars[name]; !ok {
+			unknown = append(unknown, name)
+			continue
+		}
+		values[name] = value
+	}
+	if len(unknown) > 0 {
+		sort.Strings(unknown)
+		declared := sortedVarNames(vars)
+		if len(declared) == 0 {
+			return nil, fmt.Errorf("unknown template variable(s) %s: the template declares no vars", strings.Join(unknown, ", "))
+		}
+		return nil, fmt.Errorf("unknown template variable(s) %s (declared: %s)", strings.Join(unknown, ", "), strings.Join(declared, ", "))
+	}
+	var missing []string
+	for _, name := range sortedVarNames(vars) {
+		if _, ok := values[name]; !ok {
+			desc := name
+			if d := vars[name].Description; d != "" {
+				desc += " (" + d + ")"
+			}
+			missing = append(missing, desc)
+		}
+	}
+	if len(missing) > 0 {
+		return nil, fmt.Errorf("required template variable(s) not set: %s; use --set NAME=VALUE", strings.Join(missing, ", "))
+	}
+	return values, nil
+}
+
+func sortedVarNames(vars map[string]templateVar) []string {
+	names := make([]string, 0, len(vars))
+	for name := range vars {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// interpolateTemplate expands {{ .name }} and ${name} in every scalar of a
+// parsed template except its extends and vars entries. References to names
+// that aren't declared vars are left alone, so shell variables and Go or
+// Handlebars snippets in on_create still work. Changed scalars are re-read
+// as plain YAML, so `pr: ${pr}` is a number.
+func interpolateTemplate(doc *yaml.Node, values map[string]string) {
+	if doc.Kind != yaml.DocumentNode || len(doc.Content) == 0 || doc.Content[0].Kind != yaml.MappingNode {
+		return
+	}
+	root := doc.Content[0]
+	for i := 0; i+1 < len(root.Content); i += 2 {
+		switch root.Content[i].Value {
+		case "extends", "vars":
+			continue
+		}
+		interpolateNode(root.Content[i+1], values)
+	}
+}
+
+func interpolateNode(n *yaml.Node, values map[string]string) {
+	switch n.Kind {
+	case yaml.ScalarNode:
+		if expanded := expandTemplateValue(n.Value, values); expanded != n.Value {
+			n.Value = expanded
+			n.Tag = ""
+			n.Style &^= yaml.SingleQuotedStyle | yaml.DoubleQuotedStyle
+		}
+	case yaml.MappingNode, yaml.SequenceNode:
+		for _, c := range n.Content {
+			interpolateNode(c, values)
+		}
+	}
+}
+
+var (
+	templateVarRef   = regexp.MustCompile(`\$\{([A-Za-z_][A-Za-z0-9_]*)\}`)
+	templateFieldRef = regexp.MustCompile(`\{\{-?\s*\.([A-Za-z_][A-Za-z0-9_]*)\s*-?\}\}`)
+)
+
+func expandTemplateValue(s string, values map[string]string) string {
+	for _, re := range []*regexp.Regexp{templateFieldRef, templateVarRef} {
+		s = re.ReplaceAllStringFunc(s, func(ref string) string {
+			if v, ok := values[re.FindStringSubmatch(ref)[1]]; ok {
+				return v
+			}
+			return ref
+		})
+	}
+	return s
+}
@@ -21,6 +21,7 @@ import (
 	"golang.org/x/term"
 
 	"dv/internal/config"
+	"dv/internal/docker"
 	"dv/internal/xdg"
 )
 
@@ -150,7 +151,7 @@ func (m *model) reload(cmd *cobra.Command) {
 		return
 	}
 	m.configDir = configDir
-	cfg, err := config.LoadOrCreate(configDir)
+	cfg, err := loadConfig(configDir)
 	if err != nil {
 		m.status = err.Error()
 		return
@@ -162,24 +163,14 @@ func (m *model) reload(cmd *cobra.Command) {
 }
 
 func (m *model) fetchAgentItems(cfg config.Config) []list.Item {
-	out, _ := runShell("docker ps -a --format '{{.Names}}\t{{.Image}}\t{{.Status}}\t{{.Ports}}'")
+	containers, _ := docker.ListContainers()
 	var items []list.Item
-	for _, line := range strings.Split(strings.TrimSpace(out), "\n") {
-		if strings.TrimSpace(line) == "" {
-			continue
-		}
-		parts := strings.SplitN(line, "\t", 4)
-		if len(parts) < 3 {
-			continue
-		}
-		name, image, status := parts[0], parts[1], parts[2]
+	for _, ctr := range containers {
+		name, image, status := ctr.Name, ctr.Image, ctr.Status
 		if cfg.Images[cfg.SelectedImage].Tag != image {
 			continue
 		}
-		var ports []string
-		if len(parts) == 4 {
-			ports = parseHostPortURLs(parts[3])
-		}
+		ports := parseHostPortURLs(ctr.Ports)
 		items = append(items, agentItem{name: name, image: image, status: status, ports: ports})
 	}
 	// Sort by name for stability
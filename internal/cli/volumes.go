@@ -0,0 +1,252 @@
+package cli
+
+import (
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+
+	"dv/internal/config"
+	"dv/internal/docker"
+	"dv/internal/xdg"
+)
+
+const (
+	// volumeLabelPrefix records mounted cache volumes on a container:
+	// com.dv.volume.NAME=PATH.
+	volumeLabelPrefix = "com.dv.volume."
+	// volumeNameLabel marks a Docker volume as managed by dv.
+	volumeNameLabel = "com.dv.volume-name"
+	// dockerVolumePrefix namespaces dv volumes in the runtime.
+	dockerVolumePrefix = "dv-"
+	// containerHome is the home directory of the discourse user.
+	containerHome = "/home/discourse"
+)
+
+// dockerVolumeName maps a configured volume name to its runtime name.
+func dockerVolumeName(name string) string { return dockerVolumePrefix + name }
+
+// attachVolumes creates the image's shared volumes and adds their mounts,
+// labels and env to opts. Env already present in opts is left alone.
+func attachVolumes(opts *docker.RunOptions, vols []config.VolumeMount) error {
+	for _, v := range vols {
+		volName := dockerVolumeName(v.Name)
+		if err := docker.CreateVolume(volName, map[string]string{
+			"com.dv.owner":  "dv",
+			volumeNameLabel: v.Name,
+		}); err != nil {
+			return fmt.Errorf("create volume %s: %w", volName, err)
+		}
+		opts.Volumes = append(opts.Volumes, docker.VolumeMount{Name: volName, Path: v.Path})
+		opts.Labels[volumeLabelPrefix+v.Name] = v.Path
+		if opts.Envs == nil {
+			opts.Envs = map[string]string{}
+		}
+		for k, val := range v.Env {
+			if _, ok := opts.Envs[k]; !ok {
+				opts.Envs[k] = val
+			}
+		}
+	}
+	return nil
+}
+
+// formatVolumeMounts renders mounts as NAME=PATH pairs, or "none".
+func formatVolumeMounts(vols []config.VolumeMount) string {
+	if len(vols) == 0 {
+		return "none"
+	}
+	parts := make([]string, 0, len(vols))
+	for _, v := range vols {
+		parts = append(parts, v.Name+"="+v.Path)
+	}
+	return strings.Join(parts, " ")
+}
+
+// volumeMountsFromLabels rebuilds the mounts recorded on a container, sorted by name.
+func volumeMountsFromLabels(labels map[string]string) []docker.VolumeMount {
+	var mounts []docker.VolumeMount
+	for key, p := range labels {
+		name, ok := strings.CutPrefix(key, volumeLabelPrefix)
+		if !ok || name == "" || p == "" {
+			continue
+		}
+		mounts = append(mounts, docker.VolumeMount{Name: dockerVolumeName(name), Path: p})
+	}
+	sort.Slice(mounts, func(i, j int) bool { return mounts[i].Name < mounts[j].Name })
+	return mounts
+}
+
+// fixVolumeOwnership hands mount points under the discourse home, and any
+// parents the runtime created for them, to the discourse user. Fresh volumes
+// over paths missing from the image are otherwise owned by root.
+func fixVolumeOwnership(name string, mounts []docker.VolumeMount) {
+	var dirs []string
+	seen := map[string]bool{}
+	for _, m := range mounts {
+		for p := path.Clean(m.Path); strings.HasPrefix(p, containerHome+"/"); p = path.Dir(p) {
+			if !seen[p] {
+				seen[p] = true
+				dirs = append(dirs, p)
+			}
+		}
+	}
+	if len(dirs) == 0 {
+		return
+	}
+	argv := append([]string{"chown", "discourse:discourse"}, dirs...)
+	_, _ = docker.ExecAsRoot(name, "/", nil, argv)
+}
+
+var volumesCmd = &cobra.Command{
+	Use:   "volumes",
+	Short: "Manage shared cache volumes",
+}
+
+var volumesListCmd = &cobra.Command{
+	Use:     "list",
+	Aliases: []string{"ls"},
+	Short:   "List dv cache volumes and the agents using them",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		configDir, err := xdg.ConfigDir()
+		if err != nil {
+			return err
+		}
+		cfg, err := loadConfig(configDir)
+		if err != nil {
+			return err
+		}
+		vols, err := docker.ListVolumes()
+		if err != nil {
+			return err
+		}
+		users, err := volumeUsers()
+		if err != nil {
+			return err
+		}
+
+		// Include configured volumes that haven't been created yet.
+		rows := map[string]docker.VolumeSummary{}
+		for _, v := range vols {
+			if name := v.Labels[volumeNameLabel]; v.Labels["com.dv.owner"] == "dv" && name != "" {
+				rows[name] = v
+			}
+		}
+		paths := map[string][]string{}
+		for _, imgName := range sortedImageNames(cfg) {
+			for _, v := range cfg.Images[imgName].Volumes {
+				paths[v.Name] = append(paths[v.Name], fmt.Sprintf("%s:%s", imgName, v.Path))
+				if _, ok := rows[v.Name]; !ok {
+					rows[v.Name] = docker.VolumeSummary{}
+				}
+			}
+		}
+		if len(rows) == 0 {
+			fmt.Fprintln(cmd.OutOrStdout(), "(no volumes)")
+			return nil
+		}
+		names := make([]string, 0, len(rows))
+		for name := range rows {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		w := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 4, 2, ' ', 0)
+		fmt.Fprintln(w, "NAME\tMOUNTS\tUSED BY\tCREATED")
+		for _, name := range names {
+			created := "(not created)"
+			if v := rows[name]; v.Name != "" {
+				created = "-"
+				if !v.CreatedAt.IsZero() {
+					created = v.CreatedAt.Local().Format("2006-01-02 15:04")
+				}
+			}
+			mounts := strings.Join(paths[name], ",")
+			if mounts == "" {
+				mounts = "(unconfigured)"
+			}
+			usedBy := strings.Join(users[name], ",")
+			if usedBy == "" {
+				usedBy = "-"
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", name, mounts, usedBy, created)
+		}
+		return w.Flush()
+	},
+}
+
+var volumesPruneCmd = &cobra.Command{
+	Use:   "prune",
+	Short: "Remove dv cache volumes no container uses",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		dryRun, _ := cmd.Flags().GetBool("dry-run")
+		vols, err := docker.ListVolumes()
+		if err != nil {
+			return err
+		}
+		users, err := volumeUsers()
+		if err != nil {
+			return err
+		}
+		removed := 0
+		for _, v := range vols {
+			name := v.Labels[volumeNameLabel]
+			if v.Labels["com.dv.owner"] != "dv" || name == "" || len(users[name]) > 0 {
+				continue
+			}
+			if dryRun {
+				fmt.Fprintf(cmd.OutOrStdout(), "Would remove volume %s\n", name)
+				removed++
+				continue
+			}
+			if err := docker.RemoveVolume(v.Name); err != nil {
+				fmt.Fprintf(cmd.ErrOrStderr(), "Failed to remove volume %s: %v\n", name, err)
+				continue
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "Removed volume %s\n", name)
+			removed++
+		}
+		if removed == 0 {
+			fmt.Fprintln(cmd.OutOrStdout(), "No unused volumes.")
+		}
+		return nil
+	},
+}
+
+// volumeUsers maps each dv volume name to the containers that mount it.
+func volumeUsers() (map[string][]string, error) {
+	containers, err := docker.ListContainers()
+	if err != nil {
+		return nil, err
+	}
+	users := map[string][]string{}
+	for _, ctr := range containers {
+		for key := range ctr.Labels {
+			if name, ok := strings.CutPrefix(key, volumeLabelPrefix); ok && name != "" {
+				users[name] = append(users[name], ctr.Name)
+			}
+		}
+	}
+	for name := range users {
+		sort.Strings(users[name])
+	}
+	return users, nil
+}
+
+func sortedImageNames(cfg config.Config) []string {
+	names := make([]string, 0, len(cfg.Images))
+	for name := range cfg.Images {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func init() {
+	volumesCmd.AddCommand(volumesListCmd)
+	volumesCmd.AddCommand(volumesPruneCmd)
+	volumesPruneCmd.Flags().Bool("dry-run", false, "Show what would be removed")
+}
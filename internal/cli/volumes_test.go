@@ -0,0 +1,67 @@
+package cli
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestNewMountsSharedVolumes(t *testing.T) {
+	env := newTestEnv(t)
+	env.rt.AddImage("ai_agent", nil)
+
+	env.mustRun("new", "one")
+	env.mustRun("new", "two")
+
+	for _, name := range []string{"one", "two"} {
+		ctr, _ := env.rt.Container(name)
+		vols := ctr.RunOptions.Volumes
+		if len(vols) != 3 || vols[0].Name != "dv-pnpm-store" || vols[1].Name != "dv-bundler-cache" || vols[2].Name != "dv-cache" {
+			t.Fatalf("%s volumes = %+v", name, vols)
+		}
+		if got := ctr.Labels["com.dv.volume.cache"]; got != "/home/discourse/.cache" {
+			t.Errorf("%s cache label = %q", name, got)
+		}
+		if ctr.Env["BUNDLE_USER_CACHE"] != "/home/discourse/.bundle/cache" {
+			t.Errorf("%s env = %+v", name, ctr.Env)
+		}
+	}
+
+	var chowned bool
+	for _, e := range env.rt.Execs() {
+		if e.Container == "one" && e.User == "root" && strings.HasPrefix(e.Command(), "chown discourse:discourse ") {
+			chowned = strings.Contains(e.Command(), "/home/discourse/.local/share/pnpm")
+		}
+	}
+	if !chowned {
+		t.Errorf("mount points not handed to discourse: %+v", env.rt.Execs())
+	}
+
+	out := env.mustRun("volumes", "list")
+	if !strings.Contains(out, "pnpm-store") || !strings.Contains(out, "one,two") {
+		t.Errorf("volumes list output:\n%s", out)
+	}
+}
+
+func TestVolumesPruneKeepsVolumesInUse(t *testing.T) {
+	env := newTestEnv(t)
+	env.rt.AddImage("ai_agent", nil)
+	env.mustRun("new", "keeper")
+	env.mustRun("image", "set", "discourse", "--volume", "gems=/usr/local/bundle")
+	env.mustRun("new", "gone")
+	env.mustRun("remove", "gone")
+
+	out := env.mustRun("volumes", "prune", "--dry-run")
+	if strings.TrimSpace(out) != "Would remove volume gems" {
+		t.Fatalf("dry run output:\n%s", out)
+	}
+	env.mustRun("volumes", "prune")
+	vols, _ := env.rt.ListVolumes()
+	for _, v := range vols {
+		if v.Name == "dv-gems" {
+			t.Fatal("unused volume survived prune")
+		}
+	}
+	if len(vols) != 3 {
+		t.Errorf("volumes after prune = %+v", vols)
+	}
+}
@@ -0,0 +1,242 @@
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"time"
+
+	"github.com/spf13/cobra"
+	"golang.org/x/sys/unix"
+
+	"dv/internal/config"
+	"dv/internal/localproxy"
+	"dv/internal/xdg"
+)
+
+const (
+	watcherStateFilename = "watcher-state.json"
+	watcherLockFilename  = "watcher.lock"
+	watcherInterval      = time.Minute
+	skipWatcherEnvVar    = "DV_SKIP_WATCHER"
+	watcherCommandArg    = "__watch"
+)
+
+// watcherCmd is started in the background by any dv command while an image
+// has an idle policy or the local proxy is enabled. Once a minute it stops
+// idle agents; meanwhile it keeps the proxy's routes in sync with Docker
+// events. It exits once neither feature is configured.
+//
+// One watcher serves every shell and project, so it reads only the global
+// config.json: a profile or .dv.yaml belongs to whichever command happened
+// to start it. Idle policies and localProxy set there are not enforced.
+var watcherCmd = &cobra.Command{
+	Use:    watcherCommandArg,
+	Short:  "internal background watcher",
+	Hidden: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		configDir, err := xdg.ConfigDir()
+		if err != nil {
+			return err
+		}
+		// Two dv commands can both find no live watcher and start one; the
+		// lock makes all but the first exit here.
+		lock, ok, err := lockWatcher(configDir)
+		if err != nil || !ok {
+			return err
+		}
+		defer lock.Close()
+		ctx := cmd.Context()
+		var stopSync context.CancelFunc
+		var syncing config.LocalProxyConfig
+		defer func() {
+			if stopSync != nil {
+				stopSync()
+			}
+		}()
+		for {
+			// Not loadConfig: see above.
+			cfg, err := config.LoadOrCreate(configDir)
+			if err != nil {
+				return err
+			}
+			state, err := loadWatcherState(configDir)
+			if err != nil {
+				state = &watcherState{}
+			}
+			if config.AnyIdlePolicy(cfg) {
+				checkIdleAgents(cfg, state, time.Now())
+			}
+
+			lp := cfg.LocalProxy
+			lp.ApplyDefaults()
+			if stopSync != nil && (!lp.Enabled || lp != syncing) {
+				stopSync()
+				stopSync = nil
+			}
+			if lp.Enabled && stopSync == nil {
+				stopSync, syncing = startRouteSync(ctx, lp), lp
+			}
+
+			needed := watcherNeeded(cfg)
+			state.PID, state.Heartbeat = os.Getpid(), time.Now().UTC()
+			if !needed {
+				state.PID, state.Heartbeat = 0, time.Time{}
+			}
+			if err := saveWatcherState(configDir, state); err != nil {
+				return err
+			}
+			if !needed {
+				return nil
+			}
+			select {
+			case <-ctx.Done():
+				return nil
+			case <-time.After(watcherInterval):
+			}
+		}
+	},
+}
+
+// lockWatcher takes the exclusive lock held by the running watcher. It
+// reports false when another process holds it. Closing the file, or exiting,
+// releases the lock.
+func lockWatcher(configDir string) (*os.File, bool, error) {
+	if err := os.MkdirAll(configDir, 0o755); err != nil {
+		return nil, false, err
+	}
+	f, err := os.OpenFile(filepath.Join(configDir, watcherLockFilename), os.O_CREATE|os.O_RDWR, 0o644)
+	if err != nil {
+		return nil, false, err
+	}
+	if err := unix.Flock(int(f.Fd()), unix.LOCK_EX|unix.LOCK_NB); err != nil {
+		f.Close()
+		if errors.Is(err, unix.EWOULDBLOCK) {
+			return nil, false, nil
+		}
+		return nil, false, err
+	}
+	return f, true, nil
+}
+
+func startRouteSync(ctx context.Context, lp config.LocalProxyConfig) context.CancelFunc {
+	ctx, cancel := context.WithCancel(ctx)
+	go syncLocalProxyRoutes(ctx, lp)
+	return cancel
+}
+
+// syncLocalProxyRoutes follows Docker events for the proxy, reconnecting
+// when the event stream drops (for example while the engine restarts).
+func syncLocalProxyRoutes(ctx context.Context, lp config.LocalProxyConfig) {
+	discard := func(string, ...any) {}
+	for {
+		_ = localproxy.Watch(ctx, lp, discard)
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(10 * time.Second):
+		}
+	}
+}
+
+func watcherNeeded(cfg config.Config) bool {
+	return config.AnyIdlePolicy(cfg) || cfg.LocalProxy.Enabled
+}
+
+type watcherState struct {
+	PID       int       `json:"pid,omitempty"`
+	Heartbeat time.Time `json:"heartbeat,omitempty"`
+	// Agents holds idle policy bookkeeping by agent name.
+	Agents map[string]*idleAgentState `json:"agents,omitempty"`
+}
+
+// alive reports whether a watcher checked in recently enough that another
+// one should not be started.
+func (s *watcherState) alive(now time.Time) bool {
+	return s.PID != 0 && now.Sub(s.Heartbeat) < 3*watcherInterval
+}
+
+// autoStopped returns when name was stopped for being idle, if it was.
+func (s *watcherState) autoStopped(name string) (time.Time, bool) {
+	if a := s.Agents[name]; a != nil && !a.AutoStoppedAt.IsZero() {
+		return a.AutoStoppedAt, true
+	}
+	return time.Time{}, false
+}
+
+// maybeStartWatcher launches the background watcher when it has work to do
+// and no watcher has checked in recently.
+func maybeStartWatcher() {
+	if os.Getenv(skipWatcherEnvVar) != "" {
+		return
+	}
+	configDir, err := xdg.ConfigDir()
+	if err != nil {
+		return
+	}
+	cfg, err := config.Read(configDir)
+	if err != nil || !watcherNeeded(cfg) {
+		return
+	}
+	state, err := loadWatcherState(configDir)
+	if err != nil || state.alive(time.Now()) {
+		return
+	}
+	exe, err := os.Executable()
+	if err != nil {
+		return
+	}
+	cmd := exec.Command(exe, watcherCommandArg)
+	cmd.Env = append(os.Environ(), skipWatcherEnvVar+"=1", skipUpdateEnvVar+"=1")
+	cmd.Stdout = io.Discard
+	cmd.Stderr = io.Discard
+	if err := cmd.Start(); err != nil {
+		fmt.Fprintf(os.Stderr, "dv: failed to start background watcher: %v\n", err)
+		return
+	}
+	_ = cmd.Process.Release()
+}
+
+func loadWatcherState(configDir string) (*watcherState, error) {
+	data, err := os.ReadFile(filepath.Join(configDir, watcherStateFilename))
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return &watcherState{}, nil
+		}
+		return nil, err
+	}
+	var state watcherState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return nil, err
+	}
+	return &state, nil
+}
+
+func saveWatcherState(configDir string, state *watcherState) error {
+	if err := os.MkdirAll(configDir, 0o755); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(configDir, "watcher-state-*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), filepath.Join(configDir, watcherStateFilename))
+}
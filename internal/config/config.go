@@ -13,12 +13,18 @@ import (
 )
 
 type Config struct {
+	// SchemaVersion records which migrations have been applied; see migrate.go.
+	SchemaVersion    int               `json:"schemaVersion"`
 	ImageTag         string            `json:"imageTag"`
 	DefaultContainer string            `json:"defaultContainerName"`
 	Workdir          string            `json:"workdir"`
 	CustomWorkdir    string            `json:"customWorkdir,omitempty"`
 	CustomWorkdirs   map[string]string `json:"customWorkdirs,omitempty"`
-	LocalProxy       LocalProxyConfig  `json:"localProxy,omitempty"`
+	// AgentLimits holds per-agent resource limit overrides, keyed by container name.
+	AgentLimits map[string]ResourceLimits `json:"agentLimits,omitempty"`
+	LocalProxy  LocalProxyConfig          `json:"localProxy,omitempty"`
+	// ContainerRuntime selects the container engine: "docker" (default) or "podman".
+	ContainerRuntime string `json:"containerRuntime,omitempty"`
 	// HostStartingPort is the first port to try on the host.
 	HostStartingPort    int      `json:"hostStartingPort"`
 	ContainerPort       int      `json:"containerPort"`
@@ -43,6 +49,17 @@ type Config struct {
 	// CopyRules is the preferred representation of copy mappings with optional
 	// agent scoping.
 	CopyRules []CopyRule `json:"copyRules,omitempty"`
+
+	// Templates maps names usable as `dv new --template NAME` to template
+	// paths or URLs.
+	Templates map[string]string `json:"templates,omitempty"`
+	// ExtractDir is where extracted repos are written, instead of the XDG
+	// data dir.
+	ExtractDir string `json:"extractDir,omitempty"`
+
+	// overlays and origins track values merged in by Apply.
+	overlays []appliedOverlay
+	origins  map[string]string
 }
 
 // CopyFallback specifies an alternative source when the primary host path doesn't exist.
@@ -53,13 +70,13 @@ type CopyFallback struct {
 
 // CopyRule represents a host->container copy mapping with optional agent scoping.
 type CopyRule struct {
-	Host          string        `json:"host"`
-	Container     string        `json:"container"`
-	Agents        []string      `json:"agents,omitempty"`
-	CopyKeys      []string      `json:"copyKeys,omitempty"`
-	MergeKey      string        `json:"mergeKey,omitempty"`
-	Fallback      *CopyFallback `json:"fallback,omitempty"`
-	SkipIfPresent bool          `json:"skipIfPresent,omitempty"` // skip copy if destination exists in container
+	Host          string        `json:"host" yaml:"host"`
+	Container     string        `json:"container" yaml:"container"`
+	Agents        []string      `json:"agents,omitempty" yaml:"agents,omitempty"`
+	CopyKeys      []string      `json:"copyKeys,omitempty" yaml:"copykeys,omitempty"`
+	MergeKey      string        `json:"mergeKey,omitempty" yaml:"mergekey,omitempty"`
+	Fallback      *CopyFallback `json:"fallback,omitempty" yaml:"fallback,omitempty"`
+	SkipIfPresent bool          `json:"skipIfPresent,omitempty" yaml:"skipifpresent,omitempty"` // skip copy if destination exists in container
 }
 
 // ImageSource describes how to obtain the Dockerfile for an image.
@@ -80,6 +97,17 @@ type ImageConfig struct {
 	Workdir       string      `json:"workdir"`
 	ContainerPort int         `json:"containerPort"`
 	Dockerfile    ImageSource `json:"dockerfile"`
+	// Ports lists extra named ports published alongside ContainerPort.
+	Ports []PortSpec `json:"ports,omitempty"`
+	// Limits applies to every agent created from this image.
+	Limits ResourceLimits `json:"limits,omitempty"`
+	// Volumes are named volumes shared by every agent of this image.
+	Volumes []VolumeMount `json:"volumes,omitempty"`
+	// Logs maps service names to log files for dv logs, on top of the
+	// defaults for Kind. An empty path hides a default.
+	Logs map[string]string `json:"logs,omitempty"`
+	// IdleStop stops agents of this image after a period of inactivity.
+	IdleStop IdlePolicy `json:"idleStop,omitempty"`
 }
 
 type LocalProxyConfig struct {
@@ -96,6 +124,7 @@ type LocalProxyConfig struct {
 
 func Default() Config {
 	return Config{
+		SchemaVersion:    CurrentSchemaVersion,
 		ImageTag:         "ai_agent",
 		DefaultContainer: "ai_agent",
 		Workdir:          "/var/www/discourse",
@@ -121,6 +150,7 @@ func Default() Config {
 				Workdir:       "/var/www/discourse",
 				ContainerPort: 4200,
 				Dockerfile:    ImageSource{Source: "stock", StockName: "discourse"},
+				Volumes:       DefaultVolumes(),
 			},
 		},
 		ContainerImages: map[string]string{},
@@ -130,15 +160,14 @@ func Default() Config {
 
 func Path(dir string) string { return filepath.Join(dir, "config.json") }
 
+// LoadOrCreate loads the config, writing the defaults on first use. Older
+// files are upgraded on disk after a backup of the original is written.
+// Configs with validation errors are rejected.
 func LoadOrCreate(configDir string) (Config, error) {
-	p := Path(configDir)
-	data, err := os.ReadFile(p)
+	plan, cfg, err := PlanMigration(configDir)
 	if err != nil {
 		if errors.Is(err, fs.ErrNotExist) {
 			cfg := Default()
-			if err := os.MkdirAll(configDir, 0o755); err != nil {
-				return Config{}, err
-			}
 			if err := Save(configDir, cfg); err != nil {
 				return Config{}, err
 			}
@@ -146,59 +175,94 @@ func LoadOrCreate(configDir string) (Config, error) {
 		}
 		return Config{}, err
 	}
-	var cfg Config
-	if err := json.Unmarshal(data, &cfg); err != nil {
-		return Config{}, fmt.Errorf("invalid config: %w", err)
+	if err := Validate(cfg); err != nil {
+		return Config{}, invalidConfigError(configDir, err)
 	}
-	// Migration to new image model if needed
-	// Ensure Images map is initialized and contains at least discourse
-	if cfg.Images == nil || len(cfg.Images) == 0 {
-		cfg.Images = map[string]ImageConfig{}
-		// Seed from legacy fields
-		discourse := ImageConfig{
-			Kind:          "discourse",
-			Tag:           defaultIfEmpty(cfg.ImageTag, "ai_agent"),
-			Workdir:       defaultIfEmpty(cfg.Workdir, "/var/www/discourse"),
-			ContainerPort: valueOrDefault(cfg.ContainerPort, 4200),
-			Dockerfile:    ImageSource{Source: "stock", StockName: "discourse"},
-		}
-		cfg.Images["discourse"] = discourse
+	if _, err := ApplyMigration(configDir, plan); err != nil {
+		return Config{}, err
+	}
+	return cfg, nil
+}
+
+// Read loads the config and upgrades it in memory only, for callers that
+// must never write the file.
+func Read(configDir string) (Config, error) {
+	_, cfg, err := PlanMigration(configDir)
+	if err != nil {
+		return Config{}, err
+	}
+	if err := Validate(cfg); err != nil {
+		return Config{}, invalidConfigError(configDir, err)
 	}
+	return cfg, nil
+}
+
+func invalidConfigError(configDir string, err error) error {
+	return fmt.Errorf("invalid config %s:\n%w\nrun 'dv config edit' to fix it", Path(configDir), err)
+}
+
+// normalize fills in defaults that don't change the meaning of a config, so
+// they never need a schema bump.
+func (cfg *Config) normalize() {
 	if cfg.SelectedImage == "" {
 		cfg.SelectedImage = "discourse"
 	}
+	if cfg.Images == nil {
+		cfg.Images = map[string]ImageConfig{}
+	}
 	if cfg.ContainerImages == nil {
 		cfg.ContainerImages = map[string]string{}
 	}
 	if cfg.CustomWorkdirs == nil {
 		cfg.CustomWorkdirs = map[string]string{}
 	}
-	cfg.migrateCopyFiles()
-	if w := strings.TrimSpace(cfg.CustomWorkdir); w != "" {
-		target := cfg.SelectedAgent
-		if target == "" {
-			target = cfg.DefaultContainer
-		}
-		if target == "" {
-			target = "default"
-		}
-		cfg.CustomWorkdirs[target] = w
-		cfg.CustomWorkdir = ""
-	}
 	cfg.LocalProxy.ApplyDefaults()
-	return cfg, nil
 }
 
 func Save(configDir string, cfg Config) error {
-	cfg.migrateCopyFiles()
-	if err := os.MkdirAll(configDir, 0o755); err != nil {
+	cfg = cfg.withoutOverlays()
+	cfg.SchemaVersion = CurrentSchemaVersion
+	if err := Validate(cfg); err != nil {
+		return fmt.Errorf("not saving invalid config:\n%w", err)
+	}
+	b, err := marshal(cfg)
+	if err != nil {
 		return err
 	}
+	return writeFile(configDir, b)
+}
+
+func marshal(cfg Config) ([]byte, error) {
 	b, err := json.MarshalIndent(cfg, "", "  ")
 	if err != nil {
+		return nil, err
+	}
+	return append(b, '\n'), nil
+}
+
+// writeFile replaces config.json atomically so an interrupted write never
+// leaves a truncated config behind.
+func writeFile(configDir string, data []byte) error {
+	if err := os.MkdirAll(configDir, 0o755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(configDir, "config-*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Chmod(0o644); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
 		return err
 	}
-	return os.WriteFile(Path(configDir), b, 0o644)
+	return os.Rename(tmp.Name(), Path(configDir))
 }
 
 // Helpers for migration/defaulting
@@ -0,0 +1,67 @@
+package config
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// DefaultIdleCPUPercent is the CPU use below which an agent counts as idle
+// when IdlePolicy.CPUPercent is unset. 100 is one full core.
+const DefaultIdleCPUPercent = 5
+
+// IdlePolicy stops an image's agents once nobody has used them for a while:
+// no exec sessions, no local proxy traffic and CPU below the threshold.
+type IdlePolicy struct {
+	// Minutes of continuous idleness before the agent is stopped; 0 disables.
+	Minutes int `json:"minutes,omitempty"`
+	// CPUPercent overrides DefaultIdleCPUPercent.
+	CPUPercent float64 `json:"cpuPercent,omitempty"`
+}
+
+// Enabled reports whether idle agents should be stopped.
+func (p IdlePolicy) Enabled() bool {
+	return p.Minutes > 0
+}
+
+// After is how long an agent must stay idle before it is stopped.
+func (p IdlePolicy) After() time.Duration {
+	return time.Duration(p.Minutes) * time.Minute
+}
+
+// CPUThreshold returns the CPU percentage under which an agent is idle.
+func (p IdlePolicy) CPUThreshold() float64 {
+	if p.CPUPercent > 0 {
+		return p.CPUPercent
+	}
+	return DefaultIdleCPUPercent
+}
+
+// Validate checks the policy fields are in range.
+func (p IdlePolicy) Validate() error {
+	if p.Minutes < 0 {
+		return fmt.Errorf("invalid idle stop %d minutes: expected a positive number, or 0 to disable", p.Minutes)
+	}
+	if p.CPUPercent < 0 {
+		return fmt.Errorf("invalid idle cpu %v%%: expected a positive percentage", p.CPUPercent)
+	}
+	return nil
+}
+
+// String renders the policy, e.g. "after 60m below 5% cpu", or "off".
+func (p IdlePolicy) String() string {
+	if !p.Enabled() {
+		return "off"
+	}
+	return fmt.Sprintf("after %dm below %s%% cpu", p.Minutes, strconv.FormatFloat(p.CPUThreshold(), 'f', -1, 64))
+}
+
+// AnyIdlePolicy reports whether some image has idle stopping enabled.
+func AnyIdlePolicy(cfg Config) bool {
+	for _, img := range cfg.Images {
+		if img.IdleStop.Enabled() {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,39 @@
+package config
+
+import (
+	"testing"
+	"time"
+)
+
+func TestIdlePolicy(t *testing.T) {
+	t.Parallel()
+
+	var off IdlePolicy
+	if off.Enabled() || off.String() != "off" {
+		t.Errorf("zero policy = %v, %q", off.Enabled(), off.String())
+	}
+	p := IdlePolicy{Minutes: 45}
+	if !p.Enabled() || p.After() != 45*time.Minute || p.CPUThreshold() != DefaultIdleCPUPercent {
+		t.Errorf("policy %+v: after %v, threshold %v", p, p.After(), p.CPUThreshold())
+	}
+	p.CPUPercent = 2.5
+	if got := p.String(); got != "after 45m below 2.5% cpu" {
+		t.Errorf("String() = %q", got)
+	}
+	for _, bad := range []IdlePolicy{{Minutes: -1}, {Minutes: 10, CPUPercent: -3}} {
+		if bad.Validate() == nil {
+			t.Errorf("Validate(%+v) = nil, want error", bad)
+		}
+	}
+
+	cfg := Default()
+	if AnyIdlePolicy(cfg) {
+		t.Error("default config has an idle policy")
+	}
+	img := cfg.Images["discourse"]
+	img.IdleStop = p
+	cfg.Images["discourse"] = img
+	if !AnyIdlePolicy(cfg) {
+		t.Error("AnyIdlePolicy missed the discourse policy")
+	}
+}
@@ -0,0 +1,96 @@
+package config
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// ResourceLimits caps what a container may consume. Empty fields leave the
+// runtime default (unlimited) in place.
+type ResourceLimits struct {
+	// CPUs is a fractional CPU count, e.g. "2" or "1.5" (docker run --cpus).
+	CPUs string `json:"cpus,omitempty"`
+	// Memory is a size with unit suffix, e.g. "4g" (docker run --memory).
+	Memory string `json:"memory,omitempty"`
+	// PidsLimit caps the number of processes; -1 means unlimited.
+	PidsLimit int `json:"pidsLimit,omitempty"`
+	// ShmSize sizes /dev/shm, e.g. "1g" (docker run --shm-size).
+	ShmSize string `json:"shmSize,omitempty"`
+}
+
+var sizePattern = regexp.MustCompile(`^[0-9]+(\.[0-9]+)?([bkmgtBKMGT]([iI]?[bB])?)?$`)
+
+// IsZero reports whether no limit is set.
+func (l ResourceLimits) IsZero() bool {
+	return l == ResourceLimits{}
+}
+
+// Merge returns l with every field set in over taking precedence.
+func (l ResourceLimits) Merge(over ResourceLimits) ResourceLimits {
+	if v := strings.TrimSpace(over.CPUs); v != "" {
+		l.CPUs = v
+	}
+	if v := strings.TrimSpace(over.Memory); v != "" {
+		l.Memory = v
+	}
+	if over.PidsLimit != 0 {
+		l.PidsLimit = over.PidsLimit
+	}
+	if v := strings.TrimSpace(over.ShmSize); v != "" {
+		l.ShmSize = v
+	}
+	return l
+}
+
+// Validate checks that each set field is in a form docker and podman accept.
+func (l ResourceLimits) Validate() error {
+	if v := strings.TrimSpace(l.CPUs); v != "" {
+		f, err := strconv.ParseFloat(v, 64)
+		if err != nil || f <= 0 {
+			return fmt.Errorf("invalid cpus %q: expected a positive number such as 2 or 1.5", l.CPUs)
+		}
+	}
+	if v := strings.TrimSpace(l.Memory); v != "" && !sizePattern.MatchString(v) {
+		return fmt.Errorf("invalid memory %q: expected a size such as 512m or 4g", l.Memory)
+	}
+	if v := strings.TrimSpace(l.ShmSize); v != "" && !sizePattern.MatchString(v) {
+		return fmt.Errorf("invalid shm size %q: expected a size such as 256m or 1g", l.ShmSize)
+	}
+	if l.PidsLimit < -1 {
+		return fmt.Errorf("invalid pids limit %d: expected a positive number or -1 for unlimited", l.PidsLimit)
+	}
+	return nil
+}
+
+// String renders the limits as space separated key=value pairs, or "none".
+func (l ResourceLimits) String() string {
+	var parts []string
+	if l.CPUs != "" {
+		parts = append(parts, "cpus="+l.CPUs)
+	}
+	if l.Memory != "" {
+		parts = append(parts, "memory="+l.Memory)
+	}
+	if l.PidsLimit != 0 {
+		parts = append(parts, fmt.Sprintf("pids=%d", l.PidsLimit))
+	}
+	if l.ShmSize != "" {
+		parts = append(parts, "shm-size="+l.ShmSize)
+	}
+	if len(parts) == 0 {
+		return "none"
+	}
+	return strings.Join(parts, " ")
+}
+
+// EffectiveLimits returns the limits for a container: the image limits with
+// any per-agent override from AgentLimits applied on top.
+func EffectiveLimits(cfg Config, img ImageConfig, containerName string) ResourceLimits {
+	limits := img.Limits
+	if containerName != "" && cfg.AgentLimits != nil {
+		limits = limits.Merge(cfg.AgentLimits[containerName])
+	}
+	return limits
+}
@@ -0,0 +1,71 @@
+package config
+
+import "testing"
+
+func TestResourceLimitsMerge(t *testing.T) {
+	t.Parallel()
+
+	base := ResourceLimits{CPUs: "4", Memory: "8g", PidsLimit: 1024}
+	got := base.Merge(ResourceLimits{Memory: "2g", ShmSize: "1g"})
+	want := ResourceLimits{CPUs: "4", Memory: "2g", PidsLimit: 1024, ShmSize: "1g"}
+	if got != want {
+		t.Errorf("Merge() = %+v, want %+v", got, want)
+	}
+	if got := base.Merge(ResourceLimits{}); got != base {
+		t.Errorf("Merge(empty) = %+v, want %+v", got, base)
+	}
+}
+
+func TestResourceLimitsValidate(t *testing.T) {
+	t.Parallel()
+
+	valid := []ResourceLimits{
+		{},
+		{CPUs: "1.5", Memory: "512m", PidsLimit: 256, ShmSize: "1g"},
+		{Memory: "4GB", ShmSize: "268435456", PidsLimit: -1},
+		{Memory: "2GiB"},
+	}
+	for _, l := range valid {
+		if err := l.Validate(); err != nil {
+			t.Errorf("Validate(%+v) = %v, want nil", l, err)
+		}
+	}
+	invalid := []ResourceLimits{
+		{CPUs: "0"},
+		{CPUs: "two"},
+		{Memory: "lots"},
+		{ShmSize: "1 g"},
+		{PidsLimit: -5},
+	}
+	for _, l := range invalid {
+		if err := l.Validate(); err == nil {
+			t.Errorf("Validate(%+v) succeeded, want error", l)
+		}
+	}
+}
+
+func TestEffectiveLimits_AgentOverrideTakesPrecedence(t *testing.T) {
+	t.Parallel()
+
+	cfg := Config{AgentLimits: map[string]ResourceLimits{"agent": {CPUs: "1"}}}
+	img := ImageConfig{Limits: ResourceLimits{CPUs: "4", Memory: "8g"}}
+
+	if got := EffectiveLimits(cfg, img, "agent"); got.CPUs != "1" || got.Memory != "8g" {
+		t.Errorf("EffectiveLimits(agent) = %+v", got)
+	}
+	if got := EffectiveLimits(cfg, img, "other"); got != img.Limits {
+		t.Errorf("EffectiveLimits(other) = %+v, want image limits", got)
+	}
+}
+
+func TestResourceLimitsString(t *testing.T) {
+	t.Parallel()
+
+	if got := (ResourceLimits{}).String(); got != "none" {
+		t.Errorf("String() = %q", got)
+	}
+	l := ResourceLimits{CPUs: "2", Memory: "4g", PidsLimit: 512, ShmSize: "1g"}
+	if got := l.String(); got != "cpus=2 memory=4g pids=512 shm-size=1g" {
+		t.Errorf("String() = %q", got)
+	}
+}
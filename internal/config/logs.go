@@ -0,0 +1,85 @@
+package config
+
+import (
+	"fmt"
+	"path"
+	"strings"
+)
+
+// DefaultLogPaths returns the service log files for images of the given
+// kind. Custom images have no defaults; configure them with ImageConfig.Logs.
+func DefaultLogPaths(kind string) map[string]string {
+	if kind != "discourse" {
+		return nil
+	}
+	return map[string]string{
+		"unicorn":    "/var/www/discourse/log/unicorn.log",
+		"ember-cli":  "/var/www/discourse/log/ember-cli.log",
+		"caddy":      "/var/log/caddy.log",
+		"postgresql": "/var/log/postgres/current",
+		"redis":      "/var/log/redis/current",
+	}
+}
+
+// LogPaths returns the service-to-log-file map for the image: the defaults
+// for its kind with Logs applied on top. An empty path in Logs hides a
+// default service.
+func (img ImageConfig) LogPaths() map[string]string {
+	paths := DefaultLogPaths(img.Kind)
+	if paths == nil {
+		paths = map[string]string{}
+	}
+	for name, p := range img.Logs {
+		if p == "" {
+			delete(paths, name)
+			continue
+		}
+		paths[name] = p
+	}
+	return paths
+}
+
+// ParseLogPath parses SERVICE=PATH.
+func ParseLogPath(s string) (string, string, error) {
+	name, p, ok := strings.Cut(strings.TrimSpace(s), "=")
+	name, p = strings.ToLower(strings.TrimSpace(name)), strings.TrimSpace(p)
+	if !ok {
+		return "", "", fmt.Errorf("invalid log %q: expected SERVICE=PATH", s)
+	}
+	if !portNamePattern.MatchString(name) {
+		return "", "", fmt.Errorf("invalid log service %q: use lowercase letters, digits, '-' or '_'", name)
+	}
+	if !path.IsAbs(p) {
+		return "", "", fmt.Errorf("log %s: path %q must be absolute", name, p)
+	}
+	return name, path.Clean(p), nil
+}
+
+// SetLog points service at the log file p.
+func (img *ImageConfig) SetLog(service, p string) {
+	if img.Logs == nil {
+		img.Logs = map[string]string{}
+	}
+	if DefaultLogPaths(img.Kind)[service] == p {
+		delete(img.Logs, service)
+		return
+	}
+	img.Logs[service] = p
+}
+
+// RemoveLog drops service from the image's logs, hiding it if it is one of
+// the defaults, and reports whether it was present.
+func (img *ImageConfig) RemoveLog(service string) bool {
+	if _, ok := img.LogPaths()[service]; !ok {
+		return false
+	}
+	if _, isDefault := DefaultLogPaths(img.Kind)[service]; isDefault {
+		if img.Logs == nil {
+			img.Logs = map[string]string{}
+		}
+		img.Logs[service] = ""
+	} else {
+		delete(img.Logs, service)
+	}
+	return true
+}
@@ -0,0 +1,50 @@
+package config
+
+import "testing"
+
+func TestImageLogPaths(t *testing.T) {
+	t.Parallel()
+
+	img := ImageConfig{Kind: "discourse"}
+	if got := img.LogPaths()["unicorn"]; got != "/var/www/discourse/log/unicorn.log" {
+		t.Fatalf("default unicorn log = %q", got)
+	}
+
+	img.SetLog("sidekiq", "/var/www/discourse/log/sidekiq.log")
+	img.SetLog("caddy", "/var/log/caddy/access.log")
+	if !img.RemoveLog("redis") || img.RemoveLog("redis") {
+		t.Fatal("RemoveLog should report presence once")
+	}
+	paths := img.LogPaths()
+	if paths["sidekiq"] != "/var/www/discourse/log/sidekiq.log" || paths["caddy"] != "/var/log/caddy/access.log" {
+		t.Fatalf("paths = %+v", paths)
+	}
+	if _, ok := paths["redis"]; ok {
+		t.Fatalf("removed default still present: %+v", paths)
+	}
+
+	// Setting a default back to its stock path drops the override.
+	img.SetLog("caddy", "/var/log/caddy.log")
+	if _, ok := img.Logs["caddy"]; ok {
+		t.Fatalf("override kept: %+v", img.Logs)
+	}
+
+	custom := ImageConfig{Kind: "custom"}
+	if len(custom.LogPaths()) != 0 {
+		t.Fatalf("custom image has default logs: %+v", custom.LogPaths())
+	}
+}
+
+func TestParseLogPath(t *testing.T) {
+	t.Parallel()
+
+	name, p, err := ParseLogPath(" Sidekiq = /var/log/sidekiq.log ")
+	if err != nil || name != "sidekiq" || p != "/var/log/sidekiq.log" {
+		t.Fatalf("ParseLogPath = %q, %q, %v", name, p, err)
+	}
+	for _, bad := range []string{"sidekiq", "sidekiq=log/sidekiq.log", "side kiq=/x"} {
+		if _, _, err := ParseLogPath(bad); err == nil {
+			t.Errorf("ParseLogPath(%q) succeeded", bad)
+		}
+	}
+}
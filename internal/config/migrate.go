@@ -0,0 +1,144 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+// CurrentSchemaVersion is the config schema this build reads and writes.
+// Files without a schemaVersion are version 0.
+const CurrentSchemaVersion = 3
+
+// migration upgrades a config to Version. Each step must be safe to run on a
+// config that already has the newer shape, since older dv builds drop the
+// schemaVersion field when they save.
+type migration struct {
+	Version     int
+	Description string
+	Apply       func(*Config)
+}
+
+// migrations run in order; append new steps with the next version number.
+var migrations = []migration{
+	{1, "seed images from legacy imageTag/workdir/containerPort", migrateLegacyImage},
+	{2, "convert copyFiles to copyRules", (*Config).migrateCopyFiles},
+	{3, "move customWorkdir into customWorkdirs", migrateCustomWorkdir},
+}
+
+// Migrate upgrades cfg to CurrentSchemaVersion in place and returns the
+// descriptions of the steps it ran. Configs written by a newer dv are
+// rejected rather than downgraded.
+func Migrate(cfg *Config) ([]string, error) {
+	if cfg.SchemaVersion > CurrentSchemaVersion {
+		return nil, fmt.Errorf("config schema version %d is newer than this dv supports (%d); upgrade dv", cfg.SchemaVersion, CurrentSchemaVersion)
+	}
+	var applied []string
+	for _, m := range migrations {
+		if m.Version <= cfg.SchemaVersion {
+			continue
+		}
+		m.Apply(cfg)
+		cfg.SchemaVersion = m.Version
+		applied = append(applied, m.Description)
+	}
+	return applied, nil
+}
+
+// MigrationPlan describes how loading the config file would upgrade it.
+type MigrationPlan struct {
+	From  int
+	To    int
+	Steps []string
+	// Old is the file as it is on disk and New is what would replace it.
+	Old []byte
+	New []byte
+}
+
+// Pending reports whether the file needs to be rewritten.
+func (p MigrationPlan) Pending() bool { return p.From < p.To }
+
+// PlanMigration reads the config file in configDir and works out the
+// upgrade without writing anything.
+func PlanMigration(configDir string) (MigrationPlan, Config, error) {
+	data, err := os.ReadFile(Path(configDir))
+	if err != nil {
+		return MigrationPlan{}, Config{}, err
+	}
+	var cfg Config
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return MigrationPlan{}, Config{}, fmt.Errorf("invalid config: %w", err)
+	}
+	plan := MigrationPlan{From: cfg.SchemaVersion, To: cfg.SchemaVersion, Old: data}
+	steps, err := Migrate(&cfg)
+	if err != nil {
+		return MigrationPlan{}, Config{}, err
+	}
+	cfg.normalize()
+	plan.To = cfg.SchemaVersion
+	plan.Steps = steps
+	if plan.Pending() {
+		if plan.New, err = marshal(cfg); err != nil {
+			return MigrationPlan{}, Config{}, err
+		}
+	}
+	return plan, cfg, nil
+}
+
+// ApplyMigration backs up the config file and writes the upgraded one. It
+// returns the backup path.
+func ApplyMigration(configDir string, plan MigrationPlan) (string, error) {
+	if !plan.Pending() {
+		return "", nil
+	}
+	backup := BackupPath(configDir, plan.From, time.Now())
+	if err := os.WriteFile(backup, plan.Old, 0o644); err != nil {
+		return "", fmt.Errorf("backup config before migration: %w", err)
+	}
+	if err := writeFile(configDir, plan.New); err != nil {
+		return backup, err
+	}
+	return backup, nil
+}
+
+// BackupPath is where the pre-migration copy of a version-from config goes.
+func BackupPath(configDir string, from int, now time.Time) string {
+	return fmt.Sprintf("%s.v%d-%s.bak", Path(configDir), from, now.Format("20060102-150405"))
+}
+
+func migrateLegacyImage(cfg *Config) {
+	if len(cfg.Images) > 0 {
+		return
+	}
+	cfg.Images = map[string]ImageConfig{
+		"discourse": {
+			Kind:          "discourse",
+			Tag:           defaultIfEmpty(cfg.ImageTag, "ai_agent"),
+			Workdir:       defaultIfEmpty(cfg.Workdir, "/var/www/discourse"),
+			ContainerPort: valueOrDefault(cfg.ContainerPort, 4200),
+			Dockerfile:    ImageSource{Source: "stock", StockName: "discourse"},
+			Volumes:       DefaultVolumes(),
+		},
+	}
+}
+
+func migrateCustomWorkdir(cfg *Config) {
+	w := strings.TrimSpace(cfg.CustomWorkdir)
+	if w == "" {
+		return
+	}
+	target := cfg.SelectedAgent
+	if target == "" {
+		target = cfg.DefaultContainer
+	}
+	if target == "" {
+		target = "default"
+	}
+	if cfg.CustomWorkdirs == nil {
+		cfg.CustomWorkdirs = map[string]string{}
+	}
+	cfg.CustomWorkdirs[target] = w
+	cfg.CustomWorkdir = ""
+}
@@ -0,0 +1,163 @@
+package config
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestMigrationsAreOrdered(t *testing.T) {
+	t.Parallel()
+
+	for i, m := range migrations {
+		if m.Version != i+1 {
+			t.Fatalf("migration %d (%s) has version %d, want %d", i, m.Description, m.Version, i+1)
+		}
+	}
+	if last := migrations[len(migrations)-1].Version; last != CurrentSchemaVersion {
+		t.Fatalf("CurrentSchemaVersion = %d, last migration is %d", CurrentSchemaVersion, last)
+	}
+}
+
+func TestMigrateFromVersionZero(t *testing.T) {
+	t.Parallel()
+
+	cfg := Config{
+		ImageTag:      "old-tag",
+		SelectedAgent: "agent1",
+		CustomWorkdir: "/src/plugin",
+		CopyFiles:     map[string]string{"~/.vimrc": "/home/discourse/.vimrc"},
+	}
+	steps, err := Migrate(&cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(steps) != CurrentSchemaVersion || cfg.SchemaVersion != CurrentSchemaVersion {
+		t.Fatalf("ran %d steps to version %d", len(steps), cfg.SchemaVersion)
+	}
+	if cfg.Images["discourse"].Tag != "old-tag" {
+		t.Fatalf("images = %+v", cfg.Images)
+	}
+	if cfg.CopyFiles != nil || len(cfg.CopyRules) == 0 || cfg.CopyRules[0].Host != "~/.vimrc" {
+		t.Fatalf("copy rules = %+v, copyFiles = %v", cfg.CopyRules, cfg.CopyFiles)
+	}
+	if cfg.CustomWorkdir != "" || cfg.CustomWorkdirs["agent1"] != "/src/plugin" {
+		t.Fatalf("customWorkdir = %q, customWorkdirs = %v", cfg.CustomWorkdir, cfg.CustomWorkdirs)
+	}
+}
+
+func TestMigrateSkipsAppliedSteps(t *testing.T) {
+	t.Parallel()
+
+	// A version 2 file never runs the copyFiles step again.
+	cfg := Config{
+		SchemaVersion: 2,
+		Images:        map[string]ImageConfig{"x": {}},
+		CopyRules:     []CopyRule{},
+		CustomWorkdir: "/w",
+	}
+	steps, err := Migrate(&cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(steps) != 1 || !strings.Contains(steps[0], "customWorkdir") {
+		t.Fatalf("steps = %v", steps)
+	}
+	if len(cfg.CopyRules) != 0 {
+		t.Fatalf("copy rules changed: %+v", cfg.CopyRules)
+	}
+}
+
+func TestMigrateRejectsNewerSchema(t *testing.T) {
+	t.Parallel()
+
+	cfg := Config{SchemaVersion: CurrentSchemaVersion + 1}
+	if _, err := Migrate(&cfg); err == nil || !strings.Contains(err.Error(), "newer") {
+		t.Fatalf("err = %v", err)
+	}
+}
+
+func TestLoadOrCreate_BacksUpBeforeUpgrading(t *testing.T) {
+	t.Parallel()
+
+	tmpDir := t.TempDir()
+	legacy := []byte(`{"imageTag": "old-tag", "customWorkdir": "/w"}`)
+	if err := os.WriteFile(Path(tmpDir), legacy, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := LoadOrCreate(tmpDir); err != nil {
+		t.Fatal(err)
+	}
+	backups, _ := filepath.Glob(filepath.Join(tmpDir, "config.json.v0-*.bak"))
+	if len(backups) != 1 {
+		t.Fatalf("backups = %v", backups)
+	}
+	if data, _ := os.ReadFile(backups[0]); string(data) != string(legacy) {
+		t.Fatalf("backup = %s", data)
+	}
+	var onDisk Config
+	data, _ := os.ReadFile(Path(tmpDir))
+	if err := json.Unmarshal(data, &onDisk); err != nil {
+		t.Fatal(err)
+	}
+	if onDisk.SchemaVersion != CurrentSchemaVersion || onDisk.CustomWorkdirs["default"] != "/w" {
+		t.Fatalf("upgraded file = %s", data)
+	}
+
+	// Loading again leaves the current file and backups alone.
+	if _, err := LoadOrCreate(tmpDir); err != nil {
+		t.Fatal(err)
+	}
+	again, _ := os.ReadFile(Path(tmpDir))
+	if string(again) != string(data) {
+		t.Fatalf("current config rewritten on load:\n%s", again)
+	}
+	if backups, _ := filepath.Glob(filepath.Join(tmpDir, "*.bak")); len(backups) != 1 {
+		t.Fatalf("backups = %v", backups)
+	}
+}
+
+func TestLoadOrCreate_NewerSchemaIsNotRewritten(t *testing.T) {
+	t.Parallel()
+
+	tmpDir := t.TempDir()
+	newer := []byte(`{"schemaVersion": 99, "futureField": true}`)
+	if err := os.WriteFile(Path(tmpDir), newer, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadOrCreate(tmpDir); err == nil {
+		t.Fatal("expected error for newer schema")
+	}
+	if data, _ := os.ReadFile(Path(tmpDir)); string(data) != string(newer) {
+		t.Fatalf("config rewritten: %s", data)
+	}
+}
+
+func TestPlanMigrationDoesNotWrite(t *testing.T) {
+	t.Parallel()
+
+	tmpDir := t.TempDir()
+	legacy := []byte(`{"imageTag": "old-tag"}`)
+	if err := os.WriteFile(Path(tmpDir), legacy, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	plan, cfg, err := PlanMigration(tmpDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !plan.Pending() || plan.From != 0 || plan.To != CurrentSchemaVersion {
+		t.Fatalf("plan = %+v", plan)
+	}
+	if cfg.Images["discourse"].Tag != "old-tag" || !strings.Contains(string(plan.New), `"schemaVersion": 3`) {
+		t.Fatalf("new = %s", plan.New)
+	}
+	if data, _ := os.ReadFile(Path(tmpDir)); string(data) != string(legacy) {
+		t.Fatalf("config rewritten: %s", data)
+	}
+	if entries, _ := os.ReadDir(tmpDir); len(entries) != 1 {
+		t.Fatalf("unexpected files: %v", entries)
+	}
+}
@@ -0,0 +1,289 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ProjectFile is the per-project overlay dv looks for in the current
+// directory or its git root.
+const ProjectFile = ".dv.yaml"
+
+// Overlay is a partial config merged over the global one. Empty fields
+// leave the value below alone; list entries are appended and templates are
+// added by name.
+type Overlay struct {
+	SelectedImage  string            `yaml:"selectedImage,omitempty"`
+	Agent          string            `yaml:"agent,omitempty"`
+	CopyRules      []CopyRule        `yaml:"copyRules,omitempty"`
+	EnvPassthrough []string          `yaml:"envPassthrough,omitempty"`
+	Templates      map[string]string `yaml:"templates,omitempty"`
+	ExtractDir     string            `yaml:"extractDir,omitempty"`
+	LocalProxy     *ProxyOverlay     `yaml:"localProxy,omitempty"`
+}
+
+// ProxyOverlay overrides individual local proxy settings.
+type ProxyOverlay struct {
+	Enabled   *bool  `yaml:"enabled,omitempty"`
+	HTTPPort  int    `yaml:"httpPort,omitempty"`
+	HTTPS     *bool  `yaml:"https,omitempty"`
+	HTTPSPort int    `yaml:"httpsPort,omitempty"`
+	Public    *bool  `yaml:"public,omitempty"`
+	Hostname  string `yaml:"hostname,omitempty"`
+}
+
+func (p ProxyOverlay) apply(c LocalProxyConfig) LocalProxyConfig {
+	if p.Enabled != nil {
+		c.Enabled = *p.Enabled
+	}
+	if p.HTTPPort != 0 {
+		c.HTTPPort = p.HTTPPort
+	}
+	if p.HTTPS != nil {
+		c.HTTPS = *p.HTTPS
+	}
+	if p.HTTPSPort != 0 {
+		c.HTTPSPort = p.HTTPSPort
+	}
+	if p.Public != nil {
+		c.Public = *p.Public
+	}
+	if p.Hostname != "" {
+		c.Hostname = p.Hostname
+	}
+	c.ApplyDefaults()
+	return c
+}
+
+// appliedOverlay remembers what an overlay changed so Save can leave it out
+// of config.json.
+type appliedOverlay struct {
+	overlay Overlay
+	// base holds the values the overlay's scalars and templates replaced.
+	base      map[string]string
+	copyRules []CopyRule
+	env       []string
+	// proxyBase and proxy are the local proxy settings before and after.
+	proxyBase, proxy LocalProxyConfig
+}
+
+// FindProjectFile returns the .dv.yaml in dir or, failing that, in the root
+// of the git checkout containing dir. It returns "" when there is none.
+func FindProjectFile(dir string) string {
+	if p := filepath.Join(dir, ProjectFile); fileExists(p) {
+		return p
+	}
+	for d := dir; ; {
+		if _, err := os.Stat(filepath.Join(d, ".git")); err == nil {
+			if p := filepath.Join(d, ProjectFile); fileExists(p) {
+				return p
+			}
+			return ""
+		}
+		parent := filepath.Dir(d)
+		if parent == d {
+			return ""
+		}
+		d = parent
+	}
+}
+
+func fileExists(p string) bool {
+	st, err := os.Stat(p)
+	return err == nil && !st.IsDir()
+}
+
+// LoadOverlay reads an overlay file. Unknown keys are errors so typos don't
+// go unnoticed, and relative paths are resolved against the file's directory.
+func LoadOverlay(path string) (Overlay, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Overlay{}, err
+	}
+	var o Overlay
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(&o); err != nil && !errors.Is(err, io.EOF) {
+		return Overlay{}, fmt.Errorf("%s: %w", path, err)
+	}
+	o.resolvePaths(filepath.Dir(path))
+	return o, nil
+}
+
+// SaveOverlay writes an overlay file.
+func SaveOverlay(path string, o Overlay) error {
+	data, err := yaml.Marshal(o)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0o644)
+}
+
+// LoadProjectOverlay finds and reads the project overlay for dir. It
+// returns an empty path when the project has none.
+func LoadProjectOverlay(dir string) (Overlay, string, error) {
+	p := FindProjectFile(dir)
+	if p == "" {
+		return Overlay{}, "", nil
+	}
+	o, err := LoadOverlay(p)
+	if errors.Is(err, fs.ErrNotExist) {
+		return Overlay{}, "", nil
+	}
+	return o, p, err
+}
+
+func (o *Overlay) resolvePaths(dir string) {
+	abs := func(p string) string {
+		if p == "" || filepath.IsAbs(p) || strings.HasPrefix(p, "~") || strings.HasPrefix(p, "$") || strings.Contains(p, "://") {
+			return p
+		}
+		return filepath.Join(dir, p)
+	}
+	for i := range o.CopyRules {
+		o.CopyRules[i].Host = abs(o.CopyRules[i].Host)
+	}
+	for name, p := range o.Templates {
+		o.Templates[name] = abs(p)
+	}
+	o.ExtractDir = abs(o.ExtractDir)
+}
+
+// Apply merges o over cfg, recording source as the origin of every value it
+// sets. Save removes applied overlays again, so they never leak into
+// config.json unless a command changed the value itself.
+func (cfg *Config) Apply(o Overlay, source string) {
+	a := appliedOverlay{overlay: o, base: map[string]string{}}
+	if cfg.origins == nil {
+		cfg.origins = map[string]string{}
+	}
+	setScalar := func(key string, field *string, val string) {
+		if val == "" {
+			return
+		}
+		a.base[key] = *field
+		*field = val
+		cfg.origins[key] = source
+	}
+	setScalar("selectedImage", &cfg.SelectedImage, o.SelectedImage)
+	setScalar("selectedAgent", &cfg.SelectedAgent, o.Agent)
+	setScalar("extractDir", &cfg.ExtractDir, o.ExtractDir)
+
+	if o.LocalProxy != nil {
+		a.proxyBase = cfg.LocalProxy
+		cfg.LocalProxy = o.LocalProxy.apply(cfg.LocalProxy)
+		a.proxy = cfg.LocalProxy
+		cfg.origins["localProxy"] = source
+	}
+
+	// Clone before appending so configs copied from the same base don't
+	// share backing arrays.
+	cfg.CopyRules = slices.Clone(cfg.CopyRules)
+	cfg.EnvPassthrough = slices.Clone(cfg.EnvPassthrough)
+	for _, r := range o.CopyRules {
+		cfg.origins[fmt.Sprintf("copyRules[%d]", len(cfg.CopyRules))] = source
+		cfg.CopyRules = append(cfg.CopyRules, r)
+		a.copyRules = append(a.copyRules, r)
+	}
+	for _, e := range o.EnvPassthrough {
+		if slices.Contains(cfg.EnvPassthrough, e) {
+			continue
+		}
+		cfg.origins[fmt.Sprintf("envPassthrough[%d]", len(cfg.EnvPassthrough))] = source
+		cfg.EnvPassthrough = append(cfg.EnvPassthrough, e)
+		a.env = append(a.env, e)
+	}
+	if len(o.Templates) > 0 {
+		templates := make(map[string]string, len(cfg.Templates)+len(o.Templates))
+		for k, v := range cfg.Templates {
+			templates[k] = v
+		}
+		for name, p := range o.Templates {
+			a.base["templates."+name] = templates[name]
+			templates[name] = p
+			cfg.origins["templates."+name] = source
+		}
+		cfg.Templates = templates
+	}
+	cfg.overlays = append(cfg.overlays, a)
+}
+
+// Origin reports where the value at key came from: the overlay source that
+// set it, or "" for config.json. Keys are JSON names, with [i] for list
+// entries and .name for map entries.
+func (cfg Config) Origin(key string) string {
+	return cfg.origins[key]
+}
+
+// withoutOverlays undoes Apply for values the caller hasn't changed since.
+func (cfg Config) withoutOverlays() Config {
+	for i := len(cfg.overlays) - 1; i >= 0; i-- {
+		a := cfg.overlays[i]
+		restore := func(key string, field *string, val string) {
+			if val != "" && *field == val {
+				*field = a.base[key]
+			}
+		}
+		restore("selectedImage", &cfg.SelectedImage, a.overlay.SelectedImage)
+		restore("selectedAgent", &cfg.SelectedAgent, a.overlay.Agent)
+		restore("extractDir", &cfg.ExtractDir, a.overlay.ExtractDir)
+		if a.overlay.LocalProxy != nil && cfg.LocalProxy == a.proxy {
+			cfg.LocalProxy = a.proxyBase
+		}
+
+		if len(a.copyRules) > 0 {
+			cfg.CopyRules = removeEach(cfg.CopyRules, a.copyRules)
+		}
+		if len(a.env) > 0 {
+			cfg.EnvPassthrough = removeEach(cfg.EnvPassthrough, a.env)
+		}
+		if len(a.overlay.Templates) > 0 {
+			templates := make(map[string]string, len(cfg.Templates))
+			for k, v := range cfg.Templates {
+				templates[k] = v
+			}
+			for name, p := range a.overlay.Templates {
+				if templates[name] != p {
+					continue
+				}
+				if base := a.base["templates."+name]; base != "" {
+					templates[name] = base
+				} else {
+					delete(templates, name)
+				}
+			}
+			cfg.Templates = templates
+		}
+	}
+	cfg.overlays = nil
+	cfg.origins = nil
+	return cfg
+}
+
+// removeEach returns list without one occurrence of each item in drop,
+// searching from the end where Apply appended them.
+func removeEach[T any](list []T, drop []T) []T {
+	out := slices.Clone(list)
+	for _, d := range drop {
+		for i := len(out) - 1; i >= 0; i-- {
+			if reflect.DeepEqual(out[i], d) {
+				out = slices.Delete(out, i, i+1)
+				break
+			}
+		}
+	}
+	return out
+}
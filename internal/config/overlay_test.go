@@ -0,0 +1,166 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestFindProjectFile(t *testing.T) {
+	t.Parallel()
+
+	root := t.TempDir()
+	sub := filepath.Join(root, "plugins", "chat")
+	if err := os.MkdirAll(sub, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Mkdir(filepath.Join(root, ".git"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if got := FindProjectFile(sub); got != "" {
+		t.Fatalf("found %q without a project file", got)
+	}
+
+	rootFile := filepath.Join(root, ProjectFile)
+	if err := os.WriteFile(rootFile, nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if got := FindProjectFile(sub); got != rootFile {
+		t.Fatalf("got %q, want git root file %q", got, rootFile)
+	}
+
+	subFile := filepath.Join(sub, ProjectFile)
+	if err := os.WriteFile(subFile, nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if got := FindProjectFile(sub); got != subFile {
+		t.Fatalf("got %q, want current dir file %q", got, subFile)
+	}
+}
+
+func TestLoadOverlay(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	p := filepath.Join(dir, ProjectFile)
+	data := `selectedImage: plugin
+copyRules:
+  - host: secrets/env
+    container: /home/discourse/.env
+templates:
+  chat: templates/chat.yaml
+  remote: https://example.com/t.yaml
+extractDir: ~/src
+`
+	if err := os.WriteFile(p, []byte(data), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	o, err := LoadOverlay(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if o.SelectedImage != "plugin" || o.ExtractDir != "~/src" {
+		t.Fatalf("overlay = %+v", o)
+	}
+	if o.CopyRules[0].Host != filepath.Join(dir, "secrets/env") {
+		t.Fatalf("host = %q", o.CopyRules[0].Host)
+	}
+	if o.Templates["chat"] != filepath.Join(dir, "templates/chat.yaml") || o.Templates["remote"] != "https://example.com/t.yaml" {
+		t.Fatalf("templates = %v", o.Templates)
+	}
+
+	if err := os.WriteFile(p, []byte("selectedImgae: typo\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadOverlay(p); err == nil || !strings.Contains(err.Error(), "selectedImgae") {
+		t.Fatalf("err = %v", err)
+	}
+}
+
+func TestApplyOverlayIsNotSaved(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	cfg := Default()
+	cfg.SelectedAgent = "global"
+	if err := Save(dir, cfg); err != nil {
+		t.Fatal(err)
+	}
+	before, _ := os.ReadFile(Path(dir))
+
+	cfg, err := LoadOrCreate(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rule := CopyRule{Host: "/p/.env", Container: "/home/discourse/.env"}
+	cfg.Apply(Overlay{
+		SelectedImage:  "plugin",
+		Agent:          "project",
+		CopyRules:      []CopyRule{rule},
+		EnvPassthrough: []string{"GH_TOKEN", "PROJECT_KEY"},
+		Templates:      map[string]string{"chat": "/p/chat.yaml"},
+	}, "/p/.dv.yaml")
+
+	if cfg.SelectedImage != "plugin" || cfg.SelectedAgent != "project" || cfg.Templates["chat"] != "/p/chat.yaml" {
+		t.Fatalf("overlay not applied: %+v", cfg)
+	}
+	if got := cfg.Origin("selectedAgent"); got != "/p/.dv.yaml" {
+		t.Fatalf("origin = %q", got)
+	}
+	n := len(cfg.EnvPassthrough)
+	if cfg.EnvPassthrough[n-1] != "PROJECT_KEY" || cfg.Origin(fmt.Sprintf("envPassthrough[%d]", n-1)) == "" {
+		t.Fatalf("env = %v", cfg.EnvPassthrough)
+	}
+	if cfg.Origin("envPassthrough[0]") != "" || cfg.Origin("workdir") != "" {
+		t.Fatal("global values should have no overlay origin")
+	}
+
+	if err := Save(dir, cfg); err != nil {
+		t.Fatal(err)
+	}
+	if after, _ := os.ReadFile(Path(dir)); string(after) != string(before) {
+		t.Fatalf("overlay leaked into config.json:\n%s", after)
+	}
+
+	// Values a command changes itself are saved.
+	cfg.SelectedAgent = "picked"
+	cfg.CopyRules = append(cfg.CopyRules, CopyRule{Host: "/x", Container: "/y"})
+	if err := Save(dir, cfg); err != nil {
+		t.Fatal(err)
+	}
+	var saved Config
+	data, _ := os.ReadFile(Path(dir))
+	if err := json.Unmarshal(data, &saved); err != nil {
+		t.Fatal(err)
+	}
+	if saved.SelectedAgent != "picked" || saved.SelectedImage != "discourse" || saved.Templates != nil {
+		t.Fatalf("saved = %s", data)
+	}
+	if slices.Contains(saved.EnvPassthrough, "PROJECT_KEY") || slices.ContainsFunc(saved.CopyRules, func(r CopyRule) bool { return r.Host == rule.Host }) {
+		t.Fatalf("overlay lists leaked: %s", data)
+	}
+	if last := saved.CopyRules[len(saved.CopyRules)-1]; last.Host != "/x" {
+		t.Fatalf("new copy rule not saved: %+v", last)
+	}
+}
+
+func TestApplyProxyOverlay(t *testing.T) {
+	t.Parallel()
+
+	enabled := true
+	cfg := Default()
+	cfg.Apply(Overlay{LocalProxy: &ProxyOverlay{Enabled: &enabled, HTTPPort: 8080}}, "profile themes")
+	if !cfg.LocalProxy.Enabled || cfg.LocalProxy.HTTPPort != 8080 || cfg.LocalProxy.APIPort != 2080 {
+		t.Fatalf("proxy = %+v", cfg.LocalProxy)
+	}
+	if cfg.Origin("localProxy") != "profile themes" {
+		t.Fatalf("origin = %q", cfg.Origin("localProxy"))
+	}
+	if got := cfg.withoutOverlays().LocalProxy; got != Default().LocalProxy {
+		t.Fatalf("proxy after removing overlay = %+v", got)
+	}
+}
@@ -0,0 +1,93 @@
+package config
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// PortSpec declares an extra container port an image publishes on the host
+// under a role name, e.g. mailhog=8025 or rails=3000.
+type PortSpec struct {
+	Name          string `json:"name"`
+	ContainerPort int    `json:"containerPort"`
+	// HostStartingPort is the first host port to try; defaults to ContainerPort.
+	HostStartingPort int `json:"hostStartingPort,omitempty"`
+}
+
+var portNamePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]*$`)
+
+// StartingPort returns the first host port to try for the spec.
+func (p PortSpec) StartingPort() int {
+	if p.HostStartingPort > 0 {
+		return p.HostStartingPort
+	}
+	return p.ContainerPort
+}
+
+// ParsePortSpec parses NAME=CONTAINER_PORT[:HOST_STARTING_PORT].
+func ParsePortSpec(s string) (PortSpec, error) {
+	name, rest, ok := strings.Cut(strings.TrimSpace(s), "=")
+	if !ok {
+		return PortSpec{}, fmt.Errorf("invalid port %q: expected NAME=CONTAINER_PORT[:HOST_STARTING_PORT]", s)
+	}
+	spec := PortSpec{Name: strings.ToLower(strings.TrimSpace(name))}
+	containerPart, hostPart, hasHost := strings.Cut(rest, ":")
+	var err error
+	if spec.ContainerPort, err = strconv.Atoi(strings.TrimSpace(containerPart)); err != nil {
+		return PortSpec{}, fmt.Errorf("invalid port %q: %s is not a number", s, containerPart)
+	}
+	if hasHost {
+		if spec.HostStartingPort, err = strconv.Atoi(strings.TrimSpace(hostPart)); err != nil {
+			return PortSpec{}, fmt.Errorf("invalid port %q: %s is not a number", s, hostPart)
+		}
+	}
+	return spec, spec.Validate()
+}
+
+// Validate checks the name and port ranges of a single spec.
+func (p PortSpec) Validate() error {
+	if !portNamePattern.MatchString(p.Name) {
+		return fmt.Errorf("invalid port name %q: use lowercase letters, digits, '-' or '_'", p.Name)
+	}
+	if p.ContainerPort < 1 || p.ContainerPort > 65535 {
+		return fmt.Errorf("port %s: container port %d out of range", p.Name, p.ContainerPort)
+	}
+	if p.HostStartingPort < 0 || p.HostStartingPort > 65535 {
+		return fmt.Errorf("port %s: host starting port %d out of range", p.Name, p.HostStartingPort)
+	}
+	return nil
+}
+
+// SetPort adds spec to the image, replacing any port with the same name.
+func (img *ImageConfig) SetPort(spec PortSpec) error {
+	if err := spec.Validate(); err != nil {
+		return err
+	}
+	if spec.ContainerPort == img.ContainerPort {
+		return fmt.Errorf("port %s: container port %d is already the image's main port", spec.Name, spec.ContainerPort)
+	}
+	for i, p := range img.Ports {
+		if p.Name == spec.Name {
+			img.Ports[i] = spec
+			return nil
+		}
+		if p.ContainerPort == spec.ContainerPort {
+			return fmt.Errorf("port %s: container port %d is already published as %s", spec.Name, spec.ContainerPort, p.Name)
+		}
+	}
+	img.Ports = append(img.Ports, spec)
+	return nil
+}
+
+// RemovePort drops the named port and reports whether it existed.
+func (img *ImageConfig) RemovePort(name string) bool {
+	for i, p := range img.Ports {
+		if p.Name == name {
+			img.Ports = append(img.Ports[:i], img.Ports[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
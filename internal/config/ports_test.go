@@ -0,0 +1,72 @@
+package config
+
+import "testing"
+
+func TestParsePortSpec(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		input   string
+		want    PortSpec
+		wantErr bool
+	}{
+		{input: "mailhog=8025", want: PortSpec{Name: "mailhog", ContainerPort: 8025}},
+		{input: " Rails = 3000:3100", want: PortSpec{Name: "rails", ContainerPort: 3000, HostStartingPort: 3100}},
+		{input: "sidekiq-web=9292", want: PortSpec{Name: "sidekiq-web", ContainerPort: 9292}},
+		{input: "8025", wantErr: true},
+		{input: "mail hog=8025", wantErr: true},
+		{input: "vite=abc", wantErr: true},
+		{input: "vite=70000", wantErr: true},
+		{input: "vite=5173:x", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := ParsePortSpec(tt.input)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("ParsePortSpec(%q) succeeded, want error", tt.input)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("ParsePortSpec(%q) = %+v, %v; want %+v", tt.input, got, err, tt.want)
+		}
+	}
+}
+
+func TestImageSetPort(t *testing.T) {
+	t.Parallel()
+
+	img := ImageConfig{ContainerPort: 4200}
+	if err := img.SetPort(PortSpec{Name: "mailhog", ContainerPort: 8025}); err != nil {
+		t.Fatal(err)
+	}
+	if err := img.SetPort(PortSpec{Name: "mailhog", ContainerPort: 8026}); err != nil {
+		t.Fatalf("replacing by name: %v", err)
+	}
+	if len(img.Ports) != 1 || img.Ports[0].ContainerPort != 8026 {
+		t.Errorf("Ports = %+v", img.Ports)
+	}
+	if err := img.SetPort(PortSpec{Name: "web", ContainerPort: 4200}); err == nil {
+		t.Error("duplicate of main port accepted")
+	}
+	if err := img.SetPort(PortSpec{Name: "other", ContainerPort: 8026}); err == nil {
+		t.Error("duplicate container port accepted")
+	}
+	if !img.RemovePort("mailhog") || len(img.Ports) != 0 {
+		t.Errorf("RemovePort failed: %+v", img.Ports)
+	}
+	if img.RemovePort("mailhog") {
+		t.Error("RemovePort of missing port reported success")
+	}
+}
+
+func TestPortSpecStartingPort(t *testing.T) {
+	t.Parallel()
+
+	if got := (PortSpec{ContainerPort: 3000}).StartingPort(); got != 3000 {
+		t.Errorf("StartingPort() = %d, want 3000", got)
+	}
+	if got := (PortSpec{ContainerPort: 3000, HostStartingPort: 13000}).StartingPort(); got != 13000 {
+		t.Errorf("StartingPort() = %d, want 13000", got)
+	}
+}
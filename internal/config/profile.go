@@ -0,0 +1,63 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+var profileNameRe = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]*$`)
+
+// ProfilesDir holds named profiles, one NAME.yaml overlay each.
+func ProfilesDir(configDir string) string { return filepath.Join(configDir, "profiles") }
+
+// ProfilePath returns the overlay file for the named profile.
+func ProfilePath(configDir, name string) string {
+	return filepath.Join(ProfilesDir(configDir), name+".yaml")
+}
+
+// ValidateProfileName rejects names that can't be used as file names.
+func ValidateProfileName(name string) error {
+	if !profileNameRe.MatchString(name) {
+		return fmt.Errorf("invalid profile name %q: use letters, digits, '.', '_' or '-'", name)
+	}
+	return nil
+}
+
+// LoadProfile reads the named profile.
+func LoadProfile(configDir, name string) (Overlay, error) {
+	if err := ValidateProfileName(name); err != nil {
+		return Overlay{}, err
+	}
+	o, err := LoadOverlay(ProfilePath(configDir, name))
+	if errors.Is(err, fs.ErrNotExist) {
+		return Overlay{}, fmt.Errorf("profile %q does not exist", name)
+	}
+	return o, err
+}
+
+// ListProfiles returns the names of all profiles, sorted.
+func ListProfiles(configDir string) ([]string, error) {
+	entries, err := os.ReadDir(ProfilesDir(configDir))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	for _, e := range entries {
+		name, ok := strings.CutSuffix(e.Name(), ".yaml")
+		if e.IsDir() || !ok || ValidateProfileName(name) != nil {
+			continue
+		}
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names, nil
+}
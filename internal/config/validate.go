@@ -0,0 +1,249 @@
+package config
+
+import (
+	"fmt"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Problem is something wrong at a JSON path in the config, such as
+// images.discourse.dockerfile.source or copyRules[3].container.
+type Problem struct {
+	Path    string
+	Message string
+	// Warning problems are reported by `dv config validate` but don't stop
+	// dv from loading the config.
+	Warning bool
+	// Source is the overlay that set the value, or "" for config.json.
+	Source string
+}
+
+func (p Problem) String() string {
+	s := p.Path + ": " + p.Message
+	if p.Source != "" {
+		s += " (from " + p.Source + ")"
+	}
+	return s
+}
+
+// ValidationError lists the errors that make a config unusable.
+type ValidationError struct {
+	Problems []Problem
+}
+
+func (e *ValidationError) Error() string {
+	lines := make([]string, len(e.Problems))
+	for i, p := range e.Problems {
+		lines[i] = "  " + p.String()
+	}
+	return strings.Join(lines, "\n")
+}
+
+// Validate returns a *ValidationError when cfg has errors. Warnings are
+// ignored.
+func Validate(cfg Config) error {
+	var errs []Problem
+	for _, p := range Check(cfg) {
+		if !p.Warning {
+			errs = append(errs, p)
+		}
+	}
+	if len(errs) == 0 {
+		return nil
+	}
+	return &ValidationError{Problems: errs}
+}
+
+// Check returns every problem in cfg, sorted by path.
+func Check(cfg Config) []Problem {
+	var problems []Problem
+	add := func(p, format string, args ...any) {
+		problems = append(problems, Problem{Path: p, Message: fmt.Sprintf(format, args...), Source: cfg.Origin(p)})
+	}
+	warn := func(p, format string, args ...any) {
+		add(p, format, args...)
+		problems[len(problems)-1].Warning = true
+	}
+	port := func(p string, n int) {
+		if n < 0 || n > 65535 {
+			add(p, "port %d out of range 1-65535", n)
+		}
+	}
+	absContainerPath := func(p, v string) {
+		if v != "" && !path.IsAbs(v) {
+			add(p, "container path %q must be absolute", v)
+		}
+	}
+
+	switch strings.ToLower(strings.TrimSpace(cfg.ContainerRuntime)) {
+	case "", "docker", "podman":
+	default:
+		add("containerRuntime", "unknown runtime %q (want docker or podman)", cfg.ContainerRuntime)
+	}
+	port("hostStartingPort", cfg.HostStartingPort)
+	port("containerPort", cfg.ContainerPort)
+	absContainerPath("workdir", cfg.Workdir)
+	for _, name := range sortedKeys(cfg.CustomWorkdirs) {
+		absContainerPath("customWorkdirs."+name, strings.TrimSpace(cfg.CustomWorkdirs[name]))
+	}
+
+	if _, ok := cfg.Images[cfg.SelectedImage]; !ok && len(cfg.Images) > 0 {
+		add("selectedImage", "unknown image %q", cfg.SelectedImage)
+	}
+	for _, name := range sortedKeys(cfg.Images) {
+		img := cfg.Images[name]
+		p := "images." + name
+		switch img.Kind {
+		case "discourse", "custom":
+		case "":
+			warn(p+".kind", "not set; treated as custom")
+		default:
+			add(p+".kind", "unknown kind %q (want discourse or custom)", img.Kind)
+		}
+		switch img.Dockerfile.Source {
+		case "stock":
+			if img.Dockerfile.StockName != "discourse" {
+				add(p+".dockerfile.stockName", "unknown stock Dockerfile %q (want discourse)", img.Dockerfile.StockName)
+			}
+		case "path":
+			if img.Dockerfile.Path == "" {
+				add(p+".dockerfile.path", "must be set when source is \"path\"")
+			} else if !filepath.IsAbs(img.Dockerfile.Path) {
+				add(p+".dockerfile.path", "host path %q must be absolute", img.Dockerfile.Path)
+			}
+		case "":
+			warn(p+".dockerfile.source", "not set; dv build can't build this image")
+		default:
+			add(p+".dockerfile.source", "unknown source %q (want stock or path)", img.Dockerfile.Source)
+		}
+		absContainerPath(p+".workdir", img.Workdir)
+		port(p+".containerPort", img.ContainerPort)
+		seenPorts := map[string]int{}
+		for i, spec := range img.Ports {
+			pp := fmt.Sprintf("%s.ports[%d]", p, i)
+			if err := spec.Validate(); err != nil {
+				add(pp, "%v", err)
+			}
+			if j, dup := seenPorts[spec.Name]; dup {
+				add(pp+".name", "duplicate port name %q (also %s.ports[%d])", spec.Name, p, j)
+			}
+			seenPorts[spec.Name] = i
+		}
+		seenVolumes := map[string]int{}
+		for i, v := range img.Volumes {
+			vp := fmt.Sprintf("%s.volumes[%d]", p, i)
+			if err := v.Validate(); err != nil {
+				add(vp, "%v", err)
+			}
+			if j, dup := seenVolumes[v.Name]; dup {
+				add(vp+".name", "duplicate volume name %q (also %s.volumes[%d])", v.Name, p, j)
+			}
+			seenVolumes[v.Name] = i
+		}
+		if err := img.Limits.Validate(); err != nil {
+			add(p+".limits", "%v", err)
+		}
+		if err := img.IdleStop.Validate(); err != nil {
+			add(p+".idleStop", "%v", err)
+		}
+	}
+	for _, agent := range sortedKeys(cfg.AgentLimits) {
+		if err := cfg.AgentLimits[agent].Validate(); err != nil {
+			add("agentLimits."+agent, "%v", err)
+		}
+	}
+	for _, agent := range sortedKeys(cfg.ContainerImages) {
+		if img := cfg.ContainerImages[agent]; img != "" {
+			if _, ok := cfg.Images[img]; !ok {
+				warn("containerImages."+agent, "unknown image %q", img)
+			}
+		}
+	}
+
+	checkCopyRules(cfg.CopyRules, add, warn)
+
+	lp := cfg.LocalProxy
+	port("localProxy.httpPort", lp.HTTPPort)
+	port("localProxy.apiPort", lp.APIPort)
+	port("localProxy.httpsPort", lp.HTTPSPort)
+	if lp.HTTPPort != 0 && lp.HTTPPort == lp.APIPort {
+		add("localProxy.apiPort", "port %d is also localProxy.httpPort", lp.APIPort)
+	}
+	if lp.HTTPS && lp.HTTPSPort != 0 {
+		if lp.HTTPSPort == lp.HTTPPort {
+			add("localProxy.httpsPort", "port %d is also localProxy.httpPort", lp.HTTPSPort)
+		}
+		if lp.HTTPSPort == lp.APIPort {
+			add("localProxy.httpsPort", "port %d is also localProxy.apiPort", lp.HTTPSPort)
+		}
+	}
+
+	sort.SliceStable(problems, func(i, j int) bool { return problems[i].Path < problems[j].Path })
+	return problems
+}
+
+func checkCopyRules(rules []CopyRule, add, warn func(p, format string, args ...any)) {
+	for i, r := range rules {
+		p := fmt.Sprintf("copyRules[%d]", i)
+		if strings.TrimSpace(r.Host) == "" {
+			add(p+".host", "must not be empty")
+		}
+		switch c := strings.TrimSpace(r.Container); {
+		case c == "":
+			add(p+".container", "must not be empty")
+		case !path.IsAbs(c):
+			add(p+".container", "container path %q must be absolute", r.Container)
+		}
+		if f := r.Fallback; f != nil {
+			if f.Type != "command" {
+				add(p+".fallback.type", "unknown fallback %q (want command)", f.Type)
+			} else if strings.TrimSpace(f.Exec) == "" {
+				add(p+".fallback.exec", "must not be empty")
+			}
+		}
+		// Directory destinations (trailing slash) collect several sources.
+		dest := strings.TrimSpace(r.Container)
+		if dest == "" || strings.HasSuffix(dest, "/") {
+			continue
+		}
+		for j := range i {
+			o := rules[j]
+			if path.Clean(strings.TrimSpace(o.Container)) != path.Clean(dest) || !agentsOverlap(r.Agents, o.Agents) {
+				continue
+			}
+			if strings.TrimSpace(o.Host) == strings.TrimSpace(r.Host) {
+				warn(p, "duplicate of copyRules[%d]", j)
+			} else {
+				add(p+".container", "destination %q is also written by copyRules[%d] (%s)", r.Container, j, o.Host)
+			}
+			break
+		}
+	}
+}
+
+// agentsOverlap reports whether two copy rule scopes can apply to the same
+// agent; an empty scope applies to all of them.
+func agentsOverlap(a, b []string) bool {
+	if len(a) == 0 || len(b) == 0 {
+		return true
+	}
+	for _, x := range a {
+		for _, y := range b {
+			if x == y {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
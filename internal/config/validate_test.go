@@ -0,0 +1,133 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestCheckDefaultConfig(t *testing.T) {
+	t.Parallel()
+
+	if problems := Check(Default()); len(problems) != 0 {
+		t.Fatalf("default config has problems: %v", problems)
+	}
+}
+
+func TestCheck(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		mutate  func(*Config)
+		path    string
+		warning bool
+	}{
+		{"unknown runtime", func(c *Config) { c.ContainerRuntime = "lxc" }, "containerRuntime", false},
+		{"port range", func(c *Config) { c.HostStartingPort = 70000 }, "hostStartingPort", false},
+		{"relative workdir override", func(c *Config) { c.CustomWorkdirs["a"] = "src" }, "customWorkdirs.a", false},
+		{"unknown selected image", func(c *Config) { c.SelectedImage = "nope" }, "selectedImage", false},
+		{"bad dockerfile source", func(c *Config) {
+			img := c.Images["discourse"]
+			img.Dockerfile.Source = "git"
+			c.Images["discourse"] = img
+		}, "images.discourse.dockerfile.source", false},
+		{"relative dockerfile path", func(c *Config) {
+			c.Images["x"] = ImageConfig{Kind: "custom", Dockerfile: ImageSource{Source: "path", Path: "Dockerfile"}}
+		}, "images.x.dockerfile.path", false},
+		{"bad kind", func(c *Config) {
+			img := c.Images["discourse"]
+			img.Kind = "rails"
+			c.Images["discourse"] = img
+		}, "images.discourse.kind", false},
+		{"duplicate port name", func(c *Config) {
+			img := c.Images["discourse"]
+			img.Ports = []PortSpec{{Name: "mail", ContainerPort: 8025}, {Name: "mail", ContainerPort: 1080}}
+			c.Images["discourse"] = img
+		}, "images.discourse.ports[1].name", false},
+		{"bad limits", func(c *Config) { c.AgentLimits = map[string]ResourceLimits{"a": {Memory: "lots"}} }, "agentLimits.a", false},
+		{"empty copy destination", func(c *Config) {
+			c.CopyRules = append(c.CopyRules, CopyRule{Host: "~/.x"})
+		}, "copyRules[12].container", false},
+		{"relative copy destination", func(c *Config) {
+			c.CopyRules = append(c.CopyRules, CopyRule{Host: "~/.x", Container: "x"})
+		}, "copyRules[12].container", false},
+		{"clashing copy destination", func(c *Config) {
+			c.CopyRules = append(c.CopyRules, CopyRule{Host: "~/other.json", Container: "/home/discourse/.claude.json"})
+		}, "copyRules[12].container", false},
+		{"repeated copy rule", func(c *Config) {
+			c.CopyRules = append(c.CopyRules, c.CopyRules[0])
+		}, "copyRules[12]", true},
+		{"bad fallback", func(c *Config) {
+			c.CopyRules[6].Fallback = &CopyFallback{Type: "url"}
+		}, "copyRules[6].fallback.type", false},
+		{"proxy port clash", func(c *Config) { c.LocalProxy.APIPort = c.LocalProxy.HTTPPort }, "localProxy.apiPort", false},
+		{"https port clash", func(c *Config) {
+			c.LocalProxy.HTTPS = true
+			c.LocalProxy.HTTPSPort = c.LocalProxy.APIPort
+		}, "localProxy.httpsPort", false},
+		{"dangling container image", func(c *Config) { c.ContainerImages["old"] = "gone" }, "containerImages.old", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			cfg := Default()
+			tt.mutate(&cfg)
+			problems := Check(cfg)
+			if len(problems) != 1 || problems[0].Path != tt.path || problems[0].Warning != tt.warning {
+				t.Fatalf("problems = %+v, want one at %s (warning=%v)", problems, tt.path, tt.warning)
+			}
+		})
+	}
+}
+
+func TestCheckAllowsSharedDirectoryDestinations(t *testing.T) {
+	t.Parallel()
+
+	cfg := Default()
+	cfg.CopyRules = []CopyRule{
+		{Host: "~/a.json", Container: "/home/discourse/.config/tool/"},
+		{Host: "~/b.json", Container: "/home/discourse/.config/tool/"},
+		{Host: "~/codex.json", Container: "/home/discourse/auth.json", Agents: []string{"codex"}},
+		{Host: "~/gemini.json", Container: "/home/discourse/auth.json", Agents: []string{"gemini"}},
+	}
+	if problems := Check(cfg); len(problems) != 0 {
+		t.Fatalf("problems = %v", problems)
+	}
+}
+
+func TestCheckReportsOverlaySource(t *testing.T) {
+	t.Parallel()
+
+	cfg := Default()
+	cfg.Apply(Overlay{SelectedImage: "missing"}, "/p/.dv.yaml")
+	problems := Check(cfg)
+	if len(problems) != 1 || !strings.Contains(problems[0].String(), "selectedImage: unknown image \"missing\" (from /p/.dv.yaml)") {
+		t.Fatalf("problems = %v", problems)
+	}
+}
+
+func TestLoadAndSaveRejectInvalidConfig(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	cfg := Default()
+	cfg.LocalProxy.APIPort = cfg.LocalProxy.HTTPPort
+	if err := Save(dir, cfg); err == nil {
+		t.Fatal("expected Save to reject an invalid config")
+	}
+	if _, err := os.Stat(Path(dir)); !os.IsNotExist(err) {
+		t.Fatalf("invalid config written: %v", err)
+	}
+
+	data := `{"schemaVersion": 3, "selectedImage": "discourse", "images": {"discourse": {"kind": "discourse", "dockerfile": {"source": "stock", "stockName": "discourse"}}}, "copyRules": [{"host": "~/.x", "container": ""}]}`
+	if err := os.WriteFile(Path(dir), []byte(data), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	_, err := LoadOrCreate(dir)
+	var verr *ValidationError
+	if !errors.As(err, &verr) || !strings.Contains(err.Error(), "copyRules[0].container: must not be empty") {
+		t.Fatalf("err = %v", err)
+	}
+}
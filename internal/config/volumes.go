@@ -0,0 +1,97 @@
+package config
+
+import (
+	"fmt"
+	"path"
+	"strings"
+)
+
+// VolumeMount shares a named volume across every agent of an image, e.g. the
+// pnpm store or bundler cache, so dependency installs don't start cold.
+type VolumeMount struct {
+	Name string `json:"name"`
+	// Path is the absolute mount point inside the container.
+	Path string `json:"path"`
+	// Env is set on agents that mount the volume, typically to point a tool
+	// at Path.
+	Env map[string]string `json:"env,omitempty"`
+}
+
+// DefaultVolumes returns the cache volumes used by the stock discourse image.
+func DefaultVolumes() []VolumeMount {
+	const home = "/home/discourse"
+	return []VolumeMount{
+		{
+			Name: "pnpm-store",
+			Path: home + "/.local/share/pnpm/store",
+			Env:  map[string]string{"npm_config_store_dir": home + "/.local/share/pnpm/store"},
+		},
+		{
+			Name: "bundler-cache",
+			Path: home + "/.bundle/cache",
+			Env: map[string]string{
+				"BUNDLE_USER_CACHE":       home + "/.bundle/cache",
+				"BUNDLE_GLOBAL_GEM_CACHE": "true",
+			},
+		},
+		{Name: "cache", Path: home + "/.cache"},
+	}
+}
+
+// ParseVolumeMount parses NAME=PATH.
+func ParseVolumeMount(s string) (VolumeMount, error) {
+	name, p, ok := strings.Cut(strings.TrimSpace(s), "=")
+	if !ok {
+		return VolumeMount{}, fmt.Errorf("invalid volume %q: expected NAME=PATH", s)
+	}
+	v := VolumeMount{Name: strings.ToLower(strings.TrimSpace(name)), Path: strings.TrimSpace(p)}
+	return v, v.Validate()
+}
+
+// Validate checks the name and mount path of a single volume.
+func (v VolumeMount) Validate() error {
+	if !portNamePattern.MatchString(v.Name) {
+		return fmt.Errorf("invalid volume name %q: use lowercase letters, digits, '-' or '_'", v.Name)
+	}
+	if !path.IsAbs(v.Path) {
+		return fmt.Errorf("volume %s: mount path %q must be absolute", v.Name, v.Path)
+	}
+	if path.Clean(v.Path) == "/" {
+		return fmt.Errorf("volume %s: cannot mount over /", v.Name)
+	}
+	return nil
+}
+
+// SetVolume adds v to the image, replacing any volume with the same name.
+// Env of a replaced volume is kept unless v sets its own.
+func (img *ImageConfig) SetVolume(v VolumeMount) error {
+	if err := v.Validate(); err != nil {
+		return err
+	}
+	v.Path = path.Clean(v.Path)
+	for i, existing := range img.Volumes {
+		if existing.Name == v.Name {
+			if v.Env == nil && existing.Path == v.Path {
+				v.Env = existing.Env
+			}
+			img.Volumes[i] = v
+			return nil
+		}
+		if existing.Path == v.Path {
+			return fmt.Errorf("volume %s: %s is already mounted by %s", v.Name, v.Path, existing.Name)
+		}
+	}
+	img.Volumes = append(img.Volumes, v)
+	return nil
+}
+
+// RemoveVolume drops the named volume and reports whether it existed.
+func (img *ImageConfig) RemoveVolume(name string) bool {
+	for i, v := range img.Volumes {
+		if v.Name == name {
+			img.Volumes = append(img.Volumes[:i], img.Volumes[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
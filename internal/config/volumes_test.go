@@ -0,0 +1,62 @@
+package config
+
+import "testing"
+
+func TestParseVolumeMount(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		input   string
+		want    VolumeMount
+		wantErr bool
+	}{
+		{input: "gems=/usr/local/bundle", want: VolumeMount{Name: "gems", Path: "/usr/local/bundle"}},
+		{input: " Yarn-Cache = /home/discourse/.yarn ", want: VolumeMount{Name: "yarn-cache", Path: "/home/discourse/.yarn"}},
+		{input: "gems", wantErr: true},
+		{input: "gems=relative/path", wantErr: true},
+		{input: "gems=/", wantErr: true},
+		{input: "my gems=/cache", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := ParseVolumeMount(tt.input)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("ParseVolumeMount(%q) succeeded, want error", tt.input)
+			}
+			continue
+		}
+		if err != nil || got.Name != tt.want.Name || got.Path != tt.want.Path {
+			t.Errorf("ParseVolumeMount(%q) = %+v, %v; want %+v", tt.input, got, err, tt.want)
+		}
+	}
+}
+
+func TestImageSetVolume(t *testing.T) {
+	t.Parallel()
+
+	img := ImageConfig{Volumes: DefaultVolumes()}
+	if err := img.SetVolume(VolumeMount{Name: "gems", Path: "/usr/local/bundle/"}); err != nil {
+		t.Fatal(err)
+	}
+	if got := img.Volumes[len(img.Volumes)-1].Path; got != "/usr/local/bundle" {
+		t.Fatalf("path not cleaned: %q", got)
+	}
+	if err := img.SetVolume(VolumeMount{Name: "other", Path: "/usr/local/bundle"}); err == nil {
+		t.Fatal("expected error mounting two volumes at the same path")
+	}
+
+	// Re-setting a default volume at the same path keeps its env.
+	if err := img.SetVolume(VolumeMount{Name: "pnpm-store", Path: "/home/discourse/.local/share/pnpm/store"}); err != nil {
+		t.Fatal(err)
+	}
+	if img.Volumes[0].Env["npm_config_store_dir"] == "" {
+		t.Fatalf("env dropped on replace: %+v", img.Volumes[0])
+	}
+
+	if !img.RemoveVolume("gems") || img.RemoveVolume("gems") {
+		t.Fatal("RemoveVolume should report existence once")
+	}
+	if len(img.Volumes) != len(DefaultVolumes()) {
+		t.Fatalf("volumes = %+v", img.Volumes)
+	}
+}
@@ -395,7 +395,7 @@ func hostnameForContainer(name, hostname string) string {
 // getContainerHostPort extracts the published host port from a running container
 func getContainerHostPort(containerName string) (int, error) {
 	// Use docker port command to get the mapping
-	cmd := exec.Command("docker", "port", containerName)
+	cmd := exec.Command(docker.Binary(), "port", containerName)
 	out, err := cmd.Output()
 	if err != nil {
 		return 0, fmt.Errorf("get container port: %w", err)
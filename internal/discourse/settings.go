@@ -15,6 +15,7 @@ type SiteSetting struct {
 	Default     interface{} `json:"default"`
 	Description string      `json:"description"`
 	Type        string      `json:"type"`
+	Secret      bool        `json:"secret"`
 }
 
 // SiteSettingsResponse is the API response for site settings
@@ -48,6 +49,24 @@ func (c *Client) GetSiteSetting(name string) (interface{}, error) {
 	return nil, fmt.Errorf("setting %s not found", name)
 }
 
+// ListSiteSettings retrieves every visible site setting with its value and default
+func (c *Client) ListSiteSettings() ([]SiteSetting, error) {
+	resp, body, err := c.doRequest("GET", "/admin/site_settings.json", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode != 200 {
+		return nil, fmt.Errorf("list settings: status %d: %s", resp.StatusCode, string(body))
+	}
+
+	var result SiteSettingsResponse
+	if err := json.Unmarshal(body, &result); err != nil {
+		return nil, fmt.Errorf("decode settings: %w", err)
+	}
+	return result.SiteSettings, nil
+}
+
 // SetSiteSetting updates a site setting value
 func (c *Client) SetSiteSetting(name string, value interface{}) error {
 	path := fmt.Sprintf("/admin/site_settings/%s.json", url.PathEscape(name))
@@ -0,0 +1,451 @@
+package docker
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// apiRuntime serves non-interactive operations through the engine API when
+// its socket answers, and defers to the embedded CLI runtime otherwise.
+// Interactive exec, run, build and pull always go through the CLI.
+type apiRuntime struct {
+	cliRuntime
+	hosts func() []string
+
+	once   sync.Once
+	client *engineClient
+}
+
+func newAPIRuntime(cli cliRuntime, hosts func() []string) *apiRuntime {
+	return &apiRuntime{cliRuntime: cli, hosts: hosts}
+}
+
+// engine returns the shared API client, or nil when the daemon cannot be
+// reached directly. Set DV_DOCKER_CLI=1 to force the CLI path.
+func (r *apiRuntime) engine() *engineClient {
+	r.once.Do(func() {
+		if isTruthyEnv("DV_DOCKER_CLI") {
+			return
+		}
+		// Non-default contexts may point at remote daemons we can't resolve here.
+		if ctx := dockerContext(); ctx != "default" && !r.isPodman() {
+			return
+		}
+		for _, host := range r.hosts() {
+			c, err := newEngineClient(host)
+			if err != nil {
+				continue
+			}
+			pingCtx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+			err = c.ping(pingCtx)
+			cancel()
+			if err == nil {
+				r.client = c
+				if isTruthyEnv("DV_VERBOSE") {
+					fmt.Fprintf(os.Stderr, "%s API: using %s\n", r.bin, host)
+				}
+				return
+			}
+		}
+	})
+	return r.client
+}
+
+// dockerContext returns the context the docker CLI would use, resolved the
+// way it does: DOCKER_HOST selects the default context, then DOCKER_CONTEXT,
+// then the currentContext set by `docker context use`.
+func dockerContext() string {
+	if strings.TrimSpace(os.Getenv("DOCKER_HOST")) != "" {
+		return "default"
+	}
+	if ctx := strings.TrimSpace(os.Getenv("DOCKER_CONTEXT")); ctx != "" {
+		return ctx
+	}
+	dir := os.Getenv("DOCKER_CONFIG")
+	if dir == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "default"
+		}
+		dir = filepath.Join(home, ".docker")
+	}
+	data, err := os.ReadFile(filepath.Join(dir, "config.json"))
+	if err != nil {
+		return "default"
+	}
+	var cfg struct {
+		CurrentContext string `json:"currentContext"`
+	}
+	if err := json.Unmarshal(data, &cfg); err != nil || strings.TrimSpace(cfg.CurrentContext) == "" {
+		return "default"
+	}
+	return strings.TrimSpace(cfg.CurrentContext)
+}
+
+func (r *apiRuntime) Exists(name string) bool {
+	if c := r.engine(); c != nil {
+		_, err := c.inspectContainer(context.Background(), name)
+		return err == nil
+	}
+	return r.cliRuntime.Exists(name)
+}
+
+func (r *apiRuntime) Running(name string) bool {
+	if c := r.engine(); c != nil {
+		ctr, err := c.inspectContainer(context.Background(), name)
+		return err == nil && ctr.State.Running
+	}
+	return r.cliRuntime.Running(name)
+}
+
+func (r *apiRuntime) Start(name string) error {
+	if c := r.engine(); c != nil {
+		r.verbose("start", name)
+		return c.startContainer(context.Background(), name)
+	}
+	return r.cliRuntime.Start(name)
+}
+
+func (r *apiRuntime) Stop(name string) error {
+	if c := r.engine(); c != nil {
+		r.verbose("stop", name)
+		return c.stopContainer(context.Background(), name)
+	}
+	return r.cliRuntime.Stop(name)
+}
+
+func (r *apiRuntime) Remove(name string, force bool) error {
+	if c := r.engine(); c != nil {
+		r.verbose("rm", name)
+		return c.removeContainer(context.Background(), name, force)
+	}
+	return r.cliRuntime.Remove(name, force)
+}
+
+func (r *apiRuntime) Rename(oldName, newName string) error {
+	if c := r.engine(); c != nil {
+		r.verbose("rename", oldName, newName)
+		return c.renameContainer(context.Background(), oldName, newName)
+	}
+	return r.cliRuntime.Rename(oldName, newName)
+}
+
+func (r *apiRuntime) Inspect(name string) (*ContainerInfo, error) {
+	if c := r.engine(); c != nil {
+		ctr, err := c.inspectContainer(context.Background(), name)
+		if err != nil {
+			return nil, err
+		}
+		return ctr.info(), nil
+	}
+	return r.cliRuntime.Inspect(name)
+}
+
+func (r *apiRuntime) List() ([]ContainerSummary, error) {
+	c := r.engine()
+	if c == nil {
+		return r.cliRuntime.List()
+	}
+	rows, err := c.listContainers(context.Background(), true)
+	if err != nil {
+		return nil, err
+	}
+	list := make([]ContainerSummary, 0, len(rows))
+	for _, row := range rows {
+		s := ContainerSummary{
+			Image:     row.Image,
+			Running:   row.State == "running",
+			Status:    row.Status,
+			Labels:    row.Labels,
+			CreatedAt: time.Unix(row.Created, 0),
+		}
+		if len(row.Names) > 0 {
+			s.Name = strings.TrimPrefix(row.Names[0], "/")
+		}
+		var ports []string
+		for _, p := range row.Ports {
+			ports = append(ports, formatPort(p.IP, p.PublicPort, p.PrivatePort, p.Type))
+		}
+		s.Ports = strings.Join(ports, ", ")
+		if s.Labels == nil {
+			s.Labels = map[string]string{}
+		}
+		list = append(list, s)
+	}
+	return list, nil
+}
+
+func (r *apiRuntime) Commit(name, imageTag string, labels map[string]string) error {
+	if c := r.engine(); c != nil {
+		return c.commitContainer(context.Background(), name, imageTag, labels)
+	}
+	return r.cliRuntime.Commit(name, imageTag, labels)
+}
+
+// AllocatedPorts inspects every container over the shared connection;
+// stopped containers only expose their bindings through inspect.
+func (r *apiRuntime) AllocatedPorts() (map[int]bool, error) {
+	c := r.engine()
+	if c == nil {
+		return r.cliRuntime.AllocatedPorts()
+	}
+	ctx := context.Background()
+	list, err := c.listContainers(ctx, true)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list containers: %w", err)
+	}
+	ports := make(map[int]bool)
+	for _, summary := range list {
+		ctr, err := c.inspectContainer(ctx, summary.ID)
+		if err != nil {
+			continue // skip containers removed mid-scan
+		}
+		for _, p := range ctr.hostPorts() {
+			ports[p] = true
+		}
+	}
+	return ports, nil
+}
+
+func (r *apiRuntime) Exec(ctx context.Context, name string, opts ExecOptions) (string, error) {
+	if c := r.engine(); c != nil {
+		out, err := c.exec(ctx, name, opts.User, opts.Workdir, opts.Envs, opts.Argv, opts.Combined, opts.Stdout)
+		return string(out), err
+	}
+	return r.cliRuntime.Exec(ctx, name, opts)
+}
+
+func (r *apiRuntime) CopyTo(ctx context.Context, name, srcOnHost, dstInContainer string) error {
+	if c := r.engine(); c != nil {
+		return c.copyToContainer(ctx, name, srcOnHost, dstInContainer)
+	}
+	return r.cliRuntime.CopyTo(ctx, name, srcOnHost, dstInContainer)
+}
+
+func (r *apiRuntime) CopyFrom(ctx context.Context, name, srcInContainer, dstOnHost string) error {
+	if c := r.engine(); c != nil {
+		return c.copyFromContainer(ctx, name, srcInContainer, dstOnHost)
+	}
+	return r.cliRuntime.CopyFrom(ctx, name, srcInContainer, dstOnHost)
+}
+
+func (r *apiRuntime) ImageExists(tag string) bool {
+	if c := r.engine(); c != nil {
+		ok, err := c.imageExists(context.Background(), tag)
+		return err == nil && ok
+	}
+	return r.cliRuntime.ImageExists(tag)
+}
+
+func (r *apiRuntime) ContainerSize(name string) (int64, error) {
+	if c := r.engine(); c != nil {
+		return c.containerSize(context.Background(), name)
+	}
+	return r.cliRuntime.ContainerSize(name)
+}
+
+func (r *apiRuntime) ListImages(label string) ([]ImageSummary, error) {
+	c := r.engine()
+	if c == nil {
+		return r.cliRuntime.ListImages(label)
+	}
+	rows, err := c.listImages(context.Background(), label)
+	if err != nil {
+		return nil, err
+	}
+	list := make([]ImageSummary, 0, len(rows))
+	for _, row := range rows {
+		img := ImageSummary{
+			ID:        row.ID,
+			Labels:    row.Labels,
+			CreatedAt: time.Unix(row.Created, 0),
+			Size:      row.Size,
+		}
+		for _, t := range row.RepoTags {
+			if t != "<none>:<none>" {
+				img.Tags = append(img.Tags, t)
+			}
+		}
+		if img.Labels == nil {
+			img.Labels = map[string]string{}
+		}
+		list = append(list, img)
+	}
+	return list, nil
+}
+
+func (r *apiRuntime) SaveImage(ref string, w io.Writer) error {
+	if c := r.engine(); c != nil {
+		r.verbose("save", ref)
+		return c.saveImage(context.Background(), ref, w)
+	}
+	return r.cliRuntime.SaveImage(ref, w)
+}
+
+func (r *apiRuntime) LoadImage(rd io.Reader) ([]string, error) {
+	if c := r.engine(); c != nil {
+		r.verbose("load")
+		return c.loadImage(context.Background(), rd)
+	}
+	return r.cliRuntime.LoadImage(rd)
+}
+
+func (r *apiRuntime) RemoveImage(tag string, force bool) error {
+	if c := r.engine(); c != nil {
+		r.verbose("rmi", tag)
+		return c.removeImage(context.Background(), tag, force)
+	}
+	return r.cliRuntime.RemoveImage(tag, force)
+}
+
+func (r *apiRuntime) TagImage(srcTag, dstTag string) error {
+	if c := r.engine(); c != nil {
+		r.verbose("tag", srcTag, dstTag)
+		return c.tagImage(context.Background(), srcTag, dstTag)
+	}
+	return r.cliRuntime.TagImage(srcTag, dstTag)
+}
+
+func (r *apiRuntime) CreateVolume(name string, labels map[string]string) error {
+	if c := r.engine(); c != nil {
+		r.verbose("volume", "create", name)
+		return c.createVolume(context.Background(), name, labels)
+	}
+	return r.cliRuntime.CreateVolume(name, labels)
+}
+
+func (r *apiRuntime) ListVolumes() ([]VolumeSummary, error) {
+	c := r.engine()
+	if c == nil {
+		return r.cliRuntime.ListVolumes()
+	}
+	rows, err := c.listVolumes(context.Background())
+	if err != nil {
+		return nil, err
+	}
+	list := make([]VolumeSummary, 0, len(rows))
+	for _, row := range rows {
+		v := VolumeSummary{Name: row.Name, Labels: row.Labels}
+		if v.Labels == nil {
+			v.Labels = map[string]string{}
+		}
+		if t, err := time.Parse(time.RFC3339, row.CreatedAt); err == nil {
+			v.CreatedAt = t
+		}
+		list = append(list, v)
+	}
+	return list, nil
+}
+
+func (r *apiRuntime) RemoveVolume(name string) error {
+	if c := r.engine(); c != nil {
+		r.verbose("volume", "rm", name)
+		return c.removeVolume(context.Background(), name)
+	}
+	return r.cliRuntime.RemoveVolume(name)
+}
+
+func (r *apiRuntime) Events(ctx context.Context, fn func(ContainerEvent)) error {
+	if c := r.engine(); c != nil {
+		return c.events(ctx, fn)
+	}
+	return r.cliRuntime.Events(ctx, fn)
+}
+
+func (r *apiRuntime) CPUPercent(name string) (float64, error) {
+	if c := r.engine(); c != nil {
+		return c.cpuPercent(context.Background(), name)
+	}
+	return r.cliRuntime.CPUPercent(name)
+}
+
+func (r *apiRuntime) VolumeSizes() (map[string]int64, error) {
+	if c := r.engine(); c != nil {
+		return c.volumeSizes(context.Background())
+	}
+	return r.cliRuntime.VolumeSizes()
+}
+
+// candidateDockerHosts lists daemon addresses to probe, honouring DOCKER_HOST.
+func candidateDockerHosts() []string {
+	if host := strings.TrimSpace(os.Getenv("DOCKER_HOST")); host != "" {
+		return []string{host}
+	}
+	hosts := []string{"unix:///var/run/docker.sock"}
+	if home, err := os.UserHomeDir(); err == nil {
+		hosts = append(hosts,
+			"unix://"+filepath.Join(home, ".docker", "run", "docker.sock"),
+			"unix://"+filepath.Join(home, ".orbstack", "run", "docker.sock"),
+		)
+	}
+	return hosts
+}
+
+// candidatePodmanHosts lists Podman's Docker-compatible API sockets,
+// honouring CONTAINER_HOST and preferring the rootless user socket.
+func candidatePodmanHosts() []string {
+	if host := strings.TrimSpace(os.Getenv("CONTAINER_HOST")); host != "" {
+		return []string{host}
+	}
+	var hosts []string
+	if dir := os.Getenv("XDG_RUNTIME_DIR"); dir != "" {
+		hosts = append(hosts, "unix://"+filepath.Join(dir, "podman", "podman.sock"))
+	}
+	return append(hosts, "unix:///run/podman/podman.sock")
+}
+
+// info converts raw inspect data to the runtime-neutral ContainerInfo.
+func (cj *containerJSON) info() *ContainerInfo {
+	info := &ContainerInfo{
+		ID:         cj.ID,
+		Name:       strings.TrimPrefix(cj.Name, "/"),
+		Image:      cj.Config.Image,
+		Running:    cj.State.Running,
+		WorkingDir: cj.Config.WorkingDir,
+		Env:        cj.Config.Env,
+		Labels:     cj.Config.Labels,
+		Ports:      map[int]int{},
+		IPAddress:  cj.ipAddress(),
+
+		ExecSessions: len(cj.ExecIDs),
+	}
+	if info.Labels == nil {
+		info.Labels = map[string]string{}
+	}
+	// Docker reports 0001-01-01T00:00:00Z for a container never stopped.
+	if t, err := time.Parse(time.RFC3339Nano, cj.State.FinishedAt); err == nil && t.Year() > 1 {
+		info.FinishedAt = t
+	}
+	for key, bindings := range cj.HostConfig.PortBindings {
+		if len(bindings) == 0 || !strings.HasSuffix(key, "/tcp") {
+			continue
+		}
+		var containerPort, hostPort int
+		if _, err := fmt.Sscanf(key, "%d/tcp", &containerPort); err != nil {
+			continue
+		}
+		if _, err := fmt.Sscanf(bindings[0].HostPort, "%d", &hostPort); err != nil {
+			continue
+		}
+		info.Ports[containerPort] = hostPort
+	}
+	return info
+}
+
+// sortedKeys returns map keys in lexical order.
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,211 @@
+package docker
+
+import (
+	"archive/tar"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// containerPathStat mirrors the X-Docker-Container-Path-Stat header.
+type containerPathStat struct {
+	Name       string      `json:"name"`
+	Size       int64       `json:"size"`
+	Mode       os.FileMode `json:"mode"`
+	LinkTarget string      `json:"linkTarget"`
+}
+
+func (c *engineClient) statPath(ctx context.Context, name, p string) (*containerPathStat, error) {
+	q := url.Values{"path": {p}}
+	resp, err := c.do(ctx, http.MethodHead, "/containers/"+url.PathEscape(name)+"/archive", q, nil, "")
+	if err != nil {
+		return nil, err
+	}
+	resp.Body.Close()
+	return decodePathStat(resp.Header.Get("X-Docker-Container-Path-Stat"))
+}
+
+func decodePathStat(header string) (*containerPathStat, error) {
+	if header == "" {
+		return nil, fmt.Errorf("missing path stat header")
+	}
+	data, err := base64.StdEncoding.DecodeString(header)
+	if err != nil {
+		return nil, err
+	}
+	var st containerPathStat
+	if err := json.Unmarshal(data, &st); err != nil {
+		return nil, err
+	}
+	return &st, nil
+}
+
+// copyToContainer follows `docker cp SRC NAME:DST` semantics: an existing
+// directory destination receives SRC by its base name, otherwise SRC is
+// written as DST itself.
+func (c *engineClient) copyToContainer(ctx context.Context, name, srcOnHost, dstInContainer string) error {
+	if _, err := os.Lstat(srcOnHost); err != nil {
+		return err
+	}
+	extractDir, entryName := path.Dir(path.Clean(dstInContainer)), path.Base(dstInContainer)
+	st, err := c.statPath(ctx, name, dstInContainer)
+	switch {
+	case err == nil && st.Mode.IsDir():
+		extractDir, entryName = dstInContainer, filepath.Base(srcOnHost)
+	case err == nil:
+		// existing file is overwritten in place
+	case IsNotFound(err):
+		if strings.HasSuffix(dstInContainer, "/") {
+			return fmt.Errorf("destination directory %s does not exist in container %s", dstInContainer, name)
+		}
+	default:
+		return err
+	}
+	if strings.HasSuffix(srcOnHost, string(filepath.Separator)+".") {
+		// "dir/." copies the directory's contents into the destination
+		entryName = "."
+		extractDir = dstInContainer
+	}
+
+	pr, pw := io.Pipe()
+	go func() {
+		pw.CloseWithError(writeTar(pw, srcOnHost, entryName))
+	}()
+	q := url.Values{"path": {extractDir}}
+	resp, err := c.do(ctx, http.MethodPut, "/containers/"+url.PathEscape(name)+"/archive", q, pr, "application/x-tar")
+	pr.Close()
+	if err != nil {
+		return err
+	}
+	_, _ = io.Copy(io.Discard, resp.Body)
+	return resp.Body.Close()
+}
+
+// writeTar streams srcOnHost into a tar archive rooted at entryName. Symlinks
+// are stored as links rather than followed, and ownership is reset to root
+// as `docker cp` does without --archive.
+func writeTar(w io.Writer, srcOnHost, entryName string) error {
+	tw := tar.NewWriter(w)
+	root := filepath.Clean(srcOnHost)
+	err := filepath.Walk(root, func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(root, p)
+		if err != nil {
+			return err
+		}
+		link := ""
+		if info.Mode()&os.ModeSymlink != 0 {
+			if link, err = os.Readlink(p); err != nil {
+				return err
+			}
+		}
+		hdr, err := tar.FileInfoHeader(info, link)
+		if err != nil {
+			return err
+		}
+		hdr.Name = path.Join(entryName, filepath.ToSlash(rel))
+		if info.IsDir() {
+			hdr.Name += "/"
+		}
+		hdr.Uid, hdr.Gid, hdr.Uname, hdr.Gname = 0, 0, "", ""
+		if err := tw.WriteHeader(hdr); err != nil {
+			return err
+		}
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+		f, err := os.Open(p)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		_, err = io.Copy(tw, f)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	return tw.Close()
+}
+
+// copyFromContainer follows `docker cp NAME:SRC DST` semantics: an existing
+// host directory receives SRC by its base name, otherwise SRC is written as
+// DST. A SRC ending in "/." copies the directory contents into DST.
+func (c *engineClient) copyFromContainer(ctx context.Context, name, srcInContainer, dstOnHost string) error {
+	q := url.Values{"path": {srcInContainer}}
+	resp, err := c.do(ctx, http.MethodGet, "/containers/"+url.PathEscape(name)+"/archive", q, nil, "")
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	target := dstOnHost
+	contents := strings.HasSuffix(srcInContainer, "/.")
+	if st, err := os.Stat(dstOnHost); err == nil && st.IsDir() && !contents {
+		target = filepath.Join(dstOnHost, path.Base(srcInContainer))
+	}
+	return extractTar(resp.Body, target)
+}
+
+// extractTar writes an archive whose entries share a single top-level element
+// so that element lands at target.
+func extractTar(r io.Reader, target string) error {
+	tr := tar.NewReader(r)
+	target = filepath.Clean(target)
+	top := ""
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		name := strings.TrimPrefix(hdr.Name, "/")
+		if top == "" {
+			top = strings.SplitN(name, "/", 2)[0]
+		}
+		rel := path.Clean("/" + strings.TrimPrefix(name, top))
+		dst := filepath.Join(target, filepath.FromSlash(rel))
+		if dst != target && !strings.HasPrefix(dst, target+string(filepath.Separator)) {
+			return fmt.Errorf("archive entry %q escapes destination", hdr.Name)
+		}
+		mode := os.FileMode(hdr.Mode).Perm()
+		switch hdr.Typeflag {
+		case tar.TypeDir:
+			if err := os.MkdirAll(dst, mode|0o700); err != nil {
+				return err
+			}
+		case tar.TypeSymlink:
+			_ = os.Remove(dst)
+			if err := os.Symlink(hdr.Linkname, dst); err != nil {
+				return err
+			}
+		case tar.TypeReg:
+			if err := os.MkdirAll(filepath.Dir(dst), 0o755); err != nil {
+				return err
+			}
+			f, err := os.OpenFile(dst, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, mode)
+			if err != nil {
+				return err
+			}
+			if _, err := io.Copy(f, tr); err != nil {
+				f.Close()
+				return err
+			}
+			if err := f.Close(); err != nil {
+				return err
+			}
+		}
+	}
+}
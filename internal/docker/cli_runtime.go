@@ -0,0 +1,854 @@
+package docker
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"os/exec"
+	"regexp"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/term"
+)
+
+// cliRuntime drives a docker-compatible CLI (docker or podman) by shelling
+// out. It is the fallback whenever the engine API socket is unavailable.
+type cliRuntime struct {
+	bin string
+}
+
+func (r cliRuntime) Name() string   { return r.bin }
+func (r cliRuntime) Binary() string { return r.bin }
+
+func (r cliRuntime) isPodman() bool { return r.bin == RuntimePodman }
+
+func (r cliRuntime) verbose(args ...string) {
+	if isTruthyEnv("DV_VERBOSE") {
+		fmt.Fprintf(os.Stderr, "Running: %s %s\n", r.bin, strings.Join(args, " "))
+	}
+}
+
+// run executes the CLI with output wired to the terminal.
+func (r cliRuntime) run(args ...string) error {
+	r.verbose(args...)
+	cmd := exec.Command(r.bin, args...)
+	cmd.Stdout, cmd.Stderr = os.Stdout, os.Stderr
+	return cmd.Run()
+}
+
+func (r cliRuntime) Exists(name string) bool {
+	out, _ := exec.Command("bash", "-lc", r.bin+" ps -aq -f name=^"+shellEscape(name)+"$").Output()
+	return strings.TrimSpace(string(out)) != ""
+}
+
+func (r cliRuntime) Running(name string) bool {
+	out, _ := exec.Command("bash", "-lc", r.bin+" ps -q -f status=running -f name=^"+shellEscape(name)+"$").Output()
+	return strings.TrimSpace(string(out)) != ""
+}
+
+func (r cliRuntime) Start(name string) error { return r.run("start", name) }
+func (r cliRuntime) Stop(name string) error  { return r.run("stop", name) }
+
+func (r cliRuntime) Remove(name string, force bool) error {
+	if force {
+		return r.run("rm", "-f", name)
+	}
+	return r.run("rm", name)
+}
+
+func (r cliRuntime) Rename(oldName, newName string) error {
+	return r.run("rename", oldName, newName)
+}
+
+func (r cliRuntime) Pull(ref string) error { return r.run("pull", ref) }
+
+func (r cliRuntime) TagImage(srcTag, dstTag string) error {
+	return r.run("tag", srcTag, dstTag)
+}
+
+func (r cliRuntime) ImageExists(tag string) bool {
+	out, _ := exec.Command("bash", "-lc", r.bin+" images -q "+shellEscape(tag)).Output()
+	return strings.TrimSpace(string(out)) != ""
+}
+
+// RemoveImage removes an image. Forced removal is also quiet, since it is
+// used for best-effort cleanup where failure is acceptable.
+func (r cliRuntime) RemoveImage(tag string, force bool) error {
+	if !force {
+		return r.run("rmi", tag)
+	}
+	r.verbose("rmi", "-f", tag)
+	cmd := exec.Command(r.bin, "rmi", "-f", tag)
+	cmd.Stdout, cmd.Stderr = io.Discard, io.Discard
+	return cmd.Run()
+}
+
+func (r cliRuntime) Commit(name, imageTag string, labels map[string]string) error {
+	args := []string{"commit"}
+	for _, change := range labelChanges(labels) {
+		args = append(args, "--change", change)
+	}
+	args = append(args, name, imageTag)
+	r.verbose(args...)
+	cmd := exec.Command(r.bin, args...)
+	cmd.Stdout, cmd.Stderr = os.Stdout, os.Stderr
+	return cmd.Run()
+}
+
+func (r cliRuntime) UpdateLabels(name string, labels map[string]string) error {
+	if len(labels) == 0 {
+		return nil
+	}
+	args := []string{"update"}
+	for k, v := range labels {
+		args = append(args, "--label-add", fmt.Sprintf("%s=%s", k, v))
+	}
+	args = append(args, name)
+	cmd := exec.Command(r.bin, args...)
+	cmd.Stdout, cmd.Stderr = os.Stdout, os.Stderr
+	return cmd.Run()
+}
+
+func (r cliRuntime) SaveImage(ref string, w io.Writer) error {
+	r.verbose("save", ref)
+	cmd := exec.Command(r.bin, "save", ref)
+	cmd.Stdout, cmd.Stderr = w, os.Stderr
+	return cmd.Run()
+}
+
+func (r cliRuntime) LoadImage(rd io.Reader) ([]string, error) {
+	r.verbose("load")
+	var out bytes.Buffer
+	cmd := exec.Command(r.bin, "load")
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = rd, &out, os.Stderr
+	if err := cmd.Run(); err != nil {
+		return nil, err
+	}
+	return loadedImageRefs(out.String()), nil
+}
+
+// loadedImageRefs reads the tags from `docker load` output. Docker prints
+// "Loaded image: REF" per tag; podman prints "Loaded image(s): REF,REF".
+// Untagged images ("Loaded image ID: ...") are skipped.
+func loadedImageRefs(out string) []string {
+	var refs []string
+	for _, line := range strings.Split(out, "\n") {
+		line = strings.TrimSpace(line)
+		list, ok := strings.CutPrefix(line, "Loaded image: ")
+		if !ok {
+			list, ok = strings.CutPrefix(line, "Loaded image(s): ")
+		}
+		if !ok {
+			continue
+		}
+		for _, ref := range strings.Split(list, ",") {
+			if ref = strings.TrimSpace(ref); ref != "" {
+				refs = append(refs, ref)
+			}
+		}
+	}
+	return refs
+}
+
+// cliImageJSON is the subset of `image inspect` output dv reads; docker and
+// podman agree on it.
+type cliImageJSON struct {
+	ID       string    `json:"Id"`
+	RepoTags []string  `json:"RepoTags"`
+	Created  time.Time `json:"Created"`
+	Size     int64     `json:"Size"`
+	Config   struct {
+		Labels map[string]string `json:"Labels"`
+	} `json:"Config"`
+}
+
+// ContainerSize reports the size of the container's writable layer.
+func (r cliRuntime) ContainerSize(name string) (int64, error) {
+	out, err := exec.Command(r.bin, "container", "inspect", "--size", "--format", "{{.SizeRw}}", name).Output()
+	if err != nil {
+		return 0, err
+	}
+	return strconv.ParseInt(strings.TrimSpace(string(out)), 10, 64)
+}
+
+// ListImages lists matching image IDs, then inspects them for labels, which
+// `docker images` does not print.
+func (r cliRuntime) ListImages(label string) ([]ImageSummary, error) {
+	args := []string{"images", "-q", "--no-trunc"}
+	if label != "" {
+		args = append(args, "--filter", "label="+label)
+	}
+	out, err := exec.Command(r.bin, args...).Output()
+	if err != nil {
+		return nil, err
+	}
+	ids := uniqueFields(string(out))
+	if len(ids) == 0 {
+		return nil, nil
+	}
+	out, err = exec.Command(r.bin, append([]string{"image", "inspect"}, ids...)...).Output()
+	if err != nil {
+		return nil, err
+	}
+	var rows []cliImageJSON
+	if err := json.Unmarshal(out, &rows); err != nil {
+		return nil, err
+	}
+	list := make([]ImageSummary, 0, len(rows))
+	for _, row := range rows {
+		img := ImageSummary{ID: row.ID, Tags: row.RepoTags, Labels: row.Config.Labels, CreatedAt: row.Created, Size: row.Size}
+		if img.Labels == nil {
+			img.Labels = map[string]string{}
+		}
+		list = append(list, img)
+	}
+	return list, nil
+}
+
+// uniqueFields splits s on whitespace, dropping repeats; `docker images -q`
+// prints an ID once per tag.
+func uniqueFields(s string) []string {
+	var out []string
+	seen := map[string]bool{}
+	for _, f := range strings.Fields(s) {
+		if !seen[f] {
+			seen[f] = true
+			out = append(out, f)
+		}
+	}
+	return out
+}
+
+// labelChanges renders labels as Dockerfile LABEL instructions for commit.
+func labelChanges(labels map[string]string) []string {
+	changes := make([]string, 0, len(labels))
+	for _, k := range sortedKeys(labels) {
+		v := strings.NewReplacer("\\", "\\\\", `"`, `\"`, "\n", " ").Replace(labels[k])
+		changes = append(changes, fmt.Sprintf(`LABEL %s="%s"`, k, v))
+	}
+	return changes
+}
+
+func (r cliRuntime) inspect(names ...string) ([]containerJSON, error) {
+	args := append([]string{"container", "inspect"}, names...)
+	out, err := exec.Command(r.bin, args...).Output()
+	if err != nil {
+		return nil, err
+	}
+	var list []containerJSON
+	if err := json.Unmarshal(out, &list); err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+func (r cliRuntime) Inspect(name string) (*ContainerInfo, error) {
+	list, err := r.inspect(name)
+	if err != nil {
+		return nil, err
+	}
+	if len(list) == 0 {
+		return nil, fmt.Errorf("no such container: %s", name)
+	}
+	return list[0].info(), nil
+}
+
+// AllocatedPorts returns a set of all host ports currently allocated by
+// containers (running or stopped). Containers are inspected in one batch and
+// one-by-one if that fails, so a single malformed container doesn't abort.
+func (r cliRuntime) AllocatedPorts() (map[int]bool, error) {
+	out, err := exec.Command(r.bin, "ps", "-aq").Output()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list containers: %w", err)
+	}
+	ids := strings.Fields(string(out))
+	ports := make(map[int]bool)
+	if len(ids) == 0 {
+		return ports, nil
+	}
+	list, err := r.inspect(ids...)
+	if err != nil {
+		list = nil
+		for _, id := range ids {
+			one, err := r.inspect(id)
+			if err != nil {
+				continue // skip malformed or missing containers
+			}
+			list = append(list, one...)
+		}
+	}
+	for i := range list {
+		for _, p := range list[i].hostPorts() {
+			ports[p] = true
+		}
+	}
+	return ports, nil
+}
+
+// cliPSRow is one line of `docker ps --format '{{json .}}'`.
+type cliPSRow struct {
+	Names     string `json:"Names"`
+	Image     string `json:"Image"`
+	State     string `json:"State"`
+	Status    string `json:"Status"`
+	Ports     string `json:"Ports"`
+	Labels    string `json:"Labels"`
+	CreatedAt string `json:"CreatedAt"`
+}
+
+// podmanPSRow is one element of `podman ps --format json`.
+type podmanPSRow struct {
+	Names  []string          `json:"Names"`
+	Image  string            `json:"Image"`
+	State  string            `json:"State"`
+	Status string            `json:"Status"`
+	Labels map[string]string `json:"Labels"`
+	Ports  []struct {
+		HostIP        string `json:"host_ip"`
+		ContainerPort int    `json:"container_port"`
+		HostPort      int    `json:"host_port"`
+		Protocol      string `json:"protocol"`
+	} `json:"Ports"`
+	Created int64 `json:"Created"`
+}
+
+func (r cliRuntime) List() ([]ContainerSummary, error) {
+	if r.isPodman() {
+		out, err := exec.Command(r.bin, "ps", "-a", "--format", "json").Output()
+		if err != nil {
+			return nil, err
+		}
+		var rows []podmanPSRow
+		if err := json.Unmarshal(out, &rows); err != nil {
+			return nil, err
+		}
+		list := make([]ContainerSummary, 0, len(rows))
+		for _, row := range rows {
+			s := ContainerSummary{
+				Image:     row.Image,
+				Running:   row.State == "running",
+				Status:    row.Status,
+				Labels:    row.Labels,
+				CreatedAt: time.Unix(row.Created, 0),
+			}
+			if len(row.Names) > 0 {
+				s.Name = row.Names[0]
+			}
+			var ports []string
+			for _, p := range row.Ports {
+				ports = append(ports, formatPort(p.HostIP, p.HostPort, p.ContainerPort, p.Protocol))
+			}
+			s.Ports = strings.Join(ports, ", ")
+			if s.Labels == nil {
+				s.Labels = map[string]string{}
+			}
+			list = append(list, s)
+		}
+		return list, nil
+	}
+
+	out, err := exec.Command(r.bin, "ps", "-a", "--format", "{{json .}}").Output()
+	if err != nil {
+		return nil, err
+	}
+	var list []ContainerSummary
+	scanner := bufio.NewScanner(strings.NewReader(string(out)))
+	scanner.Buffer(make([]byte, 0, 64*1024), 4*1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		var row cliPSRow
+		if err := json.Unmarshal([]byte(line), &row); err != nil {
+			continue
+		}
+		list = append(list, ContainerSummary{
+			Name:      row.Names,
+			Image:     row.Image,
+			Running:   row.State == "running",
+			Status:    row.Status,
+			Ports:     row.Ports,
+			Labels:    parseLabelList(row.Labels),
+			CreatedAt: parseCLITime(row.CreatedAt),
+		})
+	}
+	return list, scanner.Err()
+}
+
+func (r cliRuntime) Exec(ctx context.Context, name string, opts ExecOptions) (string, error) {
+	args := []string{"exec"}
+	args = append(args, execFlags(opts)...)
+	args = append(args, name)
+	args = append(args, opts.Argv...)
+	cmd := exec.CommandContext(ctx, r.bin, args...)
+	if opts.Stdout != nil {
+		cmd.Stdout = opts.Stdout
+		if opts.Combined {
+			cmd.Stderr = opts.Stdout
+		}
+		return "", cmd.Run()
+	}
+	if opts.Combined {
+		out, err := cmd.CombinedOutput()
+		return string(out), err
+	}
+	out, err := cmd.Output()
+	return string(out), err
+}
+
+func (r cliRuntime) ExecInteractive(name string, opts ExecOptions) error {
+	args := []string{"exec", "-i"}
+	// Add -t only when both stdin and stdout are TTYs
+	if term.IsTerminal(int(os.Stdin.Fd())) && term.IsTerminal(int(os.Stdout.Fd())) {
+		args = []string{"exec", "-t", "-i"}
+	}
+	args = append(args, execFlags(opts)...)
+	args = append(args, name)
+	args = append(args, opts.Argv...)
+	cmd := exec.Command(r.bin, args...)
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+	return cmd.Run()
+}
+
+func execFlags(opts ExecOptions) []string {
+	var args []string
+	if opts.User != "" {
+		args = append(args, "--user", opts.User)
+	}
+	if opts.Workdir != "" {
+		args = append(args, "-w", opts.Workdir)
+	}
+	for _, e := range opts.Envs {
+		args = append(args, "-e", e)
+	}
+	return args
+}
+
+func (r cliRuntime) copy(ctx context.Context, src, dst string) error {
+	cmd := exec.CommandContext(ctx, r.bin, "cp", src, dst)
+	if isTruthyEnv("DV_VERBOSE") {
+		cmd.Stdout = os.Stdout
+	} else {
+		cmd.Stdout = io.Discard
+	}
+	cmd.Stderr = os.Stderr
+	return cmd.Run()
+}
+
+func (r cliRuntime) CopyTo(ctx context.Context, name, srcOnHost, dstInContainer string) error {
+	return r.copy(ctx, srcOnHost, fmt.Sprintf("%s:%s", name, dstInContainer))
+}
+
+func (r cliRuntime) CopyFrom(ctx context.Context, name, srcInContainer, dstOnHost string) error {
+	return r.copy(ctx, fmt.Sprintf("%s:%s", name, srcInContainer), dstOnHost)
+}
+
+func (r cliRuntime) RunDetached(opts RunOptions) error {
+	args := []string{"run", "-d",
+		"--name", opts.Name,
+		"-w", opts.Workdir,
+		"-p", fmt.Sprintf("127.0.0.1:%d:%d", opts.HostPort, opts.ContainerPort),
+	}
+	for _, p := range opts.ExtraPorts {
+		args = append(args, "-p", fmt.Sprintf("127.0.0.1:%d:%d", p.HostPort, p.ContainerPort))
+	}
+	// hostSSHAuthSock tracks what SSH_AUTH_SOCK should be on the host for Docker to forward
+	sshAuthSock := opts.SSHAuthSock
+	hostSSHAuthSock := sshAuthSock
+	if sshAuthSock != "" {
+		var mountPath string
+		var socketSource string
+		if runtime.GOOS == "darwin" && !r.isPodman() {
+			// On macOS, Docker Desktop/OrbStack provide a magic socket that forwards
+			// the host's SSH agent. We always mount this path.
+			mountPath = "/run/host-services/ssh-auth.sock"
+
+			// Check if there's an IdentityAgent configured (e.g., 1Password).
+			// If so, we need to tell Docker to use that socket instead of SSH_AUTH_SOCK.
+			identityAgent := getIdentityAgent()
+			if identityAgent != "" {
+				hostSSHAuthSock = identityAgent
+				socketSource = "IdentityAgent from ~/.ssh/config"
+			} else {
+				socketSource = "SSH_AUTH_SOCK"
+			}
+		} else {
+			// On Linux (and with Podman everywhere), mount the host socket directly
+			mountPath = sshAuthSock
+			socketSource = "SSH_AUTH_SOCK"
+		}
+		if isTruthyEnv("DV_VERBOSE") {
+			fmt.Fprintf(os.Stderr, "SSH agent: forwarding %s (%s)\n", hostSSHAuthSock, socketSource)
+			// Check if socket exists on host
+			if _, err := os.Stat(hostSSHAuthSock); err != nil {
+				fmt.Fprintf(os.Stderr, "SSH agent: WARNING - socket does not exist: %v\n", err)
+			} else {
+				fmt.Fprintf(os.Stderr, "SSH agent: socket exists at %s\n", hostSSHAuthSock)
+			}
+		}
+		args = append(args, "-v", mountPath+":/tmp/ssh-agent.sock")
+		args = append(args, "-e", "SSH_AUTH_SOCK=/tmp/ssh-agent.sock")
+	}
+	for _, v := range opts.Volumes {
+		args = append(args, "-v", v.Name+":"+v.Path)
+	}
+	// Apply resource limits
+	if opts.CPUs != "" {
+		args = append(args, "--cpus", opts.CPUs)
+	}
+	if opts.Memory != "" {
+		args = append(args, "--memory", opts.Memory)
+	}
+	if opts.PidsLimit != 0 {
+		args = append(args, "--pids-limit", strconv.Itoa(opts.PidsLimit))
+	}
+	if opts.ShmSize != "" {
+		args = append(args, "--shm-size", opts.ShmSize)
+	}
+	// Apply extra hosts
+	for _, h := range opts.ExtraHosts {
+		args = append(args, "--add-host", h)
+	}
+	// Apply environment variables
+	for k, v := range opts.Envs {
+		if strings.TrimSpace(k) == "" || strings.Contains(k, "\n") {
+			continue
+		}
+		args = append(args, "-e", fmt.Sprintf("%s=%s", k, v))
+	}
+	// Apply labels for provenance and discovery
+	for k, v := range opts.Labels {
+		if strings.TrimSpace(k) == "" || strings.Contains(k, "\n") {
+			continue
+		}
+		args = append(args, "--label", fmt.Sprintf("%s=%s", k, v))
+	}
+	args = append(args, opts.Image, "--sysctl", "kernel.unprivileged_userns_clone=1")
+	r.verbose(args...)
+	cmd := exec.Command(r.bin, args...)
+	cmd.Stdout, cmd.Stderr = os.Stdout, os.Stderr
+	// If we detected a different SSH agent (e.g., 1Password), set SSH_AUTH_SOCK
+	// in the docker command's environment so Docker Desktop/OrbStack forwards it
+	if hostSSHAuthSock != "" && hostSSHAuthSock != sshAuthSock {
+		// Filter out existing SSH_AUTH_SOCK and replace with our value
+		env := os.Environ()
+		filteredEnv := make([]string, 0, len(env))
+		for _, e := range env {
+			if !strings.HasPrefix(e, "SSH_AUTH_SOCK=") {
+				filteredEnv = append(filteredEnv, e)
+			}
+		}
+		cmd.Env = append(filteredEnv, "SSH_AUTH_SOCK="+hostSSHAuthSock)
+		if isTruthyEnv("DV_VERBOSE") {
+			fmt.Fprintf(os.Stderr, "SSH agent: setting SSH_AUTH_SOCK=%s for docker command\n", hostSSHAuthSock)
+		}
+	}
+	return cmd.Run()
+}
+
+// Build builds an image from a specific Dockerfile and context directory,
+// preferring buildx with Docker. Podman always uses `podman build`.
+func (r cliRuntime) Build(tag, dockerfilePath, contextDir string, opts BuildOptions) error {
+	if r.isPodman() {
+		return r.runClassicBuild(tag, dockerfilePath, contextDir, opts.ExtraArgs)
+	}
+	if opts.Builder == "" {
+		if env := strings.TrimSpace(os.Getenv("DV_BUILDX_BUILDER")); env != "" {
+			opts.Builder = env
+		} else if env := strings.TrimSpace(os.Getenv("DV_BUILDER")); env != "" {
+			opts.Builder = env
+		}
+	}
+	useClassic := opts.ForceClassic || isTruthyEnv("DV_DISABLE_BUILDX")
+	buildxOK := r.buildxAvailable()
+	if !useClassic && buildxOK {
+		return r.runBuildx(tag, dockerfilePath, contextDir, opts)
+	}
+	if !opts.ForceClassic && !buildxOK {
+		if err := buildxErr; err != nil {
+			fmt.Fprintf(os.Stderr, "buildx unavailable (%v); falling back to 'docker build'.\n", err)
+		} else {
+			fmt.Fprintln(os.Stderr, "buildx unavailable; falling back to 'docker build'.")
+		}
+	}
+	return r.runClassicBuild(tag, dockerfilePath, contextDir, opts.ExtraArgs)
+}
+
+func (r cliRuntime) runClassicBuild(tag, dockerfilePath, contextDir string, args []string) error {
+	argv := []string{"build", "-t", tag, "-f", dockerfilePath}
+	argv = append(argv, args...)
+	argv = append(argv, contextDir)
+	r.verbose(argv...)
+	cmd := exec.Command(r.bin, argv...)
+	cmd.Stdout, cmd.Stderr = os.Stdout, os.Stderr
+	cmd.Env = append(os.Environ(), "DOCKER_BUILDKIT=1")
+	return cmd.Run()
+}
+
+func (r cliRuntime) runBuildx(tag, dockerfilePath, contextDir string, opts BuildOptions) error {
+	argv := []string{"buildx", "build", "--load", "-t", tag, "-f", dockerfilePath}
+	if builder := strings.TrimSpace(opts.Builder); builder != "" {
+		argv = append(argv, "--builder", builder)
+	}
+	argv = append(argv, opts.ExtraArgs...)
+	argv = append(argv, contextDir)
+	r.verbose(argv...)
+	cmd := exec.Command(r.bin, argv...)
+	cmd.Stdout, cmd.Stderr = os.Stdout, os.Stderr
+	cmd.Env = append(os.Environ(), "DOCKER_BUILDKIT=1")
+	return cmd.Run()
+}
+
+var (
+	buildxOnce sync.Once
+	buildxOK   bool
+	buildxErr  error
+)
+
+func (r cliRuntime) buildxAvailable() bool {
+	buildxOnce.Do(func() {
+		cmd := exec.Command(r.bin, "buildx", "version")
+		cmd.Stdout, cmd.Stderr = io.Discard, io.Discard
+		buildxErr = cmd.Run()
+		buildxOK = buildxErr == nil
+	})
+	return buildxOK
+}
+
+// formatPort renders a published port in `docker ps` notation.
+func formatPort(hostIP string, hostPort, containerPort int, proto string) string {
+	if proto == "" {
+		proto = "tcp"
+	}
+	if hostPort == 0 {
+		return fmt.Sprintf("%d/%s", containerPort, proto)
+	}
+	if hostIP == "" {
+		hostIP = "0.0.0.0"
+	}
+	return fmt.Sprintf("%s:%d->%d/%s", hostIP, hostPort, containerPort, proto)
+}
+
+// parseLabelList converts a `docker ps --format {{.Labels}}` string
+// (comma-separated key=value pairs) into a map. Malformed entries are ignored.
+func parseLabelList(labelsField string) map[string]string {
+	labelsField = strings.TrimSpace(labelsField)
+	if labelsField == "" {
+		return map[string]string{}
+	}
+	items := strings.Split(labelsField, ",")
+	out := make(map[string]string, len(items))
+	for _, it := range items {
+		it = strings.TrimSpace(it)
+		if it == "" {
+			continue
+		}
+		kv := strings.SplitN(it, "=", 2)
+		if len(kv) != 2 {
+			continue
+		}
+		key := strings.TrimSpace(kv[0])
+		val := strings.TrimSpace(kv[1])
+		if key != "" {
+			out[key] = val
+		}
+	}
+	return out
+}
+
+// parseCLITime parses the CreatedAt field emitted by `docker ps`.
+// Example: "2024-07-20 15:04:05 -0700 MST"
+func parseCLITime(createdAt string) time.Time {
+	createdAt = strings.TrimSpace(createdAt)
+	if createdAt == "" {
+		return time.Time{}
+	}
+	t, err := time.Parse("2006-01-02 15:04:05 -0700 MST", createdAt)
+	if err != nil {
+		return time.Time{}
+	}
+	return t
+}
+
+func (r cliRuntime) CreateVolume(name string, labels map[string]string) error {
+	if r.isPodman() && r.volumeExists(name) {
+		// podman volume create fails when the volume exists; docker does not.
+		return nil
+	}
+	args := []string{"volume", "create"}
+	for _, k := range sortedKeys(labels) {
+		args = append(args, "--label", k+"="+labels[k])
+	}
+	args = append(args, name)
+	r.verbose(args...)
+	cmd := exec.Command(r.bin, args...)
+	cmd.Stdout, cmd.Stderr = io.Discard, os.Stderr
+	return cmd.Run()
+}
+
+func (r cliRuntime) volumeExists(name string) bool {
+	return exec.Command(r.bin, "volume", "inspect", name).Run() == nil
+}
+
+// cliVolumeRow is one line of `docker volume ls --format '{{json .}}'`.
+type cliVolumeRow struct {
+	Name   string `json:"Name"`
+	Labels string `json:"Labels"`
+}
+
+// podmanVolumeRow is one element of `podman volume ls --format json`.
+type podmanVolumeRow struct {
+	Name      string            `json:"Name"`
+	Labels    map[string]string `json:"Labels"`
+	CreatedAt time.Time         `json:"CreatedAt"`
+}
+
+func (r cliRuntime) ListVolumes() ([]VolumeSummary, error) {
+	if r.isPodman() {
+		out, err := exec.Command(r.bin, "volume", "ls", "--format", "json").Output()
+		if err != nil {
+			return nil, err
+		}
+		var rows []podmanVolumeRow
+		if err := json.Unmarshal(out, &rows); err != nil {
+			return nil, err
+		}
+		list := make([]VolumeSummary, 0, len(rows))
+		for _, row := range rows {
+			if row.Labels == nil {
+				row.Labels = map[string]string{}
+			}
+			list = append(list, VolumeSummary{Name: row.Name, Labels: row.Labels, CreatedAt: row.CreatedAt})
+		}
+		return list, nil
+	}
+
+	out, err := exec.Command(r.bin, "volume", "ls", "--format", "{{json .}}").Output()
+	if err != nil {
+		return nil, err
+	}
+	var list []VolumeSummary
+	for _, line := range strings.Split(string(out), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		var row cliVolumeRow
+		if err := json.Unmarshal([]byte(line), &row); err != nil {
+			continue
+		}
+		list = append(list, VolumeSummary{Name: row.Name, Labels: parseLabelList(row.Labels)})
+	}
+	return list, nil
+}
+
+func (r cliRuntime) Events(ctx context.Context, fn func(ContainerEvent)) error {
+	args := []string{"events", "--format", "{{json .}}", "--filter", "type=container"}
+	for _, action := range containerEventActions {
+		args = append(args, "--filter", "event="+action)
+	}
+	cmd := exec.CommandContext(ctx, r.bin, args...)
+	out, err := cmd.StdoutPipe()
+	if err != nil {
+		return err
+	}
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	decodeErr := decodeEvents(out, fn)
+	waitErr := cmd.Wait()
+	if ctx.Err() != nil {
+		return nil
+	}
+	if decodeErr != nil {
+		return decodeErr
+	}
+	return waitErr
+}
+
+// CPUPercent takes one `stats` sample, which prints e.g. "12.34%".
+func (r cliRuntime) CPUPercent(name string) (float64, error) {
+	out, err := exec.Command(r.bin, "stats", "--no-stream", "--format", "{{.CPUPerc}}", name).Output()
+	if err != nil {
+		return 0, err
+	}
+	s := strings.TrimSuffix(strings.TrimSpace(string(out)), "%")
+	if s == "" || s == "--" {
+		return 0, nil
+	}
+	return strconv.ParseFloat(s, 64)
+}
+
+// VolumeSizes parses the volume table of `system df -v`, which docker and
+// podman both print as VOLUME NAME, LINKS, SIZE.
+func (r cliRuntime) VolumeSizes() (map[string]int64, error) {
+	out, err := exec.Command(r.bin, "system", "df", "-v").Output()
+	if err != nil {
+		return nil, err
+	}
+	return parseDFVolumes(string(out)), nil
+}
+
+func parseDFVolumes(out string) map[string]int64 {
+	sizes := map[string]int64{}
+	inTable := false
+	for _, line := range strings.Split(out, "\n") {
+		fields := strings.Fields(line)
+		switch {
+		case strings.HasPrefix(line, "VOLUME NAME"):
+			inTable = true
+		case !inTable:
+		case len(fields) == 0:
+			inTable = false
+		case len(fields) >= 3:
+			if n, ok := parseHumanSize(fields[len(fields)-1]); ok {
+				sizes[fields[0]] = n
+			}
+		}
+	}
+	return sizes
+}
+
+var humanSizeRe = regexp.MustCompile(`^([0-9.]+)\s*([kKMGTP]?i?B)$`)
+
+// parseHumanSize parses sizes like "1.2GB" (decimal, as docker prints them)
+// or "512MiB" (binary).
+func parseHumanSize(s string) (int64, bool) {
+	m := humanSizeRe.FindStringSubmatch(strings.TrimSpace(s))
+	if m == nil {
+		return 0, false
+	}
+	n, err := strconv.ParseFloat(m[1], 64)
+	if err != nil {
+		return 0, false
+	}
+	unit := strings.ToUpper(m[2])
+	base := 1000.0
+	if strings.Contains(unit, "I") {
+		base = 1024
+	}
+	mult := 1.0
+	if unit != "B" {
+		mult = math.Pow(base, float64(strings.IndexByte("KMGTP", unit[0])+1))
+	}
+	return int64(n * mult), true
+}
+
+func (r cliRuntime) RemoveVolume(name string) error {
+	r.verbose("volume", "rm", name)
+	out, err := exec.Command(r.bin, "volume", "rm", name).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("%s volume rm %s: %s", r.bin, name, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
@@ -4,17 +4,12 @@ import (
 	"bufio"
 	"bytes"
 	"context"
-	"encoding/json"
 	"fmt"
 	"io"
 	"os"
-	"os/exec"
 	"path/filepath"
-	"runtime"
 	"strings"
-	"sync"
-
-	"golang.org/x/term"
+	"time"
 )
 
 // getIdentityAgent parses ~/.ssh/config for a global IdentityAgent setting.
@@ -69,59 +64,32 @@ type BuildOptions struct {
 }
 
 func Exists(name string) bool {
-	out, _ := exec.Command("bash", "-lc", "docker ps -aq -f name=^"+shellEscape(name)+"$").Output()
-	return strings.TrimSpace(string(out)) != ""
+	return Current().Exists(name)
 }
 
 func Running(name string) bool {
-	out, _ := exec.Command("bash", "-lc", "docker ps -q -f status=running -f name=^"+shellEscape(name)+"$").Output()
-	return strings.TrimSpace(string(out)) != ""
+	return Current().Running(name)
 }
 
 func Stop(name string) error {
-	if isTruthyEnv("DV_VERBOSE") {
-		fmt.Fprintf(os.Stderr, "Running: docker stop %s\n", name)
-	}
-	cmd := exec.Command("docker", "stop", name)
-	cmd.Stdout, cmd.Stderr = os.Stdout, os.Stderr
-	return cmd.Run()
+	return Current().Stop(name)
 }
 
 func Remove(name string) error {
-	if isTruthyEnv("DV_VERBOSE") {
-		fmt.Fprintf(os.Stderr, "Running: docker rm %s\n", name)
-	}
-	cmd := exec.Command("docker", "rm", name)
-	cmd.Stdout, cmd.Stderr = os.Stdout, os.Stderr
-	return cmd.Run()
+	return Current().Remove(name, false)
 }
 
 func RemoveForce(name string) error {
-	if isTruthyEnv("DV_VERBOSE") {
-		fmt.Fprintf(os.Stderr, "Running: docker rm -f %s\n", name)
-	}
-	cmd := exec.Command("docker", "rm", "-f", name)
-	cmd.Stdout, cmd.Stderr = os.Stdout, os.Stderr
-	return cmd.Run()
+	return Current().Remove(name, true)
 }
 
 func Rename(oldName, newName string) error {
-	if isTruthyEnv("DV_VERBOSE") {
-		fmt.Fprintf(os.Stderr, "Running: docker rename %s %s\n", oldName, newName)
-	}
-	cmd := exec.Command("docker", "rename", oldName, newName)
-	cmd.Stdout, cmd.Stderr = os.Stdout, os.Stderr
-	return cmd.Run()
+	return Current().Rename(oldName, newName)
 }
 
 // Pull applies to an image ref (repo:tag or repo@digest)
 func Pull(ref string) error {
-	if isTruthyEnv("DV_VERBOSE") {
-		fmt.Fprintf(os.Stderr, "Running: docker pull %s\n", ref)
-	}
-	cmd := exec.Command("docker", "pull", ref)
-	cmd.Stdout, cmd.Stderr = os.Stdout, os.Stderr
-	return cmd.Run()
+	return Current().Pull(ref)
 }
 
 // PullBaseImages parses the Dockerfile at path and attempts to pull all unique
@@ -178,19 +146,7 @@ func PullBaseImages(dockerfilePath string, out io.Writer) {
 	}
 }
 
-func Build(tag string, args []string) error {
-	argv := []string{"build", "-t", tag}
-	argv = append(argv, args...)
-	argv = append(argv, ".")
-	if isTruthyEnv("DV_VERBOSE") {
-		fmt.Fprintf(os.Stderr, "Running: docker %s\n", strings.Join(argv, " "))
-	}
-	cmd := exec.Command("docker", argv...)
-	cmd.Stdout, cmd.Stderr = os.Stdout, os.Stderr
-	return cmd.Run()
-}
-
-// BuildFrom builds a Docker image from a specific Dockerfile and context
+// BuildFrom builds an image from a specific Dockerfile and context
 // directory. dockerfilePath may be absolute or relative; contextDir must be
 // a directory.
 func BuildFrom(tag, dockerfilePath, contextDir string, opts BuildOptions) error {
@@ -201,76 +157,7 @@ func BuildFrom(tag, dockerfilePath, contextDir string, opts BuildOptions) error
 	if opts.ExtraArgs == nil {
 		opts.ExtraArgs = []string{}
 	}
-	if opts.Builder == "" {
-		if env := strings.TrimSpace(os.Getenv("DV_BUILDX_BUILDER")); env != "" {
-			opts.Builder = env
-		} else if env := strings.TrimSpace(os.Getenv("DV_BUILDER")); env != "" {
-			opts.Builder = env
-		}
-	}
-	useClassic := opts.ForceClassic || isTruthyEnv("DV_DISABLE_BUILDX")
-	buildxOK := buildxAvailable()
-	if !useClassic && buildxOK {
-		return runBuildx(tag, dockerfilePath, contextDir, opts)
-	}
-	if !opts.ForceClassic && !buildxOK {
-		if err := buildxError(); err != nil {
-			fmt.Fprintf(os.Stderr, "buildx unavailable (%v); falling back to 'docker build'.\n", err)
-		} else {
-			fmt.Fprintln(os.Stderr, "buildx unavailable; falling back to 'docker build'.")
-		}
-	}
-	return runClassicBuild(tag, dockerfilePath, contextDir, opts.ExtraArgs)
-}
-
-func runClassicBuild(tag, dockerfilePath, contextDir string, args []string) error {
-	argv := []string{"build", "-t", tag, "-f", dockerfilePath}
-	argv = append(argv, args...)
-	argv = append(argv, contextDir)
-	if isTruthyEnv("DV_VERBOSE") {
-		fmt.Fprintf(os.Stderr, "Running: docker %s\n", strings.Join(argv, " "))
-	}
-	cmd := exec.Command("docker", argv...)
-	cmd.Stdout, cmd.Stderr = os.Stdout, os.Stderr
-	cmd.Env = append(os.Environ(), "DOCKER_BUILDKIT=1")
-	return cmd.Run()
-}
-
-func runBuildx(tag, dockerfilePath, contextDir string, opts BuildOptions) error {
-	argv := []string{"buildx", "build", "--load", "-t", tag, "-f", dockerfilePath}
-	if builder := strings.TrimSpace(opts.Builder); builder != "" {
-		argv = append(argv, "--builder", builder)
-	}
-	argv = append(argv, opts.ExtraArgs...)
-	argv = append(argv, contextDir)
-	if isTruthyEnv("DV_VERBOSE") {
-		fmt.Fprintf(os.Stderr, "Running: docker %s\n", strings.Join(argv, " "))
-	}
-	cmd := exec.Command("docker", argv...)
-	cmd.Stdout, cmd.Stderr = os.Stdout, os.Stderr
-	cmd.Env = append(os.Environ(), "DOCKER_BUILDKIT=1")
-	return cmd.Run()
-}
-
-var (
-	buildxOnce sync.Once
-	buildxOK   bool
-	buildxErr  error
-)
-
-func buildxAvailable() bool {
-	buildxOnce.Do(func() {
-		cmd := exec.Command("docker", "buildx", "version")
-		cmd.Stdout, cmd.Stderr = io.Discard, io.Discard
-		buildxErr = cmd.Run()
-		buildxOK = buildxErr == nil
-	})
-	return buildxOK
-}
-
-func buildxError() error {
-	buildxAvailable()
-	return buildxErr
+	return Current().Build(tag, dockerfilePath, contextDir, opts)
 }
 
 func isTruthyEnv(key string) bool {
@@ -284,178 +171,63 @@ func isTruthyEnv(key string) bool {
 }
 
 func ImageExists(tag string) bool {
-	out, _ := exec.Command("bash", "-lc", "docker images -q "+shellEscape(tag)).Output()
-	return strings.TrimSpace(string(out)) != ""
+	return Current().ImageExists(tag)
 }
 
 func RemoveImage(tag string) error {
-	if isTruthyEnv("DV_VERBOSE") {
-		fmt.Fprintf(os.Stderr, "Running: docker rmi %s\n", tag)
-	}
-	cmd := exec.Command("docker", "rmi", tag)
-	cmd.Stdout, cmd.Stderr = os.Stdout, os.Stderr
-	return cmd.Run()
+	return Current().RemoveImage(tag, false)
 }
 
 // RemoveImageQuiet removes an image, suppressing output and errors.
 // Useful for cleanup where failure is acceptable.
 func RemoveImageQuiet(tag string) error {
-	if isTruthyEnv("DV_VERBOSE") {
-		fmt.Fprintf(os.Stderr, "Running: docker rmi -f %s\n", tag)
-	}
-	cmd := exec.Command("docker", "rmi", "-f", tag)
-	cmd.Stdout, cmd.Stderr = io.Discard, io.Discard
-	return cmd.Run()
+	return Current().RemoveImage(tag, true)
 }
 
 // TagImage applies a new tag to an existing image (docker tag src dst)
 func TagImage(srcTag, dstTag string) error {
-	if isTruthyEnv("DV_VERBOSE") {
-		fmt.Fprintf(os.Stderr, "Running: docker tag %s %s\n", srcTag, dstTag)
-	}
-	cmd := exec.Command("docker", "tag", srcTag, dstTag)
-	cmd.Stdout, cmd.Stderr = os.Stdout, os.Stderr
-	return cmd.Run()
+	return Current().TagImage(srcTag, dstTag)
 }
 
 func Start(name string) error {
-	if isTruthyEnv("DV_VERBOSE") {
-		fmt.Fprintf(os.Stderr, "Running: docker start %s\n", name)
-	}
-	cmd := exec.Command("docker", "start", name)
-	cmd.Stdout, cmd.Stderr = os.Stdout, os.Stderr
-	return cmd.Run()
+	return Current().Start(name)
 }
 
 // ContainerIP returns the IP address of a running container on the default bridge network.
 func ContainerIP(name string) (string, error) {
-	out, err := exec.Command("docker", "inspect", name, "--format", "{{range .NetworkSettings.Networks}}{{.IPAddress}}{{end}}").Output()
+	info, err := Current().Inspect(name)
 	if err != nil {
 		return "", err
 	}
-	ip := strings.TrimSpace(string(out))
-	if ip == "" {
+	if info.IPAddress == "" {
 		return "", fmt.Errorf("container %s has no IP address", name)
 	}
-	return ip, nil
+	return info.IPAddress, nil
 }
 
-func RunDetached(name, workdir, image string, hostPort, containerPort int, labels map[string]string, envs map[string]string, extraHosts []string, sshAuthSock string) error {
-	args := []string{"run", "-d",
-		"--name", name,
-		"-w", workdir,
-		"-p", fmt.Sprintf("127.0.0.1:%d:%d", hostPort, containerPort),
-	}
-	// hostSSHAuthSock tracks what SSH_AUTH_SOCK should be on the host for Docker to forward
-	hostSSHAuthSock := sshAuthSock
-	if sshAuthSock != "" {
-		var mountPath string
-		var socketSource string
-		if runtime.GOOS == "darwin" {
-			// On macOS, Docker Desktop/OrbStack provide a magic socket that forwards
-			// the host's SSH agent. We always mount this path.
-			mountPath = "/run/host-services/ssh-auth.sock"
-
-			// Check if there's an IdentityAgent configured (e.g., 1Password).
-			// If so, we need to tell Docker to use that socket instead of SSH_AUTH_SOCK.
-			identityAgent := getIdentityAgent()
-			if identityAgent != "" {
-				hostSSHAuthSock = identityAgent
-				socketSource = "IdentityAgent from ~/.ssh/config"
-			} else {
-				socketSource = "SSH_AUTH_SOCK"
-			}
-		} else {
-			// On Linux, mount the host socket directly
-			mountPath = sshAuthSock
-			socketSource = "SSH_AUTH_SOCK"
-		}
-		if isTruthyEnv("DV_VERBOSE") {
-			fmt.Fprintf(os.Stderr, "SSH agent: forwarding %s (%s)\n", hostSSHAuthSock, socketSource)
-			// Check if socket exists on host
-			if _, err := os.Stat(hostSSHAuthSock); err != nil {
-				fmt.Fprintf(os.Stderr, "SSH agent: WARNING - socket does not exist: %v\n", err)
-			} else {
-				fmt.Fprintf(os.Stderr, "SSH agent: socket exists at %s\n", hostSSHAuthSock)
-			}
-		}
-		args = append(args, "-v", mountPath+":/tmp/ssh-agent.sock")
-		args = append(args, "-e", "SSH_AUTH_SOCK=/tmp/ssh-agent.sock")
-	}
-	// Apply extra hosts
-	for _, h := range extraHosts {
-		args = append(args, "--add-host", h)
-	}
-	// Apply environment variables
-	for k, v := range envs {
-		if strings.TrimSpace(k) == "" || strings.Contains(k, "\n") {
-			continue
-		}
-		args = append(args, "-e", fmt.Sprintf("%s=%s", k, v))
-	}
-	// Apply labels for provenance and discovery
-	for k, v := range labels {
-		if strings.TrimSpace(k) == "" || strings.Contains(k, "\n") {
-			continue
-		}
-		args = append(args, "--label", fmt.Sprintf("%s=%s", k, v))
-	}
-	args = append(args, image, "--sysctl", "kernel.unprivileged_userns_clone=1")
-	if isTruthyEnv("DV_VERBOSE") {
-		fmt.Fprintf(os.Stderr, "Running: docker %s\n", strings.Join(args, " "))
-	}
-	cmd := exec.Command("docker", args...)
-	cmd.Stdout, cmd.Stderr = os.Stdout, os.Stderr
-	// If we detected a different SSH agent (e.g., 1Password), set SSH_AUTH_SOCK
-	// in the docker command's environment so Docker Desktop/OrbStack forwards it
-	if hostSSHAuthSock != "" && hostSSHAuthSock != sshAuthSock {
-		// Filter out existing SSH_AUTH_SOCK and replace with our value
-		env := os.Environ()
-		filteredEnv := make([]string, 0, len(env))
-		for _, e := range env {
-			if !strings.HasPrefix(e, "SSH_AUTH_SOCK=") {
-				filteredEnv = append(filteredEnv, e)
-			}
-		}
-		cmd.Env = append(filteredEnv, "SSH_AUTH_SOCK="+hostSSHAuthSock)
-		if isTruthyEnv("DV_VERBOSE") {
-			fmt.Fprintf(os.Stderr, "SSH agent: setting SSH_AUTH_SOCK=%s for docker command\n", hostSSHAuthSock)
-		}
-	}
-	return cmd.Run()
+// Events calls fn for container lifecycle events until ctx is cancelled or
+// the stream ends.
+func Events(ctx context.Context, fn func(ContainerEvent)) error {
+	return Current().Events(ctx, fn)
+}
+
+// RunDetached creates and starts a container in the background.
+func RunDetached(opts RunOptions) error {
+	return Current().RunDetached(opts)
+}
+
+// ListContainers returns all containers known to the runtime, running or not.
+func ListContainers() ([]ContainerSummary, error) {
+	return Current().List()
 }
 
 func ExecInteractive(name, workdir string, envs Envs, argv []string) error {
-	args := []string{"exec", "-i", "--user", "discourse", "-w", workdir}
-	// Add -t only when both stdin and stdout are TTYs
-	if term.IsTerminal(int(os.Stdin.Fd())) && term.IsTerminal(int(os.Stdout.Fd())) {
-		args = append([]string{"exec", "-t"}, args[1:]...)
-	}
-	for _, e := range envs {
-		args = append(args, "-e", e)
-	}
-	args = append(args, name)
-	args = append(args, argv...)
-	cmd := exec.Command("docker", args...)
-	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
-	return cmd.Run()
+	return Current().ExecInteractive(name, ExecOptions{User: "discourse", Workdir: workdir, Envs: envs, Argv: argv})
 }
 
 // ExecInteractiveAsRoot runs an interactive command inside the container as root.
 func ExecInteractiveAsRoot(name, workdir string, envs Envs, argv []string) error {
-	args := []string{"exec", "-i", "--user", "root", "-w", workdir}
-	// Add -t only when both stdin and stdout are TTYs
-	if term.IsTerminal(int(os.Stdin.Fd())) && term.IsTerminal(int(os.Stdout.Fd())) {
-		args = append([]string{"exec", "-t"}, args[1:]...)
-	}
-	for _, e := range envs {
-		args = append(args, "-e", e)
-	}
-	args = append(args, name)
-	args = append(args, argv...)
-	cmd := exec.Command("docker", args...)
-	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
-	return cmd.Run()
+	return Current().ExecInteractive(name, ExecOptions{User: "root", Workdir: workdir, Envs: envs, Argv: argv})
 }
 
 // Envs is a typed slice for container environment variables.
@@ -466,120 +238,76 @@ type Envs []string
 // Use nil for envs when no environment variables are needed.
 // Returns stdout only; use ExecCombinedOutput if you need stderr too.
 func ExecOutput(name, workdir string, envs Envs, argv []string) (string, error) {
-	args := []string{"exec", "--user", "discourse", "-w", workdir}
-	for _, e := range envs {
-		args = append(args, "-e", e)
-	}
-	args = append(args, name)
-	args = append(args, argv...)
-	cmd := exec.Command("docker", args...)
-	out, err := cmd.Output()
-	return string(out), err
+	return ExecOutputContext(context.Background(), name, workdir, envs, argv)
 }
 
 // ExecOutputContext runs a command inside the container as the discourse user with context.
 // Use nil for envs when no environment variables are needed.
 // Returns stdout only; use ExecCombinedOutputContext if you need stderr too.
 func ExecOutputContext(ctx context.Context, name, workdir string, envs Envs, argv []string) (string, error) {
-	args := []string{"exec", "--user", "discourse", "-w", workdir}
-	for _, e := range envs {
-		args = append(args, "-e", e)
-	}
-	args = append(args, name)
-	args = append(args, argv...)
-	cmd := exec.CommandContext(ctx, "docker", args...)
-	out, err := cmd.Output()
-	return string(out), err
+	return Current().Exec(ctx, name, ExecOptions{User: "discourse", Workdir: workdir, Envs: envs, Argv: argv})
 }
 
 // ExecCombinedOutput runs a command inside the container as the discourse user.
 // Use nil for envs when no environment variables are needed.
 // Returns both stdout and stderr combined.
 func ExecCombinedOutput(name, workdir string, envs Envs, argv []string) (string, error) {
-	args := []string{"exec", "--user", "discourse", "-w", workdir}
-	for _, e := range envs {
-		args = append(args, "-e", e)
-	}
-	args = append(args, name)
-	args = append(args, argv...)
-	cmd := exec.Command("docker", args...)
-	out, err := cmd.CombinedOutput()
-	return string(out), err
+	return ExecCombinedOutputContext(context.Background(), name, workdir, envs, argv)
 }
 
 // ExecCombinedOutputContext runs a command inside the container as the discourse user with context.
 // Use nil for envs when no environment variables are needed.
 // Returns both stdout and stderr combined.
 func ExecCombinedOutputContext(ctx context.Context, name, workdir string, envs Envs, argv []string) (string, error) {
-	args := []string{"exec", "--user", "discourse", "-w", workdir}
-	for _, e := range envs {
-		args = append(args, "-e", e)
-	}
-	args = append(args, name)
-	args = append(args, argv...)
-	cmd := exec.CommandContext(ctx, "docker", args...)
-	out, err := cmd.CombinedOutput()
-	return string(out), err
+	return Current().Exec(ctx, name, ExecOptions{User: "discourse", Workdir: workdir, Envs: envs, Argv: argv, Combined: true})
 }
 
 // ExecAsRoot runs a command inside the container as root, returning output.
 // Use nil for envs when no environment variables are needed.
 // Returns stdout only; use ExecAsRootCombined if you need stderr too.
 func ExecAsRoot(name, workdir string, envs Envs, argv []string) (string, error) {
-	args := []string{"exec", "--user", "root", "-w", workdir}
-	for _, e := range envs {
-		args = append(args, "-e", e)
-	}
-	args = append(args, name)
-	args = append(args, argv...)
-	cmd := exec.Command("docker", args...)
-	out, err := cmd.Output()
-	return string(out), err
+	return ExecAsRootContext(context.Background(), name, workdir, envs, argv)
 }
 
 // ExecAsRootContext runs a command inside the container as root with context, returning output.
 // Use nil for envs when no environment variables are needed.
 // Returns stdout only; use ExecAsRootCombinedContext if you need stderr too.
 func ExecAsRootContext(ctx context.Context, name, workdir string, envs Envs, argv []string) (string, error) {
-	args := []string{"exec", "--user", "root", "-w", workdir}
-	for _, e := range envs {
-		args = append(args, "-e", e)
-	}
-	args = append(args, name)
-	args = append(args, argv...)
-	cmd := exec.CommandContext(ctx, "docker", args...)
-	out, err := cmd.Output()
-	return string(out), err
+	return Current().Exec(ctx, name, ExecOptions{User: "root", Workdir: workdir, Envs: envs, Argv: argv})
 }
 
 // ExecAsRootCombined runs a command inside the container as root.
 // Use nil for envs when no environment variables are needed.
 // Returns both stdout and stderr combined.
 func ExecAsRootCombined(name, workdir string, envs Envs, argv []string) (string, error) {
-	args := []string{"exec", "--user", "root", "-w", workdir}
-	for _, e := range envs {
-		args = append(args, "-e", e)
-	}
-	args = append(args, name)
-	args = append(args, argv...)
-	cmd := exec.Command("docker", args...)
-	out, err := cmd.CombinedOutput()
-	return string(out), err
+	return ExecAsRootCombinedContext(context.Background(), name, workdir, envs, argv)
 }
 
 // ExecAsRootCombinedContext runs a command inside the container as root with context.
 // Use nil for envs when no environment variables are needed.
 // Returns both stdout and stderr combined.
 func ExecAsRootCombinedContext(ctx context.Context, name, workdir string, envs Envs, argv []string) (string, error) {
-	args := []string{"exec", "--user", "root", "-w", workdir}
-	for _, e := range envs {
-		args = append(args, "-e", e)
+	return Current().Exec(ctx, name, ExecOptions{User: "root", Workdir: workdir, Envs: envs, Argv: argv, Combined: true})
+}
+
+// ExecCombinedStream runs a command inside the container as discourse (or
+// root when asRoot is set), copying stdout and stderr to w as they are
+// produced. Use nil for envs when no environment variables are needed.
+func ExecCombinedStream(ctx context.Context, name, workdir string, envs Envs, argv []string, asRoot bool, w io.Writer) error {
+	user := "discourse"
+	if asRoot {
+		user = "root"
 	}
-	args = append(args, name)
-	args = append(args, argv...)
-	cmd := exec.CommandContext(ctx, "docker", args...)
-	out, err := cmd.CombinedOutput()
-	return string(out), err
+	_, err := Current().Exec(ctx, name, ExecOptions{User: user, Workdir: workdir, Envs: envs, Argv: argv, Combined: true, Stdout: w})
+	return err
+}
+
+// ExecAsRootStream runs a command inside the container as root, copying its
+// stdout to w as it is produced. Use nil for envs when no environment
+// variables are needed.
+func ExecAsRootStream(ctx context.Context, name, workdir string, envs Envs, argv []string, w io.Writer) error {
+	_, err := Current().Exec(ctx, name, ExecOptions{User: "root", Workdir: workdir, Envs: envs, Argv: argv, Stdout: w})
+	return err
 }
 
 // ExpandGlobInContainer runs a shell command to expand a glob pattern inside the container.
@@ -589,14 +317,13 @@ func ExpandGlobInContainer(containerName, pattern string) ([]string, error) {
 	// Pass pattern as a positional argument to avoid command injection.
 	// The script expands ~ to $HOME, enables nullglob to handle no-match gracefully,
 	// and outputs one existing file per line.
-	cmd := exec.Command("docker", "exec", containerName, "bash", "-c",
+	out, err := Current().Exec(context.Background(), containerName, ExecOptions{Argv: []string{"bash", "-c",
 		`pattern=$1; pattern=${pattern/#\~/$HOME}; shopt -s nullglob; for f in $pattern; do [ -e "$f" ] && echo "$f"; done`,
-		"--", pattern)
-	out, err := cmd.Output()
+		"--", pattern}})
 	if err != nil {
 		return nil, err
 	}
-	lines := strings.Split(strings.TrimSpace(string(out)), "\n")
+	lines := strings.Split(strings.TrimSpace(out), "\n")
 	var paths []string
 	for _, line := range lines {
 		if line != "" {
@@ -613,47 +340,19 @@ func ContainsGlobMeta(path string) bool {
 }
 
 func CopyFromContainer(name, srcInContainer, dstOnHost string) error {
-	cmd := exec.Command("docker", "cp", fmt.Sprintf("%s:%s", name, srcInContainer), dstOnHost)
-	if isTruthyEnv("DV_VERBOSE") {
-		cmd.Stdout = os.Stdout
-	} else {
-		cmd.Stdout = io.Discard
-	}
-	cmd.Stderr = os.Stderr
-	return cmd.Run()
+	return CopyFromContainerContext(context.Background(), name, srcInContainer, dstOnHost)
 }
 
 func CopyFromContainerContext(ctx context.Context, name, srcInContainer, dstOnHost string) error {
-	cmd := exec.CommandContext(ctx, "docker", "cp", fmt.Sprintf("%s:%s", name, srcInContainer), dstOnHost)
-	if isTruthyEnv("DV_VERBOSE") {
-		cmd.Stdout = os.Stdout
-	} else {
-		cmd.Stdout = io.Discard
-	}
-	cmd.Stderr = os.Stderr
-	return cmd.Run()
+	return Current().CopyFrom(ctx, name, srcInContainer, dstOnHost)
 }
 
 func CopyToContainer(name, srcOnHost, dstInContainer string) error {
-	cmd := exec.Command("docker", "cp", srcOnHost, fmt.Sprintf("%s:%s", name, dstInContainer))
-	if isTruthyEnv("DV_VERBOSE") {
-		cmd.Stdout = os.Stdout
-	} else {
-		cmd.Stdout = io.Discard
-	}
-	cmd.Stderr = os.Stderr
-	return cmd.Run()
+	return CopyToContainerContext(context.Background(), name, srcOnHost, dstInContainer)
 }
 
 func CopyToContainerContext(ctx context.Context, name, srcOnHost, dstInContainer string) error {
-	cmd := exec.CommandContext(ctx, "docker", "cp", srcOnHost, fmt.Sprintf("%s:%s", name, dstInContainer))
-	if isTruthyEnv("DV_VERBOSE") {
-		cmd.Stdout = os.Stdout
-	} else {
-		cmd.Stdout = io.Discard
-	}
-	cmd.Stderr = os.Stderr
-	return cmd.Run()
+	return Current().CopyTo(ctx, name, srcOnHost, dstInContainer)
 }
 
 // CopyToContainerWithOwnership copies a file or directory into a container and
@@ -707,140 +406,105 @@ func shellEscape(s string) string {
 }
 
 func Labels(name string) (map[string]string, error) {
-	cmd := exec.Command("docker", "inspect", "-f", "{{json .Config.Labels}}", name)
-	out, err := cmd.Output()
+	info, err := Current().Inspect(name)
 	if err != nil {
 		return nil, err
 	}
-	labels := map[string]string{}
-	if err := json.Unmarshal(out, &labels); err != nil {
-		return nil, err
+	return info.Labels, nil
+}
+
+// ExecSessions returns how many exec instances the engine tracks for name.
+func ExecSessions(name string) (int, error) {
+	info, err := Current().Inspect(name)
+	if err != nil {
+		return 0, err
 	}
-	if labels == nil {
-		labels = map[string]string{}
+	return info.ExecSessions, nil
+}
+
+// LastStopped returns when the container last stopped, or when it was
+// created if it never has.
+func LastStopped(name string, createdAt time.Time) time.Time {
+	info, err := Current().Inspect(name)
+	if err != nil || info.FinishedAt.IsZero() {
+		return createdAt
 	}
-	return labels, nil
+	return info.FinishedAt
 }
 
 func UpdateLabels(name string, labels map[string]string) error {
-	if len(labels) == 0 {
-		return nil
-	}
-	args := []string{"update"}
-	for k, v := range labels {
-		args = append(args, "--label-add", fmt.Sprintf("%s=%s", k, v))
-	}
-	args = append(args, name)
-	cmd := exec.Command("docker", args...)
-	cmd.Stdout, cmd.Stderr = os.Stdout, os.Stderr
-	return cmd.Run()
+	return Current().UpdateLabels(name, labels)
 }
 
 // GetContainerHostPort returns the host port mapped to the given container port.
 // Returns 0 if no mapping found or container doesn't exist.
 // Works on both running and stopped containers by inspecting HostConfig.
 func GetContainerHostPort(name string, containerPort int) (int, error) {
-	// Use docker inspect to get port bindings - works even when container is stopped
-	portKey := fmt.Sprintf("%d/tcp", containerPort)
-	format := fmt.Sprintf("{{(index .HostConfig.PortBindings \"%s\" 0).HostPort}}", portKey)
-	out, err := exec.Command("docker", "inspect", "-f", format, name).Output()
+	info, err := Current().Inspect(name)
 	if err != nil {
 		return 0, err
 	}
-	portStr := strings.TrimSpace(string(out))
-	if portStr == "" || portStr == "<no value>" {
+	port, ok := info.Ports[containerPort]
+	if !ok || port == 0 {
 		return 0, fmt.Errorf("no port mapping found")
 	}
-	var port int
-	if _, err := fmt.Sscanf(portStr, "%d", &port); err != nil {
-		return 0, fmt.Errorf("invalid port number: %s", portStr)
-	}
 	return port, nil
 }
 
 // CommitContainer creates an image from a container's current filesystem state.
 func CommitContainer(name, imageTag string) error {
-	cmd := exec.Command("docker", "commit", name, imageTag)
-	cmd.Stdout, cmd.Stderr = os.Stdout, os.Stderr
-	return cmd.Run()
+	return Current().Commit(name, imageTag, nil)
 }
 
-// AllocatedPorts returns a set of all host ports currently allocated by Docker
-// containers (running or stopped). It uses a more robust approach by listing
-// all containers and inspecting them individually to avoid failing on a single
-// malformed container.
-func AllocatedPorts() (map[int]bool, error) {
-	// 1. Get all container IDs
-	out, err := exec.Command("docker", "ps", "-aq").Output()
-	if err != nil {
-		return nil, fmt.Errorf("failed to list containers: %w", err)
-	}
+// CommitContainerWithLabels is CommitContainer with extra image labels.
+func CommitContainerWithLabels(name, imageTag string, labels map[string]string) error {
+	return Current().Commit(name, imageTag, labels)
+}
 
-	ids := strings.Fields(string(out))
-	if len(ids) == 0 {
-		return make(map[int]bool), nil
-	}
+// SaveImage writes an image as a tar stream to w.
+func SaveImage(ref string, w io.Writer) error {
+	return Current().SaveImage(ref, w)
+}
 
-	// 2. Inspect all containers at once with a template that handles multiple ports
-	format := "{{range $p, $conf := .HostConfig.PortBindings}}{{(index $conf 0).HostPort}} {{end}}"
-	args := append([]string{"inspect", "-f", format}, ids...)
-	out, err = exec.Command("docker", args...).Output()
-	if err != nil {
-		// If batch inspect fails, fallback to one-by-one to be resilient
-		return allocatedPortsOneByOne(ids)
-	}
+// LoadImage imports an image tar stream produced by SaveImage and returns
+// the tags it restored.
+func LoadImage(r io.Reader) ([]string, error) {
+	return Current().LoadImage(r)
+}
 
-	ports := make(map[int]bool)
-	fields := strings.Fields(string(out))
-	for _, f := range fields {
-		var p int
-		if _, err := fmt.Sscanf(f, "%d", &p); err == nil {
-			ports[p] = true
-		}
-	}
-	return ports, nil
+// ContainerSize returns the size in bytes of a container's writable layer.
+func ContainerSize(name string) (int64, error) {
+	return Current().ContainerSize(name)
 }
 
-func allocatedPortsOneByOne(ids []string) (map[int]bool, error) {
-	ports := make(map[int]bool)
-	format := "{{range $p, $conf := .HostConfig.PortBindings}}{{(index $conf 0).HostPort}} {{end}}"
-	for _, id := range ids {
-		out, err := exec.Command("docker", "inspect", "-f", format, id).Output()
-		if err != nil {
-			continue // skip malformed or missing containers
-		}
-		fields := strings.Fields(string(out))
-		for _, f := range fields {
-			var p int
-			if _, err := fmt.Sscanf(f, "%d", &p); err == nil {
-				ports[p] = true
-			}
-		}
-	}
-	return ports, nil
+// ListImages returns images carrying the label key, or all images when label is empty.
+func ListImages(label string) ([]ImageSummary, error) {
+	return Current().ListImages(label)
+}
+
+// AllocatedPorts returns a set of all host ports currently allocated by
+// containers (running or stopped).
+func AllocatedPorts() (map[int]bool, error) {
+	return Current().AllocatedPorts()
 }
 
 // GetContainerWorkdir returns the working directory configured for a container.
 func GetContainerWorkdir(name string) (string, error) {
-	out, err := exec.Command("docker", "inspect", "-f", "{{.Config.WorkingDir}}", name).Output()
+	info, err := Current().Inspect(name)
 	if err != nil {
 		return "", err
 	}
-	return strings.TrimSpace(string(out)), nil
+	return info.WorkingDir, nil
 }
 
 // GetContainerEnv returns environment variables set on a container as a map.
 func GetContainerEnv(name string) (map[string]string, error) {
-	out, err := exec.Command("docker", "inspect", "-f", "{{json .Config.Env}}", name).Output()
+	info, err := Current().Inspect(name)
 	if err != nil {
 		return nil, err
 	}
-	var envList []string
-	if err := json.Unmarshal(out, &envList); err != nil {
-		return nil, err
-	}
 	envMap := make(map[string]string)
-	for _, e := range envList {
+	for _, e := range info.Env {
 		parts := strings.SplitN(e, "=", 2)
 		if len(parts) == 2 {
 			envMap[parts[0]] = parts[1]
@@ -848,3 +512,28 @@ func GetContainerEnv(name string) (map[string]string, error) {
 	}
 	return envMap, nil
 }
+
+// CreateVolume creates a named volume if it does not already exist.
+func CreateVolume(name string, labels map[string]string) error {
+	return Current().CreateVolume(name, labels)
+}
+
+// ListVolumes returns all volumes known to the runtime.
+func ListVolumes() ([]VolumeSummary, error) {
+	return Current().ListVolumes()
+}
+
+// CPUPercent samples a container's CPU use; 100 is one full core.
+func CPUPercent(name string) (float64, error) {
+	return Current().CPUPercent(name)
+}
+
+// VolumeSizes returns the disk usage of each volume by name.
+func VolumeSizes() (map[string]int64, error) {
+	return Current().VolumeSizes()
+}
+
+// RemoveVolume deletes a named volume. It fails while a container uses it.
+func RemoveVolume(name string) error {
+	return Current().RemoveVolume(name)
+}
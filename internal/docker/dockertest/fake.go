@@ -0,0 +1,970 @@
+// Package dockertest provides an in-memory docker.Runtime for tests.
+//
+// The fake keeps containers, images and a per-container virtual filesystem in
+// memory and records every exec, so cli commands can be exercised end to end
+// without a container engine.
+package dockertest
+
+import (
+	"archive/tar"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"dv/internal/docker"
+)
+
+// Exec is one recorded command run inside a container.
+type Exec struct {
+	Container   string
+	User        string
+	Workdir     string
+	Envs        []string
+	Argv        []string
+	Interactive bool
+}
+
+// Command returns the argv joined with spaces, for easy matching.
+func (e Exec) Command() string { return strings.Join(e.Argv, " ") }
+
+// ExecFunc produces the output of a handled exec.
+type ExecFunc func(e Exec) (string, error)
+
+// Container is the state of a fake container.
+type Container struct {
+	Name      string
+	Image     string
+	Workdir   string
+	Running   bool
+	Labels    map[string]string
+	Env       map[string]string
+	Ports     map[int]int
+	CreatedAt time.Time
+	// FinishedAt is set by Stop and reported by Inspect.
+	FinishedAt time.Time
+	// RunOptions holds what RunDetached was called with, if anything.
+	RunOptions docker.RunOptions
+	// Files is the virtual filesystem, keyed by absolute path.
+	Files map[string][]byte
+	// ExecSessions and CPUPercent are what Inspect and CPUPercent report.
+	ExecSessions int
+	CPUPercent   float64
+}
+
+type image struct {
+	labels  map[string]string
+	env     map[string]string
+	files   map[string][]byte
+	created time.Time
+}
+
+type handler struct {
+	pattern string
+	fn      ExecFunc
+}
+
+// Runtime is an in-memory implementation of docker.Runtime.
+type Runtime struct {
+	mu         sync.Mutex
+	containers map[string]*Container
+	images     map[string]*image
+	volumes    map[string]docker.VolumeSummary
+	volSizes   map[string]int64
+	execs      []Exec
+	handlers   []handler
+	builds     []string
+	dangling   int
+	watchers   []chan docker.ContainerEvent
+	now        func() time.Time
+}
+
+var _ docker.Runtime = (*Runtime)(nil)
+
+// danglingPrefix keys images that lost their tag to a rebuild. They are
+// listed without tags and removed by ID.
+const danglingPrefix = "<none>@"
+
+// New returns an empty fake runtime.
+func New() *Runtime {
+	return &Runtime{
+		containers: map[string]*Container{},
+		images:     map[string]*image{},
+		volumes:    map[string]docker.VolumeSummary{},
+		volSizes:   map[string]int64{},
+		now:        time.Now,
+	}
+}
+
+// Name identifies the fake runtime.
+func (r *Runtime) Name() string { return "fake" }
+
+// Binary returns `false`, so stray shell-outs fail without side effects.
+func (r *Runtime) Binary() string { return "false" }
+
+// AddImage registers an image tag, optionally seeded with files that new
+// containers start with.
+func (r *Runtime) AddImage(tag string, files map[string][]byte) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.images[tag] = &image{labels: map[string]string{}, files: copyFiles(files), created: r.now()}
+}
+
+// AddContainer seeds a container. Nil maps are initialised.
+func (r *Runtime) AddContainer(c Container) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	c.Labels = copyMap(c.Labels)
+	c.Env = copyMap(c.Env)
+	c.Files = copyFiles(c.Files)
+	if c.Ports == nil {
+		c.Ports = map[int]int{}
+	}
+	if c.CreatedAt.IsZero() {
+		c.CreatedAt = r.now()
+	}
+	r.containers[c.Name] = &c
+}
+
+// Container returns a snapshot of the named container.
+func (r *Runtime) Container(name string) (Container, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	c, ok := r.containers[name]
+	if !ok {
+		return Container{}, false
+	}
+	out := *c
+	out.Labels = copyMap(c.Labels)
+	out.Env = copyMap(c.Env)
+	out.Files = copyFiles(c.Files)
+	return out, true
+}
+
+// Images returns the known image tags in sorted order.
+func (r *Runtime) Images() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return sortedKeys(r.images)
+}
+
+// Builds returns the tags passed to Build, in call order.
+func (r *Runtime) Builds() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]string(nil), r.builds...)
+}
+
+// Execs returns every recorded exec in call order.
+func (r *Runtime) Execs() []Exec {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]Exec(nil), r.execs...)
+}
+
+// HandleExec registers fn for execs whose joined argv contains pattern.
+// Later registrations take precedence.
+func (r *Runtime) HandleExec(pattern string, fn ExecFunc) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.handlers = append(r.handlers, handler{pattern: pattern, fn: fn})
+}
+
+// WriteFile stores data at p inside the container's virtual filesystem.
+func (r *Runtime) WriteFile(name, p string, data []byte) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	c, ok := r.containers[name]
+	if !ok {
+		return noSuchContainer(name)
+	}
+	c.Files[path.Clean(p)] = append([]byte(nil), data...)
+	return nil
+}
+
+// ReadFile returns the file at p inside the container.
+func (r *Runtime) ReadFile(name, p string) ([]byte, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	c, ok := r.containers[name]
+	if !ok {
+		return nil, false
+	}
+	data, ok := c.Files[path.Clean(p)]
+	return data, ok
+}
+
+func (r *Runtime) Exists(name string) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	_, ok := r.containers[name]
+	return ok
+}
+
+func (r *Runtime) Running(name string) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	c, ok := r.containers[name]
+	return ok && c.Running
+}
+
+func (r *Runtime) Start(name string) error {
+	return r.setRunning(name, true)
+}
+
+func (r *Runtime) Stop(name string) error {
+	return r.setRunning(name, false)
+}
+
+func (r *Runtime) setRunning(name string, running bool) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	c, ok := r.containers[name]
+	if !ok {
+		return noSuchContainer(name)
+	}
+	if c.Running != running {
+		c.Running = running
+		action := "die"
+		if running {
+			action = "start"
+		} else {
+			c.FinishedAt = r.now()
+		}
+		r.emit(action, c)
+	}
+	return nil
+}
+
+func (r *Runtime) Remove(name string, force bool) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	c, ok := r.containers[name]
+	if !ok {
+		return noSuchContainer(name)
+	}
+	if c.Running && !force {
+		return fmt.Errorf("cannot remove container %s: container is running", name)
+	}
+	delete(r.containers, name)
+	if c.Running {
+		r.emit("die", c)
+	}
+	r.emit("destroy", c)
+	return nil
+}
+
+func (r *Runtime) Rename(oldName, newName string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	c, ok := r.containers[oldName]
+	if !ok {
+		return noSuchContainer(oldName)
+	}
+	if _, taken := r.containers[newName]; taken {
+		return fmt.Errorf("container name %s is already in use", newName)
+	}
+	delete(r.containers, oldName)
+	c.Name = newName
+	r.containers[newName] = c
+	r.emit("rename", c)
+	return nil
+}
+
+// RunDetached creates and starts a container. Like `docker run` without a
+// registry, it fails when the image is unknown.
+func (r *Runtime) RunDetached(opts docker.RunOptions) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, taken := r.containers[opts.Name]; taken {
+		return fmt.Errorf("container name %s is already in use", opts.Name)
+	}
+	img, ok := r.images[opts.Image]
+	if !ok {
+		return fmt.Errorf("unable to find image '%s' locally", opts.Image)
+	}
+	c := &Container{
+		Name:       opts.Name,
+		Image:      opts.Image,
+		Workdir:    opts.Workdir,
+		Running:    true,
+		Labels:     copyMap(img.labels),
+		Env:        copyMap(img.env),
+		Ports:      map[int]int{},
+		CreatedAt:  r.now(),
+		RunOptions: opts,
+		Files:      copyFiles(img.files),
+	}
+	for k, v := range opts.Labels {
+		c.Labels[k] = v
+	}
+	for k, v := range opts.Envs {
+		c.Env[k] = v
+	}
+	if opts.HostPort > 0 {
+		c.Ports[opts.ContainerPort] = opts.HostPort
+	}
+	for _, p := range opts.ExtraPorts {
+		c.Ports[p.ContainerPort] = p.HostPort
+	}
+	// docker run creates missing named volumes on demand.
+	for _, v := range opts.Volumes {
+		if _, ok := r.volumes[v.Name]; !ok {
+			r.volumes[v.Name] = docker.VolumeSummary{Name: v.Name, Labels: map[string]string{}, CreatedAt: r.now()}
+		}
+	}
+	r.containers[opts.Name] = c
+	r.emit("start", c)
+	return nil
+}
+
+// Events delivers lifecycle events from Start, Stop, Remove, Rename and
+// RunDetached until ctx is done.
+func (r *Runtime) Events(ctx context.Context, fn func(docker.ContainerEvent)) error {
+	ch := make(chan docker.ContainerEvent, 64)
+	r.mu.Lock()
+	r.watchers = append(r.watchers, ch)
+	r.mu.Unlock()
+	defer func() {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		for i, w := range r.watchers {
+			if w == ch {
+				r.watchers = append(r.watchers[:i], r.watchers[i+1:]...)
+				break
+			}
+		}
+	}()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case ev := <-ch:
+			fn(ev)
+		}
+	}
+}
+
+// emit queues an event for every Events caller. r.mu must be held.
+func (r *Runtime) emit(action string, c *Container) {
+	ev := docker.ContainerEvent{Action: action, Name: c.Name, Labels: copyMap(c.Labels)}
+	for _, w := range r.watchers {
+		select {
+		case w <- ev:
+		default:
+		}
+	}
+}
+
+func (r *Runtime) Inspect(name string) (*docker.ContainerInfo, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	c, ok := r.containers[name]
+	if !ok {
+		return nil, noSuchContainer(name)
+	}
+	info := &docker.ContainerInfo{
+		ID:         "fake-" + c.Name,
+		Name:       c.Name,
+		Image:      c.Image,
+		Running:    c.Running,
+		WorkingDir: c.Workdir,
+		Labels:     copyMap(c.Labels),
+		Ports:      map[int]int{},
+
+		ExecSessions: c.ExecSessions,
+		FinishedAt:   c.FinishedAt,
+	}
+	for _, k := range sortedKeys(c.Env) {
+		info.Env = append(info.Env, k+"="+c.Env[k])
+	}
+	for k, v := range c.Ports {
+		info.Ports[k] = v
+	}
+	if c.Running {
+		info.IPAddress = "172.17.0.2"
+	}
+	return info, nil
+}
+
+func (r *Runtime) List() ([]docker.ContainerSummary, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	list := make([]docker.ContainerSummary, 0, len(r.containers))
+	for _, name := range sortedKeys(r.containers) {
+		c := r.containers[name]
+		s := docker.ContainerSummary{
+			Name:      c.Name,
+			Image:     c.Image,
+			Running:   c.Running,
+			Status:    "Exited (0) 1 second ago",
+			Labels:    copyMap(c.Labels),
+			CreatedAt: c.CreatedAt,
+		}
+		if c.Running {
+			s.Status = "Up 1 second"
+		}
+		var ports []string
+		for _, cp := range sortedIntKeys(c.Ports) {
+			ports = append(ports, fmt.Sprintf("0.0.0.0:%d->%d/tcp", c.Ports[cp], cp))
+		}
+		s.Ports = strings.Join(ports, ", ")
+		list = append(list, s)
+	}
+	return list, nil
+}
+
+// UpdateLabels fails the way docker and podman do: neither can change the
+// labels of an existing container. Use SetLabels to seed them in tests.
+func (r *Runtime) UpdateLabels(name string, labels map[string]string) error {
+	return fmt.Errorf("unknown flag: --label-add")
+}
+
+// SetLabels adds labels to an existing container.
+func (r *Runtime) SetLabels(name string, labels map[string]string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	c, ok := r.containers[name]
+	if !ok {
+		return noSuchContainer(name)
+	}
+	for k, v := range labels {
+		c.Labels[k] = v
+	}
+	return nil
+}
+
+// Commit snapshots the container's labels, env and files into an image.
+func (r *Runtime) Commit(name, imageTag string, labels map[string]string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	c, ok := r.containers[name]
+	if !ok {
+		return noSuchContainer(name)
+	}
+	img := &image{labels: copyMap(c.Labels), env: copyMap(c.Env), files: copyFiles(c.Files), created: r.now()}
+	for k, v := range labels {
+		img.labels[k] = v
+	}
+	r.images[imageTag] = img
+	return nil
+}
+
+func (r *Runtime) AllocatedPorts() (map[int]bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	ports := map[int]bool{}
+	for _, c := range r.containers {
+		for _, hp := range c.Ports {
+			ports[hp] = true
+		}
+	}
+	return ports, nil
+}
+
+// Exec records the command and answers it from a registered handler, or
+// from the virtual filesystem for cat, test, mkdir and rm. Anything else
+// succeeds with no output.
+func (r *Runtime) Exec(ctx context.Context, name string, opts docker.ExecOptions) (string, error) {
+	if err := ctx.Err(); err != nil {
+		return "", err
+	}
+	out, err := r.exec(name, opts, false)
+	if opts.Stdout != nil {
+		_, _ = io.WriteString(opts.Stdout, out)
+		return "", err
+	}
+	return out, err
+}
+
+func (r *Runtime) ExecInteractive(name string, opts docker.ExecOptions) error {
+	_, err := r.exec(name, opts, true)
+	return err
+}
+
+func (r *Runtime) exec(name string, opts docker.ExecOptions, interactive bool) (string, error) {
+	e := Exec{
+		Container:   name,
+		User:        opts.User,
+		Workdir:     opts.Workdir,
+		Envs:        append([]string(nil), opts.Envs...),
+		Argv:        append([]string(nil), opts.Argv...),
+		Interactive: interactive,
+	}
+	r.mu.Lock()
+	c, ok := r.containers[name]
+	if !ok {
+		r.mu.Unlock()
+		return "", noSuchContainer(name)
+	}
+	if !c.Running {
+		r.mu.Unlock()
+		return "", fmt.Errorf("container %s is not running", name)
+	}
+	r.execs = append(r.execs, e)
+	var fn ExecFunc
+	cmdline := e.Command()
+	for i := len(r.handlers) - 1; i >= 0; i-- {
+		if strings.Contains(cmdline, r.handlers[i].pattern) {
+			fn = r.handlers[i].fn
+			break
+		}
+	}
+	if fn != nil {
+		r.mu.Unlock()
+		return fn(e)
+	}
+	defer r.mu.Unlock()
+	return c.builtin(e)
+}
+
+// builtin answers simple filesystem commands from the virtual filesystem.
+func (c *Container) builtin(e Exec) (string, error) {
+	if len(e.Argv) < 2 {
+		return "", nil
+	}
+	abs := func(p string) string {
+		if path.IsAbs(p) {
+			return path.Clean(p)
+		}
+		return path.Join(e.Workdir, p)
+	}
+	switch e.Argv[0] {
+	case "cat":
+		var b strings.Builder
+		for _, p := range e.Argv[1:] {
+			data, ok := c.Files[abs(p)]
+			if !ok {
+				return b.String(), &docker.ExitError{Code: 1, Stderr: fmt.Sprintf("cat: %s: No such file or directory", p)}
+			}
+			b.Write(data)
+		}
+		return b.String(), nil
+	case "test":
+		if len(e.Argv) != 3 {
+			return "", nil
+		}
+		p := abs(e.Argv[2])
+		_, isFile := c.Files[p]
+		isDir := c.isDir(p)
+		var ok bool
+		switch e.Argv[1] {
+		case "-e":
+			ok = isFile || isDir
+		case "-f":
+			ok = isFile
+		case "-d":
+			ok = isDir
+		}
+		if !ok {
+			return "", &docker.ExitError{Code: 1}
+		}
+		return "", nil
+	case "rm":
+		for _, p := range e.Argv[1:] {
+			if strings.HasPrefix(p, "-") {
+				continue
+			}
+			p = abs(p)
+			for f := range c.Files {
+				if f == p || strings.HasPrefix(f, p+"/") {
+					delete(c.Files, f)
+				}
+			}
+		}
+	}
+	// mkdir and everything else: directories are implicit.
+	return "", nil
+}
+
+func (c *Container) isDir(p string) bool {
+	if p == "/" {
+		return true
+	}
+	for f := range c.Files {
+		if strings.HasPrefix(f, p+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// CopyTo copies host files into the virtual filesystem with `docker cp`
+// semantics: an existing directory destination receives the source by name.
+func (r *Runtime) CopyTo(ctx context.Context, name, srcOnHost, dstInContainer string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	c, ok := r.containers[name]
+	if !ok {
+		return noSuchContainer(name)
+	}
+	contents := strings.HasSuffix(srcOnHost, string(filepath.Separator)+".")
+	root := filepath.Clean(srcOnHost)
+	dst := path.Clean(dstInContainer)
+	if !contents && c.isDir(dst) {
+		dst = path.Join(dst, filepath.Base(root))
+	}
+	return filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+		data, err := os.ReadFile(p)
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(root, p)
+		if err != nil {
+			return err
+		}
+		c.Files[path.Join(dst, filepath.ToSlash(rel))] = data
+		return nil
+	})
+}
+
+// CopyFrom writes a file or directory from the virtual filesystem to the host.
+func (r *Runtime) CopyFrom(ctx context.Context, name, srcInContainer, dstOnHost string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	c, ok := r.containers[name]
+	if !ok {
+		return noSuchContainer(name)
+	}
+	contents := strings.HasSuffix(srcInContainer, "/.")
+	src := path.Clean(srcInContainer)
+	if st, err := os.Stat(dstOnHost); err == nil && st.IsDir() && !contents {
+		dstOnHost = filepath.Join(dstOnHost, path.Base(src))
+	}
+	if data, ok := c.Files[src]; ok {
+		if err := os.MkdirAll(filepath.Dir(dstOnHost), 0o755); err != nil {
+			return err
+		}
+		return os.WriteFile(dstOnHost, data, 0o644)
+	}
+	if !c.isDir(src) {
+		return fmt.Errorf("could not find the file %s in container %s", srcInContainer, name)
+	}
+	for f, data := range c.Files {
+		if !strings.HasPrefix(f, src+"/") {
+			continue
+		}
+		target := filepath.Join(dstOnHost, filepath.FromSlash(strings.TrimPrefix(f, src+"/")))
+		if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+			return err
+		}
+		if err := os.WriteFile(target, data, 0o644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ContainerSize reports the total size of the container's files.
+func (r *Runtime) ContainerSize(name string) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	c, ok := r.containers[name]
+	if !ok {
+		return 0, noSuchContainer(name)
+	}
+	var size int64
+	for _, data := range c.Files {
+		size += int64(len(data))
+	}
+	return size, nil
+}
+
+// SetActivity sets the exec session count and CPU use a container reports.
+func (r *Runtime) SetActivity(name string, execSessions int, cpuPercent float64) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if c, ok := r.containers[name]; ok {
+		c.ExecSessions = execSessions
+		c.CPUPercent = cpuPercent
+	}
+}
+
+func (r *Runtime) CPUPercent(name string) (float64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	c, ok := r.containers[name]
+	if !ok {
+		return 0, noSuchContainer(name)
+	}
+	if !c.Running {
+		return 0, nil
+	}
+	return c.CPUPercent, nil
+}
+
+func (r *Runtime) ImageExists(tag string) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	_, ok := r.images[tag]
+	return ok
+}
+
+// ListImages reports one image per tag, and untagged images left by rebuilds. Size is the total of the image's
+// file contents.
+func (r *Runtime) ListImages(label string) ([]docker.ImageSummary, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var list []docker.ImageSummary
+	for _, tag := range sortedKeys(r.images) {
+		img := r.images[tag]
+		if _, ok := img.labels[label]; label != "" && !ok {
+			continue
+		}
+		var size int64
+		for _, data := range img.files {
+			size += int64(len(data))
+		}
+		var tags []string
+		if !strings.HasPrefix(tag, danglingPrefix) {
+			tags = []string{tag}
+		}
+		list = append(list, docker.ImageSummary{
+			ID:        "sha256:fake-" + tag,
+			Tags:      tags,
+			Labels:    copyMap(img.labels),
+			CreatedAt: img.created,
+			Size:      size,
+		})
+	}
+	return list, nil
+}
+
+// savedImage is the payload of a fake `docker save` stream.
+type savedImage struct {
+	Tag     string            `json:"tag"`
+	Labels  map[string]string `json:"labels"`
+	Env     map[string]string `json:"env"`
+	Files   map[string][]byte `json:"files"`
+	Created time.Time         `json:"created"`
+}
+
+const savedImageEntry = "dockertest-image.json"
+
+// SaveImage writes a tar stream holding the image's tag, labels, env and files.
+func (r *Runtime) SaveImage(ref string, w io.Writer) error {
+	r.mu.Lock()
+	img, ok := r.images[ref]
+	var data []byte
+	var err error
+	if ok {
+		data, err = json.Marshal(savedImage{Tag: ref, Labels: img.labels, Env: img.env, Files: img.files, Created: img.created})
+	}
+	r.mu.Unlock()
+	if !ok {
+		return fmt.Errorf("no such image: %s", ref)
+	}
+	if err != nil {
+		return err
+	}
+	tw := tar.NewWriter(w)
+	if err := tw.WriteHeader(&tar.Header{Name: savedImageEntry, Mode: 0o644, Size: int64(len(data))}); err != nil {
+		return err
+	}
+	if _, err := tw.Write(data); err != nil {
+		return err
+	}
+	return tw.Close()
+}
+
+// LoadImage reads a stream written by SaveImage.
+func (r *Runtime) LoadImage(rd io.Reader) ([]string, error) {
+	tr := tar.NewReader(rd)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil, fmt.Errorf("load image: no %s in stream", savedImageEntry)
+		}
+		if err != nil {
+			return nil, err
+		}
+		if hdr.Name != savedImageEntry {
+			continue
+		}
+		var saved savedImage
+		if err := json.NewDecoder(tr).Decode(&saved); err != nil {
+			return nil, err
+		}
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		r.images[saved.Tag] = &image{labels: copyMap(saved.Labels), env: copyMap(saved.Env), files: copyFiles(saved.Files), created: saved.Created}
+		return []string{saved.Tag}, nil
+	}
+}
+
+func (r *Runtime) RemoveImage(tag string, force bool) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	tag = strings.TrimPrefix(tag, "sha256:fake-")
+	if _, ok := r.images[tag]; !ok {
+		if force {
+			return nil
+		}
+		return fmt.Errorf("no such image: %s", tag)
+	}
+	if !force {
+		for _, c := range r.containers {
+			if c.Image == tag {
+				return fmt.Errorf("image %s is in use by container %s", tag, c.Name)
+			}
+		}
+	}
+	delete(r.images, tag)
+	return nil
+}
+
+func (r *Runtime) TagImage(srcTag, dstTag string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	img, ok := r.images[srcTag]
+	if !ok {
+		return fmt.Errorf("no such image: %s", srcTag)
+	}
+	r.images[dstTag] = &image{labels: copyMap(img.labels), env: copyMap(img.env), files: copyFiles(img.files), created: img.created}
+	return nil
+}
+
+func (r *Runtime) Pull(ref string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.images[ref]; !ok {
+		r.images[ref] = &image{labels: map[string]string{}, files: map[string][]byte{}, created: r.now()}
+	}
+	return nil
+}
+
+func (r *Runtime) Build(tag, dockerfilePath, contextDir string, opts docker.BuildOptions) error {
+	if _, err := os.Stat(dockerfilePath); err != nil {
+		return err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.builds = append(r.builds, tag)
+	// Like docker, rebuilding a tag leaves the previous image dangling.
+	if old, ok := r.images[tag]; ok {
+		r.dangling++
+		r.images[fmt.Sprintf("%s%d", danglingPrefix, r.dangling)] = old
+	}
+	labels := map[string]string{}
+	for i := 0; i+1 < len(opts.ExtraArgs); i++ {
+		if opts.ExtraArgs[i] == "--label" {
+			k, v, _ := strings.Cut(opts.ExtraArgs[i+1], "=")
+			labels[k] = v
+		}
+	}
+	r.images[tag] = &image{labels: labels, files: map[string][]byte{}, created: r.now()}
+	return nil
+}
+
+// CreateVolume records a named volume. Volume contents are not modelled.
+func (r *Runtime) CreateVolume(name string, labels map[string]string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.volumes[name]; !ok {
+		r.volumes[name] = docker.VolumeSummary{Name: name, Labels: copyMap(labels), CreatedAt: r.now()}
+	}
+	return nil
+}
+
+func (r *Runtime) ListVolumes() ([]docker.VolumeSummary, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	list := make([]docker.VolumeSummary, 0, len(r.volumes))
+	for _, name := range sortedKeys(r.volumes) {
+		v := r.volumes[name]
+		v.Labels = copyMap(v.Labels)
+		list = append(list, v)
+	}
+	return list, nil
+}
+
+// RemoveVolume deletes a volume, failing like docker while a container
+// (running or not) still mounts it.
+func (r *Runtime) RemoveVolume(name string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.volumes[name]; !ok {
+		return &docker.APIError{StatusCode: 404, Message: "get " + name + ": no such volume"}
+	}
+	for _, c := range r.containers {
+		for _, v := range c.RunOptions.Volumes {
+			if v.Name == name {
+				return &docker.APIError{StatusCode: 409, Message: "remove " + name + ": volume is in use - [" + c.Name + "]"}
+			}
+		}
+	}
+	delete(r.volumes, name)
+	return nil
+}
+
+// SetVolumeSize sets the usage VolumeSizes reports for a volume.
+func (r *Runtime) SetVolumeSize(name string, size int64) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.volSizes[name] = size
+}
+
+// VolumeSizes reports sizes set with SetVolumeSize for existing volumes.
+func (r *Runtime) VolumeSizes() (map[string]int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	sizes := map[string]int64{}
+	for name := range r.volumes {
+		if n, ok := r.volSizes[name]; ok {
+			sizes[name] = n
+		}
+	}
+	return sizes, nil
+}
+
+func noSuchContainer(name string) error {
+	return &docker.APIError{StatusCode: 404, Message: "No such container: " + name}
+}
+
+func copyMap(m map[string]string) map[string]string {
+	out := make(map[string]string, len(m))
+	for k, v := range m {
+		out[k] = v
+	}
+	return out
+}
+
+func copyFiles(m map[string][]byte) map[string][]byte {
+	out := make(map[string][]byte, len(m))
+	for k, v := range m {
+		out[path.Clean(k)] = append([]byte(nil), v...)
+	}
+	return out
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func sortedIntKeys(m map[int]int) []int {
+	keys := make([]int, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Ints(keys)
+	return keys
+}
@@ -0,0 +1,101 @@
+package dockertest
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"dv/internal/docker"
+)
+
+func TestExecRecordsAndUsesFilesystem(t *testing.T) {
+	t.Parallel()
+
+	rt := New()
+	rt.AddContainer(Container{Name: "agent", Running: true, Workdir: "/var/www/discourse"})
+	if err := rt.WriteFile("agent", "/var/www/discourse/config/site.yml", []byte("title: dv\n")); err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+
+	out, err := rt.Exec(ctx, "agent", docker.ExecOptions{Workdir: "/var/www/discourse", Argv: []string{"cat", "config/site.yml"}})
+	if err != nil || out != "title: dv\n" {
+		t.Errorf("cat = %q, %v", out, err)
+	}
+	if _, err := rt.Exec(ctx, "agent", docker.ExecOptions{Argv: []string{"test", "-d", "/var/www/discourse/config"}}); err != nil {
+		t.Errorf("test -d: %v", err)
+	}
+	if _, err := rt.Exec(ctx, "agent", docker.ExecOptions{Argv: []string{"test", "-f", "/missing"}}); err == nil {
+		t.Error("test -f /missing succeeded")
+	}
+
+	rt.HandleExec("git rev-parse", func(e Exec) (string, error) { return "main\n", nil })
+	out, _ = rt.Exec(ctx, "agent", docker.ExecOptions{User: "discourse", Argv: []string{"git", "rev-parse", "--abbrev-ref", "HEAD"}})
+	if out != "main\n" {
+		t.Errorf("handled exec = %q", out)
+	}
+
+	execs := rt.Execs()
+	if len(execs) != 4 || execs[3].User != "discourse" || execs[3].Command() != "git rev-parse --abbrev-ref HEAD" {
+		t.Errorf("execs = %+v", execs)
+	}
+
+	_ = rt.Stop("agent")
+	if _, err := rt.Exec(ctx, "agent", docker.ExecOptions{Argv: []string{"true"}}); err == nil {
+		t.Error("exec in stopped container succeeded")
+	}
+}
+
+func TestCopyRoundTrip(t *testing.T) {
+	t.Parallel()
+
+	rt := New()
+	rt.AddContainer(Container{Name: "agent", Running: true})
+	src := filepath.Join(t.TempDir(), "plugin")
+	if err := os.MkdirAll(filepath.Join(src, "lib"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(src, "lib", "a.rb"), []byte("puts 1\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+
+	if err := rt.CopyTo(ctx, "agent", src, "/plugins/my-plugin"); err != nil {
+		t.Fatalf("CopyTo: %v", err)
+	}
+	if data, ok := rt.ReadFile("agent", "/plugins/my-plugin/lib/a.rb"); !ok || string(data) != "puts 1\n" {
+		t.Errorf("copied file = %q, %v", data, ok)
+	}
+
+	dst := filepath.Join(t.TempDir(), "out")
+	if err := rt.CopyFrom(ctx, "agent", "/plugins/my-plugin", dst); err != nil {
+		t.Fatalf("CopyFrom: %v", err)
+	}
+	if data, err := os.ReadFile(filepath.Join(dst, "lib", "a.rb")); err != nil || string(data) != "puts 1\n" {
+		t.Errorf("host file = %q, %v", data, err)
+	}
+}
+
+func TestCommitCarriesFilesIntoNewContainers(t *testing.T) {
+	t.Parallel()
+
+	rt := New()
+	rt.AddContainer(Container{Name: "src", Running: true, Files: map[string][]byte{"/data/x": []byte("1")}})
+	if err := rt.Commit("src", "snap", map[string]string{"com.dv.snapshot": "snap"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := rt.RunDetached(docker.RunOptions{Name: "dst", Image: "snap", HostPort: 4201, ContainerPort: 4200}); err != nil {
+		t.Fatal(err)
+	}
+	if data, ok := rt.ReadFile("dst", "/data/x"); !ok || string(data) != "1" {
+		t.Errorf("file not carried over: %q", data)
+	}
+	ports, _ := rt.AllocatedPorts()
+	if !ports[4201] {
+		t.Errorf("AllocatedPorts = %v", ports)
+	}
+	if err := rt.RunDetached(docker.RunOptions{Name: "other", Image: "missing"}); err == nil {
+		t.Error("RunDetached with unknown image succeeded")
+	}
+}
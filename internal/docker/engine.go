@@ -0,0 +1,644 @@
+package docker
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"slices"
+	"strings"
+	"time"
+)
+
+// ErrNotFound is matched (via errors.Is) by Engine API errors for missing
+// containers, images, exec instances and paths.
+var ErrNotFound = errors.New("not found")
+
+// APIError is returned when the Engine API answers with a non-2xx status.
+type APIError struct {
+	StatusCode int
+	Message    string
+}
+
+func (e *APIError) Error() string {
+	if e.Message == "" {
+		return fmt.Sprintf("docker api: status %d", e.StatusCode)
+	}
+	return fmt.Sprintf("docker api: %s", e.Message)
+}
+
+func (e *APIError) Is(target error) bool {
+	return target == ErrNotFound && e.StatusCode == http.StatusNotFound
+}
+
+// ExitError reports a non-zero exit status from a command run inside a container.
+type ExitError struct {
+	Code   int
+	Stderr string
+}
+
+func (e *ExitError) Error() string {
+	return fmt.Sprintf("exit status %d", e.Code)
+}
+
+// IsNotFound reports whether err means the referenced object does not exist.
+func IsNotFound(err error) bool {
+	return errors.Is(err, ErrNotFound)
+}
+
+// engineClient talks to the Docker Engine API. A single http.Client is shared
+// so that keep-alive connections are reused across helper calls.
+type engineClient struct {
+	http    *http.Client
+	baseURL string
+}
+
+// newEngineClient builds a client for a DOCKER_HOST style address. Only
+// unix:// and plain tcp:// are supported; TLS and ssh hosts use the CLI.
+func newEngineClient(host string) (*engineClient, error) {
+	u, err := url.Parse(host)
+	if err != nil {
+		return nil, fmt.Errorf("invalid docker host %q: %w", host, err)
+	}
+	transport := &http.Transport{
+		MaxIdleConns:        16,
+		MaxIdleConnsPerHost: 16,
+		IdleConnTimeout:     90 * time.Second,
+	}
+	var baseURL string
+	switch u.Scheme {
+	case "unix":
+		sock := u.Path
+		if _, err := os.Stat(sock); err != nil {
+			return nil, err
+		}
+		transport.DialContext = func(ctx context.Context, _, _ string) (net.Conn, error) {
+			var d net.Dialer
+			return d.DialContext(ctx, "unix", sock)
+		}
+		baseURL = "http://docker"
+	case "tcp":
+		if isTruthyEnv("DOCKER_TLS_VERIFY") || strings.TrimSpace(os.Getenv("DOCKER_CERT_PATH")) != "" {
+			return nil, fmt.Errorf("tls docker hosts are handled by the docker CLI")
+		}
+		baseURL = "http://" + u.Host
+	default:
+		return nil, fmt.Errorf("unsupported docker host scheme %q", u.Scheme)
+	}
+	return &engineClient{http: &http.Client{Transport: transport}, baseURL: baseURL}, nil
+}
+
+func (c *engineClient) do(ctx context.Context, method, path string, query url.Values, body io.Reader, contentType string) (*http.Response, error) {
+	u := c.baseURL + path
+	if len(query) > 0 {
+		u += "?" + query.Encode()
+	}
+	req, err := http.NewRequestWithContext(ctx, method, u, body)
+	if err != nil {
+		return nil, err
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode >= 200 && resp.StatusCode < 400 {
+		return resp, nil
+	}
+	defer resp.Body.Close()
+	apiErr := &APIError{StatusCode: resp.StatusCode}
+	data, _ := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+	var msg struct {
+		Message string `json:"message"`
+	}
+	if json.Unmarshal(data, &msg) == nil && msg.Message != "" {
+		apiErr.Message = msg.Message
+	} else {
+		apiErr.Message = strings.TrimSpace(string(data))
+	}
+	return nil, apiErr
+}
+
+// call performs a request whose response body is either decoded into out or discarded.
+func (c *engineClient) call(ctx context.Context, method, path string, query url.Values, in any, out any) error {
+	var body io.Reader
+	contentType := ""
+	if in != nil {
+		b, err := json.Marshal(in)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(b)
+		contentType = "application/json"
+	}
+	resp, err := c.do(ctx, method, path, query, body, contentType)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if out == nil {
+		_, _ = io.Copy(io.Discard, resp.Body)
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+func (c *engineClient) ping(ctx context.Context) error {
+	return c.call(ctx, http.MethodGet, "/_ping", nil, nil, nil)
+}
+
+type portBinding struct {
+	HostIP   string `json:"HostIp"`
+	HostPort string `json:"HostPort"`
+}
+
+type containerJSON struct {
+	ID      string   `json:"Id"`
+	Name    string   `json:"Name"`
+	ExecIDs []string `json:"ExecIDs"`
+	State   struct {
+		Status     string `json:"Status"`
+		Running    bool   `json:"Running"`
+		Pid        int    `json:"Pid"`
+		StartedAt  string `json:"StartedAt"`
+		FinishedAt string `json:"FinishedAt"`
+	} `json:"State"`
+	Config struct {
+		Image      string            `json:"Image"`
+		WorkingDir string            `json:"WorkingDir"`
+		Env        []string          `json:"Env"`
+		Labels     map[string]string `json:"Labels"`
+	} `json:"Config"`
+	HostConfig struct {
+		PortBindings map[string][]portBinding `json:"PortBindings"`
+	} `json:"HostConfig"`
+	NetworkSettings struct {
+		Networks map[string]struct {
+			IPAddress string `json:"IPAddress"`
+		} `json:"Networks"`
+	} `json:"NetworkSettings"`
+}
+
+func (c *engineClient) containerSize(ctx context.Context, name string) (int64, error) {
+	q := url.Values{}
+	q.Set("size", "1")
+	var out struct {
+		SizeRw int64 `json:"SizeRw"`
+	}
+	if err := c.call(ctx, http.MethodGet, "/containers/"+url.PathEscape(name)+"/json", q, nil, &out); err != nil {
+		return 0, err
+	}
+	return out.SizeRw, nil
+}
+
+func (c *engineClient) cpuPercent(ctx context.Context, name string) (float64, error) {
+	q := url.Values{}
+	q.Set("stream", "false")
+	var out statsJSON
+	if err := c.call(ctx, http.MethodGet, "/containers/"+url.PathEscape(name)+"/stats", q, nil, &out); err != nil {
+		return 0, err
+	}
+	return out.cpuPercent(), nil
+}
+
+// statsJSON is the CPU part of a one-shot stats sample. The engine fills
+// PreCPUStats with the reading taken about a second earlier.
+type statsJSON struct {
+	CPUStats    cpuStatsJSON `json:"cpu_stats"`
+	PreCPUStats cpuStatsJSON `json:"precpu_stats"`
+}
+
+type cpuStatsJSON struct {
+	CPUUsage struct {
+		TotalUsage  uint64   `json:"total_usage"`
+		PercpuUsage []uint64 `json:"percpu_usage"`
+	} `json:"cpu_usage"`
+	SystemUsage uint64 `json:"system_cpu_usage"`
+	OnlineCPUs  int    `json:"online_cpus"`
+}
+
+// cpuPercent applies the calculation `docker stats` uses.
+func (s statsJSON) cpuPercent() float64 {
+	cpuDelta := float64(s.CPUStats.CPUUsage.TotalUsage) - float64(s.PreCPUStats.CPUUsage.TotalUsage)
+	sysDelta := float64(s.CPUStats.SystemUsage) - float64(s.PreCPUStats.SystemUsage)
+	if cpuDelta <= 0 || sysDelta <= 0 {
+		return 0
+	}
+	cpus := s.CPUStats.OnlineCPUs
+	if cpus == 0 {
+		cpus = len(s.CPUStats.CPUUsage.PercpuUsage)
+	}
+	return cpuDelta / sysDelta * float64(cpus) * 100
+}
+
+// inspectContainer returns the container with exactly this name (or full ID).
+// Docker resolves ID prefixes too; those are reported as not found to match
+// the `docker ps -f name=^NAME$` semantics the CLI path uses.
+func (c *engineClient) inspectContainer(ctx context.Context, name string) (*containerJSON, error) {
+	var out containerJSON
+	if err := c.call(ctx, http.MethodGet, "/containers/"+url.PathEscape(name)+"/json", nil, nil, &out); err != nil {
+		return nil, err
+	}
+	if strings.TrimPrefix(out.Name, "/") != name && out.ID != name {
+		return nil, &APIError{StatusCode: http.StatusNotFound, Message: "No such container: " + name}
+	}
+	return &out, nil
+}
+
+type containerSummary struct {
+	ID      string            `json:"Id"`
+	Names   []string          `json:"Names"`
+	Image   string            `json:"Image"`
+	State   string            `json:"State"`
+	Status  string            `json:"Status"`
+	Labels  map[string]string `json:"Labels"`
+	Created int64             `json:"Created"`
+	Ports   []struct {
+		IP          string `json:"IP"`
+		PrivatePort int    `json:"PrivatePort"`
+		PublicPort  int    `json:"PublicPort"`
+		Type        string `json:"Type"`
+	} `json:"Ports"`
+}
+
+func (c *engineClient) listContainers(ctx context.Context, all bool) ([]containerSummary, error) {
+	q := url.Values{}
+	if all {
+		q.Set("all", "1")
+	}
+	var out []containerSummary
+	if err := c.call(ctx, http.MethodGet, "/containers/json", q, nil, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *engineClient) startContainer(ctx context.Context, name string) error {
+	// 304 Not Modified (already running) is treated as success, like the CLI.
+	return c.call(ctx, http.MethodPost, "/containers/"+url.PathEscape(name)+"/start", nil, nil, nil)
+}
+
+func (c *engineClient) stopContainer(ctx context.Context, name string) error {
+	return c.call(ctx, http.MethodPost, "/containers/"+url.PathEscape(name)+"/stop", nil, nil, nil)
+}
+
+func (c *engineClient) removeContainer(ctx context.Context, name string, force bool) error {
+	q := url.Values{}
+	if force {
+		q.Set("force", "1")
+	}
+	return c.call(ctx, http.MethodDelete, "/containers/"+url.PathEscape(name), q, nil, nil)
+}
+
+func (c *engineClient) renameContainer(ctx context.Context, oldName, newName string) error {
+	q := url.Values{"name": {newName}}
+	return c.call(ctx, http.MethodPost, "/containers/"+url.PathEscape(oldName)+"/rename", q, nil, nil)
+}
+
+func (c *engineClient) commitContainer(ctx context.Context, name, imageRef string, labels map[string]string) error {
+	repo, tag := splitImageRef(imageRef)
+	q := url.Values{"container": {name}, "repo": {repo}, "tag": {tag}}
+	for _, change := range labelChanges(labels) {
+		q.Add("changes", change)
+	}
+	return c.call(ctx, http.MethodPost, "/commit", q, nil, nil)
+}
+
+type imageJSON struct {
+	ID       string            `json:"Id"`
+	RepoTags []string          `json:"RepoTags"`
+	Created  int64             `json:"Created"`
+	Size     int64             `json:"Size"`
+	Labels   map[string]string `json:"Labels"`
+}
+
+func (c *engineClient) listImages(ctx context.Context, label string) ([]imageJSON, error) {
+	q := url.Values{}
+	if label != "" {
+		filters, _ := json.Marshal(map[string][]string{"label": {label}})
+		q.Set("filters", string(filters))
+	}
+	var out []imageJSON
+	if err := c.call(ctx, http.MethodGet, "/images/json", q, nil, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *engineClient) imageExists(ctx context.Context, ref string) (bool, error) {
+	err := c.call(ctx, http.MethodGet, "/images/"+ref+"/json", nil, nil, nil)
+	if err == nil {
+		return true, nil
+	}
+	if IsNotFound(err) {
+		return false, nil
+	}
+	return false, err
+}
+
+func (c *engineClient) removeImage(ctx context.Context, ref string, force bool) error {
+	q := url.Values{}
+	if force {
+		q.Set("force", "1")
+	}
+	return c.call(ctx, http.MethodDelete, "/images/"+ref, q, nil, nil)
+}
+
+func (c *engineClient) tagImage(ctx context.Context, src, dst string) error {
+	repo, tag := splitImageRef(dst)
+	q := url.Values{"repo": {repo}, "tag": {tag}}
+	return c.call(ctx, http.MethodPost, "/images/"+src+"/tag", q, nil, nil)
+}
+
+func (c *engineClient) saveImage(ctx context.Context, ref string, w io.Writer) error {
+	resp, err := c.do(ctx, http.MethodGet, "/images/"+ref+"/get", nil, nil, "")
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	_, err = io.Copy(w, resp.Body)
+	return err
+}
+
+// loadImage uploads a tar stream and returns the tags the daemon reports
+// loading, streamed as the same "Loaded image: REF" lines the CLI prints.
+// Failures after the upload starts arrive as an error message in the JSON
+// progress stream rather than a status code.
+func (c *engineClient) loadImage(ctx context.Context, r io.Reader) ([]string, error) {
+	q := url.Values{"quiet": {"1"}}
+	resp, err := c.do(ctx, http.MethodPost, "/images/load", q, r, "application/x-tar")
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	dec := json.NewDecoder(resp.Body)
+	var stream strings.Builder
+	for {
+		var msg struct {
+			Stream string `json:"stream"`
+			Error  string `json:"error"`
+		}
+		if err := dec.Decode(&msg); err == io.EOF {
+			return loadedImageRefs(stream.String()), nil
+		} else if err != nil {
+			return nil, err
+		}
+		if msg.Error != "" {
+			return nil, fmt.Errorf("load image: %s", msg.Error)
+		}
+		stream.WriteString(msg.Stream)
+	}
+}
+
+type volumeJSON struct {
+	Name      string            `json:"Name"`
+	Labels    map[string]string `json:"Labels"`
+	CreatedAt string            `json:"CreatedAt"`
+}
+
+// createVolume is idempotent: the daemon returns the existing volume when
+// one with the same name exists.
+func (c *engineClient) createVolume(ctx context.Context, name string, labels map[string]string) error {
+	in := map[string]any{"Name": name, "Labels": labels}
+	return c.call(ctx, http.MethodPost, "/volumes/create", nil, in, nil)
+}
+
+func (c *engineClient) listVolumes(ctx context.Context) ([]volumeJSON, error) {
+	var out struct {
+		Volumes []volumeJSON `json:"Volumes"`
+	}
+	if err := c.call(ctx, http.MethodGet, "/volumes", nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return out.Volumes, nil
+}
+
+func (c *engineClient) removeVolume(ctx context.Context, name string) error {
+	return c.call(ctx, http.MethodDelete, "/volumes/"+url.PathEscape(name), nil, nil, nil)
+}
+
+func (c *engineClient) volumeSizes(ctx context.Context) (map[string]int64, error) {
+	q := url.Values{}
+	q.Set("type", "volume")
+	var out struct {
+		Volumes []struct {
+			Name      string `json:"Name"`
+			UsageData *struct {
+				Size int64 `json:"Size"`
+			} `json:"UsageData"`
+		} `json:"Volumes"`
+	}
+	if err := c.call(ctx, http.MethodGet, "/system/df", q, nil, &out); err != nil {
+		return nil, err
+	}
+	sizes := map[string]int64{}
+	for _, v := range out.Volumes {
+		// A size of -1 means the daemon could not compute it.
+		if v.UsageData != nil && v.UsageData.Size >= 0 {
+			sizes[v.Name] = v.UsageData.Size
+		}
+	}
+	return sizes, nil
+}
+
+// eventJSON is one message of the /events stream. Podman's CLI prints the
+// name and status at the top level instead of under Actor and Action.
+type eventJSON struct {
+	Type   string `json:"Type"`
+	Action string `json:"Action"`
+	Status string `json:"Status"`
+	Name   string `json:"Name"`
+	Actor  struct {
+		Attributes map[string]string `json:"Attributes"`
+	} `json:"Actor"`
+	Attributes map[string]string `json:"Attributes"`
+}
+
+// event converts a raw message, reporting false for anything that is not a
+// container lifecycle event.
+func (e eventJSON) event() (ContainerEvent, bool) {
+	action := e.Action
+	if action == "" {
+		action = e.Status
+	}
+	attrs := e.Actor.Attributes
+	if attrs == nil {
+		attrs = e.Attributes
+	}
+	name := e.Name
+	if name == "" {
+		name = attrs["name"]
+	}
+	if !strings.EqualFold(e.Type, "container") || name == "" || !slices.Contains(containerEventActions, action) {
+		return ContainerEvent{}, false
+	}
+	// Attributes mix labels with a few engine fields.
+	labels := map[string]string{}
+	for k, v := range attrs {
+		if k != "name" && k != "image" && k != "oldName" && k != "exitCode" {
+			labels[k] = v
+		}
+	}
+	return ContainerEvent{Action: action, Name: name, Labels: labels}, true
+}
+
+// decodeEvents reads a stream of event messages, calling fn for each
+// container lifecycle event.
+func decodeEvents(r io.Reader, fn func(ContainerEvent)) error {
+	dec := json.NewDecoder(r)
+	for {
+		var e eventJSON
+		if err := dec.Decode(&e); err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			return err
+		}
+		if ev, ok := e.event(); ok {
+			fn(ev)
+		}
+	}
+}
+
+func (c *engineClient) events(ctx context.Context, fn func(ContainerEvent)) error {
+	filters, _ := json.Marshal(map[string][]string{"type": {"container"}, "event": containerEventActions})
+	resp, err := c.do(ctx, http.MethodGet, "/events", url.Values{"filters": {string(filters)}}, nil, "")
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if err := decodeEvents(resp.Body, fn); err != nil && ctx.Err() == nil {
+		return err
+	}
+	return nil
+}
+
+// exec runs argv inside the container and returns its demultiplexed stdout,
+// or copies it to stream as it arrives when stream is non-nil. When combined
+// is true stderr is interleaved into the same output in the order the daemon
+// delivered it.
+func (c *engineClient) exec(ctx context.Context, name, user, workdir string, envs Envs, argv []string, combined bool, stream io.Writer) (stdout []byte, err error) {
+	create := map[string]any{
+		"AttachStdout": true,
+		"AttachStderr": true,
+		"Cmd":          argv,
+	}
+	if user != "" {
+		create["User"] = user
+	}
+	if workdir != "" {
+		create["WorkingDir"] = workdir
+	}
+	if len(envs) > 0 {
+		create["Env"] = []string(envs)
+	}
+	var created struct {
+		ID string `json:"Id"`
+	}
+	if err := c.call(ctx, http.MethodPost, "/containers/"+url.PathEscape(name)+"/exec", nil, create, &created); err != nil {
+		return nil, err
+	}
+
+	body, _ := json.Marshal(map[string]bool{"Detach": false, "Tty": false})
+	resp, err := c.do(ctx, http.MethodPost, "/exec/"+created.ID+"/start", nil, bytes.NewReader(body), "application/json")
+	if err != nil {
+		return nil, err
+	}
+	var outBuf, errBuf bytes.Buffer
+	outDst := io.Writer(&outBuf)
+	if stream != nil {
+		outDst = stream
+	}
+	errDst := io.Writer(&errBuf)
+	if combined {
+		errDst = outDst
+	}
+	copyErr := demuxStream(outDst, errDst, resp.Body)
+	resp.Body.Close()
+	if copyErr != nil {
+		return outBuf.Bytes(), copyErr
+	}
+
+	var inspect struct {
+		Running  bool `json:"Running"`
+		ExitCode int  `json:"ExitCode"`
+	}
+	if err := c.call(ctx, http.MethodGet, "/exec/"+created.ID+"/json", nil, nil, &inspect); err != nil {
+		return outBuf.Bytes(), err
+	}
+	if inspect.ExitCode != 0 {
+		return outBuf.Bytes(), &ExitError{Code: inspect.ExitCode, Stderr: errBuf.String()}
+	}
+	return outBuf.Bytes(), nil
+}
+
+// demuxStream splits a non-TTY attach stream into stdout and stderr. Each
+// frame carries an 8 byte header: stream type, three padding bytes and a
+// big-endian payload length.
+func demuxStream(stdout, stderr io.Writer, r io.Reader) error {
+	var header [8]byte
+	for {
+		if _, err := io.ReadFull(r, header[:]); err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			return err
+		}
+		size := int64(binary.BigEndian.Uint32(header[4:]))
+		var dst io.Writer
+		switch header[0] {
+		case 1:
+			dst = stdout
+		case 2:
+			dst = stderr
+		default:
+			dst = io.Discard
+		}
+		if _, err := io.CopyN(dst, r, size); err != nil {
+			return err
+		}
+	}
+}
+
+// splitImageRef splits repo[:tag] into its parts, defaulting the tag to latest.
+func splitImageRef(ref string) (repo, tag string) {
+	lastSlash := strings.LastIndex(ref, "/")
+	if i := strings.LastIndex(ref, ":"); i > lastSlash {
+		return ref[:i], ref[i+1:]
+	}
+	return ref, "latest"
+}
+
+// hostPorts flattens published host ports from a container's port bindings.
+func (cj *containerJSON) hostPorts() []int {
+	var ports []int
+	for _, bindings := range cj.HostConfig.PortBindings {
+		if len(bindings) == 0 {
+			continue
+		}
+		var p int
+		if _, err := fmt.Sscanf(bindings[0].HostPort, "%d", &p); err == nil {
+			ports = append(ports, p)
+		}
+	}
+	return ports
+}
+
+// ipAddress concatenates network IPs in key order, matching the
+// {{range .NetworkSettings.Networks}} template used by the CLI path.
+func (cj *containerJSON) ipAddress() string {
+	var b strings.Builder
+	for _, k := range sortedKeys(cj.NetworkSettings.Networks) {
+		b.WriteString(cj.NetworkSettings.Networks[k].IPAddress)
+	}
+	return b.String()
+}
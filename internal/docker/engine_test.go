@@ -0,0 +1,254 @@
+package docker
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// newFakeEngine serves handler on a unix socket and returns a client for it.
+func newFakeEngine(t *testing.T, handler http.Handler) *engineClient {
+	t.Helper()
+	// Unix socket paths are length-limited, so avoid the long t.TempDir path.
+	dir, err := os.MkdirTemp("", "dv-engine")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	sock := filepath.Join(dir, "docker.sock")
+	l, err := net.Listen("unix", sock)
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := httptest.NewUnstartedServer(handler)
+	srv.Listener = l
+	srv.Start()
+	t.Cleanup(srv.Close)
+
+	c, err := newEngineClient("unix://" + sock)
+	if err != nil {
+		t.Fatalf("newEngineClient: %v", err)
+	}
+	return c
+}
+
+func frame(stream byte, payload string) []byte {
+	header := make([]byte, 8)
+	header[0] = stream
+	binary.BigEndian.PutUint32(header[4:], uint32(len(payload)))
+	return append(header, payload...)
+}
+
+func TestDemuxStream(t *testing.T) {
+	t.Parallel()
+
+	var in bytes.Buffer
+	in.Write(frame(1, "out1 "))
+	in.Write(frame(2, "err1 "))
+	in.Write(frame(1, "out2"))
+
+	var stdout, stderr bytes.Buffer
+	if err := demuxStream(&stdout, &stderr, &in); err != nil {
+		t.Fatalf("demuxStream: %v", err)
+	}
+	if stdout.String() != "out1 out2" {
+		t.Errorf("stdout = %q", stdout.String())
+	}
+	if stderr.String() != "err1 " {
+		t.Errorf("stderr = %q", stderr.String())
+	}
+}
+
+func TestSplitImageRef(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		ref, repo, tag string
+	}{
+		{"ai_agent", "ai_agent", "latest"},
+		{"ai_agent:snap", "ai_agent", "snap"},
+		{"localhost:5000/dv/agent", "localhost:5000/dv/agent", "latest"},
+		{"localhost:5000/dv/agent:v2", "localhost:5000/dv/agent", "v2"},
+	}
+	for _, tt := range tests {
+		repo, tag := splitImageRef(tt.ref)
+		if repo != tt.repo || tag != tt.tag {
+			t.Errorf("splitImageRef(%q) = (%q, %q), want (%q, %q)", tt.ref, repo, tag, tt.repo, tt.tag)
+		}
+	}
+}
+
+func TestNewEngineClientRejectsUnsupportedHosts(t *testing.T) {
+	t.Parallel()
+
+	for _, host := range []string{"ssh://user@remote", "npipe:////./pipe/docker_engine", "unix:///nonexistent/docker.sock"} {
+		if _, err := newEngineClient(host); err == nil {
+			t.Errorf("newEngineClient(%q) succeeded, want error", host)
+		}
+	}
+}
+
+func TestEngineInspectContainer(t *testing.T) {
+	t.Parallel()
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /containers/agent/json", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"Id":"abc123","Name":"/agent","State":{"Running":true},
+			"Config":{"Labels":{"com.dv.owner":"dv"}},
+			"HostConfig":{"PortBindings":{"4200/tcp":[{"HostIp":"127.0.0.1","HostPort":"4201"}]}}}`))
+	})
+	mux.HandleFunc("GET /containers/ab/json", func(w http.ResponseWriter, r *http.Request) {
+		// Docker resolves ID prefixes; dv must not treat these as name matches.
+		w.Write([]byte(`{"Id":"abc123","Name":"/agent"}`))
+	})
+	mux.HandleFunc("GET /containers/missing/json", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(`{"message":"No such container: missing"}`))
+	})
+	c := newFakeEngine(t, mux)
+	ctx := context.Background()
+
+	ctr, err := c.inspectContainer(ctx, "agent")
+	if err != nil {
+		t.Fatalf("inspectContainer: %v", err)
+	}
+	if !ctr.State.Running || ctr.Config.Labels["com.dv.owner"] != "dv" {
+		t.Errorf("unexpected container: %+v", ctr)
+	}
+	if ports := ctr.hostPorts(); len(ports) != 1 || ports[0] != 4201 {
+		t.Errorf("hostPorts() = %v, want [4201]", ports)
+	}
+
+	_, err = c.inspectContainer(ctx, "missing")
+	if !IsNotFound(err) {
+		t.Errorf("missing container error = %v, want not found", err)
+	}
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.Message != "No such container: missing" {
+		t.Errorf("error message not decoded: %v", err)
+	}
+
+	if _, err := c.inspectContainer(ctx, "ab"); !IsNotFound(err) {
+		t.Errorf("ID prefix lookup error = %v, want not found", err)
+	}
+}
+
+func TestEngineCPUPercent(t *testing.T) {
+	t.Parallel()
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /containers/agent/stats", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("stream") != "false" {
+			t.Errorf("stats query = %s, want a single sample", r.URL.RawQuery)
+		}
+		w.Write([]byte(`{
+			"cpu_stats":{"cpu_usage":{"total_usage":3000000000},"system_cpu_usage":110000000000,"online_cpus":4},
+			"precpu_stats":{"cpu_usage":{"total_usage":2000000000},"system_cpu_usage":100000000000}}`))
+	})
+	c := newFakeEngine(t, mux)
+
+	// 1s of CPU over 10s of system time on 4 CPUs is 40% of one core.
+	got, err := c.cpuPercent(context.Background(), "agent")
+	if err != nil {
+		t.Fatalf("cpuPercent: %v", err)
+	}
+	if got < 39.99 || got > 40.01 {
+		t.Errorf("cpuPercent = %v, want 40", got)
+	}
+}
+
+func TestDecodeEvents(t *testing.T) {
+	t.Parallel()
+
+	stream := `{"Type":"container","Action":"start","Actor":{"ID":"abc","Attributes":{"name":"agent","image":"ai_agent","com.dv.owner":"dv"}}}
+{"Type":"container","Action":"exec_start: bash","Actor":{"Attributes":{"name":"agent"}}}
+{"Type":"network","Action":"connect","Actor":{"Attributes":{"name":"bridge"}}}
+{"ID":"def","Name":"other","Status":"die","Type":"container","Attributes":{"image":"x","exitCode":"0"}}
+`
+	var got []ContainerEvent
+	if err := decodeEvents(strings.NewReader(stream), func(ev ContainerEvent) { got = append(got, ev) }); err != nil {
+		t.Fatalf("decodeEvents: %v", err)
+	}
+	want := []ContainerEvent{
+		{Action: "start", Name: "agent", Labels: map[string]string{"com.dv.owner": "dv"}},
+		{Action: "die", Name: "other", Labels: map[string]string{}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("events = %+v, want %+v", got, want)
+	}
+}
+
+func TestEngineExec(t *testing.T) {
+	t.Parallel()
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /containers/agent/exec", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`{"Id":"exec1"}`))
+	})
+	mux.HandleFunc("POST /exec/exec1/start", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/vnd.docker.raw-stream")
+		w.Write(frame(1, "hello "))
+		w.Write(frame(2, "oops"))
+		w.Write(frame(1, "world"))
+	})
+	mux.HandleFunc("GET /exec/exec1/json", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"Running":false,"ExitCode":3}`))
+	})
+	c := newFakeEngine(t, mux)
+	ctx := context.Background()
+
+	out, err := c.exec(ctx, "agent", "discourse", "/var/www/discourse", nil, []string{"true"}, false, nil)
+	if string(out) != "hello world" {
+		t.Errorf("stdout = %q", out)
+	}
+	var exitErr *ExitError
+	if !errors.As(err, &exitErr) || exitErr.Code != 3 || exitErr.Stderr != "oops" {
+		t.Errorf("exec error = %#v, want exit 3 with stderr", err)
+	}
+
+	out, _ = c.exec(ctx, "agent", "discourse", "", nil, []string{"true"}, true, nil)
+	if string(out) != "hello oopsworld" {
+		t.Errorf("combined output = %q", out)
+	}
+}
+
+func TestTarRoundTrip(t *testing.T) {
+	t.Parallel()
+
+	src := filepath.Join(t.TempDir(), "plugin")
+	if err := os.MkdirAll(filepath.Join(src, "lib"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(src, "lib", "a.rb"), []byte("puts 1\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink("lib/a.rb", filepath.Join(src, "link.rb")); err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	if err := writeTar(&buf, src, "plugin"); err != nil {
+		t.Fatalf("writeTar: %v", err)
+	}
+	dst := filepath.Join(t.TempDir(), "copy")
+	if err := extractTar(&buf, dst); err != nil {
+		t.Fatalf("extractTar: %v", err)
+	}
+	data, err := os.ReadFile(filepath.Join(dst, "lib", "a.rb"))
+	if err != nil || string(data) != "puts 1\n" {
+		t.Errorf("copied file = %q, %v", data, err)
+	}
+	if target, err := os.Readlink(filepath.Join(dst, "link.rb")); err != nil || target != "lib/a.rb" {
+		t.Errorf("symlink = %q, %v", target, err)
+	}
+}
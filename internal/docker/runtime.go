@@ -0,0 +1,230 @@
+package docker
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Supported container runtimes, selected via config.Config.ContainerRuntime.
+const (
+	RuntimeDocker = "docker"
+	RuntimePodman = "podman"
+)
+
+// Runtime is the container engine dv drives. The package-level helpers in
+// this package delegate to the runtime installed with SetRuntime, so callers
+// don't need to know which engine is in use.
+type Runtime interface {
+	// Name returns the runtime identifier (RuntimeDocker or RuntimePodman).
+	Name() string
+	// Binary is the CLI executable used for interactive and fallback paths.
+	Binary() string
+
+	Exists(name string) bool
+	Running(name string) bool
+	Start(name string) error
+	Stop(name string) error
+	Remove(name string, force bool) error
+	Rename(oldName, newName string) error
+	RunDetached(opts RunOptions) error
+	Inspect(name string) (*ContainerInfo, error)
+	List() ([]ContainerSummary, error)
+	UpdateLabels(name string, labels map[string]string) error
+	// ContainerSize returns the size in bytes of the container's writable layer.
+	ContainerSize(name string) (int64, error)
+	// CPUPercent samples the container's current CPU use, where 100 is one
+	// full core.
+	CPUPercent(name string) (float64, error)
+	// Commit creates an image from a container, adding labels to its config.
+	Commit(name, imageTag string, labels map[string]string) error
+	AllocatedPorts() (map[int]bool, error)
+	// Events calls fn for each container start, die, destroy and rename until
+	// ctx is cancelled or the event stream ends.
+	Events(ctx context.Context, fn func(ContainerEvent)) error
+
+	Exec(ctx context.Context, name string, opts ExecOptions) (string, error)
+	ExecInteractive(name string, opts ExecOptions) error
+	CopyTo(ctx context.Context, name, srcOnHost, dstInContainer string) error
+	CopyFrom(ctx context.Context, name, srcInContainer, dstOnHost string) error
+
+	ImageExists(tag string) bool
+	// ListImages returns images carrying the label key, or all images when
+	// label is empty.
+	ListImages(label string) ([]ImageSummary, error)
+	// SaveImage writes ref as a `docker save` tar stream.
+	SaveImage(ref string, w io.Writer) error
+	// LoadImage imports a `docker save` tar stream, restoring its tags, and
+	// returns the tags the stream held.
+	LoadImage(r io.Reader) ([]string, error)
+	RemoveImage(tag string, force bool) error
+	TagImage(srcTag, dstTag string) error
+	Pull(ref string) error
+	Build(tag, dockerfilePath, contextDir string, opts BuildOptions) error
+
+	// CreateVolume creates a named volume, succeeding if it already exists.
+	CreateVolume(name string, labels map[string]string) error
+	ListVolumes() ([]VolumeSummary, error)
+	RemoveVolume(name string) error
+	// VolumeSizes returns the disk usage of each volume by name. Sizes the
+	// runtime cannot report are omitted. This can be slow on large volumes.
+	VolumeSizes() (map[string]int64, error)
+}
+
+// RunOptions describes a detached container to create.
+type RunOptions struct {
+	Name          string
+	Workdir       string
+	Image         string
+	HostPort      int
+	ContainerPort int
+	Labels        map[string]string
+	Envs          map[string]string
+	ExtraHosts    []string
+	// ExtraPorts are published in addition to HostPort:ContainerPort.
+	ExtraPorts []PortMapping
+	// Volumes mounts named volumes into the container.
+	Volumes []VolumeMount
+	// SSHAuthSock, when set, forwards the host SSH agent into the container.
+	SSHAuthSock string
+	// Resource limits; empty values leave the engine defaults.
+	CPUs      string
+	Memory    string
+	PidsLimit int
+	ShmSize   string
+}
+
+// PortMapping publishes a container port on a loopback host port.
+type PortMapping struct {
+	HostPort      int
+	ContainerPort int
+}
+
+// ImageSummary is one row of an image listing.
+type ImageSummary struct {
+	ID        string
+	Tags      []string
+	Labels    map[string]string
+	CreatedAt time.Time
+	// Size is the image size in bytes, including shared layers.
+	Size int64
+}
+
+// VolumeMount mounts a named volume at Path inside a container.
+type VolumeMount struct {
+	Name string
+	Path string
+}
+
+// VolumeSummary is one row of a volume listing.
+type VolumeSummary struct {
+	Name      string
+	Labels    map[string]string
+	CreatedAt time.Time
+}
+
+// ExecOptions describes a command to run inside a container. Empty User or
+// Workdir leave the container defaults in place.
+type ExecOptions struct {
+	User    string
+	Workdir string
+	Envs    Envs
+	Argv    []string
+	// Combined interleaves stderr into the returned output.
+	Combined bool
+	// Stdout, when set, receives output as it is produced and Exec returns
+	// an empty string. Used for long-running commands such as tail -F.
+	Stdout io.Writer
+}
+
+// ContainerInfo is the subset of container inspect data dv relies on.
+type ContainerInfo struct {
+	ID         string
+	Name       string
+	Image      string
+	Running    bool
+	WorkingDir string
+	Env        []string
+	Labels     map[string]string
+	// Ports maps container TCP ports to their published host ports.
+	Ports     map[int]int
+	IPAddress string
+	// ExecSessions counts exec instances the engine still tracks, such as
+	// open dv enter shells. Finished ones linger for a few minutes.
+	ExecSessions int
+	// FinishedAt is when the container last stopped, zero if it never has.
+	FinishedAt time.Time
+}
+
+// ContainerEvent is a container lifecycle event.
+type ContainerEvent struct {
+	// Action is "start", "die", "destroy" or "rename".
+	Action string
+	Name   string
+	// Labels holds the container labels at the time of the event.
+	Labels map[string]string
+}
+
+// containerEventActions are the events Runtime.Events reports.
+var containerEventActions = []string{"start", "die", "destroy", "rename"}
+
+// ContainerSummary is one row of a container listing.
+type ContainerSummary struct {
+	Name    string
+	Image   string
+	Running bool
+	// Status is the human readable state, e.g. "Up 3 hours".
+	Status string
+	// Ports uses `docker ps` notation, e.g. "127.0.0.1:4201->4200/tcp".
+	Ports     string
+	Labels    map[string]string
+	CreatedAt time.Time
+}
+
+var (
+	currentMu sync.RWMutex
+	current   Runtime
+)
+
+// NewRuntime returns the runtime for kind; an empty kind means Docker.
+func NewRuntime(kind string) (Runtime, error) {
+	switch strings.ToLower(strings.TrimSpace(kind)) {
+	case "", RuntimeDocker:
+		return newAPIRuntime(cliRuntime{bin: RuntimeDocker}, candidateDockerHosts), nil
+	case RuntimePodman:
+		return newAPIRuntime(cliRuntime{bin: RuntimePodman}, candidatePodmanHosts), nil
+	default:
+		return nil, fmt.Errorf("unknown container runtime %q (expected %s or %s)", kind, RuntimeDocker, RuntimePodman)
+	}
+}
+
+// SetRuntime installs the runtime used by the package-level helpers.
+func SetRuntime(rt Runtime) {
+	currentMu.Lock()
+	defer currentMu.Unlock()
+	current = rt
+}
+
+// Current returns the installed runtime, defaulting to Docker.
+func Current() Runtime {
+	currentMu.RLock()
+	rt := current
+	currentMu.RUnlock()
+	if rt != nil {
+		return rt
+	}
+	currentMu.Lock()
+	defer currentMu.Unlock()
+	if current == nil {
+		current, _ = NewRuntime(RuntimeDocker)
+	}
+	return current
+}
+
+// Binary returns the CLI executable of the current runtime.
+func Binary() string {
+	return Current().Binary()
+}
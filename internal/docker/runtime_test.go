@@ -0,0 +1,261 @@
+package docker
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestParseLabelList(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		input    string
+		expected map[string]string
+	}{
+		{
+			name:     "empty string",
+			input:    "",
+			expected: map[string]string{},
+		},
+		{
+			name:     "whitespace only",
+			input:    "   ",
+			expected: map[string]string{},
+		},
+		{
+			name:     "single label",
+			input:    "key=value",
+			expected: map[string]string{"key": "value"},
+		},
+		{
+			name:     "multiple labels",
+			input:    "key1=value1,key2=value2,key3=value3",
+			expected: map[string]string{"key1": "value1", "key2": "value2", "key3": "value3"},
+		},
+		{
+			name:     "labels with spaces around commas",
+			input:    "key1=value1 , key2=value2",
+			expected: map[string]string{"key1": "value1", "key2": "value2"},
+		},
+		{
+			name:     "labels with spaces around equals",
+			input:    "key = value",
+			expected: map[string]string{"key": "value"},
+		},
+		{
+			name:     "value with equals sign",
+			input:    "url=https://example.com?foo=bar",
+			expected: map[string]string{"url": "https://example.com?foo=bar"},
+		},
+		{
+			name:     "value with multiple equals signs",
+			input:    "equation=a=b=c",
+			expected: map[string]string{"equation": "a=b=c"},
+		},
+		{
+			name:     "empty value",
+			input:    "key=",
+			expected: map[string]string{"key": ""},
+		},
+		{
+			name:     "malformed entry no equals",
+			input:    "noequals",
+			expected: map[string]string{},
+		},
+		{
+			name:     "mixed valid and malformed",
+			input:    "valid=yes,malformed,also=valid",
+			expected: map[string]string{"valid": "yes", "also": "valid"},
+		},
+		{
+			name:     "empty entries in sequence",
+			input:    "key1=value1,,key2=value2",
+			expected: map[string]string{"key1": "value1", "key2": "value2"},
+		},
+		{
+			name:     "trailing comma",
+			input:    "key=value,",
+			expected: map[string]string{"key": "value"},
+		},
+		{
+			name:     "leading comma",
+			input:    ",key=value",
+			expected: map[string]string{"key": "value"},
+		},
+		{
+			name:     "unicode in values",
+			input:    "greeting=héllo,name=世界",
+			expected: map[string]string{"greeting": "héllo", "name": "世界"},
+		},
+		{
+			name:     "docker label format example",
+			input:    "com.dv.owner=dv,com.dv.image-name=discourse,com.dv.image-tag=latest",
+			expected: map[string]string{"com.dv.owner": "dv", "com.dv.image-name": "discourse", "com.dv.image-tag": "latest"},
+		},
+		{
+			name:     "empty key ignored",
+			input:    "=value",
+			expected: map[string]string{},
+		},
+		{
+			name:     "whitespace key ignored",
+			input:    "  =value",
+			expected: map[string]string{},
+		},
+		{
+			name:     "duplicate keys last wins",
+			input:    "key=first,key=second",
+			expected: map[string]string{"key": "second"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			got := parseLabelList(tt.input)
+			if !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("parseLabelList(%q) = %v, want %v", tt.input, got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestNewRuntime(t *testing.T) {
+	t.Parallel()
+
+	for kind, want := range map[string]string{"": RuntimeDocker, "docker": RuntimeDocker, " Podman ": RuntimePodman} {
+		rt, err := NewRuntime(kind)
+		if err != nil {
+			t.Fatalf("NewRuntime(%q): %v", kind, err)
+		}
+		if rt.Name() != want || rt.Binary() != want {
+			t.Errorf("NewRuntime(%q) = %s (%s), want %s", kind, rt.Name(), rt.Binary(), want)
+		}
+	}
+	if _, err := NewRuntime("containerd"); err == nil {
+		t.Error("NewRuntime(containerd) succeeded, want error")
+	}
+}
+
+func TestDockerContext(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("DOCKER_CONFIG", dir)
+	t.Setenv("DOCKER_HOST", "")
+	t.Setenv("DOCKER_CONTEXT", "")
+
+	if got := dockerContext(); got != "default" {
+		t.Errorf("no config: %q, want default", got)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "config.json"), []byte(`{"currentContext": "remote"}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if got := dockerContext(); got != "remote" {
+		t.Errorf("config currentContext: %q, want remote", got)
+	}
+	t.Setenv("DOCKER_CONTEXT", "other")
+	if got := dockerContext(); got != "other" {
+		t.Errorf("DOCKER_CONTEXT: %q, want other", got)
+	}
+	t.Setenv("DOCKER_HOST", "unix:///var/run/docker.sock")
+	if got := dockerContext(); got != "default" {
+		t.Errorf("DOCKER_HOST: %q, want default", got)
+	}
+}
+
+func TestFormatPort(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		ip              string
+		host, ctr       int
+		proto, expected string
+	}{
+		{"127.0.0.1", 4201, 4200, "tcp", "127.0.0.1:4201->4200/tcp"},
+		{"", 4201, 4200, "", "0.0.0.0:4201->4200/tcp"},
+		{"", 0, 4200, "tcp", "4200/tcp"},
+	}
+	for _, tt := range tests {
+		if got := formatPort(tt.ip, tt.host, tt.ctr, tt.proto); got != tt.expected {
+			t.Errorf("formatPort(%q, %d, %d, %q) = %q, want %q", tt.ip, tt.host, tt.ctr, tt.proto, got, tt.expected)
+		}
+	}
+}
+
+func TestLabelChanges(t *testing.T) {
+	t.Parallel()
+
+	got := labelChanges(map[string]string{
+		"com.dv.snapshot.message": "say \"hi\"\nthen C:\\tmp",
+		"com.dv.snapshot":         "seeded",
+	})
+	want := []string{
+		`LABEL com.dv.snapshot="seeded"`,
+		`LABEL com.dv.snapshot.message="say \"hi\" then C:\\tmp"`,
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("labelChanges() = %q, want %q", got, want)
+	}
+}
+
+func TestLoadedImageRefs(t *testing.T) {
+	t.Parallel()
+
+	out := "Loaded image: dv-export:src\n" +
+		"Loaded image ID: sha256:0123\n" +
+		"Loaded image(s): localhost/dv-export:a,localhost/dv-export:b\n"
+	got := loadedImageRefs(out)
+	want := []string{"dv-export:src", "localhost/dv-export:a", "localhost/dv-export:b"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("loadedImageRefs() = %q, want %q", got, want)
+	}
+}
+
+func TestParseDFVolumes(t *testing.T) {
+	t.Parallel()
+
+	out := `Images space usage:
+
+REPOSITORY   TAG       IMAGE ID       CREATED       SIZE      SHARED SIZE   UNIQUE SIZE   CONTAINERS
+ai_agent     latest    0123456789ab   2 days ago    4.1GB     0B            4.1GB         1
+
+Local Volumes space usage:
+
+VOLUME NAME        LINKS     SIZE
+dv-ai-bundle       1         1.5GB
+dv-ai-node         1         312.4MB
+dv-unused          0         0B
+
+Build cache usage: 0B
+`
+	got := parseDFVolumes(out)
+	want := map[string]int64{"dv-ai-bundle": 1_500_000_000, "dv-ai-node": 312_400_000, "dv-unused": 0}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("parseDFVolumes = %v, want %v", got, want)
+	}
+}
+
+func TestParseHumanSize(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		in   string
+		want int64
+		ok   bool
+	}{
+		{"0B", 0, true},
+		{"512B", 512, true},
+		{"1.5kB", 1500, true},
+		{"2GB", 2_000_000_000, true},
+		{"1MiB", 1 << 20, true},
+		{"N/A", 0, false},
+	}
+	for _, tt := range tests {
+		got, ok := parseHumanSize(tt.in)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("parseHumanSize(%q) = %d, %v, want %d, %v", tt.in, got, ok, tt.want, tt.ok)
+		}
+	}
+}
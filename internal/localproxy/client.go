@@ -55,6 +55,30 @@ func (c *Client) Register(host string, target string) error {
 	return fmt.Errorf("proxy registration failed: %s", readErrorBody(resp.Body))
 }
 
+// Route is a registered proxy route. LastRequest is zero when the route has
+// not served a request, or the proxy predates request tracking.
+type Route struct {
+	Host        string    `json:"host"`
+	Target      string    `json:"target"`
+	LastRequest time.Time `json:"lastRequest"`
+}
+
+func (c *Client) Routes() ([]Route, error) {
+	resp, err := c.http.Get(c.baseURL + "/api/routes")
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("proxy route list failed: %s", readErrorBody(resp.Body))
+	}
+	var routes []Route
+	if err := json.NewDecoder(resp.Body).Decode(&routes); err != nil {
+		return nil, err
+	}
+	return routes, nil
+}
+
 func (c *Client) Remove(host string) error {
 	req, err := http.NewRequest(http.MethodDelete, c.baseURL+"/api/routes/"+host, nil)
 	if err != nil {
@@ -78,6 +78,11 @@ func RemoveRoute(cfg config.LocalProxyConfig, host string) error {
 	return client.Remove(host)
 }
 
+func Routes(cfg config.LocalProxyConfig) ([]Route, error) {
+	client := newClient(cfg)
+	return client.Routes()
+}
+
 func RouteFromLabels(labels map[string]string) (host string, port int, containerPort int, httpPort int, ok bool) {
 	host = strings.TrimSpace(labels[LabelHost])
 	portStr := strings.TrimSpace(labels[LabelTargetPort])
@@ -111,13 +111,13 @@ func EnsureContainer(configDir string, cfg config.LocalProxyConfig, recreate boo
 
 	args = append(args, cfg.ImageTag)
 
-	cmd := exec.Command("docker", args...)
+	cmd := exec.Command(docker.Binary(), args...)
 	cmd.Stdout, cmd.Stderr = os.Stdout, os.Stderr
 	return cmd.Run()
 }
 
 func updateRestartPolicy(name string) {
-	cmd := exec.Command("docker", "update", "--restart", "unless-stopped", name)
+	cmd := exec.Command(docker.Binary(), "update", "--restart", "unless-stopped", name)
 	cmd.Stdout, cmd.Stderr = os.Stdout, os.Stderr
 	_ = cmd.Run()
 }
@@ -0,0 +1,129 @@
+package localproxy
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"dv/internal/config"
+	"dv/internal/docker"
+)
+
+// SyncResult lists the hosts Reconcile changed.
+type SyncResult struct {
+	Added   []string
+	Updated []string
+	Removed []string
+}
+
+// Changed reports whether Reconcile touched any route.
+func (r SyncResult) Changed() bool {
+	return len(r.Added)+len(r.Updated)+len(r.Removed) > 0
+}
+
+// Reconcile makes the proxy's in-memory route table match the running
+// containers that carry route labels (see RouteFromLabels). Routes for
+// containers that are gone or stopped are removed. Errors for individual
+// routes are joined; the remaining routes are still synced.
+func Reconcile(cfg config.LocalProxyConfig) (SyncResult, error) {
+	var res SyncResult
+	containers, err := docker.ListContainers()
+	if err != nil {
+		return res, err
+	}
+	routes, err := Routes(cfg)
+	if err != nil {
+		return res, err
+	}
+	current := map[string]string{}
+	for _, r := range routes {
+		current[r.Host] = r.Target
+	}
+
+	suffix := cfg.Hostname
+	if strings.TrimSpace(suffix) == "" {
+		suffix = "dv.localhost"
+	}
+	var errs []error
+	desired := map[string]bool{}
+	for _, ctr := range containers {
+		if !ctr.Running || ctr.Name == cfg.ContainerName {
+			continue
+		}
+		host, _, containerPort, _, ok := RouteFromLabels(ctr.Labels)
+		if !ok || !strings.HasSuffix(host, "."+suffix) {
+			continue
+		}
+		ip, err := docker.ContainerIP(ctr.Name)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		desired[host] = true
+		target := fmt.Sprintf("http://%s:%d", ip, containerPort)
+		if current[host] == target {
+			continue
+		}
+		if err := RegisterRoute(cfg, host, target); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", host, err))
+			continue
+		}
+		if _, existed := current[host]; existed {
+			res.Updated = append(res.Updated, host)
+		} else {
+			res.Added = append(res.Added, host)
+		}
+	}
+	for host := range current {
+		if desired[host] {
+			continue
+		}
+		if err := RemoveRoute(cfg, host); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", host, err))
+			continue
+		}
+		res.Removed = append(res.Removed, host)
+	}
+	sort.Strings(res.Added)
+	sort.Strings(res.Updated)
+	sort.Strings(res.Removed)
+	return res, errors.Join(errs...)
+}
+
+// Watch reconciles the route table now and again whenever a container
+// starts, stops, is removed or renamed, including the proxy itself, whose
+// table is empty after a restart. It returns when ctx is cancelled or the
+// event stream ends.
+func Watch(ctx context.Context, cfg config.LocalProxyConfig, logf func(format string, args ...any)) error {
+	reconcile := func() {
+		if !Running(cfg) {
+			return
+		}
+		res, err := Reconcile(cfg)
+		if err != nil {
+			logf("route sync: %v", err)
+		}
+		if res.Changed() {
+			logf("route sync: added %v, updated %v, removed %v", res.Added, res.Updated, res.Removed)
+		}
+	}
+	reconcile()
+	return docker.Events(ctx, func(ev docker.ContainerEvent) {
+		if ev.Name == cfg.ContainerName {
+			if ev.Action != "start" {
+				return
+			}
+			// A restarted proxy needs a moment before its API answers.
+			if err := Healthy(cfg, 10*time.Second); err != nil {
+				logf("route sync: %v", err)
+				return
+			}
+		} else if _, ok := ev.Labels[LabelHost]; !ok {
+			return
+		}
+		reconcile()
+	})
+}
@@ -0,0 +1,172 @@
+package localproxy
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"dv/internal/config"
+	"dv/internal/docker"
+	"dv/internal/docker/dockertest"
+)
+
+// fakeProxy serves the proxy's admin API from an in-memory route table.
+type fakeProxy struct {
+	mu     sync.Mutex
+	routes map[string]string
+}
+
+func (p *fakeProxy) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	switch {
+	case r.URL.Path == "/healthz":
+	case r.URL.Path == "/api/routes" && r.Method == http.MethodGet:
+		list := []Route{}
+		for host, target := range p.routes {
+			list = append(list, Route{Host: host, Target: target})
+		}
+		json.NewEncoder(w).Encode(list)
+	case r.URL.Path == "/api/routes" && r.Method == http.MethodPost:
+		var rt Route
+		json.NewDecoder(r.Body).Decode(&rt)
+		p.routes[rt.Host] = rt.Target
+		w.WriteHeader(http.StatusCreated)
+	case r.Method == http.MethodDelete:
+		delete(p.routes, strings.TrimPrefix(r.URL.Path, "/api/routes/"))
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func (p *fakeProxy) table() map[string]string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	out := map[string]string{}
+	for k, v := range p.routes {
+		out[k] = v
+	}
+	return out
+}
+
+func (p *fakeProxy) reset(routes map[string]string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.routes = routes
+}
+
+func routeLabels(name string) map[string]string {
+	return map[string]string{
+		LabelEnabled:       "true",
+		LabelHost:          name + ".dv.localhost",
+		LabelTargetPort:    "4201",
+		LabelContainerPort: "4200",
+	}
+}
+
+func newSyncFixture(t *testing.T) (*dockertest.Runtime, *fakeProxy, config.LocalProxyConfig) {
+	t.Helper()
+	proxy := &fakeProxy{routes: map[string]string{}}
+	srv := httptest.NewServer(proxy)
+	t.Cleanup(srv.Close)
+	_, port, _ := net.SplitHostPort(strings.TrimPrefix(srv.URL, "http://"))
+
+	rt := dockertest.New()
+	docker.SetRuntime(rt)
+	t.Cleanup(func() { docker.SetRuntime(nil) })
+
+	cfg := config.LocalProxyConfig{Enabled: true, ContainerName: "dv-local-proxy", Hostname: "dv.localhost"}
+	cfg.APIPort, _ = strconv.Atoi(port)
+	rt.AddContainer(dockertest.Container{Name: cfg.ContainerName, Running: true})
+	return rt, proxy, cfg
+}
+
+func TestReconcile(t *testing.T) {
+	rt, proxy, cfg := newSyncFixture(t)
+	rt.AddContainer(dockertest.Container{Name: "a", Running: true, Labels: routeLabels("a")})
+	rt.AddContainer(dockertest.Container{Name: "b", Running: true, Labels: routeLabels("b")})
+	rt.AddContainer(dockertest.Container{Name: "stopped", Labels: routeLabels("stopped")})
+	rt.AddContainer(dockertest.Container{Name: "plain", Running: true})
+	proxy.reset(map[string]string{
+		"a.dv.localhost":    "http://172.17.0.9:4200",
+		"gone.dv.localhost": "http://172.17.0.5:4200",
+	})
+
+	res, err := Reconcile(cfg)
+	if err != nil {
+		t.Fatalf("Reconcile: %v", err)
+	}
+	want := SyncResult{Added: []string{"b.dv.localhost"}, Updated: []string{"a.dv.localhost"}, Removed: []string{"gone.dv.localhost"}}
+	if !reflect.DeepEqual(res, want) {
+		t.Errorf("Reconcile = %+v, want %+v", res, want)
+	}
+	wantTable := map[string]string{
+		"a.dv.localhost": "http://172.17.0.2:4200",
+		"b.dv.localhost": "http://172.17.0.2:4200",
+	}
+	if got := proxy.table(); !reflect.DeepEqual(got, wantTable) {
+		t.Errorf("routes = %v, want %v", got, wantTable)
+	}
+
+	if res, err := Reconcile(cfg); err != nil || res.Changed() {
+		t.Errorf("second Reconcile = %+v, %v; want no changes", res, err)
+	}
+}
+
+func TestWatchRestoresRoutes(t *testing.T) {
+	rt, proxy, cfg := newSyncFixture(t)
+	rt.AddContainer(dockertest.Container{Name: "a", Running: true, Labels: routeLabels("a")})
+	rt.AddContainer(dockertest.Container{Name: "b", Labels: routeLabels("b")})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- Watch(ctx, cfg, t.Logf) }()
+	defer func() {
+		cancel()
+		<-done
+	}()
+
+	// eventually repeats poke until cond holds, since events sent before
+	// Watch subscribes are not delivered.
+	eventually := func(what string, poke func(), cond func(map[string]string) bool) {
+		t.Helper()
+		deadline := time.Now().Add(5 * time.Second)
+		for !cond(proxy.table()) {
+			if time.Now().After(deadline) {
+				t.Fatalf("%s: routes = %v", what, proxy.table())
+			}
+			poke()
+			time.Sleep(20 * time.Millisecond)
+		}
+	}
+	has := func(host string) func(map[string]string) bool {
+		return func(m map[string]string) bool { _, ok := m[host]; return ok }
+	}
+
+	eventually("initial sync", func() {}, has("a.dv.localhost"))
+	eventually("agent start", func() {
+		_ = rt.Stop("b")
+		_ = rt.Start("b")
+	}, has("b.dv.localhost"))
+
+	// A restarted proxy comes back with an empty table.
+	proxy.reset(map[string]string{})
+	eventually("proxy restart", func() {
+		_ = rt.Stop(cfg.ContainerName)
+		_ = rt.Start(cfg.ContainerName)
+	}, func(m map[string]string) bool { return len(m) == 2 })
+
+	eventually("agent stop", func() { _ = rt.Stop("a") }, func(m map[string]string) bool {
+		_, ok := m["a.dv.localhost"]
+		return !ok
+	})
+}
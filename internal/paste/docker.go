@@ -37,7 +37,7 @@ func ExecWithPaste(cfg DockerExecConfig) error {
 	}
 
 	// Ensure the temp directory exists in the container
-	mkdirCmd := exec.Command("docker", "exec", "--user", cfg.User, cfg.ContainerName,
+	mkdirCmd := exec.Command(docker.Binary(), "exec", "--user", cfg.User, cfg.ContainerName,
 		"mkdir", "-p", cfg.ImageTempDir)
 	mkdirCmd.Run() // Ignore errors, directory might already exist
 
@@ -56,7 +56,7 @@ func ExecWithPaste(cfg DockerExecConfig) error {
 	args = append(args, cfg.ContainerName)
 	args = append(args, cfg.Argv...)
 
-	cmd := exec.Command("docker", args...)
+	cmd := exec.Command(docker.Binary(), args...)
 
 	if !isTTY {
 		// No TTY, fall back to simple exec without paste interception
@@ -87,14 +87,14 @@ func ExecWithPaste(cfg DockerExecConfig) error {
 		tmpFile.Close()
 
 		// Copy to container
-		cpCmd := exec.Command("docker", "cp", tmpFile.Name(),
+		cpCmd := exec.Command(docker.Binary(), "cp", tmpFile.Name(),
 			fmt.Sprintf("%s:%s", cfg.ContainerName, containerPath))
 		if err := cpCmd.Run(); err != nil {
 			return "", err
 		}
 
 		// Set ownership
-		chownCmd := exec.Command("docker", "exec", "--user", "root", cfg.ContainerName,
+		chownCmd := exec.Command(docker.Binary(), "exec", "--user", "root", cfg.ContainerName,
 			"chown", cfg.User+":"+cfg.User, containerPath)
 		chownCmd.Run() // Best effort
 
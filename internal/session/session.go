@@ -12,7 +12,8 @@ import (
 
 // State holds per-terminal session selections.
 type State struct {
-	Sessions map[int]string `json:"sessions"` // SID -> agent name
+	Sessions map[int]string `json:"sessions"`           // SID -> agent name
+	Profiles map[int]string `json:"profiles,omitempty"` // SID -> profile name
 }
 
 func sessionsPath() (string, error) {
@@ -43,7 +44,7 @@ func Load() (*State, error) {
 
 	data, err := os.ReadFile(path)
 	if os.IsNotExist(err) {
-		return &State{Sessions: make(map[int]string)}, nil
+		return &State{Sessions: make(map[int]string), Profiles: make(map[int]string)}, nil
 	}
 	if err != nil {
 		return nil, err
@@ -51,11 +52,14 @@ func Load() (*State, error) {
 
 	var state State
 	if err := json.Unmarshal(data, &state); err != nil {
-		return &State{Sessions: make(map[int]string)}, nil
+		return &State{Sessions: make(map[int]string), Profiles: make(map[int]string)}, nil
 	}
 	if state.Sessions == nil {
 		state.Sessions = make(map[int]string)
 	}
+	if state.Profiles == nil {
+		state.Profiles = make(map[int]string)
+	}
 	return &state, nil
 }
 
@@ -72,6 +76,11 @@ func (s *State) Save() error {
 			delete(s.Sessions, sid)
 		}
 	}
+	for sid := range s.Profiles {
+		if !processExists(sid) {
+			delete(s.Profiles, sid)
+		}
+	}
 
 	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
 		return err
@@ -124,3 +133,50 @@ func SetCurrentAgent(agent string) error {
 	state.Set(sid, agent)
 	return state.Save()
 }
+
+// GetProfile returns the profile for the given session ID, or empty if not
+// found/stale.
+func (s *State) GetProfile(sid int) string {
+	if !processExists(sid) {
+		delete(s.Profiles, sid)
+		return ""
+	}
+	return s.Profiles[sid]
+}
+
+// SetProfile stores the profile for the given session ID. An empty name
+// clears it.
+func (s *State) SetProfile(sid int, profile string) {
+	if profile == "" {
+		delete(s.Profiles, sid)
+		return
+	}
+	s.Profiles[sid] = profile
+}
+
+// GetCurrentProfile returns the profile for the current terminal session.
+func GetCurrentProfile() string {
+	sid, err := CurrentSID()
+	if err != nil {
+		return ""
+	}
+	state, err := Load()
+	if err != nil {
+		return ""
+	}
+	return state.GetProfile(sid)
+}
+
+// SetCurrentProfile sets the profile for the current terminal session.
+func SetCurrentProfile(profile string) error {
+	sid, err := CurrentSID()
+	if err != nil {
+		return err
+	}
+	state, err := Load()
+	if err != nil {
+		return err
+	}
+	state.SetProfile(sid, profile)
+	return state.Save()
+}
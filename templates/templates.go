@@ -0,0 +1,8 @@
+// Package templates embeds the templates in this directory, which dv offers
+// as built-in templates for dv new --template.
+package templates
+
+import "embed"
+
+//go:embed *.yaml
+var FS embed.FS